@@ -0,0 +1,89 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
+	persistenceRegistry "github.com/hyperledger/firefly-transaction-manager/pkg/persistence/registry"
+	"github.com/spf13/cobra"
+)
+
+var dbPath string
+var archiveFile string
+
+func DBCommand() *cobra.Command {
+	return buildDBCommand(openPersistence)
+}
+
+func buildDBCommand(persistenceFactory func(ctx context.Context) (persistence.Persistence, error)) *cobra.Command {
+	dbCmd := &cobra.Command{
+		Use:   "db <subcommand>",
+		Short: "Check, repair, export and import the persistence of a blockchain connector instance, while it is stopped",
+	}
+
+	dbCmd.PersistentFlags().StringVarP(&dbPath, "path", "", "", "The path of the LevelDB persistence directory, if not set in the configuration")
+
+	dbCmd.AddCommand(dbCheckCommand(persistenceFactory))
+	dbCmd.AddCommand(dbRepairCommand(persistenceFactory))
+	dbCmd.AddCommand(dbExportCommand(persistenceFactory))
+	dbCmd.AddCommand(dbImportCommand(persistenceFactory))
+
+	return dbCmd
+}
+
+func openPersistence(ctx context.Context) (persistence.Persistence, error) {
+	pType := config.GetString(tmconfig.PersistenceType)
+	if dbPath != "" {
+		config.Set(tmconfig.PersistenceLevelDBPath, dbPath)
+	}
+	p, builtin, err := persistence.NewBuiltinPersistence(ctx, pType)
+	if err == nil && !builtin {
+		p, err = persistenceRegistry.NewPersistence(ctx, tmconfig.PersistenceBaseConfig, pType)
+	}
+	return p, err
+}
+
+func runIntegrityCheck(persistenceFactory func(ctx context.Context) (persistence.Persistence, error), repair bool) error {
+	ctx := context.Background()
+	p, err := persistenceFactory(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Close(ctx)
+	checker, ok := p.(persistence.IntegrityChecker)
+	if !ok {
+		return i18n.NewError(ctx, tmmsgs.MsgIntegrityCheckNotSupported, config.GetString(tmconfig.PersistenceType))
+	}
+	issues, err := checker.CheckIntegrity(ctx, repair)
+	if err != nil {
+		return err
+	}
+	json, _ := json.MarshalIndent(issues, "", "  ")
+	fmt.Println(string(json))
+	if !repair && len(issues) > 0 {
+		return i18n.NewError(ctx, tmmsgs.MsgIntegrityIssuesFound, len(issues))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/spf13/cobra"
+)
+
+func dbCheckCommand(persistenceFactory func(ctx context.Context) (persistence.Persistence, error)) *cobra.Command {
+	dbCheckCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Report dangling or missing indexes, and checkpoints or listeners for deleted event streams",
+		Long:  "",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runIntegrityCheck(persistenceFactory, false)
+		},
+	}
+	return dbCheckCmd
+}
@@ -0,0 +1,54 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/spf13/cobra"
+)
+
+func dbExportCommand(persistenceFactory func(ctx context.Context) (persistence.Persistence, error)) *cobra.Command {
+	dbExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export all event streams, listeners, checkpoints and transactions to an NDJSON archive",
+		Long:  "",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			var w io.Writer = os.Stdout
+			if archiveFile != "" {
+				f, err := os.Create(archiveFile)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			p, err := persistenceFactory(ctx)
+			if err != nil {
+				return err
+			}
+			defer p.Close(ctx)
+			return persistence.ExportArchive(ctx, p, w)
+		},
+	}
+	dbExportCmd.Flags().StringVarP(&archiveFile, "file", "f", "", "The archive file to write (defaults to stdout)")
+	return dbExportCmd
+}
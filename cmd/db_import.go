@@ -0,0 +1,65 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hyperledger/firefly-transaction-manager/internal/events"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/spf13/cobra"
+)
+
+func dbImportCommand(persistenceFactory func(ctx context.Context) (persistence.Persistence, error)) *cobra.Command {
+	dbImportCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import an NDJSON archive created by export. Records that already exist are skipped",
+		Long:  "",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			var r io.Reader = os.Stdin
+			if archiveFile != "" {
+				f, err := os.Open(archiveFile)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+			p, err := persistenceFactory(ctx)
+			if err != nil {
+				return err
+			}
+			defer p.Close(ctx)
+			// Imported event streams get the same defaults from the configuration as a new event stream
+			events.InitDefaults()
+			result, err := persistence.ImportArchive(ctx, p, r, events.ValidateEventStream)
+			if err != nil {
+				return err
+			}
+			json, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Println(string(json))
+			return nil
+		},
+	}
+	dbImportCmd.Flags().StringVarP(&archiveFile, "file", "f", "", "The archive file to read (defaults to stdin)")
+	return dbImportCmd
+}
@@ -0,0 +1,206 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/dbsql"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDBCommand(t *testing.T) {
+	cmd := DBCommand()
+	err := cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func TestDBCheckAndRepair(t *testing.T) {
+	tmconfig.Reset()
+	dir := t.TempDir()
+
+	// Write a listener for a stream that does not exist
+	config.Set(tmconfig.PersistenceLevelDBPath, dir)
+	p, err := persistence.NewLevelDBPersistence(context.Background())
+	assert.NoError(t, err)
+	err = p.WriteListener(context.Background(), &apitypes.Listener{ID: apitypes.NewULID(), StreamID: apitypes.NewULID()})
+	assert.NoError(t, err)
+	p.Close(context.Background())
+	tmconfig.Reset()
+
+	cmd := DBCommand()
+	cmd.SetArgs([]string{"check", "--path", dir})
+	err = cmd.Execute()
+	assert.Regexp(t, "FF21082", err)
+
+	cmd = DBCommand()
+	cmd.SetArgs([]string{"repair", "--path", dir})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	cmd = DBCommand()
+	cmd.SetArgs([]string{"check", "--path", dir})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func TestDBCheckUnsupportedType(t *testing.T) {
+	tmconfig.Reset()
+	config.Set(tmconfig.PersistenceType, "sqlite3")
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfDatasourceURL, fmt.Sprintf("file:%s/fftm.db", t.TempDir()))
+	defer tmconfig.Reset()
+
+	cmd := DBCommand()
+	cmd.SetArgs([]string{"check"})
+	err := cmd.Execute()
+	assert.Regexp(t, "FF21081", err)
+}
+
+func TestDBOpenConfiguredTypeFail(t *testing.T) {
+	tmconfig.Reset()
+	config.Set(tmconfig.PersistenceType, "postgres")
+	defer tmconfig.Reset()
+
+	cmd := DBCommand()
+	cmd.SetArgs([]string{"export"})
+	err := cmd.Execute()
+	assert.Regexp(t, "FF21049", err)
+}
+
+func TestDBOpenUnknownType(t *testing.T) {
+	tmconfig.Reset()
+	config.Set(tmconfig.PersistenceType, "unknown")
+	defer tmconfig.Reset()
+
+	cmd := DBCommand()
+	cmd.SetArgs([]string{"import"})
+	err := cmd.Execute()
+	assert.Regexp(t, "FF21043", err)
+}
+
+func TestDBExportImport(t *testing.T) {
+	tmconfig.Reset()
+	srcDir := t.TempDir()
+	archive := fmt.Sprintf("%s/archive.ndjson", t.TempDir())
+
+	config.Set(tmconfig.PersistenceLevelDBPath, srcDir)
+	p, err := persistence.NewLevelDBPersistence(context.Background())
+	assert.NoError(t, err)
+	name := "stream1"
+	es := &apitypes.EventStream{ID: apitypes.NewULID(), Name: &name}
+	err = p.WriteStream(context.Background(), es)
+	assert.NoError(t, err)
+	p.Close(context.Background())
+	tmconfig.Reset()
+
+	cmd := DBCommand()
+	cmd.SetArgs([]string{"export", "--path", srcDir, "--file", archive})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	dstDir := t.TempDir()
+	cmd = DBCommand()
+	cmd.SetArgs([]string{"import", "--path", dstDir, "--file", archive})
+	err = cmd.Execute()
+	assert.NoError(t, err)
+
+	config.Set(tmconfig.PersistenceLevelDBPath, dstDir)
+	p, err = persistence.NewLevelDBPersistence(context.Background())
+	assert.NoError(t, err)
+	defer p.Close(context.Background())
+	es2, err := p.GetStream(context.Background(), es.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "stream1", *es2.Name)
+	// The defaults of a new event stream are applied on import
+	assert.Equal(t, apitypes.EventStreamTypeWebSocket, *es2.Type)
+}
+
+func TestDBExportBadFile(t *testing.T) {
+	cmd := buildDBCommand(func(ctx context.Context) (persistence.Persistence, error) { return nil, fmt.Errorf("pop") })
+	cmd.SetArgs([]string{"export", "--file", t.TempDir()})
+	err := cmd.Execute()
+	assert.Error(t, err)
+
+	cmd = buildDBCommand(func(ctx context.Context) (persistence.Persistence, error) { return nil, fmt.Errorf("pop") })
+	cmd.SetArgs([]string{"export", "--file", fmt.Sprintf("%s/archive.ndjson", t.TempDir())})
+	err = cmd.Execute()
+	assert.Regexp(t, "pop", err)
+}
+
+func TestDBImportBadFile(t *testing.T) {
+	cmd := buildDBCommand(func(ctx context.Context) (persistence.Persistence, error) { return nil, fmt.Errorf("pop") })
+	cmd.SetArgs([]string{"import", "--file", fmt.Sprintf("%s/missing.ndjson", t.TempDir())})
+	err := cmd.Execute()
+	assert.Error(t, err)
+
+	archive := fmt.Sprintf("%s/archive.ndjson", t.TempDir())
+	err = os.WriteFile(archive, []byte("{}"), 0600)
+	assert.NoError(t, err)
+
+	cmd = buildDBCommand(func(ctx context.Context) (persistence.Persistence, error) { return nil, fmt.Errorf("pop") })
+	cmd.SetArgs([]string{"import", "--file", archive})
+	err = cmd.Execute()
+	assert.Regexp(t, "pop", err)
+
+	mp := &persistencemocks.Persistence{}
+	mp.On("Close", mock.Anything).Return()
+	cmd = buildDBCommand(func(ctx context.Context) (persistence.Persistence, error) { return mp, nil })
+	cmd.SetArgs([]string{"import", "--file", archive})
+	err = cmd.Execute()
+	assert.Regexp(t, "FF21083", err)
+}
+
+func TestDBCheckNotChecker(t *testing.T) {
+	mp := &persistencemocks.Persistence{}
+	mp.On("Close", mock.Anything).Return()
+	cmd := buildDBCommand(func(ctx context.Context) (persistence.Persistence, error) { return mp, nil })
+	cmd.SetArgs([]string{"check"})
+	err := cmd.Execute()
+	assert.Regexp(t, "FF21081", err)
+	mp.AssertExpectations(t)
+}
+
+func TestDBCheckOpenFail(t *testing.T) {
+	cmd := buildDBCommand(func(ctx context.Context) (persistence.Persistence, error) { return nil, fmt.Errorf("pop") })
+	cmd.SetArgs([]string{"repair"})
+	err := cmd.Execute()
+	assert.Regexp(t, "pop", err)
+}
+
+func TestDBCheckFail(t *testing.T) {
+	tmconfig.Reset()
+	dir := t.TempDir()
+	config.Set(tmconfig.PersistenceLevelDBPath, dir)
+	cmd := buildDBCommand(func(ctx context.Context) (persistence.Persistence, error) {
+		p, err := persistence.NewLevelDBPersistence(ctx)
+		assert.NoError(t, err)
+		p.Close(ctx) // closed before the check runs
+		return p, nil
+	})
+	cmd.SetArgs([]string{"check"})
+	err := cmd.Execute()
+	assert.Regexp(t, "FF21055", err)
+}
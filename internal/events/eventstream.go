@@ -175,6 +175,19 @@ func (es *eventStream) initAction(startedState *startedStreamState) error {
 	return nil
 }
 
+// ValidateEventStream applies the same validation and defaults to an existing event stream definition, such as one
+// read from an archive, as are applied when an event stream is created. The ID and times of the definition are kept.
+func ValidateEventStream(ctx context.Context, def *apitypes.EventStream) (*apitypes.EventStream, error) {
+	merged, _, err := mergeValidateEsConfig(ctx, nil, def)
+	if err != nil {
+		return nil, err
+	}
+	if def.Updated != nil {
+		merged.Updated = def.Updated
+	}
+	return merged, nil
+}
+
 func mergeValidateEsConfig(ctx context.Context, base *apitypes.EventStream, updates *apitypes.EventStream) (merged *apitypes.EventStream, changed bool, err error) {
 
 	// Merged is assured to not have any unset values (default set in all cases), or any EthCompat fields
@@ -208,6 +208,28 @@ func TestConfigNewDefaultsUpdate(t *testing.T) {
 
 }
 
+func TestValidateEventStream(t *testing.T) {
+	tmconfig.Reset()
+	InitDefaults()
+
+	updated := fftypes.FFTime(time.Now().Add(-1 * time.Hour))
+	es := testESConf(t, `{
+		"name": "test1"
+	}`)
+	es.Updated = &updated
+	validated, err := ValidateEventStream(context.Background(), es)
+	assert.NoError(t, err)
+	assert.Equal(t, es.ID, validated.ID)
+	assert.Equal(t, &updated, validated.Updated)
+	assert.False(t, *validated.Suspended)
+	assert.Equal(t, apitypes.EventStreamTypeWebSocket, *validated.Type)
+
+	es.Name = nil
+	_, err = ValidateEventStream(context.Background(), es)
+	assert.Regexp(t, "FF21028", err)
+
+}
+
 func TestConfigNewMissingWebhookConf(t *testing.T) {
 	tmconfig.Reset()
 	InitDefaults()
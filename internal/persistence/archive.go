@@ -0,0 +1,295 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+)
+
+const archivePageSize = 50
+
+// StreamValidator applies the validation and defaults of event stream creation to an event stream read from an
+// archive. It is supplied by the caller, as the persistence layer does not depend on the event streams.
+type StreamValidator func(ctx context.Context, es *apitypes.EventStream) (*apitypes.EventStream, error)
+
+// ExportArchive streams the full state held in the persistence into the supplied writer, as an NDJSON archive.
+// Only the Persistence interface is used, so any persistence type can be exported.
+func ExportArchive(ctx context.Context, p Persistence, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	write := func(r *apitypes.ArchiveRecord) error {
+		if err := enc.Encode(r); err != nil {
+			return i18n.WrapError(ctx, err, tmmsgs.MsgArchiveExportFailed)
+		}
+		return nil
+	}
+
+	if err := write(&apitypes.ArchiveRecord{
+		Type:    apitypes.ArchiveRecordHeader,
+		Version: apitypes.ArchiveVersion,
+		Created: fftypes.Now(),
+	}); err != nil {
+		return err
+	}
+
+	var lastStream *fftypes.UUID
+	for {
+		streams, err := p.ListStreams(ctx, lastStream, archivePageSize, SortDirectionAscending)
+		if err != nil {
+			return err
+		}
+		if len(streams) == 0 {
+			break
+		}
+		for _, es := range streams {
+			lastStream = es.ID
+			if err := write(&apitypes.ArchiveRecord{Type: apitypes.ArchiveRecordEventStream, EventStream: es}); err != nil {
+				return err
+			}
+			cp, err := p.GetCheckpoint(ctx, es.ID)
+			if err == nil && cp != nil {
+				err = write(&apitypes.ArchiveRecord{Type: apitypes.ArchiveRecordCheckpoint, Checkpoint: cp})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var lastListener *fftypes.UUID
+	for {
+		listeners, err := p.ListListeners(ctx, lastListener, archivePageSize, SortDirectionAscending)
+		if err != nil {
+			return err
+		}
+		if len(listeners) == 0 {
+			break
+		}
+		for _, l := range listeners {
+			lastListener = l.ID
+			if err := write(&apitypes.ArchiveRecord{Type: apitypes.ArchiveRecordListener, Listener: l}); err != nil {
+				return err
+			}
+		}
+	}
+
+	var lastSigner string
+	for {
+		minimums, err := p.ListSignerMinimumNonces(ctx, lastSigner, archivePageSize, SortDirectionAscending)
+		if err != nil {
+			return err
+		}
+		if len(minimums) == 0 {
+			break
+		}
+		for _, m := range minimums {
+			lastSigner = m.Signer
+			if err := write(&apitypes.ArchiveRecord{Type: apitypes.ArchiveRecordSignerMinimumNonce, SignerMinimumNonce: m}); err != nil {
+				return err
+			}
+			if err := exportNonceReservations(ctx, p, m.Signer, write); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Transactions are exported in creation order, so they are re-sequenced in the same order on import
+	var lastTX *apitypes.ManagedTX
+	for {
+		txns, err := p.ListTransactionsByCreateTime(ctx, lastTX, archivePageSize, SortDirectionAscending)
+		if err != nil {
+			return err
+		}
+		if len(txns) == 0 {
+			break
+		}
+		for _, tx := range txns {
+			lastTX = tx
+			if err := write(&apitypes.ArchiveRecord{Type: apitypes.ArchiveRecordTransaction, Transaction: tx}); err != nil {
+				return err
+			}
+			if err := exportTransactionHistory(ctx, p, tx.ID, write); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func exportTransactionHistory(ctx context.Context, p Persistence, txID string, write func(r *apitypes.ArchiveRecord) error) error {
+	var lastRecord *fftypes.UUID
+	for {
+		records, err := p.ListTransactionHistory(ctx, txID, lastRecord, archivePageSize, SortDirectionAscending)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		for _, r := range records {
+			lastRecord = r.ID
+			if err := write(&apitypes.ArchiveRecord{Type: apitypes.ArchiveRecordTransactionHistory, TXHistory: r}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// exportNonceReservations writes the reservations of a signer. Every reservation raises the minimum nonce of
+// its signer, so exporting the reservations of each signer with a minimum nonce exports all of them.
+func exportNonceReservations(ctx context.Context, p Persistence, signer string, write func(r *apitypes.ArchiveRecord) error) error {
+	var lastFirst *fftypes.FFBigInt
+	for {
+		reservations, err := p.ListNonceReservations(ctx, signer, lastFirst, archivePageSize, SortDirectionAscending)
+		if err != nil {
+			return err
+		}
+		if len(reservations) == 0 {
+			return nil
+		}
+		for _, r := range reservations {
+			lastFirst = r.First
+			if err := write(&apitypes.ArchiveRecord{Type: apitypes.ArchiveRecordNonceReservation, NonceReservation: r}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ImportArchive restores an NDJSON archive written by ExportArchive into the supplied persistence.
+// Records that already exist (by ID, or by signer for the nonce records) are skipped, so an import can safely be
+// re-run after a failure.
+// Event streams are validated with the supplied validator, which also sets the defaults for any unset fields.
+func ImportArchive(ctx context.Context, p Persistence, r io.Reader, validateStream StreamValidator) (*apitypes.ArchiveImportResult, error) {
+	result := &apitypes.ArchiveImportResult{}
+	importedTXs := make(map[string]bool)
+	dec := json.NewDecoder(r)
+	for recordNumber := 1; ; recordNumber++ {
+		var record apitypes.ArchiveRecord
+		if err := dec.Decode(&record); err != nil {
+			if err == io.EOF && recordNumber > 1 {
+				break
+			}
+			if err == io.EOF {
+				return nil, i18n.NewError(ctx, tmmsgs.MsgArchiveMissingHeader)
+			}
+			return nil, i18n.NewError(ctx, tmmsgs.MsgArchiveInvalidRecord, recordNumber, err)
+		}
+		if recordNumber == 1 {
+			if record.Type != apitypes.ArchiveRecordHeader {
+				return nil, i18n.NewError(ctx, tmmsgs.MsgArchiveMissingHeader)
+			}
+			if record.Version > apitypes.ArchiveVersion {
+				return nil, i18n.NewError(ctx, tmmsgs.MsgArchiveVersionUnsupported, record.Version, apitypes.ArchiveVersion)
+			}
+			continue
+		}
+		if err := importArchiveRecord(ctx, p, recordNumber, &record, result, importedTXs, validateStream); err != nil {
+			return nil, err
+		}
+	}
+	log.L(ctx).Infof("Imported archive: %+v", result)
+	return result, nil
+}
+
+// importArchiveRecord imports a single record. The history of a transaction is only imported if the transaction
+// itself was imported, so the history of an existing transaction is never modified.
+func importArchiveRecord(ctx context.Context, p Persistence, recordNumber int, record *apitypes.ArchiveRecord, result *apitypes.ArchiveImportResult, importedTXs map[string]bool, validateStream StreamValidator) (err error) {
+	var count *apitypes.ArchiveImportCount
+	exists := false
+	switch {
+	case record.Type == apitypes.ArchiveRecordEventStream && record.EventStream != nil && record.EventStream.ID != nil:
+		count = &result.EventStreams
+		var es *apitypes.EventStream
+		if es, err = validateStream(ctx, record.EventStream); err != nil {
+			return i18n.NewError(ctx, tmmsgs.MsgArchiveInvalidRecord, recordNumber, err)
+		}
+		var existing *apitypes.EventStream
+		if existing, err = p.GetStream(ctx, es.ID); err == nil && existing == nil {
+			err = p.WriteStream(ctx, es)
+		}
+		exists = existing != nil
+	case record.Type == apitypes.ArchiveRecordCheckpoint && record.Checkpoint != nil && record.Checkpoint.StreamID != nil:
+		count = &result.Checkpoints
+		var existing *apitypes.EventStreamCheckpoint
+		if existing, err = p.GetCheckpoint(ctx, record.Checkpoint.StreamID); err == nil && existing == nil {
+			err = p.WriteCheckpoint(ctx, record.Checkpoint)
+		}
+		exists = existing != nil
+	case record.Type == apitypes.ArchiveRecordListener && record.Listener != nil && record.Listener.ID != nil && record.Listener.StreamID != nil:
+		count = &result.Listeners
+		var existing *apitypes.Listener
+		if existing, err = p.GetListener(ctx, record.Listener.ID); err == nil && existing == nil {
+			if err = p.WriteListener(ctx, record.Listener); err == nil {
+				result.ImportedListeners = append(result.ImportedListeners, record.Listener)
+			}
+		}
+		exists = existing != nil
+	case record.Type == apitypes.ArchiveRecordTransaction && record.Transaction != nil:
+		count = &result.Transactions
+		var existing *apitypes.ManagedTX
+		if existing, err = p.GetTransactionByID(ctx, record.Transaction.ID); err == nil && existing == nil {
+			// The sequence ID is specific to the persistence that allocated it, so a new one is assigned
+			record.Transaction.SequenceID = ""
+			err = p.WriteTransaction(ctx, record.Transaction, true)
+		}
+		exists = existing != nil
+		importedTXs[record.Transaction.ID] = err == nil && !exists
+	case record.Type == apitypes.ArchiveRecordTransactionHistory && record.TXHistory != nil && record.TXHistory.ID != nil:
+		count = &result.TXHistory
+		exists = !importedTXs[record.TXHistory.TransactionID]
+		if !exists {
+			err = p.WriteTransactionHistory(ctx, record.TXHistory)
+		}
+	case record.Type == apitypes.ArchiveRecordSignerMinimumNonce && record.SignerMinimumNonce != nil && record.SignerMinimumNonce.Nonce != nil:
+		count = &result.SignerMinimumNonces
+		var existing *apitypes.SignerMinimumNonce
+		if existing, err = p.GetSignerMinimumNonce(ctx, record.SignerMinimumNonce.Signer); err == nil && existing == nil {
+			err = p.WriteSignerMinimumNonce(ctx, record.SignerMinimumNonce)
+		}
+		exists = existing != nil
+	case record.Type == apitypes.ArchiveRecordNonceReservation && record.NonceReservation != nil && record.NonceReservation.First != nil && record.NonceReservation.Last != nil:
+		count = &result.NonceReservations
+		reservation := record.NonceReservation
+		// Reservations have no ID, so an existing one is found by its first nonce
+		var existing []*apitypes.NonceReservation
+		if existing, err = p.ListNonceReservations(ctx, reservation.Signer, fftypes.NewFFBigInt(reservation.First.Int64()+1), 1, SortDirectionDescending); err == nil {
+			exists = len(existing) > 0 && existing[0].First.Int64() == reservation.First.Int64()
+			if !exists {
+				err = p.WriteNonceReservation(ctx, reservation)
+			}
+		}
+	default:
+		return i18n.NewError(ctx, tmmsgs.MsgArchiveInvalidRecord, recordNumber, record.Type)
+	}
+	if err != nil {
+		return err
+	}
+	if exists {
+		count.Skipped++
+	} else {
+		count.Imported++
+	}
+	return nil
+}
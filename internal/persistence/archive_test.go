@@ -0,0 +1,288 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+// errorWriter fails every write after the first okWrites
+type errorWriter struct {
+	okWrites int
+}
+
+func (ew *errorWriter) Write(p []byte) (int, error) {
+	if ew.okWrites > 0 {
+		ew.okWrites--
+		return len(p), nil
+	}
+	return 0, fmt.Errorf("pop")
+}
+
+// testStreamValidator stands in for the validation of the event streams, which requires a name
+func testStreamValidator(ctx context.Context, es *apitypes.EventStream) (*apitypes.EventStream, error) {
+	if es.Name == nil {
+		return nil, fmt.Errorf("missing name")
+	}
+	return es, nil
+}
+
+func TestExportImportArchive(t *testing.T) {
+	src, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	es := &apitypes.EventStream{ID: apitypes.NewULID(), Name: strPtr("stream1")}
+	err := src.WriteStream(ctx, es)
+	assert.NoError(t, err)
+	err = src.WriteCheckpoint(ctx, &apitypes.EventStreamCheckpoint{StreamID: es.ID})
+	assert.NoError(t, err)
+	err = src.WriteStream(ctx, &apitypes.EventStream{ID: apitypes.NewULID(), Name: strPtr("stream2")})
+	assert.NoError(t, err)
+	l := &apitypes.Listener{ID: apitypes.NewULID(), StreamID: es.ID}
+	err = src.WriteListener(ctx, l)
+	assert.NoError(t, err)
+	var txIDs []string
+	for i := 0; i < archivePageSize+5; i++ {
+		tx := newTestTX("0xaaaaa", int64(i), apitypes.TxStatusSucceeded)
+		err = src.WriteTransaction(ctx, tx, true)
+		assert.NoError(t, err)
+		txIDs = append(txIDs, tx.ID)
+	}
+	for i := 0; i < archivePageSize+1; i++ {
+		err = src.WriteTransactionHistory(ctx, newTestTXHistory(txIDs[0], apitypes.TxSubStatusTracking))
+		assert.NoError(t, err)
+	}
+	for i := 0; i < archivePageSize+1; i++ {
+		err = src.WriteNonceReservation(ctx, &apitypes.NonceReservation{
+			Signer: "0xaaaaa",
+			First:  fftypes.NewFFBigInt(int64(100 + i*10)),
+			Last:   fftypes.NewFFBigInt(int64(109 + i*10)),
+		})
+		assert.NoError(t, err)
+	}
+	lastReserved := int64(109 + archivePageSize*10)
+	for _, signer := range []string{"0xaaaaa", "0xbbbbb"} {
+		err = src.WriteSignerMinimumNonce(ctx, &apitypes.SignerMinimumNonce{Signer: signer, Nonce: fftypes.NewFFBigInt(lastReserved + 1)})
+		assert.NoError(t, err)
+	}
+
+	archive := new(bytes.Buffer)
+	err = ExportArchive(ctx, src, archive)
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(archive.String()), "\n"), 1+2+1+1+2+archivePageSize+1+archivePageSize+5+archivePageSize+1)
+
+	// Import into a different type of persistence
+	dst, done2 := newTestSQLitePersistence(t)
+	defer done2()
+
+	result, err := ImportArchive(ctx, dst, bytes.NewReader(archive.Bytes()), testStreamValidator)
+	assert.NoError(t, err)
+	assert.Len(t, result.ImportedListeners, 1)
+	assert.Equal(t, l.ID, result.ImportedListeners[0].ID)
+	result.ImportedListeners = nil
+	assert.Equal(t, apitypes.ArchiveImportResult{
+		EventStreams: apitypes.ArchiveImportCount{Imported: 2},
+		Checkpoints:  apitypes.ArchiveImportCount{Imported: 1},
+		Listeners:    apitypes.ArchiveImportCount{Imported: 1},
+		Transactions: apitypes.ArchiveImportCount{Imported: archivePageSize + 5},
+		TXHistory:    apitypes.ArchiveImportCount{Imported: archivePageSize + 1},
+
+		SignerMinimumNonces: apitypes.ArchiveImportCount{Imported: 2},
+		NonceReservations:   apitypes.ArchiveImportCount{Imported: archivePageSize + 1},
+	}, *result)
+
+	streams, err := dst.ListStreams(ctx, nil, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, streams, 2)
+	cp, err := dst.GetCheckpoint(ctx, es.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, cp)
+	l2, err := dst.GetListener(ctx, l.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, es.ID, l2.StreamID)
+	txns, err := dst.ListTransactionsByNonce(ctx, "0xaaaaa", nil, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, len(txIDs))
+	for i, tx := range txns {
+		assert.Equal(t, txIDs[i], tx.ID)
+	}
+	history, err := dst.ListTransactionHistory(ctx, txIDs[0], nil, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, history, archivePageSize+1)
+	minimum, err := dst.GetSignerMinimumNonce(ctx, "0xbbbbb")
+	assert.NoError(t, err)
+	assert.Equal(t, lastReserved+1, minimum.Nonce.Int64())
+	reservations, err := dst.ListNonceReservations(ctx, "0xaaaaa", nil, 1, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Equal(t, lastReserved, reservations[0].Last.Int64())
+
+	// Importing again skips everything
+	result, err = ImportArchive(ctx, dst, bytes.NewReader(archive.Bytes()), testStreamValidator)
+	assert.NoError(t, err)
+	assert.Equal(t, apitypes.ArchiveImportResult{
+		EventStreams: apitypes.ArchiveImportCount{Skipped: 2},
+		Checkpoints:  apitypes.ArchiveImportCount{Skipped: 1},
+		Listeners:    apitypes.ArchiveImportCount{Skipped: 1},
+		Transactions: apitypes.ArchiveImportCount{Skipped: archivePageSize + 5},
+		TXHistory:    apitypes.ArchiveImportCount{Skipped: archivePageSize + 1},
+
+		SignerMinimumNonces: apitypes.ArchiveImportCount{Skipped: 2},
+		NonceReservations:   apitypes.ArchiveImportCount{Skipped: archivePageSize + 1},
+	}, *result)
+}
+
+func TestExportArchiveHistoryReadFail(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	err := p.WriteTransaction(ctx, newTestTX("0xaaaaa", 1, apitypes.TxStatusSucceeded), true)
+	assert.NoError(t, err)
+	_, err = p.db.DB().Exec(`DROP TABLE transaction_history`)
+	assert.NoError(t, err)
+
+	err = ExportArchive(ctx, p, new(bytes.Buffer))
+	assert.Regexp(t, "FF00176", err)
+}
+
+func TestExportArchiveHistoryWriteFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	err := p.WriteTransactionHistory(ctx, newTestTXHistory("tx1", apitypes.TxSubStatusReceived))
+	assert.NoError(t, err)
+
+	err = exportTransactionHistory(ctx, p, "tx1", func(r *apitypes.ArchiveRecord) error { return fmt.Errorf("pop") })
+	assert.Regexp(t, "pop", err)
+}
+
+func TestExportArchiveWriteFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	err := ExportArchive(context.Background(), p, &errorWriter{})
+	assert.Regexp(t, "FF21086", err)
+}
+
+func TestExportArchiveRecordWriteFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	es := &apitypes.EventStream{ID: apitypes.NewULID()}
+	err := p.WriteStream(ctx, es)
+	assert.NoError(t, err)
+	err = p.WriteCheckpoint(ctx, &apitypes.EventStreamCheckpoint{StreamID: es.ID})
+	assert.NoError(t, err)
+	err = p.WriteListener(ctx, &apitypes.Listener{ID: apitypes.NewULID(), StreamID: es.ID})
+	assert.NoError(t, err)
+	err = p.WriteSignerMinimumNonce(ctx, &apitypes.SignerMinimumNonce{Signer: "0xaaaaa", Nonce: fftypes.NewFFBigInt(11)})
+	assert.NoError(t, err)
+	err = p.WriteNonceReservation(ctx, &apitypes.NonceReservation{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(10), Last: fftypes.NewFFBigInt(10)})
+	assert.NoError(t, err)
+	err = p.WriteTransaction(ctx, newTestTX("0xaaaaa", 1, apitypes.TxStatusSucceeded), true)
+	assert.NoError(t, err)
+
+	// Fail on each of the header, stream, checkpoint, listener, minimum nonce, reservation and transaction records in turn
+	for okWrites := 0; okWrites < 7; okWrites++ {
+		err = ExportArchive(ctx, p, &errorWriter{okWrites: okWrites})
+		assert.Regexp(t, "FF21086", err)
+	}
+}
+
+func TestExportArchiveTableReadFail(t *testing.T) {
+	for _, table := range []string{checkpointsTable, listenersTable, minNoncesTable, nonceResTable, transactionsTable} {
+		p, done := newTestSQLitePersistence(t)
+
+		ctx := context.Background()
+		err := p.WriteStream(ctx, &apitypes.EventStream{ID: apitypes.NewULID()})
+		assert.NoError(t, err)
+		err = p.WriteSignerMinimumNonce(ctx, &apitypes.SignerMinimumNonce{Signer: "0xaaaaa", Nonce: fftypes.NewFFBigInt(11)})
+		assert.NoError(t, err)
+		_, err = p.db.DB().Exec(`DROP TABLE ` + table)
+		assert.NoError(t, err)
+
+		err = ExportArchive(ctx, p, new(bytes.Buffer))
+		assert.Regexp(t, "FF00176", err)
+		done()
+	}
+}
+
+func TestExportArchiveReadFail(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	ctx := context.Background()
+	err := p.WriteStream(ctx, &apitypes.EventStream{ID: apitypes.NewULID()})
+	assert.NoError(t, err)
+	done()
+
+	err = ExportArchive(ctx, p, new(bytes.Buffer))
+	assert.Regexp(t, "FF00176", err)
+}
+
+func TestImportArchiveBadArchives(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	_, err := ImportArchive(ctx, p, strings.NewReader(""), testStreamValidator)
+	assert.Regexp(t, "FF21083", err)
+
+	_, err = ImportArchive(ctx, p, strings.NewReader(`{"type":"eventstream"}`), testStreamValidator)
+	assert.Regexp(t, "FF21083", err)
+
+	_, err = ImportArchive(ctx, p, strings.NewReader(`{"type":"header","version":99}`), testStreamValidator)
+	assert.Regexp(t, "FF21084", err)
+
+	_, err = ImportArchive(ctx, p, strings.NewReader(`{"type":"header","version":1}`+"\n"+`!json`), testStreamValidator)
+	assert.Regexp(t, "FF21085.*2", err)
+
+	_, err = ImportArchive(ctx, p, strings.NewReader(`{"type":"header","version":1}`+"\n"+`{"type":"unknown"}`), testStreamValidator)
+	assert.Regexp(t, "FF21085.*unknown", err)
+
+	_, err = ImportArchive(ctx, p, strings.NewReader(`{"type":"header","version":1}`+"\n"+`{"type":"listener"}`), testStreamValidator)
+	assert.Regexp(t, "FF21085.*listener", err)
+
+	_, err = ImportArchive(ctx, p, strings.NewReader(`{"type":"header","version":1}`+"\n"+`{"type":"noncereservation","noncereservation":{"signer":"0xaaaaa"}}`), testStreamValidator)
+	assert.Regexp(t, "FF21085.*noncereservation", err)
+
+	_, err = ImportArchive(ctx, p, strings.NewReader(`{"type":"header","version":1}`+"\n"+`{"type":"eventstream","eventstream":{"id":"`+apitypes.NewULID().String()+`"}}`), testStreamValidator)
+	assert.Regexp(t, "FF21085.*2.*missing name", err)
+}
+
+func TestImportArchiveWriteFail(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	done()
+
+	for _, record := range []string{
+		`{"type":"transaction","transaction":{"id":"tx1"}}`,
+		`{"type":"signerminimumnonce","signerminimumnonce":{"signer":"0xaaaaa","nonce":"11"}}`,
+		`{"type":"noncereservation","noncereservation":{"signer":"0xaaaaa","first":"10","last":"10"}}`,
+	} {
+		_, err := ImportArchive(context.Background(), p, strings.NewReader(`{"type":"header","version":1}`+"\n"+record), testStreamValidator)
+		assert.Regexp(t, "FF00176", err)
+	}
+}
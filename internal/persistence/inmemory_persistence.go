@@ -0,0 +1,43 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+const inMemoryPath = "<inmemory>"
+
+// NewInMemoryPersistence uses the LevelDB key layout and indexes over a memory backed storage,
+// so the ordering and pagination semantics are identical to the LevelDB persistence, but
+// nothing is written to the filesystem. All state is lost when the persistence is closed.
+func NewInMemoryPersistence(ctx context.Context) (Persistence, error) {
+	return newInMemoryPersistence(ctx, storage.NewMemStorage())
+}
+
+func newInMemoryPersistence(ctx context.Context, stor storage.Storage) (Persistence, error) {
+	db, err := leveldb.Open(stor, nil)
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceInitFailed, inMemoryPath)
+	}
+	return &leveldbPersistence{db: db}, nil
+}
@@ -0,0 +1,77 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+func TestInMemoryReadWrite(t *testing.T) {
+
+	p, err := NewInMemoryPersistence(context.Background())
+	assert.NoError(t, err)
+	defer p.Close(context.Background())
+
+	ctx := context.Background()
+	s1 := &apitypes.EventStream{ID: apitypes.NewULID()}
+	err = p.WriteStream(ctx, s1)
+	assert.NoError(t, err)
+	s2 := &apitypes.EventStream{ID: apitypes.NewULID()}
+	err = p.WriteStream(ctx, s2)
+	assert.NoError(t, err)
+
+	streams, err := p.ListStreams(ctx, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, streams, 2)
+	assert.Equal(t, s2.ID, streams[0].ID)
+	assert.Equal(t, s1.ID, streams[1].ID)
+
+	t1 := newTestTX("0xaaaaa", 10001, apitypes.TxStatusSucceeded)
+	err = p.WriteTransaction(ctx, t1, true)
+	assert.NoError(t, err)
+	t2 := newTestTX("0xaaaaa", 10002, apitypes.TxStatusPending)
+	err = p.WriteTransaction(ctx, t2, true)
+	assert.NoError(t, err)
+
+	txns, err := p.ListTransactionsPending(ctx, "", 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, t2.ID, txns[0].ID)
+
+	txns, err = p.ListTransactionsByNonce(ctx, "0xaaaaa", nil, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 2)
+	assert.Equal(t, t1.ID, txns[0].ID)
+	assert.Equal(t, t2.ID, txns[1].ID)
+
+}
+
+func TestInMemoryInitFail(t *testing.T) {
+
+	stor := storage.NewMemStorage()
+	_, err := stor.Lock()
+	assert.NoError(t, err)
+
+	_, err = newInMemoryPersistence(context.Background(), stor)
+	assert.Regexp(t, "FF21058", err)
+
+}
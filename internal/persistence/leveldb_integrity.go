@@ -0,0 +1,238 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type IntegrityIssueType string
+
+const (
+	IntegrityIssueDanglingIndex      IntegrityIssueType = "dangling_index"      // an index key pointing to a missing, or non-matching, transaction
+	IntegrityIssueMissingIndex       IntegrityIssueType = "missing_index"       // a transaction that is missing one of its index keys
+	IntegrityIssueOrphanedCheckpoint IntegrityIssueType = "orphaned_checkpoint" // a checkpoint for a stream that no longer exists
+	IntegrityIssueOrphanedListener   IntegrityIssueType = "orphaned_listener"   // a listener for a stream that no longer exists
+	IntegrityIssueOrphanedHistory    IntegrityIssueType = "orphaned_history"    // a transaction history record for a transaction that no longer exists
+	IntegrityIssueUnreadable         IntegrityIssueType = "unreadable"          // a document that cannot be parsed - reported, but never repaired
+)
+
+type IntegrityIssue struct {
+	Type     IntegrityIssueType `json:"type"`
+	Key      string             `json:"key"`
+	Detail   string             `json:"detail"`
+	Repaired bool               `json:"repaired"`
+}
+
+// IntegrityChecker is implemented by persistence types that maintain their own indexes, and hence
+// can drift after a crash. It must only be used on a store that is not in use by a running instance.
+type IntegrityChecker interface {
+	CheckIntegrity(ctx context.Context, repair bool) ([]*IntegrityIssue, error)
+}
+
+type integrityCheck struct {
+	p      *leveldbPersistence
+	repair bool
+	batch  *leveldb.Batch
+	issues []*IntegrityIssue
+}
+
+func (ic *integrityCheck) addIssue(issueType IntegrityIssueType, key []byte, detail string) *IntegrityIssue {
+	issue := &IntegrityIssue{
+		Type:     issueType,
+		Key:      string(key),
+		Detail:   detail,
+		Repaired: ic.repair && issueType != IntegrityIssueUnreadable,
+	}
+	ic.issues = append(ic.issues, issue)
+	return issue
+}
+
+func (ic *integrityCheck) deleteKey(issueType IntegrityIssueType, key []byte, detail string) {
+	if ic.addIssue(issueType, key, detail).Repaired {
+		ic.batch.Delete(key)
+	}
+}
+
+func (ic *integrityCheck) putKey(issueType IntegrityIssueType, key, value []byte, detail string) {
+	if ic.addIssue(issueType, key, detail).Repaired {
+		ic.batch.Put(key, value)
+	}
+}
+
+func (ic *integrityCheck) iterate(ctx context.Context, prefix, end string, fn func(ctx context.Context, k, v []byte) error) error {
+	it := ic.p.db.NewIterator(&util.Range{Start: []byte(prefix), Limit: []byte(end)}, &opt.ReadOptions{DontFillCache: true})
+	defer it.Release()
+	for it.Next() {
+		// The iterator re-uses the buffers, so we take a copy
+		if err := fn(ctx, append([]byte{}, it.Key()...), append([]byte{}, it.Value()...)); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceReadFailed, prefix)
+	}
+	return nil
+}
+
+// checkIndexEntry checks an index entry points to an existing transaction, for which the index
+// key is one of the keys that should exist in its current state
+func (ic *integrityCheck) checkIndexEntry(ctx context.Context, k, idKey []byte) error {
+	b, err := ic.p.getKeyValue(ctx, idKey)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		ic.deleteKey(IntegrityIssueDanglingIndex, k, "transaction '"+string(idKey)+"' does not exist")
+		return nil
+	}
+	// A transaction that cannot be parsed is reported when we scan the transactions
+	var tx *apitypes.ManagedTX
+	if err := json.Unmarshal(b, &tx); err == nil && tx != nil && !containsKey(txIndexKeys(tx), k) {
+		ic.deleteKey(IntegrityIssueDanglingIndex, k, "index does not match transaction '"+string(idKey)+"'")
+	}
+	return nil
+}
+
+// checkTransaction checks all the index keys for a transaction exist
+func (ic *integrityCheck) checkTransaction(ctx context.Context, idKey, b []byte) error {
+	var tx *apitypes.ManagedTX
+	if err := json.Unmarshal(b, &tx); err != nil || tx == nil || tx.SequenceID == "" {
+		ic.addIssue(IntegrityIssueUnreadable, idKey, "invalid transaction document")
+		return nil
+	}
+	for _, k := range txIndexKeys(tx) {
+		existing, err := ic.p.getKeyValue(ctx, k)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			ic.putKey(IntegrityIssueMissingIndex, k, idKey, "index missing for transaction '"+string(idKey)+"'")
+		}
+	}
+	return nil
+}
+
+func (ic *integrityCheck) checkTransactions(ctx context.Context) error {
+	for _, idx := range [][]string{
+		{txCreatedIndexPrefix, txCreatedIndexEnd},
+		{txPendingIndexPrefix, txPendingIndexEnd},
+		{nonceAllocationPrefix, nonceAllocationEnd},
+		{txStatusIndexPrefix, txStatusIndexEnd},
+		{txToIndexPrefix, txToIndexEnd},
+		{txHashIndexPrefix, txHashIndexEnd},
+	} {
+		if err := ic.iterate(ctx, idx[0], idx[1], ic.checkIndexEntry); err != nil {
+			return err
+		}
+	}
+	return ic.iterate(ctx, transactionsPrefix, transactionsEnd, ic.checkTransaction)
+}
+
+func (ic *integrityCheck) checkHistoryRecord(ctx context.Context, k, b []byte) error {
+	var r *apitypes.TxHistoryRecord
+	if err := json.Unmarshal(b, &r); err != nil || r == nil || r.ID == nil {
+		ic.addIssue(IntegrityIssueUnreadable, k, "invalid transaction history document")
+		return nil
+	}
+	tx, err := ic.p.getKeyValue(ctx, txDataKey(r.TransactionID))
+	if err == nil && tx == nil {
+		ic.deleteKey(IntegrityIssueOrphanedHistory, k, "transaction '"+r.TransactionID+"' does not exist")
+	}
+	return err
+}
+
+func (ic *integrityCheck) streamExists(ctx context.Context, streamID *fftypes.UUID) (bool, error) {
+	b, err := ic.p.getKeyValue(ctx, prefixedKey(eventstreamsPrefix, streamID))
+	return b != nil, err
+}
+
+func (ic *integrityCheck) checkCheckpoint(ctx context.Context, k, _ []byte) error {
+	streamID, err := fftypes.ParseUUID(ctx, strings.TrimPrefix(string(k), checkpointsPrefix))
+	if err != nil {
+		ic.addIssue(IntegrityIssueUnreadable, k, err.Error())
+		return nil
+	}
+	exists, err := ic.streamExists(ctx, streamID)
+	if err == nil && !exists {
+		ic.deleteKey(IntegrityIssueOrphanedCheckpoint, k, "event stream '"+streamID.String()+"' does not exist")
+	}
+	return err
+}
+
+func (ic *integrityCheck) checkListener(ctx context.Context, k, b []byte) error {
+	var l *apitypes.Listener
+	if err := json.Unmarshal(b, &l); err != nil || l == nil {
+		ic.addIssue(IntegrityIssueUnreadable, k, "invalid listener document")
+		return nil
+	}
+	exists, err := ic.streamExists(ctx, l.StreamID)
+	if err == nil && !exists {
+		ic.deleteKey(IntegrityIssueOrphanedListener, k, "event stream '"+l.StreamID.String()+"' does not exist")
+	}
+	return err
+}
+
+func (ic *integrityCheck) checkStreams(ctx context.Context) error {
+	if err := ic.iterate(ctx, checkpointsPrefix, checkpointsEnd, ic.checkCheckpoint); err != nil {
+		return err
+	}
+	return ic.iterate(ctx, listenersPrefix, listenersEnd, ic.checkListener)
+}
+
+// CheckIntegrity scans all the indexes and documents in the store, reporting any inconsistencies.
+// If repair is set, then all the issues that can be repaired are fixed in a single atomic batch.
+// Documents that cannot be parsed are reported, but are never modified.
+func (p *leveldbPersistence) CheckIntegrity(ctx context.Context, repair bool) ([]*IntegrityIssue, error) {
+	p.txMux.Lock()
+	defer p.txMux.Unlock()
+
+	ic := &integrityCheck{
+		p:      p,
+		repair: repair,
+		batch:  &leveldb.Batch{},
+		issues: []*IntegrityIssue{},
+	}
+	err := ic.checkTransactions(ctx)
+	if err == nil {
+		err = ic.iterate(ctx, txHistoryPrefix, txHistoryEnd, ic.checkHistoryRecord)
+	}
+	if err == nil {
+		err = ic.checkStreams(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ic.batch.Len() > 0 {
+		if err := p.writeBatch(ctx, ic.batch); err != nil {
+			return nil, err
+		}
+		log.L(ctx).Infof("Repaired %d integrity issues", ic.batch.Len())
+	}
+	return ic.issues, nil
+}
@@ -0,0 +1,222 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestCheckIntegrityRepair(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+
+	// A healthy stream, with a listener and a checkpoint
+	s1 := &apitypes.EventStream{ID: apitypes.NewULID()}
+	err := p.WriteStream(ctx, s1)
+	assert.NoError(t, err)
+	err = p.WriteListener(ctx, &apitypes.Listener{ID: apitypes.NewULID(), StreamID: s1.ID})
+	assert.NoError(t, err)
+	err = p.WriteCheckpoint(ctx, &apitypes.EventStreamCheckpoint{StreamID: s1.ID})
+	assert.NoError(t, err)
+
+	// A listener and checkpoint, for a stream that does not exist
+	missingStreamID := apitypes.NewULID()
+	orphanedListener := &apitypes.Listener{ID: apitypes.NewULID(), StreamID: missingStreamID}
+	err = p.WriteListener(ctx, orphanedListener)
+	assert.NoError(t, err)
+	err = p.WriteCheckpoint(ctx, &apitypes.EventStreamCheckpoint{StreamID: missingStreamID})
+	assert.NoError(t, err)
+
+	// A healthy transaction
+	tx1 := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	err = p.WriteTransaction(ctx, tx1, true)
+	assert.NoError(t, err)
+
+	// A transaction missing its created index
+	tx2 := newTestTX("0xaaaaa", 2, apitypes.TxStatusPending)
+	err = p.WriteTransaction(ctx, tx2, true)
+	assert.NoError(t, err)
+	err = p.db.Delete(txCreatedIndexKey(tx2), nil)
+	assert.NoError(t, err)
+
+	// A completed transaction, left with a pending index
+	tx3 := newTestTX("0xaaaaa", 3, apitypes.TxStatusSucceeded)
+	err = p.WriteTransaction(ctx, tx3, true)
+	assert.NoError(t, err)
+	err = p.db.Put(txPendingIndexKey(tx3.SequenceID), txDataKey(tx3.ID), nil)
+	assert.NoError(t, err)
+	staleStatusKey := txStatusIndexKey(&apitypes.ManagedTX{Status: apitypes.TxStatusPending, Created: tx3.Created, SequenceID: tx3.SequenceID})
+	err = p.db.Put(staleStatusKey, txDataKey(tx3.ID), nil)
+	assert.NoError(t, err)
+
+	// A nonce index pointing to a transaction that does not exist
+	danglingNonceKey := txNonceAllocationKey("0xbbbbb", tx1.Nonce)
+	err = p.db.Put(danglingNonceKey, txDataKey("missing"), nil)
+	assert.NoError(t, err)
+
+	// History for an existing transaction, and for one that does not exist
+	err = p.WriteTransactionHistory(ctx, newTestTXHistory(tx1.ID, apitypes.TxSubStatusReceived))
+	assert.NoError(t, err)
+	orphanedHistory := newTestTXHistory("missing", apitypes.TxSubStatusReceived)
+	err = p.WriteTransactionHistory(ctx, orphanedHistory)
+	assert.NoError(t, err)
+	err = p.db.Put([]byte(txHistoryPrefix+"bad/history"), []byte("!json"), nil)
+	assert.NoError(t, err)
+
+	// A transaction document that cannot be parsed
+	err = p.db.Put(txDataKey("bad"), []byte("!json"), nil)
+	assert.NoError(t, err)
+	err = p.db.Put(prefixedKey(listenersPrefix, apitypes.NewULID()), []byte("!json"), nil)
+	assert.NoError(t, err)
+	err = p.db.Put([]byte(checkpointsPrefix+"!uuid"), []byte("{}"), nil)
+	assert.NoError(t, err)
+
+	issues, err := p.CheckIntegrity(ctx, false)
+	assert.NoError(t, err)
+	byType := map[IntegrityIssueType][]string{}
+	for _, issue := range issues {
+		assert.False(t, issue.Repaired)
+		byType[issue.Type] = append(byType[issue.Type], issue.Key)
+	}
+	assert.ElementsMatch(t, []string{
+		string(txPendingIndexKey(tx3.SequenceID)),
+		string(staleStatusKey),
+		string(danglingNonceKey),
+	}, byType[IntegrityIssueDanglingIndex])
+	assert.Equal(t, []string{string(txCreatedIndexKey(tx2))}, byType[IntegrityIssueMissingIndex])
+	assert.Equal(t, []string{string(prefixedKey(checkpointsPrefix, missingStreamID))}, byType[IntegrityIssueOrphanedCheckpoint])
+	assert.Equal(t, []string{string(prefixedKey(listenersPrefix, orphanedListener.ID))}, byType[IntegrityIssueOrphanedListener])
+	assert.Equal(t, []string{string(txHistoryKey(orphanedHistory))}, byType[IntegrityIssueOrphanedHistory])
+	assert.Len(t, byType[IntegrityIssueUnreadable], 4)
+
+	// Check does not modify anything
+	issues2, err := p.CheckIntegrity(ctx, false)
+	assert.NoError(t, err)
+	assert.Len(t, issues2, len(issues))
+
+	issues, err = p.CheckIntegrity(ctx, true)
+	assert.NoError(t, err)
+	assert.Len(t, issues, len(issues2))
+	for _, issue := range issues {
+		assert.Equal(t, issue.Type != IntegrityIssueUnreadable, issue.Repaired)
+	}
+
+	// Only the unreadable entries are left
+	issues, err = p.CheckIntegrity(ctx, false)
+	assert.NoError(t, err)
+	assert.Len(t, issues, 4)
+
+	txns, err := p.ListTransactionsByCreateTime(ctx, nil, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 3)
+	txns, err = p.ListTransactionsPending(ctx, "", 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 2)
+	l, err := p.GetListener(ctx, orphanedListener.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+	cp, err := p.GetCheckpoint(ctx, s1.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, cp)
+	history, err := p.ListTransactionHistory(ctx, tx1.ID, nil, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+
+}
+
+func TestCheckIntegrityFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	err := p.WriteTransaction(ctx, newTestTX("0xaaaaa", 1, apitypes.TxStatusPending), true)
+	assert.NoError(t, err)
+
+	p.db.Close()
+
+	_, err = p.CheckIntegrity(ctx, true)
+	assert.Error(t, err)
+
+}
+
+func TestCheckIntegrityRepairFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	err := p.WriteTransaction(ctx, tx, true)
+	assert.NoError(t, err)
+	err = p.db.Delete(txCreatedIndexKey(tx), nil)
+	assert.NoError(t, err)
+
+	err = p.db.SetReadOnly()
+	assert.NoError(t, err)
+
+	_, err = p.CheckIntegrity(ctx, true)
+	assert.Regexp(t, "FF21056", err)
+
+}
+
+func TestCheckIntegrityIterateFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	err := p.writeKeyValue(ctx, []byte(`test_0/key`), []byte(`value`))
+	assert.NoError(t, err)
+
+	ic := &integrityCheck{p: p, batch: &leveldb.Batch{}}
+	err = ic.iterate(ctx, "test_0/", "test_1", func(ctx context.Context, k, v []byte) error {
+		return fmt.Errorf("pop")
+	})
+	assert.Regexp(t, "pop", err)
+
+}
+
+func TestCheckIntegrityReadFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	tx.SequenceID = apitypes.NewULID().String()
+	b, err := json.Marshal(tx)
+	assert.NoError(t, err)
+
+	p.db.Close()
+
+	ic := &integrityCheck{p: p, batch: &leveldb.Batch{}}
+	err = ic.checkIndexEntry(ctx, txCreatedIndexKey(tx), txDataKey(tx.ID))
+	assert.Regexp(t, "FF21055", err)
+	err = ic.checkTransaction(ctx, txDataKey(tx.ID), b)
+	assert.Regexp(t, "FF21055", err)
+	err = ic.checkCheckpoint(ctx, prefixedKey(checkpointsPrefix, apitypes.NewULID()), nil)
+	assert.Regexp(t, "FF21055", err)
+	err = ic.checkStreams(ctx)
+	assert.Regexp(t, "FF21055", err)
+
+}
@@ -17,6 +17,7 @@
 package persistence
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -53,23 +54,106 @@ func NewLevelDBPersistence(ctx context.Context) (Persistence, error) {
 	if err != nil {
 		return nil, i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceInitFailed, dbPath)
 	}
-	return &leveldbPersistence{
+	p := &leveldbPersistence{
 		db:         db,
 		syncWrites: config.GetBool(tmconfig.PersistenceLevelDBSyncWrites),
-	}, nil
+	}
+	if err := p.upgradeSchema(ctx); err != nil {
+		p.Close(ctx)
+		return nil, err
+	}
+	return p, nil
+}
+
+// upgradeSchema performs any one-time upgrade needed for a database written by an earlier version,
+// as recorded by the schema version stored in the database. Databases that pre-date the schema
+// version key are treated as version 0.
+func (p *leveldbPersistence) upgradeSchema(ctx context.Context) error {
+	var version int
+	if err := p.readJSON(ctx, []byte(schemaVersionKey), &version); err != nil {
+		return err
+	}
+	if version >= currentSchemaVersion {
+		return nil
+	}
+	// Version 1 added the status, to and hash indexes, which must be built for existing transactions
+	log.L(ctx).Infof("Upgrading LevelDB schema from version %d to %d", version, currentSchemaVersion)
+	if err := p.reindexTransactions(ctx); err != nil {
+		return err
+	}
+	return p.writeJSON(ctx, []byte(schemaVersionKey), currentSchemaVersion)
+}
+
+// reindexTransactions writes all the index keys for every stored transaction. Writing the keys is
+// idempotent, so if we crash part way through we just start again on the next open.
+func (p *leveldbPersistence) reindexTransactions(ctx context.Context) error {
+	it := p.db.NewIterator(&util.Range{
+		Start: []byte(transactionsPrefix),
+		Limit: []byte(transactionsEnd),
+	}, &opt.ReadOptions{DontFillCache: true})
+	defer it.Release()
+	count := 0
+	batch := &leveldb.Batch{}
+	for it.Next() {
+		var tx *apitypes.ManagedTX
+		if err := json.Unmarshal(it.Value(), &tx); err != nil {
+			// Left for the integrity check to report, rather than blocking startup
+			log.L(ctx).Warnf("Skipping reindex of unparsable transaction '%s': %s", it.Key(), err)
+			continue
+		}
+		idKey := append([]byte{}, it.Key()...)
+		for _, k := range txIndexKeys(tx) {
+			batch.Put(k, idKey)
+		}
+		count++
+		if count%reindexBatchSize == 0 {
+			if err := p.writeBatch(ctx, batch); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceReadFailed, transactionsPrefix)
+	}
+	if batch.Len() > 0 {
+		if err := p.writeBatch(ctx, batch); err != nil {
+			return err
+		}
+	}
+	log.L(ctx).Infof("Reindexed %d transactions", count)
+	return nil
 }
 
 const checkpointsPrefix = "checkpoints_0/"
+const checkpointsEnd = "checkpoints_1"
 const eventstreamsPrefix = "eventstreams_0/"
 const eventstreamsEnd = "eventstreams_1"
 const listenersPrefix = "listeners_0/"
 const listenersEnd = "listeners_1"
 const transactionsPrefix = "tx_0/"
+const transactionsEnd = "tx_1"
 const nonceAllocationPrefix = "nonce_0/"
+const nonceAllocationEnd = "nonce_1"
 const txPendingIndexPrefix = "tx_inflight_0/"
 const txPendingIndexEnd = "tx_inflight_1"
 const txCreatedIndexPrefix = "tx_created_0/"
 const txCreatedIndexEnd = "tx_created_1"
+const txStatusIndexPrefix = "tx_status_0/"
+const txStatusIndexEnd = "tx_status_1"
+const txToIndexPrefix = "tx_to_0/"
+const txToIndexEnd = "tx_to_1"
+const txHashIndexPrefix = "tx_hash_0/"
+const txHashIndexEnd = "tx_hash_1"
+const txHistoryPrefix = "txhistory_0/"
+const txHistoryEnd = "txhistory_1"
+const nonceReservationsPrefix = "noncereservations_0/"
+const signerMinimumNoncesPrefix = "signerminimumnonces_0/"
+const signerMinimumNoncesEnd = "signerminimumnonces_1"
+const uuidStringLength = 36
+const schemaVersionKey = "schema_version"
+const currentSchemaVersion = 1
+const reindexBatchSize = 1000
 
 func signerNoncePrefix(signer string) string {
 	return fmt.Sprintf("%s%s_0/", nonceAllocationPrefix, signer)
@@ -83,18 +167,91 @@ func txNonceAllocationKey(signer string, nonce *fftypes.FFBigInt) []byte {
 	return []byte(fmt.Sprintf("%s%s_0/%.24d", nonceAllocationPrefix, signer, nonce.Int()))
 }
 
+func signerNonceReservationsPrefix(signer string) string {
+	return fmt.Sprintf("%s%s_0/", nonceReservationsPrefix, signer)
+}
+
+func signerNonceReservationsEnd(signer string) string {
+	return fmt.Sprintf("%s%s_1", nonceReservationsPrefix, signer)
+}
+
+func nonceReservationKey(reservation *apitypes.NonceReservation) []byte {
+	return []byte(fmt.Sprintf("%s%.24d", signerNonceReservationsPrefix(reservation.Signer), reservation.First.Int()))
+}
+
+// signerMinimumNonceKey terminates the signer, so paging after a signer does not skip others that share its prefix
+func signerMinimumNonceKey(signer string) []byte {
+	return []byte(fmt.Sprintf("%s%s/", signerMinimumNoncesPrefix, signer))
+}
+
 func txPendingIndexKey(sequenceID string) []byte {
 	return []byte(fmt.Sprintf("%s%s", txPendingIndexPrefix, sequenceID))
 }
 
 func txCreatedIndexKey(tx *apitypes.ManagedTX) []byte {
-	return []byte(fmt.Sprintf("%s%.19d/%s", txCreatedIndexPrefix, tx.Created.UnixNano(), tx.SequenceID))
+	return []byte(txCreatedIndexPrefix + txCreatedSuffix(tx))
+}
+
+func txCreatedSuffix(tx *apitypes.ManagedTX) string {
+	return fmt.Sprintf("%.19d/%s", tx.Created.UnixNano(), tx.SequenceID)
+}
+
+func txStatusIndexKey(tx *apitypes.ManagedTX) []byte {
+	return []byte(fmt.Sprintf("%s%s/%s", txStatusIndexPrefix, tx.Status, txCreatedSuffix(tx)))
+}
+
+func txToIndexKey(tx *apitypes.ManagedTX) []byte {
+	return []byte(fmt.Sprintf("%s%s/%s", txToIndexPrefix, tx.TransactionHeaders.To, txCreatedSuffix(tx)))
+}
+
+func txHashIndexKey(tx *apitypes.ManagedTX, hash string) []byte {
+	return []byte(fmt.Sprintf("%s%s/%s", txHashIndexPrefix, hash, txCreatedSuffix(tx)))
+}
+
+// txIndexKeys returns all the index keys that should exist for a transaction in its current state
+func txIndexKeys(tx *apitypes.ManagedTX) [][]byte {
+	keys := [][]byte{
+		txCreatedIndexKey(tx),
+		txStatusIndexKey(tx),
+	}
+	// A scheduled transaction is only assigned a nonce once it is due to be submitted
+	if tx.Nonce != nil {
+		keys = append(keys, txNonceAllocationKey(tx.TransactionHeaders.From, tx.Nonce))
+	}
+	if tx.Status == apitypes.TxStatusPending {
+		keys = append(keys, txPendingIndexKey(tx.SequenceID))
+	}
+	if tx.TransactionHeaders.To != "" {
+		keys = append(keys, txToIndexKey(tx))
+	}
+	for _, hash := range tx.TransactionHashes() {
+		keys = append(keys, txHashIndexKey(tx, hash))
+	}
+	return keys
+}
+
+// txHistoryPrefixForTX returns the prefix for the history records of a transaction, which are ordered by their ULID
+func txHistoryPrefixForTX(txID string) string {
+	return fmt.Sprintf("%s%s/", txHistoryPrefix, txID)
+}
+
+func txHistoryKey(record *apitypes.TxHistoryRecord) []byte {
+	return []byte(txHistoryPrefixForTX(record.TransactionID) + record.ID.String())
 }
 
 func txDataKey(k string) []byte {
 	return []byte(fmt.Sprintf("%s%s", transactionsPrefix, k))
 }
 
+func containsKey(keys [][]byte, key []byte) bool {
+	for _, k := range keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
 func prefixedKey(prefix string, id fmt.Stringer) []byte {
 	return []byte(fmt.Sprintf("%s%s", prefix, id))
 }
@@ -230,12 +387,25 @@ itLoop:
 	return orphanedIdxKeys, nil
 }
 
+func (p *leveldbPersistence) writeBatch(ctx context.Context, batch *leveldb.Batch) error {
+	err := p.db.Write(batch, &opt.WriteOptions{Sync: p.syncWrites})
+	if err != nil {
+		return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceWriteFailed)
+	}
+	return nil
+}
+
+// deleteKeys deletes all the supplied keys atomically in a single batch
 func (p *leveldbPersistence) deleteKeys(ctx context.Context, keys ...[]byte) error {
+	batch := &leveldb.Batch{}
+	for _, key := range keys {
+		batch.Delete(key)
+	}
+	err := p.db.Write(batch, &opt.WriteOptions{Sync: p.syncWrites})
+	if err != nil {
+		return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceDeleteFailed)
+	}
 	for _, key := range keys {
-		err := p.db.Delete(key, &opt.WriteOptions{Sync: p.syncWrites})
-		if err != nil && err != leveldb.ErrNotFound {
-			return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceDeleteFailed)
-		}
 		log.L(ctx).Debugf("Deleted %s", key)
 	}
 	return nil
@@ -276,7 +446,11 @@ func (p *leveldbPersistence) WriteStream(ctx context.Context, spec *apitypes.Eve
 }
 
 func (p *leveldbPersistence) DeleteStream(ctx context.Context, streamID *fftypes.UUID) error {
-	return p.deleteKeys(ctx, prefixedKey(eventstreamsPrefix, streamID))
+	// The checkpoint is removed in the same batch, so we never leave a checkpoint behind for a deleted stream
+	return p.deleteKeys(ctx,
+		prefixedKey(eventstreamsPrefix, streamID),
+		prefixedKey(checkpointsPrefix, streamID),
+	)
 }
 
 func (p *leveldbPersistence) ListListeners(ctx context.Context, after *fftypes.UUID, limit int, dir SortDirection) ([]*apitypes.Listener, error) {
@@ -360,11 +534,69 @@ func (p *leveldbPersistence) listTransactionsByIndex(ctx context.Context, collec
 func (p *leveldbPersistence) ListTransactionsByCreateTime(ctx context.Context, after *apitypes.ManagedTX, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) {
 	afterStr := ""
 	if after != nil {
-		afterStr = fmt.Sprintf("%.19d/%s", after.Created.UnixNano(), after.SequenceID)
+		afterStr = txCreatedSuffix(after)
 	}
 	return p.listTransactionsByIndex(ctx, txCreatedIndexPrefix, txCreatedIndexEnd, afterStr, limit, dir)
 }
 
+// ListTransactionsFiltered picks the most selective of the indexes that are ordered by create time, and
+// applies the remaining filters while iterating. The create time range, and the after cursor, are applied
+// to the range of keys we iterate over.
+func (p *leveldbPersistence) ListTransactionsFiltered(ctx context.Context, filters *TransactionFilters, after *apitypes.ManagedTX, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) {
+	var prefix string
+	switch {
+	case filters.TransactionHash != "":
+		prefix = txHashIndexPrefix + filters.TransactionHash + "/"
+	case filters.To != "":
+		prefix = txToIndexPrefix + filters.To + "/"
+	case filters.Status != "":
+		prefix = txStatusIndexPrefix + string(filters.Status) + "/"
+	default:
+		prefix = txCreatedIndexPrefix
+	}
+	// Replacing the trailing '/' with '0' gives the first key after all those with the prefix
+	keyRange := &util.Range{
+		Start: []byte(prefix),
+		Limit: []byte(prefix[0:len(prefix)-1] + "0"),
+	}
+	if filters.CreatedAfter != nil {
+		keyRange.Start = []byte(fmt.Sprintf("%s%.19d", prefix, filters.CreatedAfter.UnixNano()+1))
+	}
+	if filters.CreatedBefore != nil {
+		keyRange.Limit = []byte(fmt.Sprintf("%s%.19d", prefix, filters.CreatedBefore.UnixNano()))
+	}
+	if after != nil {
+		afterKey := []byte(prefix + txCreatedSuffix(after))
+		if dir == SortDirectionAscending {
+			// The zero byte suffix makes the start the first key after the cursor
+			if afterStart := append(afterKey, 0); bytes.Compare(afterStart, keyRange.Start) > 0 {
+				keyRange.Start = afterStart
+			}
+		} else if bytes.Compare(afterKey, keyRange.Limit) < 0 {
+			keyRange.Limit = afterKey
+		}
+	}
+
+	p.txMux.RLock()
+	transactions := make([]*apitypes.ManagedTX, 0)
+	it := p.db.NewIterator(keyRange, &opt.ReadOptions{DontFillCache: true})
+	orphanedIdxKeys, err := p.iterateJSON(ctx, it, limit, dir,
+		func() interface{} { var v *apitypes.ManagedTX; return &v },
+		func(v interface{}) { transactions = append(transactions, *(v.(**apitypes.ManagedTX))) },
+		p.indexLookupCallback,
+		func(v interface{}) bool { return filters.Matches(*(v.(**apitypes.ManagedTX))) },
+	)
+	it.Release()
+	p.txMux.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(orphanedIdxKeys) > 0 {
+		p.cleanupOrphanedTXIdxKeys(ctx, orphanedIdxKeys)
+	}
+	return transactions, nil
+}
+
 func (p *leveldbPersistence) ListTransactionsByNonce(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) {
 	afterStr := ""
 	if after != nil {
@@ -392,21 +624,22 @@ func (p *leveldbPersistence) GetTransactionByNonce(ctx context.Context, signer s
 }
 
 func (p *leveldbPersistence) WriteTransaction(ctx context.Context, tx *apitypes.ManagedTX, new bool) (err error) {
-	// We take a write-lock here, because we are writing multiple values (the indexes), and anybody
-	// attempting to read the critical nonce allocation index must know the difference between a partial write
-	// (we crashed before we completed all the writes) and an incomplete write that's in process.
-	// The reading code detects partial writes and cleans them up if it finds them.
+	// All the keys for the transaction (the document and its indexes) are written in a single atomic batch,
+	// so a crash can never leave partial state. We still take a write-lock here, so that the duplicate
+	// check is atomic with the write. Databases written by earlier versions, which wrote each key
+	// separately, might still contain orphaned index keys - which the reading code detects and cleans up.
 	p.txMux.Lock()
 	defer p.txMux.Unlock()
 
 	if tx.TransactionHeaders.From == "" ||
-		tx.Nonce == nil ||
 		tx.Created == nil ||
 		tx.ID == "" ||
 		tx.Status == "" {
 		return i18n.NewError(ctx, tmmsgs.MsgPersistenceTXIncomplete)
 	}
 	idKey := txDataKey(tx.ID)
+	batch := &leveldb.Batch{}
+	var existing *apitypes.ManagedTX
 	if new {
 		if tx.SequenceID != "" {
 			// for new transactions sequence ID should always be generated by persistence layer
@@ -416,48 +649,135 @@ func (p *leveldbPersistence) WriteTransaction(ctx context.Context, tx *apitypes.
 		}
 		tx.SequenceID = apitypes.NewULID().String()
 		// This must be a unique ID, otherwise we return a conflict.
-		// Note we use the final record we write at the end for the conflict check, and also that we're write locked here
+		// Note we're write locked here, so nobody else can write the same ID before our batch is written
 		if existing, err := p.getKeyValue(ctx, idKey); err != nil {
 			return err
 		} else if existing != nil {
 			return i18n.NewError(ctx, tmmsgs.MsgDuplicateID, idKey)
 		}
-
-		// We write the index records first - because if we crash, we need to be able to know if the
-		// index records are valid or not. When reading under the read lock, if there is an index key
-		// that does not have a corresponding managed TX available, we will clean up the
-		// orphaned index (after swapping the read lock for the write lock)
-		// See listTransactionsByIndex() for the other half of this logic.
-		err = p.writeKeyValue(ctx, txCreatedIndexKey(tx), idKey)
-		if err == nil && tx.Status == apitypes.TxStatusPending {
-			err = p.writeKeyValue(ctx, txPendingIndexKey(tx.SequenceID), idKey)
-		}
-		if err == nil {
-			err = p.writeKeyValue(ctx, txNonceAllocationKey(tx.TransactionHeaders.From, tx.Nonce), idKey)
+	} else if err := p.readJSON(ctx, idKey, &existing); err != nil {
+		return err
+	}
+	// The status and hash of a transaction can change, so we remove any index keys for the previous
+	// version of the transaction that are not valid for the new version
+	newKeys := txIndexKeys(tx)
+	if existing != nil {
+		for _, oldKey := range txIndexKeys(existing) {
+			if !containsKey(newKeys, oldKey) {
+				batch.Delete(oldKey)
+			}
 		}
 	}
-	// If we are creating/updating a record that is not pending, we need to ensure there is no pending index associated with it
-	if err == nil && tx.Status != apitypes.TxStatusPending {
-		err = p.deleteKeys(ctx, txPendingIndexKey(tx.SequenceID))
+	for _, k := range newKeys {
+		batch.Put(k, idKey)
 	}
-	if err == nil {
-		err = p.writeJSON(ctx, idKey, tx)
+	b, err := json.Marshal(tx)
+	if err != nil {
+		return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceMarshalFailed)
 	}
-	return err
+	batch.Put(idKey, b)
+	if err = p.writeBatch(ctx, batch); err != nil {
+		return err
+	}
+	log.L(ctx).Debugf("Wrote %s", idKey)
+	return nil
 }
 
 func (p *leveldbPersistence) DeleteTransaction(ctx context.Context, txID string) error {
+	historyKeys, err := p.txHistoryKeys(ctx, txID)
+	if err != nil {
+		return err
+	}
 	var tx *apitypes.ManagedTX
-	err := p.readJSON(ctx, txDataKey(txID), &tx)
+	err = p.readJSON(ctx, txDataKey(txID), &tx)
 	if err != nil || tx == nil {
 		return err
 	}
-	return p.deleteKeys(ctx,
-		txDataKey(txID),
-		txCreatedIndexKey(tx),
-		txPendingIndexKey(tx.SequenceID),
-		txNonceAllocationKey(tx.TransactionHeaders.From, tx.Nonce),
-	)
+	// We always attempt to delete the pending index, in case the transaction was written by an earlier
+	// version that did not reliably remove it
+	keys := append(txIndexKeys(tx), txDataKey(txID), txPendingIndexKey(tx.SequenceID))
+	return p.deleteKeys(ctx, append(keys, historyKeys...)...)
+}
+
+// txHistoryKeys returns the keys of all the history records for a transaction
+func (p *leveldbPersistence) txHistoryKeys(ctx context.Context, txID string) ([][]byte, error) {
+	prefix := txHistoryPrefixForTX(txID)
+	it := p.db.NewIterator(util.BytesPrefix([]byte(prefix)), &opt.ReadOptions{DontFillCache: true})
+	defer it.Release()
+	keys := [][]byte{}
+	for it.Next() {
+		// The prefix might also match the records of a transaction with a longer ID that contains a '/',
+		// so we only include keys where the remainder is exactly one record ID
+		if len(it.Key()) == len(prefix)+uuidStringLength {
+			keys = append(keys, append([]byte{}, it.Key()...))
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceReadFailed, prefix)
+	}
+	return keys, nil
+}
+
+func (p *leveldbPersistence) WriteTransactionHistory(ctx context.Context, record *apitypes.TxHistoryRecord) error {
+	return p.writeJSON(ctx, txHistoryKey(record), record)
+}
+
+func (p *leveldbPersistence) ListTransactionHistory(ctx context.Context, txID string, after *fftypes.UUID, limit int, dir SortDirection) ([]*apitypes.TxHistoryRecord, error) {
+	prefix := txHistoryPrefixForTX(txID)
+	records := make([]*apitypes.TxHistoryRecord, 0)
+	if _, err := p.listJSON(ctx, prefix, prefix[0:len(prefix)-1]+"0", after.String(), limit, dir,
+		func() interface{} { var v *apitypes.TxHistoryRecord; return &v },
+		func(v interface{}) { records = append(records, *(v.(**apitypes.TxHistoryRecord))) },
+		nil,
+		func(v interface{}) bool { return (*(v.(**apitypes.TxHistoryRecord))).TransactionID == txID },
+	); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (p *leveldbPersistence) ListNonceReservations(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir SortDirection) ([]*apitypes.NonceReservation, error) {
+	afterStr := ""
+	if after != nil {
+		afterStr = fmt.Sprintf("%.24d", after.Int())
+	}
+	reservations := make([]*apitypes.NonceReservation, 0)
+	if _, err := p.listJSON(ctx, signerNonceReservationsPrefix(signer), signerNonceReservationsEnd(signer), afterStr, limit, dir,
+		func() interface{} { var v *apitypes.NonceReservation; return &v },
+		func(v interface{}) { reservations = append(reservations, *(v.(**apitypes.NonceReservation))) },
+		nil,
+	); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (p *leveldbPersistence) WriteNonceReservation(ctx context.Context, reservation *apitypes.NonceReservation) error {
+	return p.writeJSON(ctx, nonceReservationKey(reservation), reservation)
+}
+
+func (p *leveldbPersistence) GetSignerMinimumNonce(ctx context.Context, signer string) (minimum *apitypes.SignerMinimumNonce, err error) {
+	err = p.readJSON(ctx, signerMinimumNonceKey(signer), &minimum)
+	return minimum, err
+}
+
+func (p *leveldbPersistence) ListSignerMinimumNonces(ctx context.Context, after string, limit int, dir SortDirection) ([]*apitypes.SignerMinimumNonce, error) {
+	if after != "" {
+		after += "/"
+	}
+	minimums := make([]*apitypes.SignerMinimumNonce, 0)
+	if _, err := p.listJSON(ctx, signerMinimumNoncesPrefix, signerMinimumNoncesEnd, after, limit, dir,
+		func() interface{} { var v *apitypes.SignerMinimumNonce; return &v },
+		func(v interface{}) { minimums = append(minimums, *(v.(**apitypes.SignerMinimumNonce))) },
+		nil,
+	); err != nil {
+		return nil, err
+	}
+	return minimums, nil
+}
+
+func (p *leveldbPersistence) WriteSignerMinimumNonce(ctx context.Context, minimum *apitypes.SignerMinimumNonce) error {
+	return p.writeJSON(ctx, signerMinimumNonceKey(minimum.Signer), minimum)
 }
 
 func (p *leveldbPersistence) Close(ctx context.Context) {
@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/firefly-common/pkg/config"
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
@@ -30,6 +31,7 @@ import (
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
 	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
@@ -94,6 +96,161 @@ func TestLevelDBInitFail(t *testing.T) {
 
 }
 
+func newBaselineLevelDB(t *testing.T, kvs map[string]string) string {
+	dir, err := ioutil.TempDir("", "ldb_*")
+	assert.NoError(t, err)
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.NoError(t, err)
+	for k, v := range kvs {
+		err = db.Put([]byte(k), []byte(v), &opt.WriteOptions{})
+		assert.NoError(t, err)
+	}
+	err = db.Close()
+	assert.NoError(t, err)
+
+	tmconfig.Reset()
+	config.Set(tmconfig.PersistenceLevelDBPath, dir)
+	return dir
+}
+
+func TestLevelDBReindexOnOpen(t *testing.T) {
+	// The keys as written by a version of FFTM that pre-dates the status, to and hash indexes,
+	// and the schema version key
+	txJSON := `{
+		"id": "ns1/tx1",
+		"created": "2023-01-01T00:00:00Z",
+		"status": "Pending",
+		"sequenceId": "seq1",
+		"nonce": "5",
+		"transactionHeaders": {"from": "0xaaaaa", "to": "0xbbbbb"},
+		"transactionHash": "0x12345",
+		"history": [{"subStatus": "Received", "time": "2023-01-01T00:00:00Z", "actions": []}]
+	}`
+	dir := newBaselineLevelDB(t, map[string]string{
+		"tx_0/ns1/tx1":                               txJSON,
+		"tx_created_0/1672531200000000000/seq1":      "tx_0/ns1/tx1",
+		"tx_inflight_0/seq1":                         "tx_0/ns1/tx1",
+		"nonce_0/0xaaaaa_0/000000000000000000000005": "tx_0/ns1/tx1",
+		"tx_0/ns1/bad":                               "!json",
+	})
+	defer os.RemoveAll(dir)
+
+	pp, err := NewLevelDBPersistence(context.Background())
+	assert.NoError(t, err)
+	p := pp.(*leveldbPersistence)
+	defer p.Close(context.Background())
+
+	ctx := context.Background()
+	for _, filters := range []*TransactionFilters{
+		{Status: apitypes.TxStatusPending},
+		{To: "0xbbbbb"},
+		{TransactionHash: "0x12345"},
+	} {
+		txs, err := p.ListTransactionsFiltered(ctx, filters, nil, 0, SortDirectionDescending)
+		assert.NoError(t, err)
+		assert.Len(t, txs, 1)
+		assert.Equal(t, "ns1/tx1", txs[0].ID)
+	}
+
+	var version int
+	err = p.readJSON(ctx, []byte(schemaVersionKey), &version)
+	assert.NoError(t, err)
+	assert.Equal(t, currentSchemaVersion, version)
+
+	// The upgrade is not repeated once the schema version is stored
+	created := fftypes.FFTime(time.Unix(0, 1672531200000000000))
+	err = p.db.Delete(txStatusIndexKey(&apitypes.ManagedTX{
+		Created:    &created,
+		SequenceID: "seq1",
+		Status:     apitypes.TxStatusPending,
+	}), &opt.WriteOptions{})
+	assert.NoError(t, err)
+	err = p.upgradeSchema(ctx)
+	assert.NoError(t, err)
+	txs, err := p.ListTransactionsFiltered(ctx, &TransactionFilters{Status: apitypes.TxStatusPending}, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Empty(t, txs)
+}
+
+func TestLevelDBReindexInBatches(t *testing.T) {
+	kvs := map[string]string{}
+	for i := 0; i < reindexBatchSize+1; i++ {
+		kvs[fmt.Sprintf("tx_0/ns1/tx%.4d", i)] = fmt.Sprintf(`{"id":"ns1/tx%.4d","created":"2023-01-01T00:00:00Z","status":"Succeeded","sequenceId":"seq%.4d","transactionHeaders":{"from":"0xaaaaa"}}`, i, i)
+	}
+	dir := newBaselineLevelDB(t, kvs)
+	defer os.RemoveAll(dir)
+
+	pp, err := NewLevelDBPersistence(context.Background())
+	assert.NoError(t, err)
+	defer pp.Close(context.Background())
+
+	txs, err := pp.ListTransactionsFiltered(context.Background(), &TransactionFilters{Status: apitypes.TxStatusSucceeded}, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txs, reindexBatchSize+1)
+}
+
+func TestLevelDBUpgradeSchemaBadVersion(t *testing.T) {
+	dir := newBaselineLevelDB(t, map[string]string{
+		schemaVersionKey: "!json",
+	})
+	defer os.RemoveAll(dir)
+
+	_, err := NewLevelDBPersistence(context.Background())
+	assert.Regexp(t, "FF21054", err)
+}
+
+func TestLevelDBReindexFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	err := p.WriteTransaction(context.Background(), tx, true)
+	assert.NoError(t, err)
+	err = p.db.Delete([]byte(schemaVersionKey), &opt.WriteOptions{})
+	assert.NoError(t, err)
+
+	err = p.db.SetReadOnly()
+	assert.NoError(t, err)
+	err = p.upgradeSchema(context.Background())
+	assert.Regexp(t, "FF21056", err)
+}
+
+func TestLevelDBReindexBatchFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	for i := 0; i < reindexBatchSize; i++ {
+		err := p.db.Put(txDataKey(fmt.Sprintf("ns1/tx%.4d", i)), []byte(`{}`), &opt.WriteOptions{})
+		assert.NoError(t, err)
+	}
+
+	err := p.db.SetReadOnly()
+	assert.NoError(t, err)
+	err = p.reindexTransactions(context.Background())
+	assert.Regexp(t, "FF21056", err)
+}
+
+func TestLevelDBUpgradeSchemaWriteVersionFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	err := p.db.Delete([]byte(schemaVersionKey), &opt.WriteOptions{})
+	assert.NoError(t, err)
+	err = p.db.SetReadOnly()
+	assert.NoError(t, err)
+	err = p.upgradeSchema(context.Background())
+	assert.Regexp(t, "FF21056", err)
+}
+
+func TestLevelDBReindexReadFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	p.db.Close()
+	err := p.reindexTransactions(context.Background())
+	assert.Regexp(t, "FF21055", err)
+}
+
 func TestReadWriteStreams(t *testing.T) {
 
 	p, done := newTestLevelDBPersistence(t)
@@ -271,6 +428,18 @@ func newTestTX(signer string, nonce int64, status apitypes.TxStatus) *apitypes.M
 	}
 }
 
+func newTestTXHistory(txID string, subStatus apitypes.TxSubStatus) *apitypes.TxHistoryRecord {
+	return &apitypes.TxHistoryRecord{
+		TransactionID: txID,
+		TxHistoryStateTransitionEntry: apitypes.TxHistoryStateTransitionEntry{
+			ID:      apitypes.NewULID(),
+			Time:    fftypes.Now(),
+			Status:  subStatus,
+			Actions: []*apitypes.TxHistoryActionEntry{},
+		},
+	}
+}
+
 func TestReadWriteManagedTransactions(t *testing.T) {
 
 	p, done := newTestLevelDBPersistence(t)
@@ -409,6 +578,315 @@ func TestWriteTXFail(t *testing.T) {
 
 }
 
+func TestWriteTXUpdateFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	tx := newTestTX("0x1234", 1000, apitypes.TxStatusPending)
+	err := p.WriteTransaction(context.Background(), tx, true)
+	assert.NoError(t, err)
+
+	p.db.Close()
+
+	tx.Status = apitypes.TxStatusSucceeded
+	err = p.WriteTransaction(context.Background(), tx, false)
+	assert.Regexp(t, "FF21055", err)
+
+}
+
+func TestWriteTXBatchFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	err := p.db.SetReadOnly()
+	assert.NoError(t, err)
+
+	tx := newTestTX("0x1234", 1000, apitypes.TxStatusPending)
+	err = p.WriteTransaction(context.Background(), tx, true)
+	assert.Regexp(t, "FF21056", err)
+
+}
+
+func TestWriteTXFailMarshal(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	tx := newTestTX("0x1234", 1000, apitypes.TxStatusPending)
+	tx.PolicyInfo = fftypes.JSONAnyPtr(`{"bad": "json"!`)
+	err := p.WriteTransaction(context.Background(), tx, true)
+	assert.Regexp(t, "FF21053", err)
+
+}
+
+func TestWriteTXAtomicIndexes(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	tx := newTestTX("0x1234", 1000, apitypes.TxStatusPending)
+	err := p.WriteTransaction(ctx, tx, true)
+	assert.NoError(t, err)
+
+	for _, k := range [][]byte{
+		txDataKey(tx.ID),
+		txCreatedIndexKey(tx),
+		txPendingIndexKey(tx.SequenceID),
+		txNonceAllocationKey(tx.TransactionHeaders.From, tx.Nonce),
+	} {
+		v, err := p.getKeyValue(ctx, k)
+		assert.NoError(t, err)
+		assert.NotNil(t, v, string(k))
+	}
+
+	tx.Status = apitypes.TxStatusSucceeded
+	err = p.WriteTransaction(ctx, tx, false)
+	assert.NoError(t, err)
+
+	v, err := p.getKeyValue(ctx, txPendingIndexKey(tx.SequenceID))
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+}
+
+func TestWriteTXUpdatesIndexes(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	tx := newTestTX("0x1234", 1000, apitypes.TxStatusPending)
+	tx.TransactionHeaders.To = "0x5678"
+	err := p.WriteTransaction(ctx, tx, true)
+	assert.NoError(t, err)
+	pendingKey := txStatusIndexKey(tx)
+
+	tx.TransactionHash = "0xabcd"
+	err = p.WriteTransaction(ctx, tx, false)
+	assert.NoError(t, err)
+	hashKey := txHashIndexKey(tx, "0xabcd")
+
+	// Every submitted hash remains indexed
+	tx.Status = apitypes.TxStatusSucceeded
+	tx.SetTransactionHash("0xef01")
+	err = p.WriteTransaction(ctx, tx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0xabcd", "0xef01"}, tx.SubmittedHashes)
+	assert.True(t, tx.HasSubmittedHash("0xabcd"))
+	assert.False(t, tx.HasSubmittedHash("0x2345"))
+	assert.Contains(t, txIndexKeys(tx), hashKey)
+	assert.Contains(t, txIndexKeys(tx), txHashIndexKey(tx, "0xef01"))
+
+	for _, k := range txIndexKeys(tx) {
+		v, err := p.getKeyValue(ctx, k)
+		assert.NoError(t, err)
+		assert.NotNil(t, v, string(k))
+	}
+	for _, k := range [][]byte{pendingKey, txPendingIndexKey(tx.SequenceID)} {
+		v, err := p.getKeyValue(ctx, k)
+		assert.NoError(t, err)
+		assert.Nil(t, v, string(k))
+	}
+
+	err = p.DeleteTransaction(ctx, tx.ID)
+	assert.NoError(t, err)
+	for _, k := range txIndexKeys(tx) {
+		v, err := p.getKeyValue(ctx, k)
+		assert.NoError(t, err)
+		assert.Nil(t, v, string(k))
+	}
+
+}
+
+func TestListTransactionsFiltered(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	checkListTransactionsFiltered(t, p)
+}
+
+// checkListTransactionsFiltered runs the same filter scenarios against any persistence implementation
+func checkListTransactionsFiltered(t *testing.T, p Persistence) {
+	ctx := context.Background()
+	base := time.Now().Add(-1 * time.Hour)
+	submitNewTX := func(i int, signer, to, hash string, status apitypes.TxStatus, subStatus apitypes.TxSubStatus) *apitypes.ManagedTX {
+		tx := newTestTX(signer, int64(i), status)
+		created := fftypes.FFTime(base.Add(time.Duration(i) * time.Minute))
+		tx.Created = &created
+		tx.Updated = &created
+		tx.TransactionHeaders.To = to
+		tx.TransactionHash = hash
+		tx.History = []*apitypes.TxHistoryStateTransitionEntry{{Status: subStatus}}
+		err := p.WriteTransaction(ctx, tx, true)
+		assert.NoError(t, err)
+		return tx
+	}
+	t1 := submitNewTX(1, "0xaaaa", "0x1111", "0xh1", apitypes.TxStatusSucceeded, apitypes.TxSubStatusConfirmed)
+	t2 := submitNewTX(2, "0xbbbb", "0x1111", "0xh2", apitypes.TxStatusFailed, apitypes.TxSubStatusFailed)
+	t3 := submitNewTX(3, "0xaaaa", "0x2222", "0xh3", apitypes.TxStatusPending, apitypes.TxSubStatusTracking)
+	t4 := submitNewTX(4, "0xaaaa", "0x1111", "", apitypes.TxStatusPending, apitypes.TxSubStatusReceived)
+	t5 := submitNewTX(5, "0xbbbb", "", "0xh3", apitypes.TxStatusPending, apitypes.TxSubStatusTracking)
+
+	checkList := func(filters *TransactionFilters, after *apitypes.ManagedTX, limit int, dir SortDirection, expected ...*apitypes.ManagedTX) {
+		txns, err := p.ListTransactionsFiltered(ctx, filters, after, limit, dir)
+		assert.NoError(t, err)
+		ids := make([]string, len(txns))
+		for i, tx := range txns {
+			ids[i] = tx.ID
+		}
+		expectedIDs := make([]string, len(expected))
+		for i, tx := range expected {
+			expectedIDs[i] = tx.ID
+		}
+		assert.Equal(t, expectedIDs, ids)
+	}
+
+	checkList(&TransactionFilters{}, nil, 0, SortDirectionDescending, t5, t4, t3, t2, t1)
+	checkList(&TransactionFilters{Status: apitypes.TxStatusPending}, nil, 0, SortDirectionAscending, t3, t4, t5)
+	checkList(&TransactionFilters{Status: apitypes.TxStatusPending, Signer: "0xaaaa"}, nil, 0, SortDirectionDescending, t4, t3)
+	checkList(&TransactionFilters{SubStatus: apitypes.TxSubStatusTracking}, nil, 0, SortDirectionDescending, t5, t3)
+	checkList(&TransactionFilters{To: "0x1111"}, nil, 0, SortDirectionDescending, t4, t2, t1)
+	checkList(&TransactionFilters{To: "0x1111", Status: apitypes.TxStatusPending}, nil, 0, SortDirectionDescending, t4)
+	checkList(&TransactionFilters{TransactionHash: "0xh3"}, nil, 0, SortDirectionDescending, t5, t3)
+	checkList(&TransactionFilters{TransactionHash: "0xh3", Signer: "0xbbbb"}, nil, 0, SortDirectionDescending, t5)
+
+	// Resubmission with a new hash, where the transaction can be found by either hash
+	t1.SetTransactionHash("0xh1b")
+	err := p.WriteTransaction(ctx, t1, false)
+	assert.NoError(t, err)
+	checkList(&TransactionFilters{TransactionHash: "0xh1"}, nil, 0, SortDirectionDescending, t1)
+	checkList(&TransactionFilters{TransactionHash: "0xh1b"}, nil, 0, SortDirectionDescending, t1)
+	checkList(&TransactionFilters{TransactionHash: "0xh1b", Signer: "0xbbbb"}, nil, 0, SortDirectionDescending)
+
+	// Time ranges, which are exclusive
+	checkList(&TransactionFilters{CreatedAfter: t2.Created, CreatedBefore: t5.Created}, nil, 0, SortDirectionDescending, t4, t3)
+	checkList(&TransactionFilters{To: "0x1111", CreatedAfter: t1.Created}, nil, 0, SortDirectionAscending, t2, t4)
+	checkList(&TransactionFilters{UpdatedAfter: t3.Updated}, nil, 0, SortDirectionDescending, t5, t4)
+	checkList(&TransactionFilters{UpdatedBefore: t3.Updated}, nil, 0, SortDirectionDescending, t2, t1)
+
+	// Cursor pagination in both directions, including with the cursor outside the time range
+	checkList(&TransactionFilters{Status: apitypes.TxStatusPending}, t3, 1, SortDirectionAscending, t4)
+	checkList(&TransactionFilters{Status: apitypes.TxStatusPending}, t4, 0, SortDirectionDescending, t3)
+	checkList(&TransactionFilters{CreatedAfter: t2.Created}, t1, 1, SortDirectionAscending, t3)
+	checkList(&TransactionFilters{CreatedBefore: t3.Created}, t5, 0, SortDirectionDescending, t2, t1)
+	checkList(&TransactionFilters{}, t2, 0, SortDirectionDescending, t1)
+	checkList(&TransactionFilters{}, t4, 0, SortDirectionAscending, t5)
+
+	// Pagination continues correctly when the cursor transaction has been deleted
+	err = p.DeleteTransaction(ctx, t3.ID)
+	assert.NoError(t, err)
+	checkList(&TransactionFilters{Status: apitypes.TxStatusPending}, t3, 0, SortDirectionAscending, t4, t5)
+}
+
+func TestTransactionWithoutNonce(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	checkTransactionWithoutNonce(t, p)
+}
+
+// checkTransactionWithoutNonce checks a scheduled transaction can be written before it is assigned a nonce, against any persistence implementation
+func checkTransactionWithoutNonce(t *testing.T, p Persistence) {
+	ctx := context.Background()
+	t1 := newTestTX("0xaaaa", 1, apitypes.TxStatusPending)
+	err := p.WriteTransaction(ctx, t1, true)
+	assert.NoError(t, err)
+	t2 := newTestTX("0xaaaa", 0, apitypes.TxStatusPending)
+	t2.Nonce = nil
+	err = p.WriteTransaction(ctx, t2, true)
+	assert.NoError(t, err)
+
+	// Only listed by nonce once it has one
+	txns, err := p.ListTransactionsByNonce(ctx, "0xaaaa", nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, t1.ID, txns[0].ID)
+	txns, err = p.ListTransactionsByNonce(ctx, "0xaaaa", nil, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	txns, err = p.ListTransactionsPending(ctx, "", 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 2)
+	txns, err = p.ListTransactionsFiltered(ctx, &TransactionFilters{Signer: "0xaaaa", NoNonce: true}, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, t2.ID, txns[0].ID)
+
+	t2.Nonce = fftypes.NewFFBigInt(2)
+	err = p.WriteTransaction(ctx, t2, false)
+	assert.NoError(t, err)
+	txns, err = p.ListTransactionsByNonce(ctx, "0xaaaa", nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 2)
+	assert.Equal(t, t2.ID, txns[0].ID)
+	tx, err := p.GetTransactionByNonce(ctx, "0xaaaa", fftypes.NewFFBigInt(2))
+	assert.NoError(t, err)
+	assert.Equal(t, t2.ID, tx.ID)
+}
+
+func TestListTransactionsFilteredFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	tx := &apitypes.ManagedTX{
+		ID:         fmt.Sprintf("ns1:%s", apitypes.NewULID()),
+		Created:    fftypes.Now(),
+		Status:     apitypes.TxStatusPending,
+		SequenceID: apitypes.NewULID().String(),
+	}
+	err := p.writeKeyValue(context.Background(), txStatusIndexKey(tx), txDataKey(tx.ID))
+	assert.NoError(t, err)
+	err = p.db.Put(txDataKey(tx.ID), []byte("{! not json"), &opt.WriteOptions{})
+	assert.NoError(t, err)
+
+	_, err = p.ListTransactionsFiltered(context.Background(), &TransactionFilters{Status: apitypes.TxStatusPending}, nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF21054", err)
+
+}
+
+func TestListTransactionsFilteredCleanupOrphans(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	tx := &apitypes.ManagedTX{
+		ID:         fmt.Sprintf("ns1:%s", apitypes.NewULID()),
+		Created:    fftypes.Now(),
+		SequenceID: apitypes.NewULID().String(),
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			To: "0x1234",
+		},
+	}
+	err := p.writeKeyValue(context.Background(), txToIndexKey(tx), txDataKey(tx.ID))
+	assert.NoError(t, err)
+
+	txns, err := p.ListTransactionsFiltered(context.Background(), &TransactionFilters{To: "0x1234"}, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+
+	cleanedUpIndex, err := p.getKeyValue(context.Background(), txToIndexKey(tx))
+	assert.NoError(t, err)
+	assert.Nil(t, cleanedUpIndex)
+
+}
+
+func TestDeleteStreamRemovesCheckpoint(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	s := &apitypes.EventStream{ID: apitypes.NewULID()}
+	err := p.WriteStream(ctx, s)
+	assert.NoError(t, err)
+	err = p.WriteCheckpoint(ctx, &apitypes.EventStreamCheckpoint{StreamID: s.ID})
+	assert.NoError(t, err)
+
+	err = p.DeleteStream(ctx, s.ID)
+	assert.NoError(t, err)
+
+	cp, err := p.GetCheckpoint(ctx, s.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, cp)
+
+}
+
 func TestWriteCheckpointFailMarshal(t *testing.T) {
 	p, done := newTestLevelDBPersistence(t)
 	defer done()
@@ -639,3 +1117,201 @@ func TestDeleteTransactionMissing(t *testing.T) {
 	assert.NoError(t, err)
 
 }
+
+func TestDeleteTransactionFail(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	p.db.Close()
+
+	err := p.DeleteTransaction(context.Background(), "tx1")
+	assert.Regexp(t, "FF21055", err)
+
+}
+
+func TestTransactionHistory(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	checkTransactionHistory(t, p)
+}
+
+// checkTransactionHistory runs the same history scenarios against any persistence implementation
+func checkTransactionHistory(t *testing.T, p Persistence) {
+	ctx := context.Background()
+	tx1 := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	tx2 := newTestTX("0xaaaaa", 2, apitypes.TxStatusPending)
+	tx2.ID = tx1.ID + "/sub" // shares the prefix of tx1
+	for _, tx := range []*apitypes.ManagedTX{tx1, tx2} {
+		err := p.WriteTransaction(ctx, tx, true)
+		assert.NoError(t, err)
+	}
+
+	h1 := newTestTXHistory(tx1.ID, apitypes.TxSubStatusReceived)
+	h2 := newTestTXHistory(tx1.ID, apitypes.TxSubStatusTracking)
+	h3 := newTestTXHistory(tx2.ID, apitypes.TxSubStatusReceived)
+	h4 := newTestTXHistory(tx1.ID, apitypes.TxSubStatusConfirmed)
+	for _, h := range []*apitypes.TxHistoryRecord{h1, h2, h3, h4} {
+		err := p.WriteTransactionHistory(ctx, h)
+		assert.NoError(t, err)
+	}
+
+	// Updates replace the existing record
+	h2.Actions = append(h2.Actions, &apitypes.TxHistoryActionEntry{Action: apitypes.TxActionSubmitTransaction, Count: 1})
+	err := p.WriteTransactionHistory(ctx, h2)
+	assert.NoError(t, err)
+
+	checkList := func(txID string, after *apitypes.TxHistoryRecord, limit int, dir SortDirection, expected ...*apitypes.TxHistoryRecord) {
+		var afterID *fftypes.UUID
+		if after != nil {
+			afterID = after.ID
+		}
+		records, err := p.ListTransactionHistory(ctx, txID, afterID, limit, dir)
+		assert.NoError(t, err)
+		ids := make([]string, len(records))
+		for i, r := range records {
+			ids[i] = r.ID.String()
+			assert.Equal(t, txID, r.TransactionID)
+		}
+		expectedIDs := make([]string, len(expected))
+		for i, r := range expected {
+			expectedIDs[i] = r.ID.String()
+		}
+		assert.Equal(t, expectedIDs, ids)
+	}
+	checkList(tx1.ID, nil, 0, SortDirectionAscending, h1, h2, h4)
+	checkList(tx1.ID, nil, 0, SortDirectionDescending, h4, h2, h1)
+	checkList(tx1.ID, h1, 1, SortDirectionAscending, h2)
+	checkList(tx1.ID, h4, 0, SortDirectionDescending, h2, h1)
+	checkList(tx2.ID, nil, 0, SortDirectionDescending, h3)
+
+	records, err := p.ListTransactionHistory(ctx, tx1.ID, h1.ID, 1, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, records[0].Actions, 1)
+
+	// Deleting the transaction deletes its history, but not the history of other transactions
+	err = p.DeleteTransaction(ctx, tx1.ID)
+	assert.NoError(t, err)
+	checkList(tx1.ID, nil, 0, SortDirectionDescending)
+	checkList(tx2.ID, nil, 0, SortDirectionDescending, h3)
+}
+
+func TestListTransactionHistoryBadJSON(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	h := newTestTXHistory("tx1", apitypes.TxSubStatusReceived)
+	err := p.writeKeyValue(context.Background(), txHistoryKey(h), []byte("!json"))
+	assert.NoError(t, err)
+
+	_, err = p.ListTransactionHistory(context.Background(), "tx1", nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF21054", err)
+}
+
+func TestNonceReservations(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	checkNonceReservations(t, p)
+}
+
+// checkNonceReservations runs the same nonce reservation scenarios against any persistence implementation
+func checkNonceReservations(t *testing.T, p Persistence) {
+	ctx := context.Background()
+	r1 := &apitypes.NonceReservation{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(10), Last: fftypes.NewFFBigInt(19)}
+	r2 := &apitypes.NonceReservation{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(100), Last: fftypes.NewFFBigInt(100)}
+	r3 := &apitypes.NonceReservation{Signer: "0xaaaaa0", First: fftypes.NewFFBigInt(5), Last: fftypes.NewFFBigInt(6)} // shares the prefix of the first signer
+	r4 := &apitypes.NonceReservation{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(20), Last: fftypes.NewFFBigInt(29)}
+	for _, r := range []*apitypes.NonceReservation{r1, r2, r3, r4} {
+		err := p.WriteNonceReservation(ctx, r)
+		assert.NoError(t, err)
+	}
+
+	// Updates replace the existing reservation
+	r4.Last = fftypes.NewFFBigInt(39)
+	err := p.WriteNonceReservation(ctx, r4)
+	assert.NoError(t, err)
+
+	checkList := func(signer string, after *fftypes.FFBigInt, limit int, dir SortDirection, expected ...*apitypes.NonceReservation) {
+		reservations, err := p.ListNonceReservations(ctx, signer, after, limit, dir)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, reservations)
+	}
+	checkList("0xaaaaa", nil, 0, SortDirectionAscending, r1, r4, r2)
+	checkList("0xaaaaa", nil, 1, SortDirectionDescending, r2)
+	checkList("0xaaaaa", r2.First, 0, SortDirectionDescending, r4, r1)
+	checkList("0xaaaaa", r1.First, 1, SortDirectionAscending, r4)
+	checkList("0xaaaaa0", nil, 0, SortDirectionDescending, r3)
+
+	reservations, err := p.ListNonceReservations(ctx, "0xbbbbb", nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Empty(t, reservations)
+}
+
+func TestListNonceReservationsBadJSON(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	r := &apitypes.NonceReservation{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(10), Last: fftypes.NewFFBigInt(19)}
+	err := p.writeKeyValue(context.Background(), nonceReservationKey(r), []byte("!json"))
+	assert.NoError(t, err)
+
+	_, err = p.ListNonceReservations(context.Background(), "0xaaaaa", nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF21054", err)
+}
+
+func TestSignerMinimumNonces(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	checkSignerMinimumNonces(t, p)
+}
+
+// checkSignerMinimumNonces runs the same signer minimum nonce scenarios against any persistence implementation
+func checkSignerMinimumNonces(t *testing.T, p Persistence) {
+	ctx := context.Background()
+	m1 := &apitypes.SignerMinimumNonce{Signer: "0xaaaaa", Nonce: fftypes.NewFFBigInt(10), Updated: fftypes.Now()}
+	m2 := &apitypes.SignerMinimumNonce{Signer: "0xaaaaa0", Nonce: fftypes.NewFFBigInt(5), Updated: fftypes.Now()} // shares the prefix of the first signer
+	m3 := &apitypes.SignerMinimumNonce{Signer: "0xbbbbb", Nonce: fftypes.NewFFBigInt(100), Updated: fftypes.Now()}
+	for _, m := range []*apitypes.SignerMinimumNonce{m3, m1, m2} {
+		err := p.WriteSignerMinimumNonce(ctx, m)
+		assert.NoError(t, err)
+	}
+
+	// Updates replace the existing minimum
+	m1.Nonce = fftypes.NewFFBigInt(20)
+	err := p.WriteSignerMinimumNonce(ctx, m1)
+	assert.NoError(t, err)
+
+	m, err := p.GetSignerMinimumNonce(ctx, "0xaaaaa")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), m.Nonce.Int64())
+	m, err = p.GetSignerMinimumNonce(ctx, "0xccccc")
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	checkList := func(after string, limit int, dir SortDirection, expected ...string) {
+		minimums, err := p.ListSignerMinimumNonces(ctx, after, limit, dir)
+		assert.NoError(t, err)
+		signers := make([]string, len(minimums))
+		for i, m := range minimums {
+			signers[i] = m.Signer
+		}
+		assert.Equal(t, expected, signers)
+	}
+	checkList("", 0, SortDirectionAscending, "0xaaaaa", "0xaaaaa0", "0xbbbbb")
+	checkList("", 1, SortDirectionDescending, "0xbbbbb")
+	checkList("0xaaaaa", 0, SortDirectionAscending, "0xaaaaa0", "0xbbbbb")
+	checkList("0xbbbbb", 0, SortDirectionDescending, "0xaaaaa0", "0xaaaaa")
+}
+
+func TestListSignerMinimumNoncesBadJSON(t *testing.T) {
+	p, done := newTestLevelDBPersistence(t)
+	defer done()
+
+	err := p.writeKeyValue(context.Background(), signerMinimumNonceKey("0xaaaaa"), []byte("!json"))
+	assert.NoError(t, err)
+
+	_, err = p.ListSignerMinimumNonces(context.Background(), "", 0, SortDirectionDescending)
+	assert.Regexp(t, "FF21054", err)
+}
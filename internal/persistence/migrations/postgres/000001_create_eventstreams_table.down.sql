@@ -0,0 +1,4 @@
+BEGIN;
+DROP INDEX IF EXISTS eventstreams_id;
+DROP TABLE IF EXISTS eventstreams;
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+CREATE TABLE eventstreams (
+  seq         SERIAL          PRIMARY KEY,
+  id          VARCHAR(36)     NOT NULL,
+  name        VARCHAR(256),
+  created     BIGINT,
+  updated     BIGINT,
+  doc         TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX eventstreams_id ON eventstreams(id);
+COMMIT;
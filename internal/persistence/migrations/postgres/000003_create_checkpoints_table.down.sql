@@ -0,0 +1,4 @@
+BEGIN;
+DROP INDEX IF EXISTS checkpoints_stream;
+DROP TABLE IF EXISTS checkpoints;
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+CREATE TABLE checkpoints (
+  seq         SERIAL          PRIMARY KEY,
+  stream_id   VARCHAR(36)     NOT NULL,
+  time        BIGINT,
+  doc         TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX checkpoints_stream ON checkpoints(stream_id);
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+DROP INDEX IF EXISTS transactions_status;
+DROP INDEX IF EXISTS transactions_created;
+DROP INDEX IF EXISTS transactions_nonce;
+DROP INDEX IF EXISTS transactions_sequence;
+DROP INDEX IF EXISTS transactions_id;
+DROP TABLE IF EXISTS transactions;
+COMMIT;
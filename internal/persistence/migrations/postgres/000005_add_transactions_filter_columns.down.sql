@@ -0,0 +1,6 @@
+BEGIN;
+DROP INDEX IF EXISTS transactions_tx_hash;
+DROP INDEX IF EXISTS transactions_to_address;
+ALTER TABLE transactions DROP COLUMN IF EXISTS sub_status;
+ALTER TABLE transactions DROP COLUMN IF EXISTS to_address;
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+CREATE TABLE transaction_hashes (
+  seq            SERIAL          PRIMARY KEY,
+  transaction_id VARCHAR(256)    NOT NULL,
+  hash           VARCHAR(256)    NOT NULL
+);
+CREATE UNIQUE INDEX transaction_hashes_id ON transaction_hashes(transaction_id, hash);
+CREATE INDEX transaction_hashes_hash ON transaction_hashes(hash);
+INSERT INTO transaction_hashes (transaction_id, hash)
+  SELECT id, tx_hash FROM transactions WHERE tx_hash IS NOT NULL AND tx_hash <> '';
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+DROP INDEX IF EXISTS transaction_history_transaction;
+DROP INDEX IF EXISTS transaction_history_id;
+DROP TABLE IF EXISTS transaction_history;
+COMMIT;
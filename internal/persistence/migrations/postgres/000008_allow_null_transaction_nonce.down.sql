@@ -0,0 +1,4 @@
+BEGIN;
+DELETE FROM transactions WHERE nonce IS NULL;
+ALTER TABLE transactions ALTER COLUMN nonce SET NOT NULL;
+COMMIT;
@@ -0,0 +1,3 @@
+BEGIN;
+ALTER TABLE transactions ALTER COLUMN nonce DROP NOT NULL;
+COMMIT;
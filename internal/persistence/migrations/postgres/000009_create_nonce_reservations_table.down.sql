@@ -0,0 +1,4 @@
+BEGIN;
+DROP INDEX IF EXISTS nonce_reservations_nonce;
+DROP TABLE IF EXISTS nonce_reservations;
+COMMIT;
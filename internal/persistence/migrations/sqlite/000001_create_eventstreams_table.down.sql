@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS eventstreams_id;
+DROP TABLE IF EXISTS eventstreams;
@@ -0,0 +1,9 @@
+CREATE TABLE eventstreams (
+  seq         INTEGER         PRIMARY KEY AUTOINCREMENT,
+  id          VARCHAR(36)     NOT NULL,
+  name        VARCHAR(256),
+  created     BIGINT,
+  updated     BIGINT,
+  doc         TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX eventstreams_id ON eventstreams(id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS listeners_stream;
+DROP INDEX IF EXISTS listeners_id;
+DROP TABLE IF EXISTS listeners;
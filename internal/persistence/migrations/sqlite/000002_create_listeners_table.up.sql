@@ -0,0 +1,11 @@
+CREATE TABLE listeners (
+  seq         INTEGER         PRIMARY KEY AUTOINCREMENT,
+  id          VARCHAR(36)     NOT NULL,
+  stream_id   VARCHAR(36),
+  name        VARCHAR(256),
+  created     BIGINT,
+  updated     BIGINT,
+  doc         TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX listeners_id ON listeners(id);
+CREATE INDEX listeners_stream ON listeners(stream_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS checkpoints_stream;
+DROP TABLE IF EXISTS checkpoints;
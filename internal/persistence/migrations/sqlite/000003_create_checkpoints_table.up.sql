@@ -0,0 +1,7 @@
+CREATE TABLE checkpoints (
+  seq         INTEGER         PRIMARY KEY AUTOINCREMENT,
+  stream_id   VARCHAR(36)     NOT NULL,
+  time        BIGINT,
+  doc         TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX checkpoints_stream ON checkpoints(stream_id);
@@ -0,0 +1,17 @@
+CREATE TABLE transactions (
+  seq         INTEGER         PRIMARY KEY AUTOINCREMENT,
+  id          VARCHAR(256)    NOT NULL,
+  sequence_id VARCHAR(36)     NOT NULL,
+  created     BIGINT          NOT NULL,
+  updated     BIGINT,
+  status      VARCHAR(64)     NOT NULL,
+  signer      VARCHAR(256)    NOT NULL,
+  nonce       BIGINT          NOT NULL,
+  tx_hash     VARCHAR(256),
+  doc         TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX transactions_id ON transactions(id);
+CREATE UNIQUE INDEX transactions_sequence ON transactions(sequence_id);
+CREATE INDEX transactions_nonce ON transactions(signer, nonce);
+CREATE INDEX transactions_created ON transactions(created, sequence_id);
+CREATE INDEX transactions_status ON transactions(status, sequence_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS transactions_tx_hash;
+DROP INDEX IF EXISTS transactions_to_address;
+ALTER TABLE transactions DROP COLUMN sub_status;
+ALTER TABLE transactions DROP COLUMN to_address;
@@ -0,0 +1,7 @@
+ALTER TABLE transactions ADD COLUMN to_address VARCHAR(256);
+ALTER TABLE transactions ADD COLUMN sub_status VARCHAR(64);
+UPDATE transactions SET
+  to_address = json_extract(doc, '$.transactionHeaders.to'),
+  sub_status = json_extract(doc, '$.history[#-1].subStatus');
+CREATE INDEX transactions_to_address ON transactions(to_address, created);
+CREATE INDEX transactions_tx_hash ON transactions(tx_hash, created);
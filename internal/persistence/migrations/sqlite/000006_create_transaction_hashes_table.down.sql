@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS transaction_hashes_hash;
+DROP INDEX IF EXISTS transaction_hashes_id;
+DROP TABLE IF EXISTS transaction_hashes;
@@ -0,0 +1,8 @@
+CREATE TABLE transaction_history (
+  seq            INTEGER         PRIMARY KEY AUTOINCREMENT,
+  id             VARCHAR(36)     NOT NULL,
+  transaction_id VARCHAR(256)    NOT NULL,
+  doc            TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX transaction_history_id ON transaction_history(id);
+CREATE INDEX transaction_history_transaction ON transaction_history(transaction_id, id);
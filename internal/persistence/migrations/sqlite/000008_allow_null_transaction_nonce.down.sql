@@ -0,0 +1,25 @@
+CREATE TABLE transactions_old (
+  seq         INTEGER         PRIMARY KEY AUTOINCREMENT,
+  id          VARCHAR(256)    NOT NULL,
+  sequence_id VARCHAR(36)     NOT NULL,
+  created     BIGINT          NOT NULL,
+  updated     BIGINT,
+  status      VARCHAR(64)     NOT NULL,
+  signer      VARCHAR(256)    NOT NULL,
+  nonce       BIGINT          NOT NULL,
+  tx_hash     VARCHAR(256),
+  doc         TEXT            NOT NULL,
+  to_address  VARCHAR(256),
+  sub_status  VARCHAR(64)
+);
+INSERT INTO transactions_old (seq, id, sequence_id, created, updated, status, signer, nonce, tx_hash, doc, to_address, sub_status)
+  SELECT seq, id, sequence_id, created, updated, status, signer, nonce, tx_hash, doc, to_address, sub_status FROM transactions WHERE nonce IS NOT NULL;
+DROP TABLE transactions;
+ALTER TABLE transactions_old RENAME TO transactions;
+CREATE UNIQUE INDEX transactions_id ON transactions(id);
+CREATE UNIQUE INDEX transactions_sequence ON transactions(sequence_id);
+CREATE INDEX transactions_nonce ON transactions(signer, nonce);
+CREATE INDEX transactions_created ON transactions(created, sequence_id);
+CREATE INDEX transactions_status ON transactions(status, sequence_id);
+CREATE INDEX transactions_to_address ON transactions(to_address, created);
+CREATE INDEX transactions_tx_hash ON transactions(tx_hash, created);
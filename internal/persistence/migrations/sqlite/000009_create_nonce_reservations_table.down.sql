@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS nonce_reservations_nonce;
+DROP TABLE IF EXISTS nonce_reservations;
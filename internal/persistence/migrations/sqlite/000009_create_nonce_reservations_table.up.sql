@@ -0,0 +1,8 @@
+CREATE TABLE nonce_reservations (
+  seq            INTEGER         PRIMARY KEY AUTOINCREMENT,
+  signer         VARCHAR(256)    NOT NULL,
+  first_nonce    BIGINT          NOT NULL,
+  last_nonce     BIGINT          NOT NULL,
+  doc            TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX nonce_reservations_nonce ON nonce_reservations(signer, first_nonce);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS signer_minimum_nonces_signer;
+DROP TABLE IF EXISTS signer_minimum_nonces;
@@ -0,0 +1,7 @@
+CREATE TABLE signer_minimum_nonces (
+  seq            INTEGER         PRIMARY KEY AUTOINCREMENT,
+  signer         VARCHAR(256)    NOT NULL,
+  nonce          BIGINT          NOT NULL,
+  doc            TEXT            NOT NULL
+);
+CREATE UNIQUE INDEX signer_minimum_nonces_signer ON signer_minimum_nonces(signer);
@@ -20,6 +20,8 @@ import (
 	"context"
 
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 )
 
@@ -30,6 +32,78 @@ const (
 	SortDirectionDescending
 )
 
+// TransactionFilters are the criteria for ListTransactionsFiltered. All supplied criteria must match,
+// and empty/nil fields are ignored. Time ranges are exclusive.
+type TransactionFilters struct {
+	Signer          string
+	Status          apitypes.TxStatus
+	SubStatus       apitypes.TxSubStatus
+	To              string
+	TransactionHash string // matches the current hash, or any hash the transaction was previously submitted with
+	CreatedAfter    *fftypes.FFTime
+	CreatedBefore   *fftypes.FFTime
+	UpdatedAfter    *fftypes.FFTime
+	UpdatedBefore   *fftypes.FFTime
+	NoNonce         bool // only those not yet assigned a nonce, such as scheduled transactions
+}
+
+// Matches checks the filters against a transaction, for persistence implementations that cannot apply
+// all of the filters in their query
+func (f *TransactionFilters) Matches(tx *apitypes.ManagedTX) bool {
+	updated := tx.Updated
+	if updated == nil {
+		updated = tx.Created
+	}
+	switch {
+	case f.Signer != "" && tx.TransactionHeaders.From != f.Signer,
+		f.Status != "" && tx.Status != f.Status,
+		f.SubStatus != "" && CurrentSubStatus(tx) != f.SubStatus,
+		f.To != "" && tx.TransactionHeaders.To != f.To,
+		f.TransactionHash != "" && !tx.HasSubmittedHash(f.TransactionHash),
+		f.CreatedAfter != nil && tx.Created.UnixNano() <= f.CreatedAfter.UnixNano(),
+		f.CreatedBefore != nil && tx.Created.UnixNano() >= f.CreatedBefore.UnixNano(),
+		f.UpdatedAfter != nil && updated.UnixNano() <= f.UpdatedAfter.UnixNano(),
+		f.UpdatedBefore != nil && updated.UnixNano() >= f.UpdatedBefore.UnixNano(),
+		f.NoNonce && tx.Nonce != nil:
+		return false
+	}
+	return true
+}
+
+// CurrentSubStatus returns the sub-status of the most recent history entry of the transaction
+func CurrentSubStatus(tx *apitypes.ManagedTX) apitypes.TxSubStatus {
+	if len(tx.History) == 0 {
+		return ""
+	}
+	return tx.History[len(tx.History)-1].Status
+}
+
+var builtinPersistence = map[string]func(ctx context.Context) (Persistence, error){
+	"leveldb":  NewLevelDBPersistence,
+	"postgres": NewPostgresPersistence,
+	"sqlite3":  NewSQLitePersistence,
+	"inmemory": NewInMemoryPersistence,
+}
+
+// IsBuiltinPersistence returns true if pType is one of the persistence types built into FFTM
+func IsBuiltinPersistence(pType string) bool {
+	_, ok := builtinPersistence[pType]
+	return ok
+}
+
+// NewBuiltinPersistence creates one of the persistence types built into FFTM. The boolean return is false
+// if pType is not a built-in type, in which case it might be resolved through the persistence registry.
+func NewBuiltinPersistence(ctx context.Context, pType string) (p Persistence, builtin bool, err error) {
+	newPersistence, ok := builtinPersistence[pType]
+	if !ok {
+		return nil, false, nil
+	}
+	if p, err = newPersistence(ctx); err != nil {
+		return nil, true, i18n.NewError(ctx, tmmsgs.MsgPersistenceInitFail, pType, err)
+	}
+	return p, true, nil
+}
+
 // Persistence interface contains all the functions a persistence instance needs to implement.
 // Sub set of functions are grouped into sub interfaces to provide a clear view of what
 // persistent functions will be made available for each sub components to use after the persistent
@@ -38,6 +112,7 @@ type Persistence interface {
 	EventStreamPersistence
 	ListenerPersistence
 	TransactionPersistence
+	TransactionHistoryPersistence
 
 	// close function is controlled by the manager
 	Close(ctx context.Context)
@@ -61,11 +136,22 @@ type ListenerPersistence interface {
 	DeleteListener(ctx context.Context, listenerID *fftypes.UUID) error
 }
 type TransactionPersistence interface {
-	ListTransactionsByCreateTime(ctx context.Context, after *apitypes.ManagedTX, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error)         // reverse create time order
-	ListTransactionsByNonce(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) // reverse nonce order within signer
-	ListTransactionsPending(ctx context.Context, afterSequenceID string, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error)                 // reverse UUIDv1 order, only those in pending state
+	ListTransactionsByCreateTime(ctx context.Context, after *apitypes.ManagedTX, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error)                          // reverse create time order
+	ListTransactionsByNonce(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error)                  // reverse nonce order within signer, excluding those not yet assigned a nonce
+	ListTransactionsPending(ctx context.Context, afterSequenceID string, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error)                                  // reverse UUIDv1 order, only those in pending state
+	ListTransactionsFiltered(ctx context.Context, filters *TransactionFilters, after *apitypes.ManagedTX, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) // reverse create time order, only those matching all the filters
 	GetTransactionByID(ctx context.Context, txID string) (*apitypes.ManagedTX, error)
 	GetTransactionByNonce(ctx context.Context, signer string, nonce *fftypes.FFBigInt) (*apitypes.ManagedTX, error)
 	WriteTransaction(ctx context.Context, tx *apitypes.ManagedTX, new bool) error // must reject if new is true, and the request ID is no
-	DeleteTransaction(ctx context.Context, txID string) error
+	DeleteTransaction(ctx context.Context, txID string) error                     // must also delete the history records of the transaction
+
+	ListNonceReservations(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir SortDirection) ([]*apitypes.NonceReservation, error) // reverse order of first nonce within signer
+	WriteNonceReservation(ctx context.Context, reservation *apitypes.NonceReservation) error                                                               // inserts, or replaces the existing reservation with the same first nonce for the signer
+	GetSignerMinimumNonce(ctx context.Context, signer string) (*apitypes.SignerMinimumNonce, error)
+	ListSignerMinimumNonces(ctx context.Context, after string, limit int, dir SortDirection) ([]*apitypes.SignerMinimumNonce, error) // reverse signer order
+	WriteSignerMinimumNonce(ctx context.Context, minimum *apitypes.SignerMinimumNonce) error                                         // inserts, or replaces the existing minimum for the signer
+}
+type TransactionHistoryPersistence interface {
+	WriteTransactionHistory(ctx context.Context, record *apitypes.TxHistoryRecord) error                                                             // inserts, or replaces the existing record with the same ID
+	ListTransactionHistory(ctx context.Context, txID string, after *fftypes.UUID, limit int, dir SortDirection) ([]*apitypes.TxHistoryRecord, error) // reverse ULID order
 }
@@ -0,0 +1,68 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/dbsql"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBuiltinPersistence(t *testing.T) {
+	ctx := context.Background()
+	tmconfig.Reset()
+	config.Set(tmconfig.PersistenceLevelDBPath, t.TempDir())
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfDatasourceURL, fmt.Sprintf("file:%s", path.Join(t.TempDir(), "fftm.db")))
+
+	for _, pType := range []string{"leveldb", "sqlite3", "inmemory"} {
+		p, builtin, err := NewBuiltinPersistence(ctx, pType)
+		assert.NoError(t, err)
+		assert.True(t, builtin)
+		p.Close(ctx)
+	}
+
+	p, builtin, err := NewBuiltinPersistence(ctx, "postgres")
+	assert.Regexp(t, "FF21049.*FF00183", err)
+	assert.True(t, builtin)
+	assert.Nil(t, p)
+
+	p, builtin, err = NewBuiltinPersistence(ctx, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, builtin)
+	assert.Nil(t, p)
+
+	assert.True(t, IsBuiltinPersistence("leveldb"))
+	assert.False(t, IsBuiltinPersistence("wrong"))
+}
+
+func TestTransactionFiltersMatchCreatedWhenNotUpdated(t *testing.T) {
+	created := fftypes.FFTime(time.Now())
+	tx := &apitypes.ManagedTX{Created: &created}
+
+	before := fftypes.FFTime(created.Time().Add(-1 * time.Second))
+	assert.True(t, (&TransactionFilters{UpdatedAfter: &before}).Matches(tx))
+	assert.False(t, (&TransactionFilters{UpdatedBefore: &before}).Matches(tx))
+}
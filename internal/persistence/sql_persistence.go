@@ -0,0 +1,605 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"path"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/dbsql"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+const (
+	eventstreamsTable = "eventstreams"
+	listenersTable    = "listeners"
+	checkpointsTable  = "checkpoints"
+	transactionsTable = "transactions"
+	txHashesTable     = "transaction_hashes"
+	txHistoryTable    = "transaction_history"
+	nonceResTable     = "nonce_reservations"
+	minNoncesTable    = "signer_minimum_nonces"
+)
+
+type sqlPersistence struct {
+	db dbsql.Database
+}
+
+// embeddedMigrationsConfig hides the migrations.auto setting from dbsql.Database.Init, which would otherwise
+// attempt to load the migrations from a directory on disk. Ours are embedded in the binary, and applied
+// separately in applyEmbeddedMigrations()
+type embeddedMigrationsConfig struct {
+	config.Section
+}
+
+func (c *embeddedMigrationsConfig) GetBool(key string) bool {
+	if key == dbsql.SQLConfMigrationsAuto {
+		return false
+	}
+	return c.Section.GetBool(key)
+}
+
+func NewPostgresPersistence(ctx context.Context) (Persistence, error) {
+	return newSQLPersistence(ctx, &postgresProvider{}, tmconfig.PersistencePostgresConfig)
+}
+
+func NewSQLitePersistence(ctx context.Context) (Persistence, error) {
+	return newSQLPersistence(ctx, &sqliteProvider{}, tmconfig.PersistenceSQLiteConfig)
+}
+
+func newSQLPersistence(ctx context.Context, provider dbsql.Provider, conf config.Section) (*sqlPersistence, error) {
+	p := &sqlPersistence{}
+	if err := p.db.Init(ctx, provider, &embeddedMigrationsConfig{Section: conf}); err != nil {
+		return nil, err
+	}
+	if conf.GetBool(dbsql.SQLConfMigrationsAuto) {
+		if err := p.applyEmbeddedMigrations(ctx, provider); err != nil {
+			p.db.Close()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *sqlPersistence) applyEmbeddedMigrations(ctx context.Context, provider dbsql.Provider) error {
+	source, err := iofs.New(migrationsFS, path.Join("migrations", provider.MigrationsDir()))
+	var driver migratedb.Driver
+	if err == nil {
+		driver, err = provider.GetMigrationDriver(p.db.DB())
+	}
+	var m *migrate.Migrate
+	if err == nil {
+		m, err = migrate.NewWithInstance("iofs", source, provider.MigrationsDir(), driver)
+	}
+	if err == nil {
+		err = m.Up()
+	}
+	if err != nil && err != migrate.ErrNoChange {
+		return i18n.WrapError(ctx, err, i18n.MsgDBMigrationFailed)
+	}
+	return nil
+}
+
+func (p *sqlPersistence) readDoc(ctx context.Context, table string, where sq.Sqlizer, target interface{}) error {
+	rows, _, err := p.db.Query(ctx, table,
+		sq.Select("doc").From(table).Where(where).OrderBy("seq DESC").Limit(1),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		log.L(ctx).Debugf("%s %v not found", table, where)
+		return nil
+	}
+	var doc string
+	if err = rows.Scan(&doc); err != nil {
+		return i18n.WrapError(ctx, err, i18n.MsgDBReadErr, table)
+	}
+	if err = json.Unmarshal([]byte(doc), target); err != nil {
+		return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceUnmarshalFailed)
+	}
+	return nil
+}
+
+func (p *sqlPersistence) listDocs(ctx context.Context, table string, q sq.SelectBuilder, limit int,
+	val func() interface{}, // return a pointer to a pointer variable, of the type to unmarshal
+	add func(interface{}), // passes back the val() for adding to the list
+) error {
+	if limit > 0 {
+		q = q.Limit(uint64(limit))
+	}
+	rows, _, err := p.db.Query(ctx, table, q.Columns("doc").From(table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		var doc string
+		if err = rows.Scan(&doc); err != nil {
+			return i18n.WrapError(ctx, err, i18n.MsgDBReadErr, table)
+		}
+		v := val()
+		if err = json.Unmarshal([]byte(doc), v); err != nil {
+			return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceUnmarshalFailed)
+		}
+		add(v)
+		count++
+	}
+	log.L(ctx).Debugf("Listed %d items", count)
+	return nil
+}
+
+// upsertDoc updates the row matching the key, or inserts a new row if none exists
+func (p *sqlPersistence) upsertDoc(ctx context.Context, table string, key sq.Eq, values map[string]interface{}, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return i18n.WrapError(ctx, err, tmmsgs.MsgPersistenceMarshalFailed)
+	}
+	values["doc"] = string(b)
+
+	ctx, tx, autoCommit, err := p.db.BeginOrUseTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.db.RollbackTx(ctx, tx, autoCommit)
+
+	updated, err := p.db.UpdateTx(ctx, table, tx, sq.Update(table).SetMap(values).Where(key), nil)
+	if err == nil && updated == 0 {
+		for k, v := range key {
+			values[k] = v
+		}
+		_, err = p.db.InsertTx(ctx, table, tx, sq.Insert(table).SetMap(values), nil)
+	}
+	if err != nil {
+		return err
+	}
+	log.L(ctx).Debugf("Wrote %s %v", table, key)
+	return p.db.CommitTx(ctx, tx, autoCommit)
+}
+
+func (p *sqlPersistence) deleteRows(ctx context.Context, table string, key sq.Eq) error {
+	ctx, tx, autoCommit, err := p.db.BeginOrUseTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.db.RollbackTx(ctx, tx, autoCommit)
+
+	err = p.db.DeleteTx(ctx, table, tx, sq.Delete(table).Where(key), nil)
+	if err != nil && err != fftypes.DeleteRecordNotFound {
+		return err
+	}
+	log.L(ctx).Debugf("Deleted %s %v", table, key)
+	return p.db.CommitTx(ctx, tx, autoCommit)
+}
+
+// pageByID applies the "after" semantics of the LevelDB key ordering to a string ID column
+func pageByID(column, after string, dir SortDirection) sq.SelectBuilder {
+	q := sq.Select()
+	switch dir {
+	case SortDirectionAscending:
+		if after != "" {
+			q = q.Where(sq.Gt{column: after})
+		}
+		return q.OrderBy(column + " ASC")
+	default:
+		if after != "" {
+			q = q.Where(sq.Lt{column: after})
+		}
+		return q.OrderBy(column + " DESC")
+	}
+}
+
+func (p *sqlPersistence) WriteCheckpoint(ctx context.Context, checkpoint *apitypes.EventStreamCheckpoint) error {
+	return p.upsertDoc(ctx, checkpointsTable, sq.Eq{"stream_id": checkpoint.StreamID.String()}, map[string]interface{}{
+		"time": checkpoint.Time.UnixNano(),
+	}, checkpoint)
+}
+
+func (p *sqlPersistence) GetCheckpoint(ctx context.Context, streamID *fftypes.UUID) (cp *apitypes.EventStreamCheckpoint, err error) {
+	err = p.readDoc(ctx, checkpointsTable, sq.Eq{"stream_id": streamID.String()}, &cp)
+	return cp, err
+}
+
+func (p *sqlPersistence) DeleteCheckpoint(ctx context.Context, streamID *fftypes.UUID) error {
+	return p.deleteRows(ctx, checkpointsTable, sq.Eq{"stream_id": streamID.String()})
+}
+
+func (p *sqlPersistence) ListStreams(ctx context.Context, after *fftypes.UUID, limit int, dir SortDirection) ([]*apitypes.EventStream, error) {
+	streams := make([]*apitypes.EventStream, 0)
+	if err := p.listDocs(ctx, eventstreamsTable, pageByID("id", after.String(), dir), limit,
+		func() interface{} { var v *apitypes.EventStream; return &v },
+		func(v interface{}) { streams = append(streams, *(v.(**apitypes.EventStream))) },
+	); err != nil {
+		return nil, err
+	}
+	return streams, nil
+}
+
+func (p *sqlPersistence) GetStream(ctx context.Context, streamID *fftypes.UUID) (es *apitypes.EventStream, err error) {
+	err = p.readDoc(ctx, eventstreamsTable, sq.Eq{"id": streamID.String()}, &es)
+	return es, err
+}
+
+func (p *sqlPersistence) WriteStream(ctx context.Context, spec *apitypes.EventStream) error {
+	return p.upsertDoc(ctx, eventstreamsTable, sq.Eq{"id": spec.ID.String()}, map[string]interface{}{
+		"name":    spec.Name,
+		"created": spec.Created.UnixNano(),
+		"updated": spec.Updated.UnixNano(),
+	}, spec)
+}
+
+func (p *sqlPersistence) DeleteStream(ctx context.Context, streamID *fftypes.UUID) error {
+	// The checkpoint is removed in the same DB transaction, so we never leave a checkpoint behind for a deleted stream
+	ctx, tx, autoCommit, err := p.db.BeginOrUseTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.db.RollbackTx(ctx, tx, autoCommit)
+
+	err = p.deleteRows(ctx, checkpointsTable, sq.Eq{"stream_id": streamID.String()})
+	if err == nil {
+		err = p.deleteRows(ctx, eventstreamsTable, sq.Eq{"id": streamID.String()})
+	}
+	if err != nil {
+		return err
+	}
+	return p.db.CommitTx(ctx, tx, autoCommit)
+}
+
+func (p *sqlPersistence) ListListeners(ctx context.Context, after *fftypes.UUID, limit int, dir SortDirection) ([]*apitypes.Listener, error) {
+	listeners := make([]*apitypes.Listener, 0)
+	if err := p.listDocs(ctx, listenersTable, pageByID("id", after.String(), dir), limit,
+		func() interface{} { var v *apitypes.Listener; return &v },
+		func(v interface{}) { listeners = append(listeners, *(v.(**apitypes.Listener))) },
+	); err != nil {
+		return nil, err
+	}
+	return listeners, nil
+}
+
+func (p *sqlPersistence) ListStreamListeners(ctx context.Context, after *fftypes.UUID, limit int, dir SortDirection, streamID *fftypes.UUID) ([]*apitypes.Listener, error) {
+	listeners := make([]*apitypes.Listener, 0)
+	if err := p.listDocs(ctx, listenersTable, pageByID("id", after.String(), dir).Where(sq.Eq{"stream_id": streamID.String()}), limit,
+		func() interface{} { var v *apitypes.Listener; return &v },
+		func(v interface{}) { listeners = append(listeners, *(v.(**apitypes.Listener))) },
+	); err != nil {
+		return nil, err
+	}
+	return listeners, nil
+}
+
+func (p *sqlPersistence) GetListener(ctx context.Context, listenerID *fftypes.UUID) (l *apitypes.Listener, err error) {
+	err = p.readDoc(ctx, listenersTable, sq.Eq{"id": listenerID.String()}, &l)
+	return l, err
+}
+
+func (p *sqlPersistence) WriteListener(ctx context.Context, spec *apitypes.Listener) error {
+	return p.upsertDoc(ctx, listenersTable, sq.Eq{"id": spec.ID.String()}, map[string]interface{}{
+		"stream_id": spec.StreamID.String(),
+		"name":      spec.Name,
+		"created":   spec.Created.UnixNano(),
+		"updated":   spec.Updated.UnixNano(),
+	}, spec)
+}
+
+func (p *sqlPersistence) DeleteListener(ctx context.Context, listenerID *fftypes.UUID) error {
+	return p.deleteRows(ctx, listenersTable, sq.Eq{"id": listenerID.String()})
+}
+
+func (p *sqlPersistence) listTransactions(ctx context.Context, q sq.SelectBuilder, limit int) ([]*apitypes.ManagedTX, error) {
+	transactions := make([]*apitypes.ManagedTX, 0)
+	if err := p.listDocs(ctx, transactionsTable, q, limit,
+		func() interface{} { var v *apitypes.ManagedTX; return &v },
+		func(v interface{}) { transactions = append(transactions, *(v.(**apitypes.ManagedTX))) },
+	); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// createTimeOrder orders a query by create time, starting after the supplied transaction if non-nil
+func createTimeOrder(q sq.SelectBuilder, after *apitypes.ManagedTX, dir SortDirection) sq.SelectBuilder {
+	switch dir {
+	case SortDirectionAscending:
+		if after != nil {
+			q = q.Where(sq.Or{
+				sq.Gt{"created": after.Created.UnixNano()},
+				sq.And{sq.Eq{"created": after.Created.UnixNano()}, sq.Gt{"sequence_id": after.SequenceID}},
+			})
+		}
+		return q.OrderBy("created ASC", "sequence_id ASC")
+	default:
+		if after != nil {
+			q = q.Where(sq.Or{
+				sq.Lt{"created": after.Created.UnixNano()},
+				sq.And{sq.Eq{"created": after.Created.UnixNano()}, sq.Lt{"sequence_id": after.SequenceID}},
+			})
+		}
+		return q.OrderBy("created DESC", "sequence_id DESC")
+	}
+}
+
+func (p *sqlPersistence) ListTransactionsByCreateTime(ctx context.Context, after *apitypes.ManagedTX, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) {
+	return p.listTransactions(ctx, createTimeOrder(sq.Select(), after, dir), limit)
+}
+
+func (p *sqlPersistence) ListTransactionsFiltered(ctx context.Context, filters *TransactionFilters, after *apitypes.ManagedTX, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) {
+	q := sq.Select()
+	for _, f := range []struct{ col, val string }{
+		{"signer", filters.Signer},
+		{"status", string(filters.Status)},
+		{"sub_status", string(filters.SubStatus)},
+		{"to_address", filters.To},
+	} {
+		if f.val != "" {
+			q = q.Where(sq.Eq{f.col: f.val})
+		}
+	}
+	if filters.TransactionHash != "" {
+		q = q.Where(sq.Expr("id IN (SELECT transaction_id FROM "+txHashesTable+" WHERE hash = ?)", filters.TransactionHash))
+	}
+	if filters.CreatedAfter != nil {
+		q = q.Where(sq.Gt{"created": filters.CreatedAfter.UnixNano()})
+	}
+	if filters.CreatedBefore != nil {
+		q = q.Where(sq.Lt{"created": filters.CreatedBefore.UnixNano()})
+	}
+	if filters.UpdatedAfter != nil {
+		q = q.Where(sq.Gt{"updated": filters.UpdatedAfter.UnixNano()})
+	}
+	if filters.UpdatedBefore != nil {
+		q = q.Where(sq.Lt{"updated": filters.UpdatedBefore.UnixNano()})
+	}
+	if filters.NoNonce {
+		q = q.Where(sq.Eq{"nonce": nil})
+	}
+	return p.listTransactions(ctx, createTimeOrder(q, after, dir), limit)
+}
+
+func (p *sqlPersistence) ListTransactionsByNonce(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) {
+	q := sq.Select().Where(sq.Eq{"signer": signer}).Where(sq.NotEq{"nonce": nil})
+	switch dir {
+	case SortDirectionAscending:
+		if after != nil {
+			q = q.Where(sq.Gt{"nonce": after.Int64()})
+		}
+		q = q.OrderBy("nonce ASC", "seq ASC")
+	default:
+		if after != nil {
+			q = q.Where(sq.Lt{"nonce": after.Int64()})
+		}
+		q = q.OrderBy("nonce DESC", "seq DESC")
+	}
+	return p.listTransactions(ctx, q, limit)
+}
+
+func (p *sqlPersistence) ListTransactionsPending(ctx context.Context, afterSequenceID string, limit int, dir SortDirection) ([]*apitypes.ManagedTX, error) {
+	return p.listTransactions(ctx, pageByID("sequence_id", afterSequenceID, dir).Where(sq.Eq{"status": apitypes.TxStatusPending}), limit)
+}
+
+func (p *sqlPersistence) GetTransactionByID(ctx context.Context, txID string) (tx *apitypes.ManagedTX, err error) {
+	err = p.readDoc(ctx, transactionsTable, sq.Eq{"id": txID}, &tx)
+	return tx, err
+}
+
+func (p *sqlPersistence) GetTransactionByNonce(ctx context.Context, signer string, nonce *fftypes.FFBigInt) (tx *apitypes.ManagedTX, err error) {
+	err = p.readDoc(ctx, transactionsTable, sq.Eq{"signer": signer, "nonce": nonce.Int64()}, &tx)
+	return tx, err
+}
+
+func (p *sqlPersistence) WriteTransaction(ctx context.Context, tx *apitypes.ManagedTX, new bool) (err error) {
+	if tx.TransactionHeaders.From == "" ||
+		tx.Created == nil ||
+		tx.ID == "" ||
+		tx.Status == "" {
+		return i18n.NewError(ctx, tmmsgs.MsgPersistenceTXIncomplete)
+	}
+
+	// All the checks and writes happen in a single DB transaction, so the indexed columns
+	// and the document are always consistent with each other
+	ctx, dbTX, autoCommit, err := p.db.BeginOrUseTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.db.RollbackTx(ctx, dbTX, autoCommit)
+
+	if new {
+		if tx.SequenceID != "" {
+			// for new transactions sequence ID should always be generated by persistence layer
+			// as the format of its value is persistence service specific
+			log.L(ctx).Errorf("Sequence ID is not allowed for new transaction %s", tx.ID)
+			return i18n.NewError(ctx, tmmsgs.MsgPersistenceSequenceIDNotAllowed)
+		}
+		tx.SequenceID = apitypes.NewULID().String()
+		// This must be a unique ID, otherwise we return a conflict.
+		var existing *apitypes.ManagedTX
+		if err := p.readDoc(ctx, transactionsTable, sq.Eq{"id": tx.ID}, &existing); err != nil {
+			return err
+		} else if existing != nil {
+			return i18n.NewError(ctx, tmmsgs.MsgDuplicateID, tx.ID)
+		}
+	}
+
+	if err = p.upsertDoc(ctx, transactionsTable, sq.Eq{"id": tx.ID}, map[string]interface{}{
+		"sequence_id": tx.SequenceID,
+		"created":     tx.Created.UnixNano(),
+		"updated":     tx.Updated.UnixNano(),
+		"status":      string(tx.Status),
+		"signer":      tx.TransactionHeaders.From,
+		"nonce":       nonceColumn(tx.Nonce),
+		"tx_hash":     tx.TransactionHash,
+		"to_address":  tx.TransactionHeaders.To,
+		"sub_status":  string(CurrentSubStatus(tx)),
+	}, tx); err != nil {
+		return err
+	}
+	if err = p.writeTransactionHashes(ctx, dbTX, tx); err != nil {
+		return err
+	}
+	return p.db.CommitTx(ctx, dbTX, autoCommit)
+}
+
+// nonceColumn is the value of the nonce column, which is NULL for a scheduled transaction that is not yet assigned a nonce
+func nonceColumn(nonce *fftypes.FFBigInt) interface{} {
+	if nonce == nil {
+		return nil
+	}
+	return nonce.Int64()
+}
+
+// writeTransactionHashes inserts a row for each hash the transaction has been submitted with, that is not already stored
+func (p *sqlPersistence) writeTransactionHashes(ctx context.Context, dbTX *dbsql.TXWrapper, tx *apitypes.ManagedTX) error {
+	hashes := tx.TransactionHashes()
+	if len(hashes) == 0 {
+		return nil
+	}
+	rows, _, err := p.db.QueryTx(ctx, txHashesTable, dbTX,
+		sq.Select("hash").From(txHashesTable).Where(sq.Eq{"transaction_id": tx.ID}),
+	)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err = rows.Scan(&hash); err != nil {
+			rows.Close()
+			return i18n.WrapError(ctx, err, i18n.MsgDBReadErr, txHashesTable)
+		}
+		existing[hash] = true
+	}
+	rows.Close()
+	for _, hash := range hashes {
+		if !existing[hash] {
+			if _, err = p.db.InsertTx(ctx, txHashesTable, dbTX, sq.Insert(txHashesTable).SetMap(map[string]interface{}{
+				"transaction_id": tx.ID,
+				"hash":           hash,
+			}), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *sqlPersistence) DeleteTransaction(ctx context.Context, txID string) error {
+	ctx, tx, autoCommit, err := p.db.BeginOrUseTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.db.RollbackTx(ctx, tx, autoCommit)
+
+	for _, table := range []string{txHistoryTable, txHashesTable} {
+		if err = p.deleteRows(ctx, table, sq.Eq{"transaction_id": txID}); err != nil {
+			return err
+		}
+	}
+	if err = p.deleteRows(ctx, transactionsTable, sq.Eq{"id": txID}); err != nil {
+		return err
+	}
+	return p.db.CommitTx(ctx, tx, autoCommit)
+}
+
+func (p *sqlPersistence) WriteTransactionHistory(ctx context.Context, record *apitypes.TxHistoryRecord) error {
+	return p.upsertDoc(ctx, txHistoryTable, sq.Eq{"id": record.ID.String()}, map[string]interface{}{
+		"transaction_id": record.TransactionID,
+	}, record)
+}
+
+func (p *sqlPersistence) ListTransactionHistory(ctx context.Context, txID string, after *fftypes.UUID, limit int, dir SortDirection) ([]*apitypes.TxHistoryRecord, error) {
+	records := make([]*apitypes.TxHistoryRecord, 0)
+	if err := p.listDocs(ctx, txHistoryTable, pageByID("id", after.String(), dir).Where(sq.Eq{"transaction_id": txID}), limit,
+		func() interface{} { var v *apitypes.TxHistoryRecord; return &v },
+		func(v interface{}) { records = append(records, *(v.(**apitypes.TxHistoryRecord))) },
+	); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (p *sqlPersistence) ListNonceReservations(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir SortDirection) ([]*apitypes.NonceReservation, error) {
+	q := sq.Select().Where(sq.Eq{"signer": signer})
+	switch dir {
+	case SortDirectionAscending:
+		if after != nil {
+			q = q.Where(sq.Gt{"first_nonce": after.Int64()})
+		}
+		q = q.OrderBy("first_nonce ASC")
+	default:
+		if after != nil {
+			q = q.Where(sq.Lt{"first_nonce": after.Int64()})
+		}
+		q = q.OrderBy("first_nonce DESC")
+	}
+	reservations := make([]*apitypes.NonceReservation, 0)
+	if err := p.listDocs(ctx, nonceResTable, q, limit,
+		func() interface{} { var v *apitypes.NonceReservation; return &v },
+		func(v interface{}) { reservations = append(reservations, *(v.(**apitypes.NonceReservation))) },
+	); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (p *sqlPersistence) WriteNonceReservation(ctx context.Context, reservation *apitypes.NonceReservation) error {
+	return p.upsertDoc(ctx, nonceResTable, sq.Eq{"signer": reservation.Signer, "first_nonce": reservation.First.Int64()}, map[string]interface{}{
+		"last_nonce": reservation.Last.Int64(),
+	}, reservation)
+}
+
+func (p *sqlPersistence) GetSignerMinimumNonce(ctx context.Context, signer string) (minimum *apitypes.SignerMinimumNonce, err error) {
+	err = p.readDoc(ctx, minNoncesTable, sq.Eq{"signer": signer}, &minimum)
+	return minimum, err
+}
+
+func (p *sqlPersistence) ListSignerMinimumNonces(ctx context.Context, after string, limit int, dir SortDirection) ([]*apitypes.SignerMinimumNonce, error) {
+	minimums := make([]*apitypes.SignerMinimumNonce, 0)
+	if err := p.listDocs(ctx, minNoncesTable, pageByID("signer", after, dir), limit,
+		func() interface{} { var v *apitypes.SignerMinimumNonce; return &v },
+		func(v interface{}) { minimums = append(minimums, *(v.(**apitypes.SignerMinimumNonce))) },
+	); err != nil {
+		return nil, err
+	}
+	return minimums, nil
+}
+
+func (p *sqlPersistence) WriteSignerMinimumNonce(ctx context.Context, minimum *apitypes.SignerMinimumNonce) error {
+	return p.upsertDoc(ctx, minNoncesTable, sq.Eq{"signer": minimum.Signer}, map[string]interface{}{
+		"nonce": minimum.Nonce.Int64(),
+	}, minimum)
+}
+
+func (p *sqlPersistence) Close(ctx context.Context) {
+	p.db.Close()
+}
@@ -0,0 +1,795 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/hyperledger/firefly-common/pkg/dbsql"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSQLitePersistence(t *testing.T) (*sqlPersistence, func()) {
+
+	tmconfig.Reset()
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfDatasourceURL, fmt.Sprintf("file:%s", path.Join(t.TempDir(), "fftm.db")))
+
+	pp, err := NewSQLitePersistence(context.Background())
+	assert.NoError(t, err)
+
+	p := pp.(*sqlPersistence)
+	return p, func() {
+		p.Close(context.Background())
+	}
+
+}
+
+func TestSQLiteInitMissingURL(t *testing.T) {
+
+	tmconfig.Reset()
+
+	_, err := NewSQLitePersistence(context.Background())
+	assert.Regexp(t, "FF00183", err)
+
+}
+
+func TestPostgresInitMissingURL(t *testing.T) {
+
+	tmconfig.Reset()
+
+	_, err := NewPostgresPersistence(context.Background())
+	assert.Regexp(t, "FF00183", err)
+
+}
+
+func TestSQLiteInitMigrationsFail(t *testing.T) {
+
+	tmconfig.Reset()
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfDatasourceURL, fmt.Sprintf("file:%s?mode=ro", path.Join(t.TempDir(), "fftm.db")))
+
+	_, err := NewSQLitePersistence(context.Background())
+	assert.Regexp(t, "FF00184", err)
+
+}
+
+func TestSQLiteInitMigrationsDisabled(t *testing.T) {
+
+	tmconfig.Reset()
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfDatasourceURL, fmt.Sprintf("file:%s", path.Join(t.TempDir(), "fftm.db")))
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfMigrationsAuto, false)
+
+	p, err := NewSQLitePersistence(context.Background())
+	assert.NoError(t, err)
+	defer p.Close(context.Background())
+
+	// Tables have not been created
+	_, err = p.GetStream(context.Background(), fftypes.NewUUID())
+	assert.Regexp(t, "FF00176", err)
+
+}
+
+func TestSQLiteMigrationBackfillsFilterColumns(t *testing.T) {
+
+	tmconfig.Reset()
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfDatasourceURL, fmt.Sprintf("file:%s", path.Join(t.TempDir(), "fftm.db")))
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfMigrationsAuto, false)
+
+	// Create the transactions table as it was before the filter columns were added
+	p, err := NewSQLitePersistence(context.Background())
+	assert.NoError(t, err)
+	provider := &sqliteProvider{}
+	source, err := iofs.New(migrationsFS, path.Join("migrations", provider.MigrationsDir()))
+	assert.NoError(t, err)
+	driver, err := provider.GetMigrationDriver(p.(*sqlPersistence).db.DB())
+	assert.NoError(t, err)
+	m, err := migrate.NewWithInstance("iofs", source, provider.MigrationsDir(), driver)
+	assert.NoError(t, err)
+	err = m.Migrate(4)
+	assert.NoError(t, err)
+	_, err = p.(*sqlPersistence).db.DB().Exec(
+		`INSERT INTO transactions (id, sequence_id, created, status, signer, nonce, doc) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		"ns1:tx1", fftypes.NewUUID().String(), fftypes.Now().UnixNano(), apitypes.TxStatusPending, "0xaaaaa", 1,
+		`{"id":"ns1:tx1","transactionHeaders":{"from":"0xaaaaa","to":"0xbbbbb"},"history":[{"subStatus":"Received"},{"subStatus":"Tracking"}]}`,
+	)
+	assert.NoError(t, err)
+	p.Close(context.Background())
+
+	// Apply the remaining migrations, and check the existing transaction matches the new filters
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfMigrationsAuto, true)
+	p, err = NewSQLitePersistence(context.Background())
+	assert.NoError(t, err)
+	defer p.Close(context.Background())
+
+	txns, err := p.ListTransactionsFiltered(context.Background(), &TransactionFilters{
+		To:        "0xbbbbb",
+		SubStatus: apitypes.TxSubStatusTracking,
+	}, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, "ns1:tx1", txns[0].ID)
+
+}
+
+func TestSQLReadWriteStreams(t *testing.T) {
+
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	s1 := &apitypes.EventStream{
+		ID:   apitypes.NewULID(), // ensure we get sequentially ascending IDs
+		Name: strPtr("stream1"),
+	}
+	p.WriteStream(ctx, s1)
+	s2 := &apitypes.EventStream{
+		ID:   apitypes.NewULID(),
+		Name: strPtr("stream2"),
+	}
+	p.WriteStream(ctx, s2)
+	s3 := &apitypes.EventStream{
+		ID:   apitypes.NewULID(),
+		Name: strPtr("stream3"),
+	}
+	p.WriteStream(ctx, s3)
+
+	streams, err := p.ListStreams(ctx, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, streams, 3)
+
+	assert.Equal(t, s3.ID, streams[0].ID)
+	assert.Equal(t, s2.ID, streams[1].ID)
+	assert.Equal(t, s1.ID, streams[2].ID)
+
+	// Test pagination
+
+	streams, err = p.ListStreams(ctx, nil, 2, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, streams, 2)
+	assert.Equal(t, s3.ID, streams[0].ID)
+	assert.Equal(t, s2.ID, streams[1].ID)
+
+	streams, err = p.ListStreams(ctx, streams[1].ID, 2, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, streams, 1)
+	assert.Equal(t, s1.ID, streams[0].ID)
+
+	streams, err = p.ListStreams(ctx, s1.ID, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, streams, 2)
+	assert.Equal(t, s2.ID, streams[0].ID)
+	assert.Equal(t, s3.ID, streams[1].ID)
+
+	// Test update
+
+	s3.Name = strPtr("stream3a")
+	err = p.WriteStream(ctx, s3)
+	assert.NoError(t, err)
+
+	// Test delete
+
+	err = p.DeleteStream(ctx, s2.ID)
+	assert.NoError(t, err)
+	streams, err = p.ListStreams(ctx, nil, 2, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, streams, 2)
+	assert.Equal(t, s3.ID, streams[0].ID)
+	assert.Equal(t, s1.ID, streams[1].ID)
+
+	// Test get direct
+
+	s, err := p.GetStream(ctx, s3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, s3.ID, s.ID)
+	assert.Equal(t, "stream3a", *s.Name)
+
+	s, err = p.GetStream(ctx, s2.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+}
+
+func TestSQLDeleteStreamRemovesCheckpoint(t *testing.T) {
+
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	s := &apitypes.EventStream{ID: apitypes.NewULID()}
+	err := p.WriteStream(ctx, s)
+	assert.NoError(t, err)
+	err = p.WriteCheckpoint(ctx, &apitypes.EventStreamCheckpoint{StreamID: s.ID})
+	assert.NoError(t, err)
+
+	err = p.DeleteStream(ctx, s.ID)
+	assert.NoError(t, err)
+
+	cp, err := p.GetCheckpoint(ctx, s.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, cp)
+
+}
+
+func TestSQLReadWriteListeners(t *testing.T) {
+
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+
+	sID1 := apitypes.NewULID()
+	sID2 := apitypes.NewULID()
+
+	s1l1 := &apitypes.Listener{
+		ID:       apitypes.NewULID(),
+		StreamID: sID1,
+	}
+	err := p.WriteListener(ctx, s1l1)
+	assert.NoError(t, err)
+
+	s2l1 := &apitypes.Listener{
+		ID:       apitypes.NewULID(),
+		StreamID: sID2,
+	}
+	err = p.WriteListener(ctx, s2l1)
+	assert.NoError(t, err)
+
+	s1l2 := &apitypes.Listener{
+		ID:       apitypes.NewULID(),
+		StreamID: sID1,
+	}
+	err = p.WriteListener(ctx, s1l2)
+	assert.NoError(t, err)
+
+	listeners, err := p.ListListeners(ctx, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, listeners, 3)
+
+	assert.Equal(t, s1l2.ID, listeners[0].ID)
+	assert.Equal(t, s2l1.ID, listeners[1].ID)
+	assert.Equal(t, s1l1.ID, listeners[2].ID)
+
+	// Test stream filter
+
+	listeners, err = p.ListStreamListeners(ctx, nil, 0, SortDirectionDescending, sID1)
+	assert.NoError(t, err)
+	assert.Len(t, listeners, 2)
+	assert.Equal(t, s1l2.ID, listeners[0].ID)
+	assert.Equal(t, s1l1.ID, listeners[1].ID)
+
+	// Test delete
+
+	err = p.DeleteListener(ctx, s2l1.ID)
+	assert.NoError(t, err)
+	listeners, err = p.ListStreamListeners(ctx, nil, 0, SortDirectionDescending, sID2)
+	assert.NoError(t, err)
+	assert.Len(t, listeners, 0)
+
+	// Test get direct
+
+	l, err := p.GetListener(ctx, s1l2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, s1l2.ID, l.ID)
+
+	l, err = p.GetListener(ctx, s2l1.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+}
+
+func TestSQLReadWriteCheckpoints(t *testing.T) {
+
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	cp1 := &apitypes.EventStreamCheckpoint{
+		StreamID: apitypes.NewULID(),
+	}
+	cp2 := &apitypes.EventStreamCheckpoint{
+		StreamID: apitypes.NewULID(),
+	}
+
+	err := p.WriteCheckpoint(ctx, cp1)
+	assert.NoError(t, err)
+
+	err = p.WriteCheckpoint(ctx, cp2)
+	assert.NoError(t, err)
+
+	cp2.Time = fftypes.Now()
+	err = p.WriteCheckpoint(ctx, cp2)
+	assert.NoError(t, err)
+
+	err = p.DeleteCheckpoint(ctx, cp1.StreamID)
+	assert.NoError(t, err)
+
+	err = p.DeleteCheckpoint(ctx, cp1.StreamID)
+	assert.NoError(t, err) // No-op
+
+	cp, err := p.GetCheckpoint(ctx, cp1.StreamID)
+	assert.NoError(t, err)
+	assert.Nil(t, cp)
+
+	cp, err = p.GetCheckpoint(ctx, cp2.StreamID)
+	assert.NoError(t, err)
+	assert.Equal(t, cp2.StreamID, cp.StreamID)
+	assert.Equal(t, cp2.Time.UnixNano(), cp.Time.UnixNano())
+}
+
+func TestSQLReadWriteManagedTransactions(t *testing.T) {
+
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	submitNewTX := func(signer string, nonce int64, status apitypes.TxStatus) *apitypes.ManagedTX {
+		tx := newTestTX(signer, nonce, status)
+		err := p.WriteTransaction(ctx, tx, true)
+		assert.NoError(t, err)
+		return tx
+	}
+
+	s1t1 := submitNewTX("0xaaaaa", 10001, apitypes.TxStatusSucceeded)
+	s2t1 := submitNewTX("0xbbbbb", 10001, apitypes.TxStatusFailed)
+	s1t2 := submitNewTX("0xaaaaa", 10002, apitypes.TxStatusPending)
+	s1t3 := submitNewTX("0xaaaaa", 10003, apitypes.TxStatusPending)
+
+	// Check new transaction should not have a sequenceID
+	err := p.WriteTransaction(ctx, s1t1, true)
+	assert.Regexp(t, "FF21075", err)
+
+	// Check dup
+	s1t1.SequenceID = ""
+	err = p.WriteTransaction(ctx, s1t1, true)
+	assert.Regexp(t, "FF21065", err)
+
+	txns, err := p.ListTransactionsByCreateTime(ctx, nil, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 4)
+
+	assert.Equal(t, s1t3.ID, txns[0].ID)
+	assert.Equal(t, s1t2.ID, txns[1].ID)
+	assert.Equal(t, s2t1.ID, txns[2].ID)
+	assert.Equal(t, s1t1.ID, txns[3].ID)
+
+	// Only list pending
+
+	txns, err = p.ListTransactionsPending(ctx, "", 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 2)
+
+	assert.Equal(t, s1t3.ID, txns[0].ID)
+	assert.Equal(t, s1t2.ID, txns[1].ID)
+
+	txns, err = p.ListTransactionsPending(ctx, s1t2.SequenceID, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, s1t3.ID, txns[0].ID)
+
+	// List with time range
+
+	txns, err = p.ListTransactionsByCreateTime(ctx, s1t2, 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 2)
+	assert.Equal(t, s2t1.ID, txns[0].ID)
+	assert.Equal(t, s1t1.ID, txns[1].ID)
+
+	txns, err = p.ListTransactionsByCreateTime(ctx, s2t1, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 2)
+	assert.Equal(t, s1t2.ID, txns[0].ID)
+	assert.Equal(t, s1t3.ID, txns[1].ID)
+
+	// Test update moves out of the pending list
+
+	s1t3.Status = apitypes.TxStatusSucceeded
+	s1t3.Updated = fftypes.Now()
+	err = p.WriteTransaction(ctx, s1t3, false)
+	assert.NoError(t, err)
+	txns, err = p.ListTransactionsPending(ctx, "", 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, s1t2.ID, txns[0].ID)
+
+	// Test delete, and querying by nonce to limit TX returned
+
+	err = p.DeleteTransaction(ctx, s1t2.ID)
+	assert.NoError(t, err)
+	txns, err = p.ListTransactionsByNonce(ctx, "0xaaaaa", s1t1.Nonce, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, s1t3.ID, txns[0].ID)
+
+	// Check we can use after with the deleted nonce, and not skip the one after
+	txns, err = p.ListTransactionsByNonce(ctx, "0xaaaaa", s1t2.Nonce, 0, SortDirectionAscending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, s1t3.ID, txns[0].ID)
+
+	txns, err = p.ListTransactionsByNonce(ctx, "0xaaaaa", nil, 1, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, s1t3.ID, txns[0].ID)
+
+	// Test get direct
+
+	v, err := p.GetTransactionByID(ctx, s1t3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, s1t3.ID, v.ID)
+	assert.Equal(t, s1t3.Nonce, v.Nonce)
+	assert.Equal(t, apitypes.TxStatusSucceeded, v.Status)
+
+	v, err = p.GetTransactionByNonce(ctx, "0xbbbbb", s2t1.Nonce)
+	assert.NoError(t, err)
+	assert.Equal(t, s2t1.ID, v.ID)
+	assert.Equal(t, s2t1.Nonce, v.Nonce)
+
+	v, err = p.GetTransactionByID(ctx, s1t2.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestSQLListTransactionsFiltered(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	checkListTransactionsFiltered(t, p)
+}
+
+func TestSQLTransactionWithoutNonce(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	checkTransactionWithoutNonce(t, p)
+}
+
+func TestSQLListTransactionsFilteredFail(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	done()
+
+	_, err := p.ListTransactionsFiltered(context.Background(), &TransactionFilters{Signer: "0x1234"}, nil, 0, SortDirectionDescending)
+	assert.Error(t, err)
+}
+
+func TestSQLTransactionHashes(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	countHashes := func(txID string) (count int) {
+		err := p.db.DB().QueryRow(`SELECT COUNT(*) FROM transaction_hashes WHERE transaction_id = ?`, txID).Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	tx.TransactionHash = "0x1111"
+	err := p.WriteTransaction(ctx, tx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, countHashes(tx.ID))
+
+	tx.SetTransactionHash("0x2222")
+	err = p.WriteTransaction(ctx, tx, false)
+	assert.NoError(t, err)
+	err = p.WriteTransaction(ctx, tx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, countHashes(tx.ID))
+
+	err = p.DeleteTransaction(ctx, tx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, countHashes(tx.ID))
+}
+
+func TestSQLWriteTransactionHashesFail(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	_, err := p.db.DB().Exec(`DROP TABLE transaction_hashes`)
+	assert.NoError(t, err)
+
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	tx.TransactionHash = "0x1111"
+	err = p.WriteTransaction(ctx, tx, true)
+	assert.Regexp(t, "FF00176", err)
+
+	err = p.DeleteTransaction(ctx, tx.ID)
+	assert.Regexp(t, "FF00179", err)
+}
+
+func TestSQLTransactionHistory(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	checkTransactionHistory(t, p)
+}
+
+func TestSQLTransactionHistoryFail(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	err := p.WriteTransaction(ctx, tx, true)
+	assert.NoError(t, err)
+	_, err = p.db.DB().Exec(`DROP TABLE transaction_history`)
+	assert.NoError(t, err)
+
+	err = p.WriteTransactionHistory(ctx, newTestTXHistory(tx.ID, apitypes.TxSubStatusReceived))
+	assert.Regexp(t, "FF00178", err)
+
+	_, err = p.ListTransactionHistory(ctx, tx.ID, nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF00176", err)
+
+	err = p.DeleteTransaction(ctx, tx.ID)
+	assert.Regexp(t, "FF00179", err)
+}
+
+func TestSQLWriteTransactionIncomplete(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	err := p.WriteTransaction(context.Background(), &apitypes.ManagedTX{}, true)
+	assert.Regexp(t, "FF21059", err)
+
+}
+
+func TestSQLDeleteTransactionMissing(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	err := p.DeleteTransaction(context.Background(), "missing")
+	assert.NoError(t, err)
+
+}
+
+func TestSQLReadBadJSON(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	_, err := p.db.DB().Exec(`INSERT INTO eventstreams (id, doc) VALUES ('bad', '!json')`)
+	assert.NoError(t, err)
+	_, err = p.db.DB().Exec(`INSERT INTO listeners (id, doc) VALUES ('bad', '!json')`)
+	assert.NoError(t, err)
+
+	_, err = p.ListStreams(ctx, nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF21054", err)
+
+	_, err = p.ListListeners(ctx, nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF21054", err)
+
+	err = p.readDoc(ctx, eventstreamsTable, sq.Eq{"id": "bad"}, new(*apitypes.EventStream))
+	assert.Regexp(t, "FF21054", err)
+
+}
+
+func TestSQLWriteFailClosed(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	done()
+
+	ctx := context.Background()
+	err := p.WriteStream(ctx, &apitypes.EventStream{ID: apitypes.NewULID()})
+	assert.Regexp(t, "FF00175", err)
+
+	err = p.DeleteStream(ctx, apitypes.NewULID())
+	assert.Regexp(t, "FF00175", err)
+
+	err = p.WriteTransaction(ctx, newTestTX("0xaaaaa", 1, apitypes.TxStatusPending), true)
+	assert.Regexp(t, "FF00175", err)
+
+	err = p.DeleteTransaction(ctx, "ns1:tx1")
+	assert.Regexp(t, "FF00175", err)
+
+	_, err = p.ListTransactionsPending(ctx, "", 0, SortDirectionDescending)
+	assert.Regexp(t, "FF00176", err)
+
+	_, err = p.GetTransactionByID(ctx, "any")
+	assert.Regexp(t, "FF00176", err)
+
+	_, err = p.ListStreamListeners(ctx, nil, 0, SortDirectionDescending, apitypes.NewULID())
+	assert.Regexp(t, "FF00176", err)
+
+	_, err = p.ListTransactionsByNonce(ctx, "0xaaaaa", nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF00176", err)
+
+}
+
+func TestSQLReadNullColumns(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	// Replace the tables with ones that allow NULL values, which cannot be read into a string
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`DROP TABLE eventstreams`,
+		`CREATE TABLE eventstreams (seq INTEGER PRIMARY KEY AUTOINCREMENT, id VARCHAR(36), doc TEXT)`,
+		`INSERT INTO eventstreams (id, doc) VALUES ('null', NULL)`,
+		`DROP TABLE transaction_hashes`,
+		`CREATE TABLE transaction_hashes (seq INTEGER PRIMARY KEY AUTOINCREMENT, transaction_id VARCHAR(256), hash VARCHAR(256))`,
+		`INSERT INTO transaction_hashes (transaction_id, hash) VALUES ('ns1:tx1', NULL)`,
+	} {
+		_, err := p.db.DB().Exec(stmt)
+		assert.NoError(t, err)
+	}
+
+	err := p.readDoc(ctx, eventstreamsTable, sq.Eq{"id": "null"}, new(*apitypes.EventStream))
+	assert.Regexp(t, "FF00182", err)
+
+	_, err = p.ListStreams(ctx, nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF00182", err)
+
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	tx.ID = "ns1:tx1"
+	tx.TransactionHash = "0x12345"
+	err = p.WriteTransaction(ctx, tx, true)
+	assert.Regexp(t, "FF00182", err)
+
+}
+
+func TestSQLWriteTransactionHashesFailInsert(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	// A unique hash column means the insert fails, after the query for the existing hashes succeeds
+	ctx := context.Background()
+	_, err := p.db.DB().Exec(`CREATE UNIQUE INDEX transaction_hashes_unique ON transaction_hashes(hash)`)
+	assert.NoError(t, err)
+	_, err = p.db.DB().Exec(`INSERT INTO transaction_hashes (transaction_id, hash) VALUES ('ns1:other', '0x12345')`)
+	assert.NoError(t, err)
+
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	tx.TransactionHash = "0x12345"
+	err = p.WriteTransaction(ctx, tx, true)
+	assert.Regexp(t, "FF00177", err)
+
+}
+
+func TestSQLNonceReservations(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	checkNonceReservations(t, p)
+}
+
+func TestSQLNonceReservationsFail(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	_, err := p.db.DB().Exec(`DROP TABLE nonce_reservations`)
+	assert.NoError(t, err)
+
+	err = p.WriteNonceReservation(ctx, &apitypes.NonceReservation{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(10), Last: fftypes.NewFFBigInt(19)})
+	assert.Regexp(t, "FF00178", err)
+
+	_, err = p.ListNonceReservations(ctx, "0xaaaaa", nil, 0, SortDirectionDescending)
+	assert.Regexp(t, "FF00176", err)
+}
+
+func TestSQLSignerMinimumNonces(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	checkSignerMinimumNonces(t, p)
+}
+
+func TestSQLSignerMinimumNoncesFail(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	_, err := p.db.DB().Exec(`DROP TABLE signer_minimum_nonces`)
+	assert.NoError(t, err)
+
+	err = p.WriteSignerMinimumNonce(ctx, &apitypes.SignerMinimumNonce{Signer: "0xaaaaa", Nonce: fftypes.NewFFBigInt(10)})
+	assert.Regexp(t, "FF00178", err)
+
+	_, err = p.GetSignerMinimumNonce(ctx, "0xaaaaa")
+	assert.Regexp(t, "FF00176", err)
+
+	_, err = p.ListSignerMinimumNonces(ctx, "", 0, SortDirectionDescending)
+	assert.Regexp(t, "FF00176", err)
+}
+
+func TestSQLTableDropFailures(t *testing.T) {
+	ctx := context.Background()
+	dropTable := func(table string) (*sqlPersistence, func()) {
+		p, done := newTestSQLitePersistence(t)
+		_, err := p.db.DB().Exec(`DROP TABLE ` + table)
+		assert.NoError(t, err)
+		return p, done
+	}
+
+	p, done := dropTable(checkpointsTable)
+	err := p.DeleteStream(ctx, apitypes.NewULID())
+	assert.Regexp(t, "FF00179", err)
+	done()
+
+	p, done = dropTable(transactionsTable)
+	err = p.WriteTransaction(ctx, newTestTX("0xaaaaa", 1, apitypes.TxStatusPending), true)
+	assert.Regexp(t, "FF00176", err)
+	err = p.WriteTransaction(ctx, newTestTX("0xaaaaa", 1, apitypes.TxStatusPending), false)
+	assert.Regexp(t, "FF00178", err)
+	err = p.DeleteTransaction(ctx, "ns1:tx1")
+	assert.Regexp(t, "FF00179", err)
+	done()
+
+	p, done = dropTable(txHashesTable)
+	tx := newTestTX("0xaaaaa", 1, apitypes.TxStatusPending)
+	tx.TransactionHash = "0x12345"
+	err = p.WriteTransaction(ctx, tx, true)
+	assert.Regexp(t, "FF00176", err)
+	done()
+
+	p, done = dropTable(txHistoryTable)
+	err = p.DeleteTransaction(ctx, "ns1:tx1")
+	assert.Regexp(t, "FF00179", err)
+	done()
+}
+
+func TestSQLDeleteRowsFailClosed(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	done()
+
+	err := p.deleteRows(context.Background(), eventstreamsTable, sq.Eq{"id": "any"})
+	assert.Regexp(t, "FF00175", err)
+
+}
+
+func TestSQLListTransactionsByNonceDescendingAfter(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	ctx := context.Background()
+	for nonce := int64(1); nonce <= 3; nonce++ {
+		err := p.WriteTransaction(ctx, newTestTX("0xaaaaa", nonce, apitypes.TxStatusPending), true)
+		assert.NoError(t, err)
+	}
+
+	txns, err := p.ListTransactionsByNonce(ctx, "0xaaaaa", fftypes.NewFFBigInt(3), 0, SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 2)
+	assert.Equal(t, int64(2), txns[0].Nonce.Int64())
+	assert.Equal(t, int64(1), txns[1].Nonce.Int64())
+
+}
+
+func TestSQLEmbeddedMigrationsConfig(t *testing.T) {
+	tmconfig.Reset()
+
+	conf := &embeddedMigrationsConfig{Section: tmconfig.PersistenceSQLiteConfig}
+	assert.True(t, tmconfig.PersistenceSQLiteConfig.GetBool(dbsql.SQLConfMigrationsAuto))
+	assert.False(t, conf.GetBool(dbsql.SQLConfMigrationsAuto))
+	assert.Equal(t, tmconfig.PersistenceSQLiteConfig.GetBool(dbsql.SQLConfMaxConnections), conf.GetBool(dbsql.SQLConfMaxConnections))
+
+}
+
+func TestSQLWriteCheckpointFailMarshal(t *testing.T) {
+	p, done := newTestSQLitePersistence(t)
+	defer done()
+
+	err := p.upsertDoc(context.Background(), checkpointsTable, sq.Eq{"stream_id": "any"}, map[string]interface{}{}, map[bool]bool{false: true})
+	assert.Regexp(t, "FF21053", err)
+
+}
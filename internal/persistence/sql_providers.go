@@ -0,0 +1,102 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/hyperledger/firefly-common/pkg/dbsql"
+
+	// Import the SQL drivers. The SQLite driver is pure Go, as FFTM builds with CGO disabled
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+type postgresProvider struct{}
+
+func (pp *postgresProvider) Name() string {
+	return "postgres"
+}
+
+func (pp *postgresProvider) MigrationsDir() string {
+	return "postgres"
+}
+
+func (pp *postgresProvider) SequenceColumn() string {
+	return "seq"
+}
+
+func (pp *postgresProvider) Features() dbsql.SQLFeatures {
+	features := dbsql.DefaultSQLProviderFeatures()
+	features.PlaceholderFormat = sq.Dollar
+	features.UseILIKE = true
+	return features
+}
+
+func (pp *postgresProvider) ApplyInsertQueryCustomizations(insert sq.InsertBuilder, requestConflictEmptyResult bool) (sq.InsertBuilder, bool) {
+	suffix := "RETURNING seq"
+	if requestConflictEmptyResult {
+		suffix = "ON CONFLICT DO NOTHING " + suffix
+	}
+	return insert.Suffix(suffix), true
+}
+
+func (pp *postgresProvider) Open(url string) (*sql.DB, error) {
+	return sql.Open(pp.Name(), url)
+}
+
+func (pp *postgresProvider) GetMigrationDriver(db *sql.DB) (migratedb.Driver, error) {
+	return postgres.WithInstance(db, &postgres.Config{})
+}
+
+type sqliteProvider struct{}
+
+func (sp *sqliteProvider) Name() string {
+	return "sqlite3"
+}
+
+func (sp *sqliteProvider) MigrationsDir() string {
+	return "sqlite"
+}
+
+func (sp *sqliteProvider) SequenceColumn() string {
+	return "seq"
+}
+
+func (sp *sqliteProvider) Features() dbsql.SQLFeatures {
+	features := dbsql.DefaultSQLProviderFeatures()
+	features.PlaceholderFormat = sq.Dollar
+	features.UseILIKE = false // not supported
+	return features
+}
+
+func (sp *sqliteProvider) ApplyInsertQueryCustomizations(insert sq.InsertBuilder, _ bool) (sq.InsertBuilder, bool) {
+	// Nothing required - the sequence is returned via LastInsertId
+	return insert, false
+}
+
+func (sp *sqliteProvider) Open(url string) (*sql.DB, error) {
+	return sql.Open("sqlite", url)
+}
+
+func (sp *sqliteProvider) GetMigrationDriver(db *sql.DB) (migratedb.Driver, error) {
+	return sqlite.WithInstance(db, &sqlite.Config{})
+}
@@ -0,0 +1,61 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresProvider(t *testing.T) {
+	pp := &postgresProvider{}
+	assert.Equal(t, "postgres", pp.Name())
+	assert.Equal(t, "postgres", pp.MigrationsDir())
+	assert.Equal(t, "seq", pp.SequenceColumn())
+	assert.Equal(t, sq.Dollar, pp.Features().PlaceholderFormat)
+
+	insert, useQuery := pp.ApplyInsertQueryCustomizations(sq.Insert("test").Columns("a").Values(1), false)
+	assert.True(t, useQuery)
+	sql, _, err := insert.PlaceholderFormat(sq.Dollar).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO test (a) VALUES ($1) RETURNING seq", sql)
+
+	insert, _ = pp.ApplyInsertQueryCustomizations(sq.Insert("test").Columns("a").Values(1), true)
+	sql, _, err = insert.PlaceholderFormat(sq.Dollar).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO test (a) VALUES ($1) ON CONFLICT DO NOTHING RETURNING seq", sql)
+
+	db, err := pp.Open("postgres://localhost:0/bad?connect_timeout=1")
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = pp.GetMigrationDriver(db)
+	assert.Error(t, err)
+}
+
+func TestSQLiteProvider(t *testing.T) {
+	sp := &sqliteProvider{}
+	assert.Equal(t, "sqlite3", sp.Name())
+	assert.Equal(t, "sqlite", sp.MigrationsDir())
+	assert.Equal(t, "seq", sp.SequenceColumn())
+	assert.False(t, sp.Features().UseILIKE)
+
+	insert := sq.Insert("test").Columns("a").Values(1)
+	_, useQuery := sp.ApplyInsertQueryCustomizations(insert, true)
+	assert.False(t, useQuery)
+}
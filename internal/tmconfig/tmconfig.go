@@ -18,6 +18,7 @@ package tmconfig
 
 import (
 	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/dbsql"
 	"github.com/hyperledger/firefly-common/pkg/ffresty"
 	"github.com/hyperledger/firefly-common/pkg/httpserver"
 	"github.com/spf13/viper"
@@ -30,7 +31,10 @@ var (
 	ConfirmationsBlockQueueLength                 = ffc("confirmations.blockQueueLength")
 	ConfirmationsStaleReceiptTimeout              = ffc("confirmations.staleReceiptTimeout")
 	ConfirmationsNotificationQueueLength          = ffc("confirmations.notificationQueueLength")
-	TransactionsMaxHistoryCount                   = ffc("transactions.maxHistoryCount")
+	TransactionsHistoryEnabled                    = ffc("transactions.history.enabled")
+	TransactionsRetentionMaxAge                   = ffc("transactions.retention.maxAge")
+	TransactionsRetentionMaxCountPerSigner        = ffc("transactions.retention.maxCountPerSigner")
+	TransactionsRetentionInterval                 = ffc("transactions.retention.interval")
 	EventStreamsDefaultsBatchSize                 = ffc("eventstreams.defaults.batchSize")
 	EventStreamsDefaultsBatchTimeout              = ffc("eventstreams.defaults.batchTimeout")
 	EventStreamsDefaultsErrorHandling             = ffc("eventstreams.defaults.errorHandling")
@@ -63,6 +67,7 @@ var (
 	DeprecatedPolicyLoopRetryMaxDelay       = ffc("policyloop.retry.maxDelay")
 	DeprecatedPolicyLoopRetryFactor         = ffc("policyloop.retry.factor")
 	DeprecatedPolicyEngineName              = ffc("policyengine.name")
+	DeprecatedTransactionsMaxHistoryCount   = ffc("transactions.maxHistoryCount")
 )
 
 var APIConfig config.Section
@@ -77,8 +82,15 @@ var WebhookPrefix config.Section
 
 var MetricsConfig config.Section
 
+var PersistenceBaseConfig config.Section
+
+var PersistencePostgresConfig config.Section
+
+var PersistenceSQLiteConfig config.Section
+
 func setDefaults() {
-	viper.SetDefault(string(TransactionsMaxHistoryCount), 50)
+	viper.SetDefault(string(TransactionsHistoryEnabled), true)
+	viper.SetDefault(string(TransactionsRetentionInterval), "5m")
 	viper.SetDefault(string(ConfirmationsRequired), 20)
 	viper.SetDefault(string(ConfirmationsBlockQueueLength), 50)
 	viper.SetDefault(string(ConfirmationsNotificationQueueLength), 50)
@@ -138,4 +150,21 @@ func Reset() {
 
 	MetricsConfig = config.RootSection("metrics")
 	httpserver.InitHTTPConfig(MetricsConfig, 6000)
+
+	PersistenceBaseConfig = config.RootSection("persistence") // Additional persistence types can be registered outside of this package
+
+	PersistencePostgresConfig = config.RootSection("persistence.postgres")
+	initSQLConfig(PersistencePostgresConfig, 50)
+
+	PersistenceSQLiteConfig = config.RootSection("persistence.sqlite3")
+	initSQLConfig(PersistenceSQLiteConfig, 1)
+}
+
+func initSQLConfig(conf config.Section, defaultMaxConns int) {
+	conf.AddKnownKey(dbsql.SQLConfDatasourceURL)
+	conf.AddKnownKey(dbsql.SQLConfMigrationsAuto, true)
+	conf.AddKnownKey(dbsql.SQLConfMaxConnections, defaultMaxConns)
+	conf.AddKnownKey(dbsql.SQLConfMaxConnIdleTime, "1m")
+	conf.AddKnownKey(dbsql.SQLConfMaxIdleConns)
+	conf.AddKnownKey(dbsql.SQLConfMaxConnLifetime)
 }
@@ -36,6 +36,11 @@ var (
 	APIEndpointGetEventStreams              = ffm("api.endpoints.get.eventstreams", "List event streams")
 	APIEndpointGetEventStream               = ffm("api.endpoints.get.eventstream", "Get an event stream with status")
 	APIEndpointDeleteEventStream            = ffm("api.endpoints.delete.eventstream", "Delete an event stream")
+	APIEndpointGetTransactions              = ffm("api.endpoints.get.transactions", "List transactions, optionally filtered by a combination of signer, status, sub-status, to address, transaction hash and time range")
+	APIEndpointGetTransactionHistory        = ffm("api.endpoints.get.transaction.history", "List the history of sub-status changes, and the actions taken, for a transaction")
+	APIEndpointPostTransactionCancel        = ffm("api.endpoints.post.transaction.cancel", "Request cancellation of a submitted transaction, by replacing it with a zero value transaction at the same nonce. The transaction remains pending until either it, or the replacement, is mined. A scheduled transaction that is not yet assigned a nonce fails immediately")
+	APIEndpointPostTransactionResubmit      = ffm("api.endpoints.post.transaction.resubmit", "Resubmit a pending transaction immediately, optionally with an explicit gas price or a percentage increase over the last submitted gas price")
+	APIEndpointPostTransactionsBatch        = ffm("api.endpoints.post.transactions.batch", "Submit a batch of transactions and contract deployments in one request. The transactions for each signer are assigned contiguous nonces in the order of the batch, and a result is returned for each request as each succeeds or fails on its own")
 	APIEndpointDeleteTransaction            = ffm("api.endpoints.delete.transaction", "Request transaction deletion by the policy engine. Result could be immediate (200), asynchronous (202), or rejected with an error")
 	APIEndpointGetStatusLive                = ffm("api.endpoints.get.status.live", "Get the liveness status of the connector")
 	APIEndpointGetStatusReady               = ffm("api.endpoints.get.status.ready", "Get the readiness status of the connector")
@@ -52,16 +57,30 @@ var (
 	APIEndpointPatchEventStreamListener     = ffm("api.endpoints.patch.eventstream.listener", "Update event stream listener")
 	APIEndpointDeleteEventStreamListener    = ffm("api.endpoints.delete.eventstream.listener", "Delete event stream listener")
 	APIEndpointGetAddressBalance            = ffm("api.endpoints.get.address.balance", "Get gas token balance for a signer address")
+	APIEndpointGetSignerStatus              = ffm("api.endpoints.get.signer.status", "Get the status of the nonces of a signer address, including any gaps that prevent its pending transactions being mined, and whether submission of its transactions is paused due to insufficient funds")
+	APIEndpointGetSignerNonce               = ffm("api.endpoints.get.signer.nonce", "Get the next nonce for a signer address, according to persistence, the blockchain node, and any in-progress nonce assignment")
+	APIEndpointPostSignerNonceReset         = ffm("api.endpoints.post.signer.nonce.reset", "Re-sync the next nonce for a signer address from the blockchain node, for example after the signing key has been used by another system. Nonces are never assigned below a transaction already in persistence")
+	APIEndpointPostSignerNonceReserve       = ffm("api.endpoints.post.signer.nonce.reserve", "Reserve a block of nonces for a signer address for use by another system, so they are not assigned to any transaction, or filled as nonce gaps")
 	APIEndpointGetGasPrice                  = ffm("api.endpoints.get.gasprice", "Get the current gas price of the connector's chain")
+	APIEndpointGetAdminExport               = ffm("api.endpoints.get.admin.export", "Export all event streams, listeners, checkpoints and transactions as a versioned NDJSON archive")
+	APIEndpointPostAdminImport              = ffm("api.endpoints.post.admin.import", "Import an NDJSON archive created by an export. Records that already exist are skipped")
 
-	APIParamStreamID      = ffm("api.params.streamId", "Event Stream ID")
-	APIParamListenerID    = ffm("api.params.listenerId", "Listener ID")
-	APIParamTransactionID = ffm("api.params.transactionId", "Transaction ID")
-	APIParamLimit         = ffm("api.params.limit", "Maximum number of entries to return")
-	APIParamAfter         = ffm("api.params.after", "Return entries after this ID - for pagination (non-inclusive)")
-	APIParamTXSigner      = ffm("api.params.txSigner", "Return only transactions for a specific signing address, in reverse nonce order")
-	APIParamTXPending     = ffm("api.params.txPending", "Return only pending transactions, in reverse submission sequence (a 'sequenceId' is assigned to each transaction to determine its sequence")
-	APIParamSortDirection = ffm("api.params.sortDirection", "Sort direction: 'asc'/'ascending' or 'desc'/'descending'")
-	APIParamSignerAddress = ffm("api.params.signerAddress", "A signing address, for example to get the gas token balance for")
-	APIParamBlocktag      = ffm("api.params.blocktag", "The optional block tag to use when making a gas token balance query")
+	APIParamStreamID        = ffm("api.params.streamId", "Event Stream ID")
+	APIParamListenerID      = ffm("api.params.listenerId", "Listener ID")
+	APIParamTransactionID   = ffm("api.params.transactionId", "Transaction ID")
+	APIParamLimit           = ffm("api.params.limit", "Maximum number of entries to return")
+	APIParamAfter           = ffm("api.params.after", "Return entries after this ID - for pagination (non-inclusive)")
+	APIParamTXSigner        = ffm("api.params.txSigner", "Return only transactions for a specific signing address. Sorted in reverse nonce order if no other filters are supplied, followed by any transactions that are not yet assigned a nonce, such as scheduled transactions")
+	APIParamTXPending       = ffm("api.params.txPending", "Return only pending transactions, in reverse submission sequence (a 'sequenceId' is assigned to each transaction to determine its sequence")
+	APIParamTXStatus        = ffm("api.params.txStatus", "Return only transactions with this status: 'Pending', 'Succeeded' or 'Failed'")
+	APIParamTXSubStatus     = ffm("api.params.txSubStatus", "Return only transactions currently in this sub-status, such as 'Scheduled', 'Received', 'Tracking' or 'Stale'")
+	APIParamTXTo            = ffm("api.params.txTo", "Return only transactions sent to this address")
+	APIParamTXHash          = ffm("api.params.txHash", "Return only transactions that have been submitted with this transaction hash, including hashes replaced by a later resubmission")
+	APIParamTXCreatedAfter  = ffm("api.params.txCreatedAfter", "Return only transactions created after this time")
+	APIParamTXCreatedBefore = ffm("api.params.txCreatedBefore", "Return only transactions created before this time")
+	APIParamTXUpdatedAfter  = ffm("api.params.txUpdatedAfter", "Return only transactions updated after this time")
+	APIParamTXUpdatedBefore = ffm("api.params.txUpdatedBefore", "Return only transactions updated before this time")
+	APIParamSortDirection   = ffm("api.params.sortDirection", "Sort direction: 'asc'/'ascending' or 'desc'/'descending'")
+	APIParamSignerAddress   = ffm("api.params.signerAddress", "A signing address, for example to get the gas token balance for")
+	APIParamBlocktag        = ffm("api.params.blocktag", "The optional block tag to use when making a gas token balance query")
 )
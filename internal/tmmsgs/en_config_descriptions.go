@@ -45,10 +45,15 @@ var (
 	ConfigConfirmationsRequired                 = ffc("config.confirmations.required", "Number of confirmations required to consider a transaction/event final", i18n.IntType)
 	ConfigConfirmationsStaleReceiptTimeout      = ffc("config.confirmations.staleReceiptTimeout", "Duration after which to force a receipt check for a pending transaction", i18n.TimeDurationType)
 
-	ConfigTransactionsMaxHistoryCount = ffc("config.transactions.maxHistoryCount", "The number of historical status updates to retain in the operation", i18n.IntType)
+	ConfigTransactionsHistoryEnabled = ffc("config.transactions.history.enabled", "Whether to record the history of status updates for each transaction, which is stored separately from the transaction", i18n.BooleanType)
+
+	ConfigTransactionsRetentionMaxAge            = ffc("config.transactions.retention.maxAge", "Succeeded and Failed transactions last updated longer ago than this are purged from persistence. Unset or zero disables age based purging", i18n.TimeDurationType)
+	ConfigTransactionsRetentionMaxCountPerSigner = ffc("config.transactions.retention.maxCountPerSigner", "The maximum number of Succeeded and Failed transactions to retain for each signing address, with the highest nonces retained. Unset or zero disables count based purging", i18n.IntType)
+	ConfigTransactionsRetentionInterval          = ffc("config.transactions.retention.interval", "Interval at which to purge transactions that fall outside of the retention policy", i18n.TimeDurationType)
 
 	DeprecatedConfigTransactionsMaxInflight                  = ffc("config.transactions.maxInFlight", "Deprecated: Please use 'transactions.handler.simple.maxInFlight' instead", i18n.IntType)
 	DeprecatedConfigTransactionsNonceStateTimeout            = ffc("config.transactions.nonceStateTimeout", "Deprecated: Please use 'transactions.handler.simple.nonceStateTimeout' instead", i18n.TimeDurationType)
+	DeprecatedConfigTransactionsMaxHistoryCount              = ffc("config.transactions.maxHistoryCount", "Deprecated: Please use 'transactions.history.enabled' instead. Setting this to 0 disables the history", i18n.IntType)
 	DeprecatedConfigPolicyEngineName                         = ffc("config.policyengine.name", "Deprecated: Please use 'transactions.handler.name' instead", i18n.StringType)
 	DeprecatedConfigLoopInterval                             = ffc("config.policyloop.interval", "Deprecated: Please use 'transactions.handler.simple.interval' instead", i18n.TimeDurationType)
 	DeprecatedConfigPolicyEngineSimpleFixedGasPrice          = ffc("config.policyengine.simple.fixedGasPrice", "Deprecated: Please use 'transactions.handler.simple.fixedGasPrice' instead", "Raw JSON")
@@ -65,10 +70,13 @@ var (
 
 	ConfigTXHandlerName              = ffc("config.transactions.handler.name", "The name of the transaction handler to use", i18n.StringType)
 	ConfigTXHandlerMaxInflight       = ffc("config.transactions.handler.simple.maxInFlight", "The maximum number of transactions to have in-flight with the transaction handler / blockchain transaction pool", i18n.IntType)
+	ConfigTXHandlerMaxInflightSigner = ffc("config.transactions.handler.simple.maxInFlightPerSigner", "The maximum number of transactions for each signing address to have in-flight. When set, the in-flight set is filled round-robin across signers, so a busy signer cannot starve the others. The pending transactions are read each time the in-flight set is refilled, until there is a signer for each space. Set to 0 for no limit, which fills the in-flight set in submission order", i18n.IntType)
+	ConfigTXHandlerMaxBatchSize      = ffc("config.transactions.handler.simple.maxBatchSize", "The maximum number of new transactions in a batch request. Requests in the batch for transactions that already exist are not included. Set to 0 for no limit", i18n.IntType)
 	ConfigTXHandlerNonceStateTimeout = ffc("config.transactions.handler.simple.nonceStateTimeout", "How old the most recently submitted transaction record in our local state needs to be, before we make a request to the node to query the next nonce for a signing address", i18n.TimeDurationType)
 
 	ConfigTXHandlerSimpleInterval               = ffc("config.transactions.handler.simple.interval", "Interval at which to invoke the transaction handler loop to evaluate outstanding transactions", i18n.TimeDurationType)
 	ConfigTXHandlerSimpleFixedGasPrice          = ffc("config.transactions.handler.simple.fixedGasPrice", "A fixed gasPrice value/structure to pass to the connector", "Raw JSON")
+	ConfigTXHandlerSimpleDefaultDeadline        = ffc("config.transactions.handler.simple.defaultDeadline", "The time after submission by which a transaction must be mined, when the request does not specify a deadline. After this the transaction is cancelled, and is marked as failed once the cancellation is mined. Not set by default", i18n.TimeDurationType)
 	ConfigTXHandlerSimpleResubmitInterval       = ffc("config.transactions.handler.simple.resubmitInterval", "The time between warning and re-sending a transaction (same nonce) when a blockchain transaction has not been allocated a receipt", i18n.TimeDurationType)
 	ConfigTXHandlerSimpleRetryInitDelay         = ffc("config.transactions.handler.simple.retry.initialDelay", "Initial retry delay for retrieving transactions from the persistence", i18n.TimeDurationType)
 	ConfigTXHandlerSimpleRetryMaxDelay          = ffc("config.transactions.handler.simple.retry.maxDelay", "Maximum delay between retries for retrieving transactions from the persistence", i18n.TimeDurationType)
@@ -80,6 +88,17 @@ var (
 	ConfigPTXHandlerSimpleGasOracleMethod       = ffc("config.transactions.handler.simple.gasOracle.method", "The HTTP Method to use when invoking the Gas Oracle REST API", i18n.StringType)
 	ConfigTXHandlerSimpleGasOracleQueryInterval = ffc("config.transactions.handler.simple.gasOracle.queryInterval", "The minimum interval between queries to the Gas Oracle", i18n.TimeDurationType)
 
+	ConfigTXHandlerSimpleGasPriceEscalationPercentage      = ffc("config.transactions.handler.simple.gasPriceEscalation.percentage", "The percentage to increase the gas price by, over the last submitted gas price, each time a transaction is resubmitted. For EIP-1559 fees the maxFeePerGas and maxPriorityFeePerGas are increased independently. Set to 0 to disable", i18n.IntType)
+	ConfigTXHandlerSimpleGasPriceEscalationMinimumIncrease = ffc("config.transactions.handler.simple.gasPriceEscalation.minimumIncrease", "The minimum increase over the last submitted gas price each time a transaction is resubmitted, in the smallest unit of the chain (wei)", i18n.StringType)
+	ConfigTXHandlerSimpleGasPriceEscalationMaximumGasPrice = ffc("config.transactions.handler.simple.gasPriceEscalation.maximumGasPrice", "The maximum gas price, or EIP-1559 maxFeePerGas, that will ever be submitted, in the smallest unit of the chain (wei)", i18n.StringType)
+	ConfigTXHandlerSimpleNonceGapsFill                     = ffc("config.transactions.handler.simple.nonceGaps.fill", "Whether to submit zero value transfers to fill gaps in the nonces of signers with pending transactions. A gap occurs when a transaction is deleted or fails after its nonce is assigned, and prevents any later transaction for the signer being mined", i18n.BooleanType)
+	ConfigTXHandlerSimpleNonceGapsCheckInterval            = ffc("config.transactions.handler.simple.nonceGaps.checkInterval", "How often to check the signers of in-flight transactions for gaps in their nonces, when filling is enabled", i18n.TimeDurationType)
+	ConfigTXHandlerSimplePriorityEnabled                   = ffc("config.transactions.handler.simple.priority.enabled", "Whether to select higher priority transactions first when filling the in-flight set. Earlier transactions for the same signer are selected along with them, so nonces are still used in order. This requires all pending transactions to be read each time the in-flight set is refilled", i18n.BooleanType)
+	ConfigTXHandlerSimplePriorityGasPricePercentage        = ffc("config.transactions.handler.simple.priority.gasPricePercentage", "The percentage to increase the gas price from the gas oracle by, for each level of priority above zero set in the request headers of a transaction. Set to 0 to use the same gas price for all priorities", i18n.IntType)
+	ConfigTXHandlerSimpleInsufficientFundsCheckInterval    = ffc("config.transactions.handler.simple.insufficientFunds.balanceCheckInterval", "How often to check the balance of a signer whose transactions are paused, after the node rejected a transaction for insufficient funds. Submission resumes once the balance covers the cost of its in-flight transactions", i18n.TimeDurationType)
+	ConfigTXHandlerSimplePreflightEnabled                  = ffc("config.transactions.handler.simple.preflight.enabled", "Whether to estimate the gas for each new transaction before it is assigned a nonce, so that a transaction that would revert is rejected with the revert reason rather than being submitted to the chain", i18n.BooleanType)
+	ConfigTXHandlerSimplePreflightGasMultiplier            = ffc("config.transactions.handler.simple.preflight.gasMultiplier", "The multiplier applied to the gas estimate, to set the gas for a transaction that does not specify it in the request. Must be at least 1", i18n.FloatType)
+
 	ConfigEventStreamsDefaultsBatchSize                 = ffc("config.eventstreams.defaults.batchSize", "Default batch size for newly created event streams", i18n.IntType)
 	ConfigEventStreamsDefaultsBatchTimeout              = ffc("config.eventstreams.defaults.batchTimeout", "Default batch timeout for newly created event streams", i18n.TimeDurationType)
 	ConfigEventStreamsDefaultsErrorHandling             = ffc("config.eventstreams.defaults.errorHandling", "Default error handling for newly created event streams", "'skip' or 'block'")
@@ -92,11 +111,25 @@ var (
 	ConfigEventStreamsRetryMaxDelay                     = ffc("config.eventstreams.retry.maxDelay", "Maximum delay between retries", i18n.TimeDurationType)
 	ConfigEventStreamsRetryFactor                       = ffc("config.eventstreams.retry.factor", "Factor to increase the delay by, between each retry", i18n.FloatType)
 
-	ConfigPersistenceType              = ffc("config.persistence.type", "The type of persistence to use", "Only 'leveldb' currently supported")
+	ConfigPersistenceType              = ffc("config.persistence.type", "The type of persistence to use", "'leveldb', 'postgres', 'sqlite3' or 'inmemory'")
 	ConfigPersistenceLevelDBPath       = ffc("config.persistence.leveldb.path", "The path for the LevelDB persistence directory", i18n.StringType)
 	ConfigPersistenceLevelDBMaxHandles = ffc("config.persistence.leveldb.maxHandles", "The maximum number of cached file handles LevelDB should keep open", i18n.IntType)
 	ConfigPersistenceLevelDBSyncWrites = ffc("config.persistence.leveldb.syncWrites", "Whether to synchronously perform writes to the storage", i18n.BooleanType)
 
+	ConfigPersistencePostgresURL             = ffc("config.persistence.postgres.url", "The PostgreSQL connection URL for the database", i18n.StringType)
+	ConfigPersistencePostgresMigrationsAuto  = ffc("config.persistence.postgres.migrations.auto", "Whether to apply the schema migrations embedded in the binary to the database on startup", i18n.BooleanType)
+	ConfigPersistencePostgresMaxConns        = ffc("config.persistence.postgres.maxConns", "Maximum connections to the database", i18n.IntType)
+	ConfigPersistencePostgresMaxConnIdleTime = ffc("config.persistence.postgres.maxConnIdleTime", "The maximum amount of time a database connection can be idle", i18n.TimeDurationType)
+	ConfigPersistencePostgresMaxIdleConns    = ffc("config.persistence.postgres.maxIdleConns", "The maximum number of idle connections to the database", i18n.IntType)
+	ConfigPersistencePostgresMaxConnLifetime = ffc("config.persistence.postgres.maxConnLifetime", "The maximum amount of time to keep a database connection open", i18n.TimeDurationType)
+
+	ConfigPersistenceSQLiteURL             = ffc("config.persistence.sqlite3.url", "The SQLite connection string for the database", i18n.StringType)
+	ConfigPersistenceSQLiteMigrationsAuto  = ffc("config.persistence.sqlite3.migrations.auto", "Whether to apply the schema migrations embedded in the binary to the database on startup", i18n.BooleanType)
+	ConfigPersistenceSQLiteMaxConns        = ffc("config.persistence.sqlite3.maxConns", "Maximum connections to the database", i18n.IntType)
+	ConfigPersistenceSQLiteMaxConnIdleTime = ffc("config.persistence.sqlite3.maxConnIdleTime", "The maximum amount of time a database connection can be idle", i18n.TimeDurationType)
+	ConfigPersistenceSQLiteMaxIdleConns    = ffc("config.persistence.sqlite3.maxIdleConns", "The maximum number of idle connections to the database", i18n.IntType)
+	ConfigPersistenceSQLiteMaxConnLifetime = ffc("config.persistence.sqlite3.maxConnLifetime", "The maximum amount of time to keep a database connection open", i18n.TimeDurationType)
+
 	ConfigWebhooksAllowPrivateIPs = ffc("config.webhooks.allowPrivateIPs", "Whether to allow WebHook URLs that resolve to Private IP address ranges (vs. internet addresses)", i18n.BooleanType)
 	ConfigWebhooksURL             = ffc("config.webhooks.url", "Unused (overridden by the WebHook configuration of an individual event stream)", i18n.IgnoredType)
 	ConfigWebhooksProxyURL        = ffc("config.webhooks.proxy.url", "Optional HTTP proxy to use when invoking WebHooks", i18n.StringType)
@@ -78,7 +78,6 @@ var (
 	MsgPersistenceTXIncomplete                 = ffe("FF21059", "Transaction is missing indexed fields")
 	MsgNotStarted                              = ffe("FF21060", "Connector has not fully started yet", http.StatusServiceUnavailable)
 	MsgPaginationErrTxNotFound                 = ffe("FF21062", "The ID specified in the 'after' option (for pagination) must match an existing transaction: '%s'", http.StatusNotFound)
-	MsgTXConflictSignerPending                 = ffe("FF21063", "Only one of 'signer' and 'pending' can be supplied when querying transactions", http.StatusBadRequest)
 	MsgInvalidSortDirection                    = ffe("FF21064", "Sort direction must be 'asc'/'ascending' or 'desc'/'descending': '%s'", http.StatusBadRequest)
 	MsgDuplicateID                             = ffe("FF21065", "ID '%s' is not unique", http.StatusConflict)
 	MsgTransactionFailed                       = ffe("FF21066", "Transaction execution failed")
@@ -97,4 +96,36 @@ var (
 	MsgTHMetricsInvalidName     = ffe("FF21077", "Transaction handler metrics registration name can only contain lowercase letters and underscore. Actual name: %s")
 	MsgTHMetricsHelpTextMissing = ffe("FF21078", "Transaction handler metrics registration help text must be provided")
 	MsgTHMetricsDuplicateName   = ffe("FF21080", "Transaction handler metrics registration invalid name already registered: %s")
+
+	MsgIntegrityCheckNotSupported = ffe("FF21081", "Integrity check and repair is not supported for persistence type '%s'")
+	MsgIntegrityIssuesFound       = ffe("FF21082", "%d integrity issue(s) found in persistence")
+	MsgArchiveMissingHeader       = ffe("FF21083", "Archive must begin with a header record", http.StatusBadRequest)
+	MsgArchiveVersionUnsupported  = ffe("FF21084", "Archive version %d is not supported (maximum %d)", http.StatusBadRequest)
+	MsgArchiveInvalidRecord       = ffe("FF21085", "Invalid archive record %d: %s", http.StatusBadRequest)
+	MsgArchiveExportFailed        = ffe("FF21086", "Failed to write archive")
+	MsgInvalidTXStatus            = ffe("FF21087", "Invalid transaction status '%s'", http.StatusBadRequest)
+	MsgTXConflictPendingStatus    = ffe("FF21088", "Query for pending transactions cannot be combined with status '%s'", http.StatusBadRequest)
+	MsgInvalidTimeFilter          = ffe("FF21089", "Invalid time for '%s': %s", http.StatusBadRequest)
+	MsgInvalidGasPriceEscalation  = ffe("FF21090", "Invalid gas price escalation configuration '%s': %v")
+	MsgTXDeadlineExceeded         = ffe("FF21091", "Transaction was not mined before its deadline %s")
+	MsgTXNotCancellable           = ffe("FF21092", "Transaction '%s' cannot be cancelled as it has already been mined, or is no longer pending", http.StatusConflict)
+	MsgTXCancelled                = ffe("FF21093", "Transaction was cancelled by a replacement transaction %s")
+	MsgTXNotResubmittable         = ffe("FF21094", "Transaction '%s' cannot be resubmitted as it has not yet been submitted, has already been mined, or is being cancelled or deleted", http.StatusConflict)
+	MsgResubmitGasPriceAndBump    = ffe("FF21095", "Only one of 'gasPrice' and 'bumpPercentage' can be set", http.StatusBadRequest)
+	MsgResubmitInvalidBump        = ffe("FF21096", "Invalid bump percentage %d", http.StatusBadRequest)
+	MsgInvalidNonceReservation    = ffe("FF21097", "Invalid nonce reservation count %d - must be between 1 and %d", http.StatusBadRequest)
+	MsgInvalidPriorityConfig      = ffe("FF21098", "Invalid transaction priority configuration '%s': %v")
+	MsgTXRequestConflict          = ffe("FF21099", "Transaction '%s' already exists, and was created by a different request", http.StatusConflict)
+	MsgTransactionReverted        = ffe("FF21100", "Transaction reverted in pre-flight gas estimation: %s", http.StatusBadRequest)
+	MsgInvalidPreflightConfig     = ffe("FF21101", "Invalid pre-flight configuration '%s': %v")
+	MsgMissingRawTransactionField = ffe("FF21102", "Missing '%s' in raw transaction request", http.StatusBadRequest)
+	MsgTXPreSignedNotReplaceable  = ffe("FF21103", "Transaction '%s' was pre-signed, so cannot be submitted with a different gas price or cancelled by a replacement", http.StatusConflict)
+	MsgNotBeforeAfterDeadline     = ffe("FF21104", "The notBefore time %s must be before the deadline %s", http.StatusBadRequest)
+	MsgTXScheduledCancelled       = ffe("FF21105", "Transaction was cancelled before it was submitted at its notBefore time")
+	MsgPersistenceNameClash       = ffe("FF21106", "Persistence type '%s' cannot be registered, as it is the name of a built-in persistence type")
+	MsgTXHandlerNotSupported      = ffe("FF21107", "The transaction handler does not support this operation, as it does not implement %s", http.StatusNotImplemented)
+	MsgTransactionBatchTooLarge   = ffe("FF21108", "Batch of %d new transactions exceeds the maximum batch size of %d", http.StatusBadRequest)
+	MsgNonceAlreadyUsed           = ffe("FF21109", "Nonce %s for signer '%s' is already used by transaction '%s'", http.StatusConflict)
+	MsgRawTransactionNotBefore    = ffe("FF21110", "A not-before time cannot be set on a pre-signed transaction, as its nonce is fixed by the signature and it would hold up all the later transactions for the signer", http.StatusBadRequest)
+	MsgRawTransactionNonceGap     = ffe("FF21111", "Nonce %s for signer '%s' is ahead of the next nonce %d, so the transaction would leave a gap in the nonces of the signer", http.StatusBadRequest)
 )
@@ -132,6 +132,32 @@ func (_m *Persistence) GetListener(ctx context.Context, listenerID *fftypes.UUID
 	return r0, r1
 }
 
+// GetSignerMinimumNonce provides a mock function with given fields: ctx, signer
+func (_m *Persistence) GetSignerMinimumNonce(ctx context.Context, signer string) (*apitypes.SignerMinimumNonce, error) {
+	ret := _m.Called(ctx, signer)
+
+	var r0 *apitypes.SignerMinimumNonce
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*apitypes.SignerMinimumNonce, error)); ok {
+		return rf(ctx, signer)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *apitypes.SignerMinimumNonce); ok {
+		r0 = rf(ctx, signer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*apitypes.SignerMinimumNonce)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, signer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetStream provides a mock function with given fields: ctx, streamID
 func (_m *Persistence) GetStream(ctx context.Context, streamID *fftypes.UUID) (*apitypes.EventStream, error) {
 	ret := _m.Called(ctx, streamID)
@@ -236,6 +262,58 @@ func (_m *Persistence) ListListeners(ctx context.Context, after *fftypes.UUID, l
 	return r0, r1
 }
 
+// ListNonceReservations provides a mock function with given fields: ctx, signer, after, limit, dir
+func (_m *Persistence) ListNonceReservations(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir persistence.SortDirection) ([]*apitypes.NonceReservation, error) {
+	ret := _m.Called(ctx, signer, after, limit, dir)
+
+	var r0 []*apitypes.NonceReservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *fftypes.FFBigInt, int, persistence.SortDirection) ([]*apitypes.NonceReservation, error)); ok {
+		return rf(ctx, signer, after, limit, dir)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *fftypes.FFBigInt, int, persistence.SortDirection) []*apitypes.NonceReservation); ok {
+		r0 = rf(ctx, signer, after, limit, dir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*apitypes.NonceReservation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *fftypes.FFBigInt, int, persistence.SortDirection) error); ok {
+		r1 = rf(ctx, signer, after, limit, dir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSignerMinimumNonces provides a mock function with given fields: ctx, after, limit, dir
+func (_m *Persistence) ListSignerMinimumNonces(ctx context.Context, after string, limit int, dir persistence.SortDirection) ([]*apitypes.SignerMinimumNonce, error) {
+	ret := _m.Called(ctx, after, limit, dir)
+
+	var r0 []*apitypes.SignerMinimumNonce
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, persistence.SortDirection) ([]*apitypes.SignerMinimumNonce, error)); ok {
+		return rf(ctx, after, limit, dir)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, persistence.SortDirection) []*apitypes.SignerMinimumNonce); ok {
+		r0 = rf(ctx, after, limit, dir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*apitypes.SignerMinimumNonce)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, persistence.SortDirection) error); ok {
+		r1 = rf(ctx, after, limit, dir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListStreamListeners provides a mock function with given fields: ctx, after, limit, dir, streamID
 func (_m *Persistence) ListStreamListeners(ctx context.Context, after *fftypes.UUID, limit int, dir persistence.SortDirection, streamID *fftypes.UUID) ([]*apitypes.Listener, error) {
 	ret := _m.Called(ctx, after, limit, dir, streamID)
@@ -288,6 +366,32 @@ func (_m *Persistence) ListStreams(ctx context.Context, after *fftypes.UUID, lim
 	return r0, r1
 }
 
+// ListTransactionHistory provides a mock function with given fields: ctx, txID, after, limit, dir
+func (_m *Persistence) ListTransactionHistory(ctx context.Context, txID string, after *fftypes.UUID, limit int, dir persistence.SortDirection) ([]*apitypes.TxHistoryRecord, error) {
+	ret := _m.Called(ctx, txID, after, limit, dir)
+
+	var r0 []*apitypes.TxHistoryRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *fftypes.UUID, int, persistence.SortDirection) ([]*apitypes.TxHistoryRecord, error)); ok {
+		return rf(ctx, txID, after, limit, dir)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *fftypes.UUID, int, persistence.SortDirection) []*apitypes.TxHistoryRecord); ok {
+		r0 = rf(ctx, txID, after, limit, dir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*apitypes.TxHistoryRecord)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *fftypes.UUID, int, persistence.SortDirection) error); ok {
+		r1 = rf(ctx, txID, after, limit, dir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListTransactionsByCreateTime provides a mock function with given fields: ctx, after, limit, dir
 func (_m *Persistence) ListTransactionsByCreateTime(ctx context.Context, after *apitypes.ManagedTX, limit int, dir persistence.SortDirection) ([]*apitypes.ManagedTX, error) {
 	ret := _m.Called(ctx, after, limit, dir)
@@ -340,6 +444,32 @@ func (_m *Persistence) ListTransactionsByNonce(ctx context.Context, signer strin
 	return r0, r1
 }
 
+// ListTransactionsFiltered provides a mock function with given fields: ctx, filters, after, limit, dir
+func (_m *Persistence) ListTransactionsFiltered(ctx context.Context, filters *persistence.TransactionFilters, after *apitypes.ManagedTX, limit int, dir persistence.SortDirection) ([]*apitypes.ManagedTX, error) {
+	ret := _m.Called(ctx, filters, after, limit, dir)
+
+	var r0 []*apitypes.ManagedTX
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *persistence.TransactionFilters, *apitypes.ManagedTX, int, persistence.SortDirection) ([]*apitypes.ManagedTX, error)); ok {
+		return rf(ctx, filters, after, limit, dir)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *persistence.TransactionFilters, *apitypes.ManagedTX, int, persistence.SortDirection) []*apitypes.ManagedTX); ok {
+		r0 = rf(ctx, filters, after, limit, dir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*apitypes.ManagedTX)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *persistence.TransactionFilters, *apitypes.ManagedTX, int, persistence.SortDirection) error); ok {
+		r1 = rf(ctx, filters, after, limit, dir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListTransactionsPending provides a mock function with given fields: ctx, afterSequenceID, limit, dir
 func (_m *Persistence) ListTransactionsPending(ctx context.Context, afterSequenceID string, limit int, dir persistence.SortDirection) ([]*apitypes.ManagedTX, error) {
 	ret := _m.Called(ctx, afterSequenceID, limit, dir)
@@ -394,6 +524,34 @@ func (_m *Persistence) WriteListener(ctx context.Context, spec *apitypes.Listene
 	return r0
 }
 
+// WriteNonceReservation provides a mock function with given fields: ctx, reservation
+func (_m *Persistence) WriteNonceReservation(ctx context.Context, reservation *apitypes.NonceReservation) error {
+	ret := _m.Called(ctx, reservation)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *apitypes.NonceReservation) error); ok {
+		r0 = rf(ctx, reservation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WriteSignerMinimumNonce provides a mock function with given fields: ctx, minimum
+func (_m *Persistence) WriteSignerMinimumNonce(ctx context.Context, minimum *apitypes.SignerMinimumNonce) error {
+	ret := _m.Called(ctx, minimum)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *apitypes.SignerMinimumNonce) error); ok {
+		r0 = rf(ctx, minimum)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WriteStream provides a mock function with given fields: ctx, spec
 func (_m *Persistence) WriteStream(ctx context.Context, spec *apitypes.EventStream) error {
 	ret := _m.Called(ctx, spec)
@@ -422,6 +580,20 @@ func (_m *Persistence) WriteTransaction(ctx context.Context, tx *apitypes.Manage
 	return r0
 }
 
+// WriteTransactionHistory provides a mock function with given fields: ctx, record
+func (_m *Persistence) WriteTransactionHistory(ctx context.Context, record *apitypes.TxHistoryRecord) error {
+	ret := _m.Called(ctx, record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *apitypes.TxHistoryRecord) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewPersistence interface {
 	mock.TestingT
 	Cleanup(func())
@@ -33,6 +33,32 @@ func (_m *TransactionPersistence) DeleteTransaction(ctx context.Context, txID st
 	return r0
 }
 
+// GetSignerMinimumNonce provides a mock function with given fields: ctx, signer
+func (_m *TransactionPersistence) GetSignerMinimumNonce(ctx context.Context, signer string) (*apitypes.SignerMinimumNonce, error) {
+	ret := _m.Called(ctx, signer)
+
+	var r0 *apitypes.SignerMinimumNonce
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*apitypes.SignerMinimumNonce, error)); ok {
+		return rf(ctx, signer)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *apitypes.SignerMinimumNonce); ok {
+		r0 = rf(ctx, signer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*apitypes.SignerMinimumNonce)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, signer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTransactionByID provides a mock function with given fields: ctx, txID
 func (_m *TransactionPersistence) GetTransactionByID(ctx context.Context, txID string) (*apitypes.ManagedTX, error) {
 	ret := _m.Called(ctx, txID)
@@ -85,6 +111,58 @@ func (_m *TransactionPersistence) GetTransactionByNonce(ctx context.Context, sig
 	return r0, r1
 }
 
+// ListNonceReservations provides a mock function with given fields: ctx, signer, after, limit, dir
+func (_m *TransactionPersistence) ListNonceReservations(ctx context.Context, signer string, after *fftypes.FFBigInt, limit int, dir persistence.SortDirection) ([]*apitypes.NonceReservation, error) {
+	ret := _m.Called(ctx, signer, after, limit, dir)
+
+	var r0 []*apitypes.NonceReservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *fftypes.FFBigInt, int, persistence.SortDirection) ([]*apitypes.NonceReservation, error)); ok {
+		return rf(ctx, signer, after, limit, dir)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *fftypes.FFBigInt, int, persistence.SortDirection) []*apitypes.NonceReservation); ok {
+		r0 = rf(ctx, signer, after, limit, dir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*apitypes.NonceReservation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *fftypes.FFBigInt, int, persistence.SortDirection) error); ok {
+		r1 = rf(ctx, signer, after, limit, dir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSignerMinimumNonces provides a mock function with given fields: ctx, after, limit, dir
+func (_m *TransactionPersistence) ListSignerMinimumNonces(ctx context.Context, after string, limit int, dir persistence.SortDirection) ([]*apitypes.SignerMinimumNonce, error) {
+	ret := _m.Called(ctx, after, limit, dir)
+
+	var r0 []*apitypes.SignerMinimumNonce
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, persistence.SortDirection) ([]*apitypes.SignerMinimumNonce, error)); ok {
+		return rf(ctx, after, limit, dir)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, persistence.SortDirection) []*apitypes.SignerMinimumNonce); ok {
+		r0 = rf(ctx, after, limit, dir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*apitypes.SignerMinimumNonce)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, persistence.SortDirection) error); ok {
+		r1 = rf(ctx, after, limit, dir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListTransactionsByCreateTime provides a mock function with given fields: ctx, after, limit, dir
 func (_m *TransactionPersistence) ListTransactionsByCreateTime(ctx context.Context, after *apitypes.ManagedTX, limit int, dir persistence.SortDirection) ([]*apitypes.ManagedTX, error) {
 	ret := _m.Called(ctx, after, limit, dir)
@@ -137,6 +215,32 @@ func (_m *TransactionPersistence) ListTransactionsByNonce(ctx context.Context, s
 	return r0, r1
 }
 
+// ListTransactionsFiltered provides a mock function with given fields: ctx, filters, after, limit, dir
+func (_m *TransactionPersistence) ListTransactionsFiltered(ctx context.Context, filters *persistence.TransactionFilters, after *apitypes.ManagedTX, limit int, dir persistence.SortDirection) ([]*apitypes.ManagedTX, error) {
+	ret := _m.Called(ctx, filters, after, limit, dir)
+
+	var r0 []*apitypes.ManagedTX
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *persistence.TransactionFilters, *apitypes.ManagedTX, int, persistence.SortDirection) ([]*apitypes.ManagedTX, error)); ok {
+		return rf(ctx, filters, after, limit, dir)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *persistence.TransactionFilters, *apitypes.ManagedTX, int, persistence.SortDirection) []*apitypes.ManagedTX); ok {
+		r0 = rf(ctx, filters, after, limit, dir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*apitypes.ManagedTX)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *persistence.TransactionFilters, *apitypes.ManagedTX, int, persistence.SortDirection) error); ok {
+		r1 = rf(ctx, filters, after, limit, dir)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListTransactionsPending provides a mock function with given fields: ctx, afterSequenceID, limit, dir
 func (_m *TransactionPersistence) ListTransactionsPending(ctx context.Context, afterSequenceID string, limit int, dir persistence.SortDirection) ([]*apitypes.ManagedTX, error) {
 	ret := _m.Called(ctx, afterSequenceID, limit, dir)
@@ -163,6 +267,34 @@ func (_m *TransactionPersistence) ListTransactionsPending(ctx context.Context, a
 	return r0, r1
 }
 
+// WriteNonceReservation provides a mock function with given fields: ctx, reservation
+func (_m *TransactionPersistence) WriteNonceReservation(ctx context.Context, reservation *apitypes.NonceReservation) error {
+	ret := _m.Called(ctx, reservation)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *apitypes.NonceReservation) error); ok {
+		r0 = rf(ctx, reservation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WriteSignerMinimumNonce provides a mock function with given fields: ctx, minimum
+func (_m *TransactionPersistence) WriteSignerMinimumNonce(ctx context.Context, minimum *apitypes.SignerMinimumNonce) error {
+	ret := _m.Called(ctx, minimum)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *apitypes.SignerMinimumNonce) error); ok {
+		r0 = rf(ctx, minimum)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WriteTransaction provides a mock function with given fields: ctx, tx, new
 func (_m *TransactionPersistence) WriteTransaction(ctx context.Context, tx *apitypes.ManagedTX, new bool) error {
 	ret := _m.Called(ctx, tx, new)
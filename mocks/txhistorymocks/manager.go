@@ -43,6 +43,11 @@ func (_m *Manager) SetSubStatus(ctx context.Context, mtx *apitypes.ManagedTX, su
 	_m.Called(ctx, mtx, subStatus)
 }
 
+// WriteNewTransactionHistory provides a mock function with given fields: ctx, mtx
+func (_m *Manager) WriteNewTransactionHistory(ctx context.Context, mtx *apitypes.ManagedTX) {
+	_m.Called(ctx, mtx)
+}
+
 type mockConstructorTestingTNewManager interface {
 	mock.TestingT
 	Cleanup(func())
@@ -137,6 +137,57 @@ type LiveGasPrice struct {
 	ffcapi.GasPriceEstimateResponse
 }
 
+// SignerStatus is the status of the nonces of a signing address, as determined by the transaction handler
+type SignerStatus struct {
+	Signer           string            `json:"signer"`
+	NextNonce        *fftypes.FFBigInt `json:"nextNonce"`                  // the next nonce according to the node, which includes transactions in its pending pool
+	LastPendingNonce *fftypes.FFBigInt `json:"lastPendingNonce,omitempty"` // the highest nonce of a pending transaction for the signer
+	NonceGaps        []*NonceGap       `json:"nonceGaps"`
+	Underfunded      bool              `json:"underfunded"`                // submission of transactions is paused, as the node rejected a transaction for insufficient funds
+	UnderfundedSince *fftypes.FFTime   `json:"underfundedSince,omitempty"` // when submission was paused
+	Balance          *fftypes.FFBigInt `json:"balance,omitempty"`          // the balance at the last check while paused
+	PendingCost      *fftypes.FFBigInt `json:"pendingCost,omitempty"`      // the cost of the in-flight transactions, that the balance must cover to resume submission
+}
+
+// NonceGap is a range of nonces that are not used by any pending transaction, but are below the nonce of a pending
+// transaction. The node cannot mine any of the later transactions for the signer until the gap is filled.
+type NonceGap struct {
+	First *fftypes.FFBigInt `json:"first"`
+	Last  *fftypes.FFBigInt `json:"last"` // inclusive
+}
+
+// SignerNonceStatus is the next nonce for a signing address, according to each of the sources the transaction handler uses
+type SignerNonceStatus struct {
+	Signer             string            `json:"signer"`
+	NextNonce          *fftypes.FFBigInt `json:"nextNonce"`                    // the nonce that will be assigned to the next transaction
+	PersistedNextNonce *fftypes.FFBigInt `json:"persistedNextNonce,omitempty"` // one after the highest nonce of a transaction in persistence
+	PersistedStale     bool              `json:"persistedStale"`               // the last transaction in persistence is too old to be relied on, so the node is also queried
+	NodeNextNonce      *fftypes.FFBigInt `json:"nodeNextNonce"`                // the next nonce according to the node, which includes transactions in its pending pool
+	MinimumNonce       *fftypes.FFBigInt `json:"minimumNonce,omitempty"`       // the lowest nonce that can be assigned, after a reset or a reservation of nonces for external use
+	Locked             bool              `json:"locked"`                       // a nonce is currently being assigned
+	LockedBy           string            `json:"lockedBy,omitempty"`           // the ID of the transaction being assigned a nonce
+}
+
+// NonceReservationRequest requests a block of nonces for a signing address, for use outside of the transaction manager
+type NonceReservationRequest struct {
+	Count int64 `json:"count"`
+}
+
+// NonceReservation is a block of nonces for a signing address that will not be assigned to any transaction
+type NonceReservation struct {
+	Signer string            `json:"signer"`
+	First  *fftypes.FFBigInt `json:"first"`
+	Last   *fftypes.FFBigInt `json:"last"` // inclusive
+}
+
+// SignerMinimumNonce is the lowest nonce that can be assigned to a transaction for a signing address. It is raised
+// when the next nonce is reset from the node, and when a block of nonces is reserved.
+type SignerMinimumNonce struct {
+	Signer  string            `json:"signer"`
+	Nonce   *fftypes.FFBigInt `json:"nonce"`
+	Updated *fftypes.FFTime   `json:"updated"`
+}
+
 // CheckUpdateString helper merges supplied configuration, with a base, and applies a default if unset
 func CheckUpdateString(changed bool, merged **string, old *string, new *string, defValue string) bool {
 	if new != nil {
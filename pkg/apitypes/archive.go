@@ -0,0 +1,82 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitypes
+
+import "github.com/hyperledger/firefly-common/pkg/fftypes"
+
+// ArchiveVersion is the current version of the NDJSON archive format written by an export.
+// Imports accept any archive with a version less than or equal to this.
+// Version 2 added the transaction history records.
+// Version 3 added the signer minimum nonce and nonce reservation records.
+const ArchiveVersion = 3
+
+// ArchiveRecordType is the type of each line in an NDJSON archive
+type ArchiveRecordType string
+
+const (
+	// ArchiveRecordHeader is always the first record in the archive
+	ArchiveRecordHeader ArchiveRecordType = "header"
+	// ArchiveRecordEventStream contains an event stream definition
+	ArchiveRecordEventStream ArchiveRecordType = "eventstream"
+	// ArchiveRecordCheckpoint contains the checkpoint of an event stream
+	ArchiveRecordCheckpoint ArchiveRecordType = "checkpoint"
+	// ArchiveRecordListener contains a listener definition
+	ArchiveRecordListener ArchiveRecordType = "listener"
+	// ArchiveRecordTransaction contains a managed transaction
+	ArchiveRecordTransaction ArchiveRecordType = "transaction"
+	// ArchiveRecordTransactionHistory contains a history record, which follows the transaction it belongs to
+	ArchiveRecordTransactionHistory ArchiveRecordType = "txhistory"
+	// ArchiveRecordSignerMinimumNonce contains the minimum nonce of a signer
+	ArchiveRecordSignerMinimumNonce ArchiveRecordType = "signerminimumnonce"
+	// ArchiveRecordNonceReservation contains a nonce reservation, which follows the minimum nonce of its signer
+	ArchiveRecordNonceReservation ArchiveRecordType = "noncereservation"
+)
+
+// ArchiveRecord is a single line in an NDJSON archive. Only the field matching the type is set.
+type ArchiveRecord struct {
+	Type               ArchiveRecordType      `json:"type"`
+	Version            int                    `json:"version,omitempty"` // header only
+	Created            *fftypes.FFTime        `json:"created,omitempty"` // header only
+	EventStream        *EventStream           `json:"eventstream,omitempty"`
+	Checkpoint         *EventStreamCheckpoint `json:"checkpoint,omitempty"`
+	Listener           *Listener              `json:"listener,omitempty"`
+	Transaction        *ManagedTX             `json:"transaction,omitempty"`
+	TXHistory          *TxHistoryRecord       `json:"txhistory,omitempty"`
+	SignerMinimumNonce *SignerMinimumNonce    `json:"signerminimumnonce,omitempty"`
+	NonceReservation   *NonceReservation      `json:"noncereservation,omitempty"`
+}
+
+// ArchiveImportCount records how many records of a type were imported, and how many were skipped
+// because a record with the same ID already existed
+type ArchiveImportCount struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// ArchiveImportResult is the summary of an import
+type ArchiveImportResult struct {
+	EventStreams        ArchiveImportCount `json:"eventStreams"`
+	Checkpoints         ArchiveImportCount `json:"checkpoints"`
+	Listeners           ArchiveImportCount `json:"listeners"`
+	Transactions        ArchiveImportCount `json:"transactions"`
+	TXHistory           ArchiveImportCount `json:"txhistory"`
+	SignerMinimumNonces ArchiveImportCount `json:"signerMinimumNonces"`
+	NonceReservations   ArchiveImportCount `json:"nonceReservations"`
+
+	// ImportedListeners are the listeners that were imported, so a running instance can start them
+	ImportedListeners []*Listener `json:"-"`
+}
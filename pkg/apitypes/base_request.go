@@ -16,7 +16,11 @@
 
 package apitypes
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+)
 
 // BaseRequest is the common headers to all requests, and captures the full input payload for later decoding to a specific type
 type BaseRequest struct {
@@ -38,14 +42,18 @@ func (br *BaseRequest) UnmarshalTo(o interface{}) error {
 }
 
 type RequestHeaders struct {
-	ID   string      `ffstruct:"fftmrequest" json:"id"`
-	Type RequestType `json:"type"`
+	ID        string          `ffstruct:"fftmrequest" json:"id"`
+	Type      RequestType     `json:"type"`
+	Deadline  *fftypes.FFTime `json:"deadline,omitempty"`  // optional time after which a transaction that has not been mined is cancelled
+	Priority  int             `json:"priority,omitempty"`  // optional priority, where higher priority transactions are processed ahead of others and can pay a higher gas price
+	NotBefore *fftypes.FFTime `json:"notBefore,omitempty"` // optional time before which the transaction is not submitted
 }
 
 type RequestType string
 
 const (
-	RequestTypeSendTransaction RequestType = "SendTransaction"
-	RequestTypeQuery           RequestType = "Query"
-	RequestTypeDeploy          RequestType = "DeployContract"
+	RequestTypeSendTransaction    RequestType = "SendTransaction"
+	RequestTypeSendRawTransaction RequestType = "SendRawTransaction"
+	RequestTypeQuery              RequestType = "Query"
+	RequestTypeDeploy             RequestType = "DeployContract"
 )
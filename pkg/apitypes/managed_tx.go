@@ -49,14 +49,26 @@ const (
 	TxSubStatusConfirmed TxSubStatus = "Confirmed"
 	// TxSubStatusFailed indicates we have failed to process the transaction and it will no longer be tracked
 	TxSubStatusFailed TxSubStatus = "Failed"
+	// TxSubStatusCancelling indicates we are attempting to cancel the transaction, by replacing it at the same nonce
+	TxSubStatusCancelling TxSubStatus = "Cancelling"
+	// TxSubStatusScheduled indicates the transaction is waiting for its not-before time, before it is submitted
+	TxSubStatusScheduled TxSubStatus = "Scheduled"
 )
 
 // TxHistoryStateTransitionEntry represents a state that the policy engine that manages transaction submission has entered,
 // and a list of the actions attempted within that state in order to attempt to move to the next state.
 type TxHistoryStateTransitionEntry struct {
-	Status  TxSubStatus             `json:"subStatus"` // the subStatus we entered
-	Time    *fftypes.FFTime         `json:"time"`      // the time we transitioned to this subStatus
-	Actions []*TxHistoryActionEntry `json:"actions"`   // the unique actions we attempted while in this sub-status
+	ID      *fftypes.UUID           `json:"id,omitempty"` // the ID of the history record, which is ordered by creation time
+	Status  TxSubStatus             `json:"subStatus"`    // the subStatus we entered
+	Time    *fftypes.FFTime         `json:"time"`         // the time we transitioned to this subStatus
+	Actions []*TxHistoryActionEntry `json:"actions"`      // the unique actions we attempted while in this sub-status
+}
+
+// TxHistoryRecord is a history entry for a transaction, as stored separately from the transaction itself.
+// Every sub-status transition of the transaction is recorded, so this provides a full audit trail.
+type TxHistoryRecord struct {
+	TransactionID string `json:"transactionId"`
+	TxHistoryStateTransitionEntry
 }
 
 // TxHistorySummaryEntry records summarize the transaction history, by recording the number of times each
@@ -86,6 +98,14 @@ const (
 	TxActionReceiveReceipt TxAction = "ReceiveReceipt"
 	// TxActionConfirmTransaction indicates that the transaction has been confirmed
 	TxActionConfirmTransaction TxAction = "Confirm"
+	// TxActionDeadlineExceeded indicates that the transaction was not mined before its deadline
+	TxActionDeadlineExceeded TxAction = "DeadlineExceeded"
+	// TxActionSubmitCancellation indicates that a replacement transaction has been submitted at the same nonce, to cancel the transaction
+	TxActionSubmitCancellation TxAction = "SubmitCancellation"
+	// TxActionManualResubmit indicates that a resubmission of the transaction was requested through the API
+	TxActionManualResubmit TxAction = "ManualResubmit"
+	// TxActionResolveCancellation indicates whether the transaction, or the replacement submitted to cancel it, was mined
+	TxActionResolveCancellation TxAction = "ResolveCancellation"
 )
 
 // An action taken in order to progress a transaction, e.g. retrieve gas price from an oracle.
@@ -115,6 +135,9 @@ type TxHistoryActionEntry struct {
 //	  the key includes the ID of the TX for uniqueness.
 //	- Pending sequence: An entry in this index only exists while the transaction is pending, and is
 //	  ordered by a UUIDv1 sequence allocated to each entry.
+//	- Status, to address and transaction hash: timestamp ordered indexes within each value, for
+//	  filtering transaction queries. The entry for the status moves as the transaction is updated, and
+//	  there is an entry for every hash the transaction has been submitted with.
 //
 // Index cleanup after partial write:
 //   - All indexes are stored before the TX itself.
@@ -126,22 +149,30 @@ type ManagedTX struct {
 	Updated            *fftypes.FFTime           `json:"updated"`
 	Status             TxStatus                  `json:"status"`
 	DeleteRequested    *fftypes.FFTime           `json:"deleteRequested,omitempty"`
+	CancelRequested    *fftypes.FFTime           `json:"cancelRequested,omitempty"`
 	SequenceID         string                    `json:"sequenceId"`
 	Nonce              *fftypes.FFBigInt         `json:"nonce"`
 	Gas                *fftypes.FFBigInt         `json:"gas"`
 	TransactionHeaders ffcapi.TransactionHeaders `json:"transactionHeaders"`
 	TransactionData    string                    `json:"transactionData"`
+	PreSigned          bool                      `json:"preSigned,omitempty"` // the transaction data is a transaction signed outside of the connector, which is submitted as-is
 	TransactionHash    string                    `json:"transactionHash,omitempty"`
+	SubmittedHashes    []string                  `json:"submittedHashes,omitempty"`
+	CancellationHashes []string                  `json:"cancellationHashes,omitempty"` // the hashes of replacement transactions submitted at the same nonce to cancel this transaction
 	GasPrice           *fftypes.JSONAny          `json:"gasPrice"`
 	PolicyInfo         *fftypes.JSONAny          `json:"policyInfo"`
 	FirstSubmit        *fftypes.FFTime           `json:"firstSubmit,omitempty"`
 	LastSubmit         *fftypes.FFTime           `json:"lastSubmit,omitempty"`
+	Deadline           *fftypes.FFTime           `json:"deadline,omitempty"`
+	NotBefore          *fftypes.FFTime           `json:"notBefore,omitempty"` // the transaction is not submitted, or assigned a nonce if it does not have one, before this time
+	Priority           int                       `json:"priority,omitempty"`
+	RequestHash        string                    `json:"requestHash,omitempty"` // a hash of the request that created the transaction, to detect a different request submitted with the same ID
 	ErrorMessage       string                    `json:"errorMessage,omitempty"`
 
 	Receipt       *ffcapi.TransactionReceiptResponse `json:"receipt,omitempty"`
 	Confirmations []BlockInfo                        `json:"confirmations,omitempty"`
 
-	History        []*TxHistoryStateTransitionEntry `json:"history,omitempty"`
+	History        []*TxHistoryStateTransitionEntry `json:"history,omitempty"` // only the current entry - the full history is stored as separate TxHistoryRecord entries
 	HistorySummary []*TxHistorySummaryEntry         `json:"historySummary,omitempty"`
 }
 
@@ -150,6 +181,40 @@ func (mtx *ManagedTX) Namespace(ctx context.Context) string {
 	return namespace
 }
 
+// SetTransactionHash updates the current transaction hash, retaining the history of every hash
+// that has been submitted so the transaction can be found from any of them
+func (mtx *ManagedTX) SetTransactionHash(hash string) {
+	for _, h := range []string{mtx.TransactionHash, hash} {
+		if h != "" && !containsString(mtx.SubmittedHashes, h) {
+			mtx.SubmittedHashes = append(mtx.SubmittedHashes, h)
+		}
+	}
+	mtx.TransactionHash = hash
+}
+
+// HasSubmittedHash returns true if the hash is the current transaction hash, or any previously submitted hash
+func (mtx *ManagedTX) HasSubmittedHash(hash string) bool {
+	return mtx.TransactionHash == hash || containsString(mtx.SubmittedHashes, hash)
+}
+
+// TransactionHashes returns every hash the transaction has been submitted with, including the current hash
+// for a transaction written before the submitted hashes were recorded
+func (mtx *ManagedTX) TransactionHashes() []string {
+	if mtx.TransactionHash != "" && !containsString(mtx.SubmittedHashes, mtx.TransactionHash) {
+		return append([]string{mtx.TransactionHash}, mtx.SubmittedHashes...)
+	}
+	return mtx.SubmittedHashes
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
 type BlockInfo struct {
 	BlockNumber       fftypes.FFuint64 `json:"blockNumber"`
 	BlockHash         string           `json:"blockHash"`
@@ -181,6 +246,15 @@ type TransactionUpdateReply struct {
 	ProtocolID       string           `json:"protocolId"`
 	TransactionHash  string           `json:"transactionHash,omitempty"`
 	ContractLocation *fftypes.JSONAny `json:"contractLocation,omitempty"`
+	ErrorMessage     string           `json:"errorMessage,omitempty"`
+}
+
+// ResubmitTransactionRequest is the input to request immediate resubmission of a pending transaction.
+// At most one of an explicit gas price, or a percentage increase over the last submitted gas price, can be set.
+// If neither is set, the gas price is determined by the policy of the transaction handler.
+type ResubmitTransactionRequest struct {
+	GasPrice       *fftypes.JSONAny `json:"gasPrice,omitempty"`
+	BumpPercentage *int64           `json:"bumpPercentage,omitempty"`
 }
 
 // ManagedTransactionEventType is a enum type that contains all types of transaction process events
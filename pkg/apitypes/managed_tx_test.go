@@ -39,3 +39,18 @@ func TestManagedTxNamespace(t *testing.T) {
 	ns = mtx.Namespace(ctx)
 	assert.Equal(t, "ns1", ns)
 }
+
+func TestManagedTxSubmittedHashes(t *testing.T) {
+	mtx := &ManagedTX{TransactionHash: "0x1111"}
+	assert.True(t, mtx.HasSubmittedHash("0x1111"))
+	assert.Equal(t, []string{"0x1111"}, mtx.TransactionHashes())
+
+	mtx.SetTransactionHash("0x2222")
+	mtx.SetTransactionHash("0x2222")
+	mtx.SetTransactionHash("0x1111")
+	assert.Equal(t, "0x1111", mtx.TransactionHash)
+	assert.Equal(t, []string{"0x1111", "0x2222"}, mtx.SubmittedHashes)
+	assert.True(t, mtx.HasSubmittedHash("0x2222"))
+	assert.False(t, mtx.HasSubmittedHash("0x3333"))
+	assert.Equal(t, []string{"0x1111", "0x2222"}, mtx.TransactionHashes())
+}
@@ -17,6 +17,11 @@
 package apitypes
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
 	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
 )
 
@@ -31,3 +36,81 @@ type ContractDeployRequest struct {
 	Headers RequestHeaders `json:"headers"`
 	ffcapi.ContractDeployPrepareRequest
 }
+
+// RawTransactionRequest is the payload sent to submit a transaction that has already been signed, such as by an HSM
+// outside of the connector. The signer and nonce must be supplied, as they cannot be changed, and are used to track
+// the transaction alongside the other transactions for the signer.
+type RawTransactionRequest struct {
+	Headers RequestHeaders `json:"headers"`
+	ffcapi.TransactionHeaders
+	RawTransaction string `json:"rawTransaction"`
+}
+
+// RequestHash returns a hash of the request, which is stored on the transaction so that a request submitted again
+// with the same ID can be checked to be identical
+func (tr *TransactionRequest) RequestHash() string {
+	return hashRequest(tr)
+}
+
+// RequestHash returns a hash of the request, which is stored on the transaction so that a request submitted again
+// with the same ID can be checked to be identical
+func (dr *ContractDeployRequest) RequestHash() string {
+	return hashRequest(dr)
+}
+
+// RequestHash returns a hash of the request, which is stored on the transaction so that a request submitted again
+// with the same ID can be checked to be identical
+func (rr *RawTransactionRequest) RequestHash() string {
+	return hashRequest(rr)
+}
+
+// hashRequest hashes a canonical form of the JSON of the request, with the keys of every object sorted and no
+// whitespace, so the hash does not depend on how the JSON fields (such as the method and params) were formatted
+func hashRequest(req interface{}) string {
+	b, _ := json.Marshal(req)
+	var canonical interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber() // keep large numbers exactly as they were supplied
+	if err := d.Decode(&canonical); err == nil {
+		b, _ = json.Marshal(canonical)
+	}
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:])
+}
+
+// TransactionBatchRequest is the payload sent to initiate many transactions in one call. Each request is in the same
+// format as a request to initiate it on its own, and must be of type SendTransaction or DeployContract.
+type TransactionBatchRequest struct {
+	Requests []*BaseRequest `json:"requests"`
+}
+
+// TransactionBatchItem is a request from a batch, decoded as either a transaction or a contract deployment
+type TransactionBatchItem struct {
+	Transaction *TransactionRequest
+	Deploy      *ContractDeployRequest
+}
+
+func (item *TransactionBatchItem) RequestHeaders() *RequestHeaders {
+	if item.Deploy != nil {
+		return &item.Deploy.Headers
+	}
+	return &item.Transaction.Headers
+}
+
+func (item *TransactionBatchItem) RequestHash() string {
+	if item.Deploy != nil {
+		return item.Deploy.RequestHash()
+	}
+	return item.Transaction.RequestHash()
+}
+
+// TransactionBatchResponse contains a result for each request in a batch, in the same order as the requests
+type TransactionBatchResponse struct {
+	Results []*TransactionBatchResult `json:"results"`
+}
+
+// TransactionBatchResult is the result of a request in a batch - each request succeeds or fails on its own
+type TransactionBatchResult struct {
+	Transaction *ManagedTX `json:"transaction,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
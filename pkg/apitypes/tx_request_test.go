@@ -0,0 +1,78 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitypes
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestHash(t *testing.T) {
+
+	tx := &TransactionBatchItem{
+		Transaction: &TransactionRequest{
+			Headers: RequestHeaders{ID: "tx1", Type: RequestTypeSendTransaction},
+			TransactionInput: ffcapi.TransactionInput{
+				TransactionHeaders: ffcapi.TransactionHeaders{From: "0x12345"},
+				Method:             fftypes.JSONAnyPtr(`{"name":"set"}`),
+			},
+		},
+	}
+	deploy := &TransactionBatchItem{
+		Deploy: &ContractDeployRequest{
+			Headers: RequestHeaders{ID: "tx1", Type: RequestTypeDeploy},
+			ContractDeployPrepareRequest: ffcapi.ContractDeployPrepareRequest{
+				TransactionHeaders: ffcapi.TransactionHeaders{From: "0x12345"},
+			},
+		},
+	}
+
+	assert.Equal(t, "tx1", tx.RequestHeaders().ID)
+	assert.Equal(t, "tx1", deploy.RequestHeaders().ID)
+	assert.Len(t, tx.RequestHash(), 64)
+	assert.Equal(t, tx.Transaction.RequestHash(), tx.RequestHash())
+	assert.Equal(t, deploy.Deploy.RequestHash(), deploy.RequestHash())
+	assert.NotEqual(t, tx.RequestHash(), deploy.RequestHash())
+
+	raw := &RawTransactionRequest{
+		Headers:        RequestHeaders{ID: "tx1", Type: RequestTypeSendRawTransaction},
+		RawTransaction: "0xf86c0a85",
+	}
+	assert.Len(t, raw.RequestHash(), 64)
+	assert.NotEqual(t, tx.RequestHash(), raw.RequestHash())
+
+	hash := tx.RequestHash()
+	tx.Transaction.From = "0x67890"
+	assert.NotEqual(t, hash, tx.RequestHash())
+
+	// The formatting of the JSON fields, and the order of their keys, does not change the hash
+	tx.Transaction.Method = fftypes.JSONAnyPtr(`{"name":"set","inputs":[{"type":"uint256"}]}`)
+	tx.Transaction.Params = []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"a":1,"b":123456789012345678901234567890}`)}
+	hash = tx.RequestHash()
+	tx.Transaction.Method = fftypes.JSONAnyPtr(`{
+		"inputs": [ { "type": "uint256" } ],
+		"name": "set"
+	}`)
+	tx.Transaction.Params = []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{ "b": 123456789012345678901234567890, "a": 1 }`)}
+	assert.Equal(t, hash, tx.RequestHash())
+	tx.Transaction.Params = []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"a":1,"b":123456789012345678901234567891}`)}
+	assert.NotEqual(t, hash, tx.RequestHash())
+
+}
@@ -19,9 +19,12 @@ package fftm
 import (
 	"context"
 
+	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/txhandler"
 )
 
 func (m *manager) getLiveBalance(ctx context.Context, address string, blockTag string) (resp *apitypes.LiveAddressBalance, err error) {
@@ -35,3 +38,43 @@ func (m *manager) getLiveBalance(ctx context.Context, address string, blockTag s
 	}
 	return resp, nil
 }
+
+func (m *manager) getSignerStatus(ctx context.Context, address string) (resp *apitypes.SignerStatus, err error) {
+	h, ok := m.txHandler.(txhandler.SignerStatusHandler)
+	if !ok {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgTXHandlerNotSupported, "SignerStatusHandler")
+	}
+	return h.GetSignerStatus(ctx, address)
+}
+
+func (m *manager) signerNonceHandler(ctx context.Context) (txhandler.SignerNonceHandler, error) {
+	h, ok := m.txHandler.(txhandler.SignerNonceHandler)
+	if !ok {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgTXHandlerNotSupported, "SignerNonceHandler")
+	}
+	return h, nil
+}
+
+func (m *manager) getSignerNonce(ctx context.Context, address string) (resp *apitypes.SignerNonceStatus, err error) {
+	h, err := m.signerNonceHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.GetSignerNonceStatus(ctx, address)
+}
+
+func (m *manager) resetSignerNonce(ctx context.Context, address string) (resp *apitypes.SignerNonceStatus, err error) {
+	h, err := m.signerNonceHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.HandleResetSignerNonce(ctx, address)
+}
+
+func (m *manager) reserveSignerNonces(ctx context.Context, address string, req *apitypes.NonceReservationRequest) (resp *apitypes.NonceReservation, err error) {
+	h, err := m.signerNonceHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.HandleReserveSignerNonces(ctx, address, req)
+}
@@ -17,6 +17,7 @@
 package fftm
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -28,6 +29,7 @@ import (
 	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/confirmationsmocks"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/ffcapimocks"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
 	"github.com/stretchr/testify/assert"
@@ -453,3 +455,127 @@ func TestNotFound(t *testing.T) {
 	assert.Equal(t, 404, res.StatusCode())
 	assert.Regexp(t, "FF00167", errRes.Error)
 }
+
+func TestSendTransactionIdempotent(t *testing.T) {
+
+	url, m, cancel := newTestManager(t)
+	defer cancel()
+
+	var tReq apitypes.TransactionRequest
+	err := json.Unmarshal([]byte(sampleSendTX), &tReq)
+	assert.NoError(t, err)
+	err = m.persistence.WriteTransaction(m.ctx, &apitypes.ManagedTX{
+		ID:          tReq.Headers.ID,
+		Created:     fftypes.Now(),
+		Status:      apitypes.TxStatusSucceeded,
+		RequestHash: tReq.RequestHash(),
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: tReq.From,
+		},
+		Nonce: fftypes.NewFFBigInt(12345),
+	}, true)
+	assert.NoError(t, err)
+
+	m.Start()
+
+	// An identical request returns the existing transaction, without submitting a new one
+	var mtx apitypes.ManagedTX
+	res, err := resty.New().R().
+		SetBody(strings.NewReader(sampleSendTX)).
+		SetResult(&mtx).
+		Post(url)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, tReq.Headers.ID, mtx.ID)
+
+	// A different request with the same ID is a conflict
+	mtx = apitypes.ManagedTX{}
+	res, err = resty.New().R().
+		SetBody(strings.NewReader(sampleDeployTX)).
+		SetError(&mtx).
+		Post(url)
+	assert.NoError(t, err)
+	assert.Equal(t, 409, res.StatusCode())
+	assert.Equal(t, tReq.Headers.ID, mtx.ID)
+
+}
+
+func TestSendTransactionIdempotentConcurrentCreate(t *testing.T) {
+
+	url, m, cancel := newTestManager(t)
+	defer cancel()
+
+	m.Start()
+
+	var tReq apitypes.TransactionRequest
+	err := json.Unmarshal([]byte(sampleSendTX), &tReq)
+	assert.NoError(t, err)
+	mth := txhandlermocks.TransactionHandler{}
+	mth.On("HandleNewTransaction", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		// Another request with the same ID creates the transaction while we are submitting ours
+		err := m.persistence.WriteTransaction(m.ctx, &apitypes.ManagedTX{
+			ID:          tReq.Headers.ID,
+			Created:     fftypes.Now(),
+			Status:      apitypes.TxStatusSucceeded,
+			RequestHash: tReq.RequestHash(),
+			TransactionHeaders: ffcapi.TransactionHeaders{
+				From: tReq.From,
+			},
+			Nonce: fftypes.NewFFBigInt(12345),
+		}, true)
+		assert.NoError(t, err)
+	}).Return(nil, fmt.Errorf("pop")).Once()
+	m.txHandler = &mth
+
+	var mtx apitypes.ManagedTX
+	res, err := resty.New().R().
+		SetBody(strings.NewReader(sampleSendTX)).
+		SetResult(&mtx).
+		Post(url)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, tReq.Headers.ID, mtx.ID)
+
+	mth.AssertExpectations(t)
+
+}
+
+func TestSendRawTransaction(t *testing.T) {
+
+	url, m, cancel := newTestManager(t)
+	defer cancel()
+
+	m.Start()
+
+	mth := txhandlermocks.RawTransactionHandler{}
+	mth.On("HandleNewRawTransaction", mock.Anything, mock.MatchedBy(func(req *apitypes.RawTransactionRequest) bool {
+		return req.From == "0xaaaaa" && req.Nonce.Int64() == 10 && req.RawTransaction == "0xf86c0a85"
+	})).Return(&apitypes.ManagedTX{ID: "ns1:raw1", PreSigned: true}, nil).Once()
+	m.txHandler = &mockCapableTXHandler{RawTransactionHandler: &mth}
+
+	var mtx apitypes.ManagedTX
+	res, err := resty.New().R().
+		SetBody(map[string]interface{}{
+			"headers":        map[string]interface{}{"id": "ns1:raw1", "type": "SendRawTransaction"},
+			"from":           "0xaaaaa",
+			"nonce":          "10",
+			"rawTransaction": "0xf86c0a85",
+		}).
+		SetResult(&mtx).
+		Post(url)
+	assert.NoError(t, err)
+	assert.Equal(t, 202, res.StatusCode())
+	assert.True(t, mtx.PreSigned)
+
+	res, err = resty.New().R().
+		SetBody(map[string]interface{}{
+			"headers": map[string]interface{}{"type": "SendRawTransaction"},
+			"from":    false,
+		}).
+		Post(url)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, res.StatusCode())
+
+	mth.AssertExpectations(t)
+
+}
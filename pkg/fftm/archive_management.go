@@ -0,0 +1,69 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"context"
+	"io"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/events"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+)
+
+func (m *manager) exportArchive(ctx context.Context) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		// Closing the writer with a nil error is a normal EOF for the reader
+		err := persistence.ExportArchive(ctx, m.persistence, w)
+		if err != nil {
+			log.L(ctx).Errorf("Export failed: %s", err)
+		}
+		_ = w.CloseWithError(err)
+	}()
+	return r
+}
+
+func (m *manager) importArchive(ctx context.Context, r io.Reader) (*apitypes.ArchiveImportResult, error) {
+	// Take a copy of the streams that are already running, before the import
+	m.mux.Lock()
+	running := make(map[fftypes.UUID]events.Stream, len(m.eventStreams))
+	for id, s := range m.eventStreams {
+		running[id] = s
+	}
+	m.mux.Unlock()
+
+	result, err := persistence.ImportArchive(ctx, m.persistence, r, events.ValidateEventStream)
+	if err != nil {
+		return nil, err
+	}
+	// Start any event streams added by the import, which starts their listeners
+	if err := m.restoreStreams(); err != nil {
+		return nil, err
+	}
+	// Listeners imported onto streams that were already running must be added to those streams
+	for _, l := range result.ImportedListeners {
+		if s, ok := running[*l.StreamID]; ok {
+			if _, err := s.AddOrUpdateListener(ctx, l.ID, l, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
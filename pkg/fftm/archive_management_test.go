@@ -0,0 +1,139 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-transaction-manager/mocks/eventsmocks"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/ffcapimocks"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExportArchiveFail(t *testing.T) {
+
+	_, m, done := newTestManagerMockPersistence(t)
+	defer done()
+
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("ListStreams", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	r := m.exportArchive(m.ctx)
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	assert.Regexp(t, "pop", err)
+	// The header is streamed before the failure
+	assert.Regexp(t, `"type":"header"`, string(b))
+
+}
+
+func TestImportArchiveRestoreFail(t *testing.T) {
+
+	_, m, done := newTestManagerMockPersistence(t)
+	defer done()
+
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("ListStreams", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	_, err := m.importArchive(m.ctx, strings.NewReader(`{"type":"header","version":1}`))
+	assert.Regexp(t, "pop", err)
+
+}
+
+func testListenerArchive(streamID, listenerID string) string {
+	return fmt.Sprintf(`{"type":"header","version":1}
+{"type":"listener","listener":{"id":"%s","stream":"%s"}}
+`, listenerID, streamID)
+}
+
+func TestImportArchiveListenerOnRunningStream(t *testing.T) {
+
+	_, m, done := newTestManager(t)
+	defer done()
+
+	streamID := apitypes.NewULID()
+	listenerID := apitypes.NewULID()
+	mes := &eventsmocks.Stream{}
+	mes.On("AddOrUpdateListener", mock.Anything, listenerID, mock.MatchedBy(func(l *apitypes.Listener) bool {
+		return l.StreamID.Equals(streamID)
+	}), false).Return(nil, nil)
+	m.eventStreams[*streamID] = mes
+
+	result, err := m.importArchive(m.ctx, strings.NewReader(testListenerArchive(streamID.String(), listenerID.String())))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Listeners.Imported)
+
+	// An existing listener is not added again
+	result, err = m.importArchive(m.ctx, strings.NewReader(testListenerArchive(streamID.String(), listenerID.String())))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Listeners.Skipped)
+
+	mes.AssertNumberOfCalls(t, "AddOrUpdateListener", 1)
+
+}
+
+func TestImportArchiveListenerOnRunningStreamFail(t *testing.T) {
+
+	_, m, done := newTestManager(t)
+	defer done()
+
+	streamID := apitypes.NewULID()
+	mes := &eventsmocks.Stream{}
+	mes.On("AddOrUpdateListener", mock.Anything, mock.Anything, mock.Anything, false).Return(nil, fmt.Errorf("pop"))
+	m.eventStreams[*streamID] = mes
+
+	_, err := m.importArchive(m.ctx, strings.NewReader(testListenerArchive(streamID.String(), apitypes.NewULID().String())))
+	assert.Regexp(t, "pop", err)
+
+}
+
+func TestImportArchiveStreamDefaults(t *testing.T) {
+
+	_, m, done := newTestManager(t)
+	defer done()
+
+	mfc := m.connector.(*ffcapimocks.API)
+	mfc.On("EventStreamStart", mock.Anything, mock.Anything).Return(&ffcapi.EventStreamStartResponse{}, ffcapi.ErrorReason(""), nil)
+	mfc.On("EventStreamStopped", mock.Anything, mock.Anything).Return(&ffcapi.EventStreamStoppedResponse{}, ffcapi.ErrorReason(""), nil).Maybe()
+
+	// A stream with only an ID and a name gets the same defaults as a new stream, so it can be started
+	streamID := apitypes.NewULID()
+	result, err := m.importArchive(m.ctx, strings.NewReader(`{"type":"header","version":1}
+{"type":"eventstream","eventstream":{"id":"`+streamID.String()+`","name":"imported"}}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.EventStreams.Imported)
+	es, err := m.persistence.GetStream(m.ctx, streamID)
+	assert.NoError(t, err)
+	assert.False(t, *es.Suspended)
+	assert.Equal(t, apitypes.EventStreamTypeWebSocket, *es.Type)
+	assert.NotNil(t, m.eventStreams[*streamID])
+
+	// A stream without a name is rejected, identifying the record
+	_, err = m.importArchive(m.ctx, strings.NewReader(`{"type":"header","version":1}
+{"type":"eventstream","eventstream":{"id":"`+apitypes.NewULID().String()+`"}}
+`))
+	assert.Regexp(t, "FF21085.*2.*FF21028", err)
+
+}
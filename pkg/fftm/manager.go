@@ -24,7 +24,6 @@ import (
 	"github.com/hyperledger/firefly-common/pkg/config"
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-common/pkg/httpserver"
-	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-common/pkg/log"
 	"github.com/hyperledger/firefly-transaction-manager/internal/blocklistener"
 	"github.com/hyperledger/firefly-transaction-manager/internal/confirmations"
@@ -32,9 +31,9 @@ import (
 	"github.com/hyperledger/firefly-transaction-manager/internal/metrics"
 	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
 	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
-	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
 	"github.com/hyperledger/firefly-transaction-manager/internal/ws"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	persistenceRegistry "github.com/hyperledger/firefly-transaction-manager/pkg/persistence/registry"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/txhandler"
 	txRegistry "github.com/hyperledger/firefly-transaction-manager/pkg/txhandler/registry"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/txhistory"
@@ -71,6 +70,8 @@ type manager struct {
 	metricsManager    metrics.Metrics
 	debugServer       *http.Server
 	debugServerDone   chan struct{}
+	txRetention       *txRetentionPolicy
+	txPurgerDone      chan struct{}
 }
 
 func InitConfig() {
@@ -99,11 +100,10 @@ func newManager(ctx context.Context, connector ffcapi.API) *manager {
 		eventStreams:      make(map[fftypes.UUID]events.Stream),
 		streamsByName:     make(map[string]*fftypes.UUID),
 		metricsManager:    metrics.NewMetricsManager(ctx),
-		txhistory:         txhistory.NewTxHistoryManager(ctx),
+		txRetention:       newTXRetentionPolicy(),
 	}
 	m.toolkit = &txhandler.Toolkit{
 		Connector:      m.connector,
-		TXHistory:      m.txhistory,
 		MetricsManager: m.metricsManager,
 	}
 	m.ctx, m.cancelCtx = context.WithCancel(ctx)
@@ -111,6 +111,9 @@ func newManager(ctx context.Context, connector ffcapi.API) *manager {
 }
 
 func (m *manager) initServices(ctx context.Context) (err error) {
+	// History is stored separately from the transactions, so requires the persistence to be initialized first
+	m.txhistory = txhistory.NewTxHistoryManager(ctx, m.persistence)
+	m.toolkit.TXHistory = m.txhistory
 	m.confirmations = confirmations.NewBlockConfirmationManager(ctx, m.connector, "receipts")
 	m.wsServer = ws.NewWebSocketServer(ctx)
 	m.apiServer, err = httpserver.NewHTTPServer(ctx, "api", m.router(m.metricsEnabled), m.apiServerDone, tmconfig.APIConfig, tmconfig.CorsConfig)
@@ -147,16 +150,17 @@ func (m *manager) initServices(ctx context.Context) (err error) {
 
 func (m *manager) initPersistence(ctx context.Context) (err error) {
 	pType := config.GetString(tmconfig.PersistenceType)
-	switch pType {
-	case "leveldb":
-		if m.persistence, err = persistence.NewLevelDBPersistence(ctx); err != nil {
-			return i18n.NewError(ctx, tmmsgs.MsgPersistenceInitFail, pType, err)
-		}
-		m.toolkit.TXPersistence = m.persistence
-		return nil
-	default:
-		return i18n.NewError(ctx, tmmsgs.MsgUnknownPersistence, pType)
+	p, builtin, err := persistence.NewBuiltinPersistence(ctx, pType)
+	if err == nil && !builtin {
+		// Resolve any additional persistence types registered via the persistence registry
+		p, err = persistenceRegistry.NewPersistence(ctx, tmconfig.PersistenceBaseConfig, pType)
+	}
+	if err != nil {
+		return err
 	}
+	m.persistence = p
+	m.toolkit.TXPersistence = m.persistence
+	return nil
 }
 
 func (m *manager) Start() error {
@@ -183,6 +187,10 @@ func (m *manager) Start() error {
 	if err != nil {
 		return err
 	}
+	if m.txRetention.enabled() {
+		m.txPurgerDone = make(chan struct{})
+		go m.txPurgerLoop()
+	}
 	m.started = true
 	return nil
 }
@@ -201,6 +209,9 @@ func (m *manager) Close() {
 		<-m.txHandlerDone
 		<-m.blockListenerDone
 		<-m.debugServerDone
+		if m.txPurgerDone != nil {
+			<-m.txPurgerDone
+		}
 
 		streams := []events.Stream{}
 		m.mux.Lock()
@@ -25,7 +25,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-resty/resty/v2"
 	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/dbsql"
 	"github.com/hyperledger/firefly-common/pkg/httpserver"
 	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
 	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
@@ -33,7 +35,9 @@ import (
 	"github.com/hyperledger/firefly-transaction-manager/mocks/ffcapimocks"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	persistenceRegistry "github.com/hyperledger/firefly-transaction-manager/pkg/persistence/registry"
 	txRegistry "github.com/hyperledger/firefly-transaction-manager/pkg/txhandler/registry"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/txhandler/simple"
 	"github.com/spf13/viper"
@@ -234,6 +238,69 @@ func TestNewManagerBadPersistenceConfig(t *testing.T) {
 
 }
 
+func TestNewManagerSQLitePersistence(t *testing.T) {
+
+	tmconfig.Reset()
+	config.Set(tmconfig.PersistenceType, "sqlite3")
+	tmconfig.PersistenceSQLiteConfig.Set(dbsql.SQLConfDatasourceURL, fmt.Sprintf("file:%s/fftm.db", t.TempDir()))
+
+	m := newManager(context.Background(), nil)
+	err := m.initPersistence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, m.persistence, m.toolkit.TXPersistence)
+	m.persistence.Close(context.Background())
+
+}
+
+func TestNewManagerInMemoryPersistence(t *testing.T) {
+
+	tmconfig.Reset()
+	config.Set(tmconfig.PersistenceType, "inmemory")
+
+	m := newManager(context.Background(), nil)
+	err := m.initPersistence(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, m.persistence, m.toolkit.TXPersistence)
+	m.persistence.Close(context.Background())
+
+}
+
+func TestNewManagerBadPostgresConfig(t *testing.T) {
+
+	tmconfig.Reset()
+	config.Set(tmconfig.PersistenceType, "postgres")
+
+	m := newManager(context.Background(), nil)
+	err := m.initPersistence(context.Background())
+	assert.Regexp(t, "FF21049.*FF00183", err)
+
+}
+
+type testPersistenceFactory struct{}
+
+func (tpf *testPersistenceFactory) Name() string { return "unittest" }
+
+func (tpf *testPersistenceFactory) InitConfig(conf config.Section) {}
+
+func (tpf *testPersistenceFactory) NewPersistence(ctx context.Context, conf config.Section) (persistenceRegistry.Persistence, error) {
+	return &persistencemocks.Persistence{}, nil
+}
+
+func TestNewManagerRegisteredPersistence(t *testing.T) {
+
+	tmconfig.Reset()
+	_, err := persistenceRegistry.RegisterPersistence(&testPersistenceFactory{})
+	assert.NoError(t, err)
+	config.Set(tmconfig.PersistenceType, "unittest")
+
+	m := newManager(context.Background(), nil)
+	err = m.initPersistence(context.Background())
+	assert.NoError(t, err)
+	assert.IsType(t, &persistencemocks.Persistence{}, m.persistence)
+	assert.Equal(t, m.persistence, m.toolkit.TXPersistence)
+
+}
+
 func TestNewManagerInvalidTransactionHandlerName(t *testing.T) {
 
 	tmconfig.Reset()
@@ -322,3 +389,45 @@ func TestStartBlockListenerFail(t *testing.T) {
 	assert.Regexp(t, "pop", err)
 
 }
+
+// mockCapableTXHandler is a transaction handler that implements the optional interfaces, with a separate mock for each
+type mockCapableTXHandler struct {
+	*txhandlermocks.TransactionHandler
+	*txhandlermocks.CancelByReplacementHandler
+	*txhandlermocks.ResubmitHandler
+	*txhandlermocks.SignerStatusHandler
+	*txhandlermocks.SignerNonceHandler
+	*txhandlermocks.TransactionBatchHandler
+	*txhandlermocks.RawTransactionHandler
+}
+
+func TestTXHandlerCapabilitiesNotSupported(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+	m.txHandler = &txhandlermocks.TransactionHandler{}
+
+	_, err = m.getSignerStatus(m.ctx, "0xaaaaa")
+	assert.Regexp(t, "FF21107.*SignerStatusHandler", err)
+	_, err = m.getSignerNonce(m.ctx, "0xaaaaa")
+	assert.Regexp(t, "FF21107.*SignerNonceHandler", err)
+	_, err = m.resetSignerNonce(m.ctx, "0xaaaaa")
+	assert.Regexp(t, "FF21107.*SignerNonceHandler", err)
+	_, err = m.reserveSignerNonces(m.ctx, "0xaaaaa", &apitypes.NonceReservationRequest{Count: 1})
+	assert.Regexp(t, "FF21107.*SignerNonceHandler", err)
+	_, err = m.requestTransactionCancellation(m.ctx, "tx1")
+	assert.Regexp(t, "FF21107.*CancelByReplacementHandler", err)
+	_, err = m.requestTransactionResubmit(m.ctx, "tx1", &apitypes.ResubmitTransactionRequest{})
+	assert.Regexp(t, "FF21107.*ResubmitHandler", err)
+	_, err = m.sendTransactionBatch(m.ctx, &apitypes.TransactionBatchRequest{})
+	assert.Regexp(t, "FF21107.*TransactionBatchHandler", err)
+	_, _, err = m.sendRawTransaction(m.ctx, &apitypes.RawTransactionRequest{})
+	assert.Regexp(t, "FF21107.*RawTransactionHandler", err)
+
+	res, err := resty.New().R().
+		Get(url + "/signers/0xaaaaa")
+	assert.NoError(t, err)
+	assert.Equal(t, 501, res.StatusCode())
+}
@@ -47,6 +47,10 @@ var postRootCommand = func(m *manager) *ffapi.Route {
 			if err == nil {
 				schemas = append(schemas, deployRequest)
 			}
+			rawRequest, err := schemaGen(&apitypes.RawTransactionRequest{})
+			if err == nil {
+				schemas = append(schemas, rawRequest)
+			}
 			queryRequest, err := schemaGen(&apitypes.QueryRequest{})
 			if err == nil {
 				schemas = append(schemas, queryRequest)
@@ -70,7 +74,7 @@ var postRootCommand = func(m *manager) *ffapi.Route {
 				},
 			}, nil
 		},
-		JSONOutputCodes: []int{http.StatusAccepted},
+		JSONOutputCodes: []int{http.StatusAccepted, http.StatusOK, http.StatusConflict},
 		JSONHandler: func(r *ffapi.APIRequest) (output interface{}, err error) {
 			baseReq := r.Input.(*apitypes.BaseRequest)
 			switch baseReq.Headers.Type {
@@ -79,13 +83,26 @@ var postRootCommand = func(m *manager) *ffapi.Route {
 				if err = baseReq.UnmarshalTo(&tReq); err != nil {
 					return nil, i18n.NewError(r.Req.Context(), tmmsgs.MsgInvalidRequestErr, baseReq.Headers.Type, err)
 				}
-				return m.txHandler.HandleNewTransaction(r.Req.Context(), &tReq)
+				r.SuccessStatus, output, err = m.submitIdempotent(r.Req.Context(), &tReq.Headers, tReq.RequestHash(), func() (*apitypes.ManagedTX, error) {
+					return m.txHandler.HandleNewTransaction(r.Req.Context(), &tReq)
+				})
+				return output, err
+			case apitypes.RequestTypeSendRawTransaction:
+				var tReq apitypes.RawTransactionRequest
+				if err = baseReq.UnmarshalTo(&tReq); err != nil {
+					return nil, i18n.NewError(r.Req.Context(), tmmsgs.MsgInvalidRequestErr, baseReq.Headers.Type, err)
+				}
+				r.SuccessStatus, output, err = m.sendRawTransaction(r.Req.Context(), &tReq)
+				return output, err
 			case apitypes.RequestTypeDeploy:
 				var tReq apitypes.ContractDeployRequest
 				if err = baseReq.UnmarshalTo(&tReq); err != nil {
 					return nil, i18n.NewError(r.Req.Context(), tmmsgs.MsgInvalidRequestErr, baseReq.Headers.Type, err)
 				}
-				return m.txHandler.HandleNewContractDeployment(r.Req.Context(), &tReq)
+				r.SuccessStatus, output, err = m.submitIdempotent(r.Req.Context(), &tReq.Headers, tReq.RequestHash(), func() (*apitypes.ManagedTX, error) {
+					return m.txHandler.HandleNewContractDeployment(r.Req.Context(), &tReq)
+				})
+				return output, err
 			case apitypes.RequestTypeQuery:
 				var tReq apitypes.QueryRequest
 				if err = baseReq.UnmarshalTo(&tReq); err != nil {
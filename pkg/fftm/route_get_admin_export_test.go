@@ -0,0 +1,64 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAdminExport(t *testing.T) {
+
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	truthy := true
+	var es apitypes.EventStream
+	res, err := resty.New().R().SetBody(&apitypes.EventStream{Name: strPtr("stream1"), Suspended: &truthy}).SetResult(&es).Post(url + "/eventstreams")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+
+	res, err = resty.New().R().
+		SetDoNotParseResponse(true).
+		Get(url + "/admin/export")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	defer res.RawBody().Close()
+
+	var records []*apitypes.ArchiveRecord
+	scanner := bufio.NewScanner(res.RawBody())
+	for scanner.Scan() {
+		var record apitypes.ArchiveRecord
+		err := json.Unmarshal(bytes.TrimSpace(scanner.Bytes()), &record)
+		assert.NoError(t, err)
+		records = append(records, &record)
+	}
+	assert.Len(t, records, 2)
+	assert.Equal(t, apitypes.ArchiveRecordHeader, records[0].Type)
+	assert.Equal(t, apitypes.ArchiveRecordEventStream, records[1].Type)
+	assert.Equal(t, es.ID, records[1].EventStream.ID)
+
+}
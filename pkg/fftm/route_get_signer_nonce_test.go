@@ -0,0 +1,58 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetSignerNonce(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+	mth := txhandlermocks.SignerNonceHandler{}
+	mth.On("GetSignerNonceStatus", mock.Anything, "0x0aaaaa").Return(&apitypes.SignerNonceStatus{
+		Signer:             "0x0aaaaa",
+		NextNonce:          fftypes.NewFFBigInt(11),
+		PersistedNextNonce: fftypes.NewFFBigInt(11),
+		NodeNextNonce:      fftypes.NewFFBigInt(10),
+		Locked:             true,
+		LockedBy:           "ns1:tx1",
+	}, nil).Once()
+	m.txHandler = &mockCapableTXHandler{SignerNonceHandler: &mth}
+
+	var status apitypes.SignerNonceStatus
+	res, err := resty.New().R().
+		SetResult(&status).
+		Get(url + "/signers/0x0aaaaa/nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, int64(11), status.NextNonce.Int64())
+	assert.Equal(t, int64(10), status.NodeNextNonce.Int64())
+	assert.Equal(t, "ns1:tx1", status.LockedBy)
+
+	mth.AssertExpectations(t)
+}
@@ -0,0 +1,77 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/ffcapimocks"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetSignerStatusOK(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+	mth := txhandlermocks.SignerStatusHandler{}
+	mth.On("GetSignerStatus", mock.Anything, "0x0aaaaa").Return(&apitypes.SignerStatus{
+		Signer:           "0x0aaaaa",
+		NextNonce:        fftypes.NewFFBigInt(10000),
+		LastPendingNonce: fftypes.NewFFBigInt(10003),
+		NonceGaps: []*apitypes.NonceGap{
+			{First: fftypes.NewFFBigInt(10000), Last: fftypes.NewFFBigInt(10001)},
+		},
+	}, nil).Once()
+	m.txHandler = &mockCapableTXHandler{SignerStatusHandler: &mth}
+
+	var status apitypes.SignerStatus
+	res, err := resty.New().R().
+		SetResult(&status).
+		Get(url + "/signers/0x0aaaaa")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, int64(10000), status.NextNonce.Int64())
+	assert.Len(t, status.NonceGaps, 1)
+	assert.Equal(t, int64(10001), status.NonceGaps[0].Last.Int64())
+
+	mth.AssertExpectations(t)
+}
+
+func TestGetSignerStatusFail(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	mfc := m.connector.(*ffcapimocks.API)
+	mfc.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop"))
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	res, err := resty.New().R().
+		Get(url + "/signers/0x0aaaaa")
+	assert.NoError(t, err)
+	assert.Equal(t, 500, res.StatusCode())
+}
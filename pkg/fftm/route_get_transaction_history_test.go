@@ -0,0 +1,77 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTransactionHistory(t *testing.T) {
+
+	url, m, done := newTestManager(t)
+	defer done()
+	err := m.Start()
+	assert.NoError(t, err)
+
+	txIn := newTestTxn(t, m, "0xaaaaa", 10001, apitypes.TxStatusPending)
+	m.txhistory.SetSubStatus(m.ctx, txIn, apitypes.TxSubStatusReceived)
+	m.txhistory.SetSubStatus(m.ctx, txIn, apitypes.TxSubStatusTracking)
+
+	var historyOut []*apitypes.TxHistoryRecord
+	res, err := resty.New().R().
+		SetResult(&historyOut).
+		Get(fmt.Sprintf("%s/transactions/%s/history", url, txIn.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Len(t, historyOut, 2)
+	assert.Equal(t, apitypes.TxSubStatusTracking, historyOut[0].Status)
+	assert.Equal(t, txIn.ID, historyOut[0].TransactionID)
+
+	res, err = resty.New().R().
+		SetResult(&historyOut).
+		Get(fmt.Sprintf("%s/transactions/%s/history?direction=asc&limit=1", url, txIn.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Len(t, historyOut, 1)
+	assert.Equal(t, apitypes.TxSubStatusReceived, historyOut[0].Status)
+
+}
+
+func TestGetTransactionHistoryError(t *testing.T) {
+
+	url, m, done := newTestManager(t)
+	defer done()
+	err := m.Start()
+	assert.NoError(t, err)
+
+	res, err := resty.New().R().
+		Get(fmt.Sprintf("%s/transactions/%s/history", url, "does not exist"))
+	assert.NoError(t, err)
+	assert.Equal(t, 404, res.StatusCode())
+
+	txIn := newTestTxn(t, m, "0xaaaaa", 10001, apitypes.TxStatusPending)
+	res, err = resty.New().R().
+		Get(fmt.Sprintf("%s/transactions/%s/history?direction=sideways", url, txIn.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 400, res.StatusCode())
+
+}
@@ -36,14 +36,26 @@ var getTransactions = func(m *manager) *ffapi.Route {
 			{Name: "after", Description: tmmsgs.APIParamAfter},
 			{Name: "signer", Description: tmmsgs.APIParamTXSigner},
 			{Name: "pending", Description: tmmsgs.APIParamTXPending, IsBool: true},
+			{Name: "status", Description: tmmsgs.APIParamTXStatus},
+			{Name: "subStatus", Description: tmmsgs.APIParamTXSubStatus},
+			{Name: "to", Description: tmmsgs.APIParamTXTo},
+			{Name: "hash", Description: tmmsgs.APIParamTXHash},
+			{Name: "createdAfter", Description: tmmsgs.APIParamTXCreatedAfter},
+			{Name: "createdBefore", Description: tmmsgs.APIParamTXCreatedBefore},
+			{Name: "updatedAfter", Description: tmmsgs.APIParamTXUpdatedAfter},
+			{Name: "updatedBefore", Description: tmmsgs.APIParamTXUpdatedBefore},
 			{Name: "direction", Description: tmmsgs.APIParamSortDirection},
 		},
-		Description:     tmmsgs.APIEndpointGetSubscriptions,
+		Description:     tmmsgs.APIEndpointGetTransactions,
 		JSONInputValue:  nil,
 		JSONOutputValue: func() interface{} { return []*apitypes.ManagedTX{} },
 		JSONOutputCodes: []int{http.StatusOK},
 		JSONHandler: func(r *ffapi.APIRequest) (output interface{}, err error) {
-			return m.getTransactions(r.Req.Context(), r.QP["after"], r.QP["limit"], r.QP["signer"], strings.EqualFold(r.QP["pending"], "true"), r.QP["direction"])
+			filters, err := m.parseTransactionFilters(r.Req.Context(), r.QP)
+			if err != nil {
+				return nil, err
+			}
+			return m.getTransactions(r.Req.Context(), r.QP["after"], r.QP["limit"], filters, strings.EqualFold(r.QP["pending"], "true"), r.QP["direction"])
 		},
 	}
 }
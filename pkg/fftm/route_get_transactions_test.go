@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
@@ -102,6 +103,99 @@ func TestGetTransactions(t *testing.T) {
 	assert.Len(t, transactions, 1)
 	assert.Equal(t, s2t1.ID, transactions[0].ID)
 
+	// Combine the signer and pending filters
+	res, err = resty.New().R().
+		SetResult(&transactions).
+		Get(url + "/transactions?pending&signer=0xaaaaa")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, s1t3.ID, transactions[0].ID)
+
+	// Filter on status and a create time range
+	res, err = resty.New().R().
+		SetResult(&transactions).
+		SetQueryParam("status", "Pending").
+		SetQueryParam("createdAfter", s1t1.Created.String()).
+		SetQueryParam("createdBefore", s1t3.Created.String()).
+		Get(url + "/transactions")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, s2t1.ID, transactions[0].ID)
+
+	// Filter on the sub-status with pagination
+	res, err = resty.New().R().
+		SetResult(&transactions).
+		Get(url + "/transactions?subStatus=Received&limit=2&after=" + s1t2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, s2t1.ID, transactions[0].ID)
+	assert.Equal(t, s1t1.ID, transactions[1].ID)
+
+}
+
+func TestGetTransactionsSignerWithoutNonce(t *testing.T) {
+
+	url, m, done := newTestManager(t)
+	defer done()
+	err := m.Start()
+	assert.NoError(t, err)
+
+	// Scheduled transactions are not assigned a nonce until they are due
+	notBefore := fftypes.FFTime(time.Now().Add(1 * time.Hour))
+	newScheduledTxn := func(signer string) *apitypes.ManagedTX {
+		tx := genTestTxn(signer, 0, apitypes.TxStatusPending)
+		tx.Nonce, tx.NotBefore = nil, &notBefore
+		err := m.persistence.WriteTransaction(context.Background(), tx, true)
+		assert.NoError(t, err)
+		return tx
+	}
+	t1 := newTestTxn(t, m, "0xaaaaa", 10001, apitypes.TxStatusSucceeded)
+	s1 := newScheduledTxn("0xaaaaa")
+	t2 := newTestTxn(t, m, "0xaaaaa", 10002, apitypes.TxStatusPending)
+	s2 := newScheduledTxn("0xaaaaa")
+	newScheduledTxn("0xbbbbb")
+
+	getIDs := func(query string) []string {
+		var transactions []*apitypes.ManagedTX
+		res, err := resty.New().R().
+			SetResult(&transactions).
+			Get(url + "/transactions?signer=0xaaaaa" + query)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, res.StatusCode())
+		ids := make([]string, len(transactions))
+		for i, tx := range transactions {
+			ids[i] = tx.ID
+		}
+		return ids
+	}
+
+	// Those without a nonce follow the others in either direction
+	assert.Equal(t, []string{t2.ID, t1.ID, s2.ID, s1.ID}, getIDs(""))
+	assert.Equal(t, []string{t1.ID, t2.ID, s1.ID, s2.ID}, getIDs("&direction=asc"))
+
+	// Pagination across the two
+	assert.Equal(t, []string{t2.ID, t1.ID}, getIDs("&limit=2"))
+	assert.Equal(t, []string{t1.ID, s2.ID}, getIDs("&limit=2&after="+t2.ID))
+	assert.Equal(t, []string{s1.ID}, getIDs("&limit=2&after="+s2.ID))
+
+}
+
+func TestGetTransactionsBadFilter(t *testing.T) {
+
+	url, m, done := newTestManager(t)
+	defer done()
+	err := m.Start()
+	assert.NoError(t, err)
+
+	res, err := resty.New().R().
+		Get(url + "/transactions?status=wrong")
+	assert.NoError(t, err)
+	assert.Equal(t, 400, res.StatusCode())
+	assert.Regexp(t, "FF21087", res.String())
+
 }
 
 func TestGetTransactionsError(t *testing.T) {
@@ -0,0 +1,110 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func testArchive(esID string) string {
+	return fmt.Sprintf(`{"type":"header","version":1}
+{"type":"eventstream","eventstream":{"id":"%s","name":"stream1","suspended":true}}
+`, esID)
+}
+
+func TestPostAdminImport(t *testing.T) {
+
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	esID := apitypes.NewULID()
+	var result apitypes.ArchiveImportResult
+	res, err := resty.New().R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(testArchive(esID.String())).
+		SetResult(&result).
+		Post(url + "/admin/import")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, 1, result.EventStreams.Imported)
+
+	es, err := m.getStream(m.ctx, esID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, apitypes.EventStreamStatusStopped, es.Status)
+
+	// Importing again skips the existing stream
+	res, err = resty.New().R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(testArchive(esID.String())).
+		SetResult(&result).
+		Post(url + "/admin/import")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, 0, result.EventStreams.Imported)
+	assert.Equal(t, 1, result.EventStreams.Skipped)
+
+}
+
+func TestPostAdminImportMultipart(t *testing.T) {
+
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	var result apitypes.ArchiveImportResult
+	res, err := resty.New().R().
+		SetFileReader("file", "archive.ndjson", bytes.NewReader([]byte(testArchive(apitypes.NewULID().String())))).
+		SetResult(&result).
+		Post(url + "/admin/import")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, 1, result.EventStreams.Imported)
+
+}
+
+func TestPostAdminImportBadArchive(t *testing.T) {
+
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	var errRes struct {
+		Error string `json:"error"`
+	}
+	res, err := resty.New().R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(`{"type":"eventstream"}`).
+		SetError(&errRes).
+		Post(url + "/admin/import")
+	assert.NoError(t, err)
+	assert.Equal(t, 400, res.StatusCode())
+	assert.Regexp(t, "FF21083", errRes.Error)
+
+}
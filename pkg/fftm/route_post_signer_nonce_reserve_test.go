@@ -0,0 +1,70 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostSignerNonceReserve(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+	mth := txhandlermocks.SignerNonceHandler{}
+	mth.On("HandleReserveSignerNonces", mock.Anything, "0x0aaaaa", &apitypes.NonceReservationRequest{Count: 5}).Return(&apitypes.NonceReservation{
+		Signer: "0x0aaaaa",
+		First:  fftypes.NewFFBigInt(20),
+		Last:   fftypes.NewFFBigInt(24),
+	}, nil).Once()
+	m.txHandler = &mockCapableTXHandler{SignerNonceHandler: &mth}
+
+	var reservation apitypes.NonceReservation
+	res, err := resty.New().R().
+		SetBody(&apitypes.NonceReservationRequest{Count: 5}).
+		SetResult(&reservation).
+		Post(url + "/signers/0x0aaaaa/nonce/reserve")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, int64(20), reservation.First.Int64())
+	assert.Equal(t, int64(24), reservation.Last.Int64())
+
+	mth.AssertExpectations(t)
+}
+
+func TestPostSignerNonceReserveBadCount(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	res, err := resty.New().R().
+		SetBody(&apitypes.NonceReservationRequest{Count: 0}).
+		Post(url + "/signers/0x0aaaaa/nonce/reserve")
+	assert.NoError(t, err)
+	assert.Equal(t, 400, res.StatusCode())
+	assert.Regexp(t, "FF21097", res.String())
+}
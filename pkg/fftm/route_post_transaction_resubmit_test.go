@@ -0,0 +1,73 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostTransactionResubmit(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+	mth := txhandlermocks.ResubmitHandler{}
+	mth.On("HandleResubmitTransaction", mock.Anything, "1234", mock.MatchedBy(func(req *apitypes.ResubmitTransactionRequest) bool {
+		return req.BumpPercentage != nil && *req.BumpPercentage == 20 && req.GasPrice == nil
+	})).Return(&apitypes.ManagedTX{
+		ID:              "1234",
+		TransactionHash: "0x2222",
+		GasPrice:        fftypes.JSONAnyPtr(`"1200"`),
+	}, nil).Once()
+	m.txHandler = &mockCapableTXHandler{ResubmitHandler: &mth}
+
+	var txOut *apitypes.ManagedTX
+	res, err := resty.New().R().
+		SetBody(map[string]interface{}{"bumpPercentage": 20}).
+		SetResult(&txOut).
+		Post(fmt.Sprintf("%s/transactions/%s/resubmit", url, "1234"))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, res.StatusCode())
+	assert.Equal(t, "0x2222", txOut.TransactionHash)
+
+	mth.AssertExpectations(t)
+}
+
+func TestPostTransactionResubmitCompleted(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+	tx := newTestTxn(t, m, "0x0aaaaa", 10001, apitypes.TxStatusSucceeded)
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	res, err := resty.New().R().
+		SetBody(map[string]interface{}{"gasPrice": "2000"}).
+		Post(fmt.Sprintf("%s/transactions/%s/resubmit", url, tx.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 409, res.StatusCode())
+	assert.Regexp(t, "FF21094", res.String())
+}
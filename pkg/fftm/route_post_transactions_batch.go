@@ -0,0 +1,68 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/hyperledger/firefly-common/pkg/ffapi"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+)
+
+var postTransactionsBatch = func(m *manager) *ffapi.Route {
+	return &ffapi.Route{
+		Name:           "postTransactionsBatch",
+		Path:           "/transactions/batch",
+		Method:         http.MethodPost,
+		PathParams:     nil,
+		QueryParams:    nil,
+		Description:    tmmsgs.APIEndpointPostTransactionsBatch,
+		JSONInputValue: func() interface{} { return &apitypes.TransactionBatchRequest{} },
+		JSONInputSchema: func(_ context.Context, schemaGen ffapi.SchemaGenerator) (*openapi3.SchemaRef, error) {
+			schemas := []*openapi3.SchemaRef{}
+			txRequest, err := schemaGen(&apitypes.TransactionRequest{})
+			if err == nil {
+				schemas = append(schemas, txRequest)
+			}
+			deployRequest, err := schemaGen(&apitypes.ContractDeployRequest{})
+			if err == nil {
+				schemas = append(schemas, deployRequest)
+			}
+			return &openapi3.SchemaRef{
+				Value: &openapi3.Schema{
+					Type: "object",
+					Properties: openapi3.Schemas{
+						"requests": &openapi3.SchemaRef{
+							Value: &openapi3.Schema{
+								Type:  "array",
+								Items: &openapi3.SchemaRef{Value: &openapi3.Schema{AnyOf: schemas}},
+							},
+						},
+					},
+				},
+			}, err
+		},
+		JSONOutputValue: func() interface{} { return &apitypes.TransactionBatchResponse{} },
+		JSONOutputCodes: []int{http.StatusAccepted},
+		JSONHandler: func(r *ffapi.APIRequest) (output interface{}, err error) {
+			return m.sendTransactionBatch(r.Req.Context(), r.Input.(*apitypes.TransactionBatchRequest))
+		},
+	}
+}
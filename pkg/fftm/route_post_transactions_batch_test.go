@@ -0,0 +1,123 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostTransactionsBatch(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+	mth := txhandlermocks.TransactionBatchHandler{}
+	mth.On("HandleNewTransactionBatch", mock.Anything, mock.MatchedBy(func(items []*apitypes.TransactionBatchItem) bool {
+		return len(items) == 2 &&
+			items[0].Transaction.Headers.ID == "tx1" && items[0].Transaction.From == "0xaaaaa" &&
+			items[1].Deploy.Headers.ID == "deploy1" && items[1].Deploy.From == "0xbbbbb"
+	})).Return([]*apitypes.TransactionBatchResult{
+		{Transaction: &apitypes.ManagedTX{ID: "tx1"}},
+		{Error: "pop"},
+	}, nil).Once()
+	m.txHandler = &mockCapableTXHandler{TransactionBatchHandler: &mth}
+
+	var batchOut *apitypes.TransactionBatchResponse
+	res, err := resty.New().R().
+		SetBody(map[string]interface{}{
+			"requests": []interface{}{
+				map[string]interface{}{
+					"headers": map[string]interface{}{"id": "query1", "type": "Query"},
+				},
+				map[string]interface{}{
+					"headers": map[string]interface{}{"id": "tx1", "type": "SendTransaction"},
+					"from":    "0xaaaaa",
+				},
+				map[string]interface{}{
+					"headers": map[string]interface{}{"id": "tx2", "type": "SendTransaction"},
+					"from":    false,
+				},
+				nil,
+				map[string]interface{}{
+					"headers": map[string]interface{}{"id": "deploy1", "type": "DeployContract"},
+					"from":    "0xbbbbb",
+				},
+			},
+		}).
+		SetResult(&batchOut).
+		Post(fmt.Sprintf("%s/transactions/batch", url))
+	assert.NoError(t, err)
+	assert.Equal(t, 202, res.StatusCode())
+	assert.Len(t, batchOut.Results, 5)
+	assert.Regexp(t, "FF21023", batchOut.Results[0].Error)
+	assert.Equal(t, "tx1", batchOut.Results[1].Transaction.ID)
+	assert.Regexp(t, "FF21022", batchOut.Results[2].Error)
+	assert.Regexp(t, "FF21023", batchOut.Results[3].Error)
+	assert.Equal(t, "pop", batchOut.Results[4].Error)
+
+	mth.AssertExpectations(t)
+}
+
+func TestPostTransactionsBatchEmpty(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	var batchOut *apitypes.TransactionBatchResponse
+	res, err := resty.New().R().
+		SetBody(map[string]interface{}{"requests": []interface{}{}}).
+		SetResult(&batchOut).
+		Post(fmt.Sprintf("%s/transactions/batch", url))
+	assert.NoError(t, err)
+	assert.Equal(t, 202, res.StatusCode())
+	assert.Empty(t, batchOut.Results)
+}
+
+func TestPostTransactionsBatchFail(t *testing.T) {
+	url, m, done := newTestManager(t)
+	defer done()
+
+	err := m.Start()
+	assert.NoError(t, err)
+	mth := txhandlermocks.TransactionBatchHandler{}
+	mth.On("HandleNewTransactionBatch", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop")).Once()
+	m.txHandler = &mockCapableTXHandler{TransactionBatchHandler: &mth}
+
+	res, err := resty.New().R().
+		SetBody(map[string]interface{}{
+			"requests": []interface{}{
+				map[string]interface{}{
+					"headers": map[string]interface{}{"id": "tx1", "type": "SendTransaction"},
+				},
+			},
+		}).
+		Post(fmt.Sprintf("%s/transactions/batch", url))
+	assert.NoError(t, err)
+	assert.Equal(t, 500, res.StatusCode())
+
+	mth.AssertExpectations(t)
+}
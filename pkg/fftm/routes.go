@@ -34,6 +34,7 @@ func (m *manager) routes() []*ffapi.Route {
 		getSubscriptions(m),
 		getReadyStatus(m),
 		getTransaction(m),
+		getTransactionHistory(m),
 		getTransactions(m),
 		patchEventStream(m),
 		patchEventStreamListener(m),
@@ -46,7 +47,16 @@ func (m *manager) routes() []*ffapi.Route {
 		postRootCommand(m),
 		postSubscriptionReset(m),
 		postSubscriptions(m),
+		postTransactionCancel(m),
+		postTransactionResubmit(m),
+		postTransactionsBatch(m),
 		getAddressBalance(m),
+		getSignerNonce(m),
+		getSignerStatus(m),
+		postSignerNonceReserve(m),
+		postSignerNonceReset(m),
 		getGasPrice(m),
+		getAdminExport(m),
+		postAdminImport(m),
 	}
 }
@@ -52,7 +52,10 @@ func (m *manager) restoreStreams() error {
 				return err
 			}
 			// check to see if it's already started
-			if _, ok := m.eventStreams[*def.ID]; !ok {
+			m.mux.Lock()
+			_, ok := m.eventStreams[*def.ID]
+			m.mux.Unlock()
+			if !ok {
 				closeoutName, err := m.reserveStreamName(m.ctx, *def.Name, def.ID)
 				var s events.Stream
 				if err == nil {
@@ -87,6 +87,7 @@ func (eh *ManagedTransactionEventHandler) sendWSReply(mtx *apitypes.ManagedTX) {
 		},
 		Status:          mtx.Status,
 		TransactionHash: mtx.TransactionHash,
+		ErrorMessage:    mtx.ErrorMessage,
 	}
 
 	if mtx.Receipt != nil && mtx.Receipt.ContractLocation != nil {
@@ -104,6 +104,37 @@ func TestHandleTransactionProcessFailEvent(t *testing.T) {
 	mws.AssertExpectations(t)
 }
 
+func TestHandleTransactionProcessFailEventWithErrorMessage(t *testing.T) {
+	testTx := &apitypes.ManagedTX{
+		ID:         fmt.Sprintf("ns1:%s", fftypes.NewUUID()),
+		Created:    fftypes.Now(),
+		SequenceID: apitypes.NewULID().String(),
+		Nonce:      fftypes.NewFFBigInt(1),
+		Status:     apitypes.TxStatusFailed,
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0x0000",
+		},
+		ErrorMessage: "FF21091: Transaction was not mined before its deadline",
+	}
+	eh := newTestManagedTransactionEventHandler()
+	mws := &wsmocks.WebSocketServer{}
+	mws.On("SendReply", mock.MatchedBy(func(r *apitypes.TransactionUpdateReply) bool {
+		return r.Headers.RequestID == testTx.ID &&
+			r.Headers.Type == apitypes.TransactionUpdateFailure &&
+			r.Status == testTx.Status &&
+			r.ErrorMessage == testTx.ErrorMessage &&
+			r.ProtocolID == ""
+	})).Return(nil).Once()
+	eh.WsServer = mws
+
+	eh.HandleEvent(context.Background(), apitypes.ManagedTransactionEvent{
+		Type: apitypes.ManagedTXProcessFailed,
+		Tx:   testTx,
+	})
+
+	mws.AssertExpectations(t)
+}
+
 func TestHandleTransactionHashUpdateEventAddHash(t *testing.T) {
 	eh := newTestManagedTransactionEventHandler()
 	mcm := &confirmationsmocks.Manager{}
@@ -23,9 +23,11 @@ import (
 
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
 	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
 	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/txhandler"
 )
 
 func (m *manager) getTransactionByID(ctx context.Context, txID string) (transaction *apitypes.ManagedTX, err error) {
@@ -39,19 +41,72 @@ func (m *manager) getTransactionByID(ctx context.Context, txID string) (transact
 	return tx, nil
 }
 
-func (m *manager) getTransactions(ctx context.Context, afterStr, limitStr, signer string, pending bool, dirString string) (transactions []*apitypes.ManagedTX, err error) {
-	limit, err := m.parseLimit(ctx, limitStr)
+func parseTimeFilter(ctx context.Context, name, value string) (*fftypes.FFTime, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := fftypes.ParseTimeString(value)
 	if err != nil {
-		return nil, err
+		return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidTimeFilter, name, err)
+	}
+	return t, nil
+}
+
+func (m *manager) parseTransactionFilters(ctx context.Context, qp map[string]string) (filters *persistence.TransactionFilters, err error) {
+	filters = &persistence.TransactionFilters{
+		Signer:          qp["signer"],
+		SubStatus:       apitypes.TxSubStatus(qp["subStatus"]),
+		To:              qp["to"],
+		TransactionHash: qp["hash"],
+	}
+	if status := qp["status"]; status != "" {
+		for _, s := range []apitypes.TxStatus{apitypes.TxStatusPending, apitypes.TxStatusSucceeded, apitypes.TxStatusFailed} {
+			if strings.EqualFold(status, string(s)) {
+				filters.Status = s
+			}
+		}
+		if filters.Status == "" {
+			return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidTXStatus, status)
+		}
+	}
+	for name, target := range map[string]**fftypes.FFTime{
+		"createdAfter":  &filters.CreatedAfter,
+		"createdBefore": &filters.CreatedBefore,
+		"updatedAfter":  &filters.UpdatedAfter,
+		"updatedBefore": &filters.UpdatedBefore,
+	} {
+		if *target, err = parseTimeFilter(ctx, name, qp[name]); err != nil {
+			return nil, err
+		}
 	}
-	var dir persistence.SortDirection
+	return filters, nil
+}
+
+func parseSortDirection(ctx context.Context, dirString string) (persistence.SortDirection, error) {
 	switch strings.ToLower(dirString) {
 	case "", "desc", "descending":
-		dir = persistence.SortDirectionDescending // descending is default
+		return persistence.SortDirectionDescending, nil // descending is default
 	case "asc", "ascending":
-		dir = persistence.SortDirectionAscending
+		return persistence.SortDirectionAscending, nil
 	default:
-		return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidSortDirection, dirString)
+		return -1, i18n.NewError(ctx, tmmsgs.MsgInvalidSortDirection, dirString)
+	}
+}
+
+func (m *manager) getTransactions(ctx context.Context, afterStr, limitStr string, filters *persistence.TransactionFilters, pending bool, dirString string) (transactions []*apitypes.ManagedTX, err error) {
+	limit, err := m.parseLimit(ctx, limitStr)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := parseSortDirection(ctx, dirString)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		if filters.Status != "" && filters.Status != apitypes.TxStatusPending {
+			return nil, i18n.NewError(ctx, tmmsgs.MsgTXConflictPendingStatus, filters.Status)
+		}
+		filters.Status = apitypes.TxStatusPending
 	}
 	var afterTx *apitypes.ManagedTX
 	if afterStr != "" {
@@ -64,25 +119,171 @@ func (m *manager) getTransactions(ctx context.Context, afterStr, limitStr, signe
 			return nil, i18n.NewError(ctx, tmmsgs.MsgPaginationErrTxNotFound, afterStr)
 		}
 	}
-	switch {
-	case signer != "" && pending:
-		return nil, i18n.NewError(ctx, tmmsgs.MsgTXConflictSignerPending)
-	case signer != "":
-		var afterNonce *fftypes.FFBigInt
-		if afterTx != nil {
-			afterNonce = afterTx.Nonce
+	// The signer and pending queries on their own retain their original ordering, using the dedicated indexes
+	switch *filters {
+	case persistence.TransactionFilters{}:
+		return m.persistence.ListTransactionsByCreateTime(ctx, afterTx, limit, dir)
+	case persistence.TransactionFilters{Signer: filters.Signer}:
+		return m.getSignerTransactions(ctx, filters.Signer, afterTx, limit, dir)
+	case persistence.TransactionFilters{Status: apitypes.TxStatusPending}:
+		if pending {
+			var afterSequence string
+			if afterTx != nil {
+				afterSequence = afterTx.SequenceID
+			}
+			return m.persistence.ListTransactionsPending(ctx, afterSequence, limit, dir)
 		}
-		return m.persistence.ListTransactionsByNonce(ctx, signer, afterNonce, limit, dir)
-	case pending:
-		var afterSequence string
-		if afterTx != nil {
-			afterSequence = afterTx.SequenceID
+	}
+	return m.persistence.ListTransactionsFiltered(ctx, filters, afterTx, limit, dir)
+}
+
+// getSignerTransactions lists the transactions of a signer in nonce order. The transactions that have not been
+// assigned a nonce, such as scheduled transactions, follow all the others in creation order.
+func (m *manager) getSignerTransactions(ctx context.Context, signer string, afterTx *apitypes.ManagedTX, limit int, dir persistence.SortDirection) ([]*apitypes.ManagedTX, error) {
+	noNonce := &persistence.TransactionFilters{Signer: signer, NoNonce: true}
+	if afterTx != nil && afterTx.Nonce == nil {
+		return m.persistence.ListTransactionsFiltered(ctx, noNonce, afterTx, limit, dir)
+	}
+	var afterNonce *fftypes.FFBigInt
+	if afterTx != nil {
+		afterNonce = afterTx.Nonce
+	}
+	transactions, err := m.persistence.ListTransactionsByNonce(ctx, signer, afterNonce, limit, dir)
+	if err != nil || (limit > 0 && len(transactions) >= limit) {
+		return transactions, err
+	}
+	remaining := 0
+	if limit > 0 {
+		remaining = limit - len(transactions)
+	}
+	withoutNonce, err := m.persistence.ListTransactionsFiltered(ctx, noNonce, nil, remaining, dir)
+	if err != nil {
+		return nil, err
+	}
+	return append(transactions, withoutNonce...), nil
+}
+
+func (m *manager) getTransactionHistory(ctx context.Context, txID, afterStr, limitStr, dirString string) ([]*apitypes.TxHistoryRecord, error) {
+	after, limit, err := m.parseAfterAndLimit(ctx, afterStr, limitStr)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := parseSortDirection(ctx, dirString)
+	if err != nil {
+		return nil, err
+	}
+	// We return a 404 for an unknown transaction, rather than an empty list
+	if _, err := m.getTransactionByID(ctx, txID); err != nil {
+		return nil, err
+	}
+	return m.persistence.ListTransactionHistory(ctx, txID, after, limit, dir)
+}
+
+// findExistingTransaction returns the transaction already created with the ID of a request, if there is one. If it was
+// created by an identical request the status is 200, so a client can safely retry a request. Otherwise it is a 409.
+// A transaction without a request hash, such as one created before the hash was stored, is treated as created by an
+// identical request, as there is nothing to compare the request with.
+func (m *manager) findExistingTransaction(ctx context.Context, reqHeaders *apitypes.RequestHeaders, requestHash string) (status int, existing *apitypes.ManagedTX, err error) {
+	if reqHeaders.ID == "" {
+		return 0, nil, nil
+	}
+	existing, err = m.persistence.GetTransactionByID(ctx, reqHeaders.ID)
+	if err != nil || existing == nil {
+		return 0, nil, err
+	}
+	if existing.RequestHash == "" {
+		log.L(ctx).Infof("Transaction %s already exists, and has no request hash to compare", existing.ID)
+		return http.StatusOK, existing, nil
+	}
+	if existing.RequestHash != requestHash {
+		log.L(ctx).Warnf("Transaction %s already exists, and was created by a different request", existing.ID)
+		return http.StatusConflict, existing, nil
+	}
+	log.L(ctx).Infof("Transaction %s already exists for an identical request", existing.ID)
+	return http.StatusOK, existing, nil
+}
+
+// submitIdempotent submits a new transaction, unless a transaction already exists with the ID of the request
+func (m *manager) submitIdempotent(ctx context.Context, reqHeaders *apitypes.RequestHeaders, requestHash string, submit func() (*apitypes.ManagedTX, error)) (status int, mtx *apitypes.ManagedTX, err error) {
+	if status, existing, err := m.findExistingTransaction(ctx, reqHeaders, requestHash); err != nil || existing != nil {
+		return status, existing, err
+	}
+	mtx, err = submit()
+	if err != nil {
+		// A concurrent request with the same ID might have created the transaction since we checked
+		if status, existing, _ := m.findExistingTransaction(ctx, reqHeaders, requestHash); existing != nil {
+			return status, existing, nil
 		}
-		return m.persistence.ListTransactionsPending(ctx, afterSequence, limit, dir)
-	default:
-		return m.persistence.ListTransactionsByCreateTime(ctx, afterTx, limit, dir)
+		return 0, nil, err
+	}
+	return http.StatusAccepted, mtx, nil
+}
+
+// sendTransactionBatch decodes each request in the batch, and passes those that are valid to the transaction handler.
+// Requests that cannot be decoded fail on their own, without affecting the rest of the batch.
+func (m *manager) sendTransactionBatch(ctx context.Context, req *apitypes.TransactionBatchRequest) (*apitypes.TransactionBatchResponse, error) {
+	batchHandler, ok := m.txHandler.(txhandler.TransactionBatchHandler)
+	if !ok {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgTXHandlerNotSupported, "TransactionBatchHandler")
+	}
+	res := &apitypes.TransactionBatchResponse{
+		Results: make([]*apitypes.TransactionBatchResult, len(req.Requests)),
+	}
+	items := make([]*apitypes.TransactionBatchItem, 0, len(req.Requests))
+	indexes := make([]int, 0, len(req.Requests))
+	for i, baseReq := range req.Requests {
+		item, err := decodeTransactionBatchItem(ctx, baseReq)
+		if err != nil {
+			res.Results[i] = &apitypes.TransactionBatchResult{Error: err.Error()}
+			continue
+		}
+		status, existing, err := m.findExistingTransaction(ctx, item.RequestHeaders(), item.RequestHash())
+		switch {
+		case err != nil:
+			res.Results[i] = &apitypes.TransactionBatchResult{Error: err.Error()}
+			continue
+		case status == http.StatusConflict:
+			res.Results[i] = &apitypes.TransactionBatchResult{Transaction: existing, Error: i18n.NewError(ctx, tmmsgs.MsgTXRequestConflict, existing.ID).Error()}
+			continue
+		case existing != nil:
+			res.Results[i] = &apitypes.TransactionBatchResult{Transaction: existing}
+			continue
+		}
+		items = append(items, item)
+		indexes = append(indexes, i)
 	}
+	if len(items) > 0 {
+		results, err := batchHandler.HandleNewTransactionBatch(ctx, items)
+		if err != nil {
+			return nil, err
+		}
+		for i, result := range results {
+			res.Results[indexes[i]] = result
+		}
+	}
+	return res, nil
+}
 
+func decodeTransactionBatchItem(ctx context.Context, baseReq *apitypes.BaseRequest) (*apitypes.TransactionBatchItem, error) {
+	if baseReq == nil {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgUnsupportedRequestType, "")
+	}
+	item := &apitypes.TransactionBatchItem{}
+	var target interface{}
+	switch baseReq.Headers.Type {
+	case apitypes.RequestTypeSendTransaction:
+		item.Transaction = &apitypes.TransactionRequest{}
+		target = item.Transaction
+	case apitypes.RequestTypeDeploy:
+		item.Deploy = &apitypes.ContractDeployRequest{}
+		target = item.Deploy
+	default:
+		return nil, i18n.NewError(ctx, tmmsgs.MsgUnsupportedRequestType, baseReq.Headers.Type)
+	}
+	if err := baseReq.UnmarshalTo(target); err != nil {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidRequestErr, baseReq.Headers.Type, err)
+	}
+	return item, nil
 }
 
 func (m *manager) requestTransactionDeletion(ctx context.Context, txID string) (status int, transaction *apitypes.ManagedTX, err error) {
@@ -96,3 +297,29 @@ func (m *manager) requestTransactionDeletion(ctx context.Context, txID string) (
 	return http.StatusAccepted, canceledTx, nil
 
 }
+
+func (m *manager) requestTransactionCancellation(ctx context.Context, txID string) (transaction *apitypes.ManagedTX, err error) {
+	h, ok := m.txHandler.(txhandler.CancelByReplacementHandler)
+	if !ok {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgTXHandlerNotSupported, "CancelByReplacementHandler")
+	}
+	return h.HandleCancelTransactionByReplacement(ctx, txID)
+}
+
+func (m *manager) requestTransactionResubmit(ctx context.Context, txID string, req *apitypes.ResubmitTransactionRequest) (transaction *apitypes.ManagedTX, err error) {
+	h, ok := m.txHandler.(txhandler.ResubmitHandler)
+	if !ok {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgTXHandlerNotSupported, "ResubmitHandler")
+	}
+	return h.HandleResubmitTransaction(ctx, txID, req)
+}
+
+func (m *manager) sendRawTransaction(ctx context.Context, txReq *apitypes.RawTransactionRequest) (status int, transaction *apitypes.ManagedTX, err error) {
+	h, ok := m.txHandler.(txhandler.RawTransactionHandler)
+	if !ok {
+		return 0, nil, i18n.NewError(ctx, tmmsgs.MsgTXHandlerNotSupported, "RawTransactionHandler")
+	}
+	return m.submitIdempotent(ctx, &txReq.Headers, txReq.RequestHash(), func() (*apitypes.ManagedTX, error) {
+		return h.HandleNewRawTransaction(ctx, txReq)
+	})
+}
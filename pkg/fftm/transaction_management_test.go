@@ -17,12 +17,15 @@
 package fftm
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
 
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -57,25 +60,66 @@ func TestGetTransactionsErrors(t *testing.T) {
 	mp.On("GetTransactionByID", m.ctx, mock.Anything).Return(nil, nil).Once()
 	mp.On("Close", mock.Anything).Return(nil).Maybe()
 
-	_, err := m.getTransactions(m.ctx, "", "bad limit", "", false, "")
+	_, err := m.getTransactions(m.ctx, "", "bad limit", &persistence.TransactionFilters{}, false, "")
 	assert.Regexp(t, "FF21044", err)
 
-	_, err = m.getTransactions(m.ctx, "", "", "", false, "wrong")
+	_, err = m.getTransactions(m.ctx, "", "", &persistence.TransactionFilters{}, false, "wrong")
 	assert.Regexp(t, "FF21064", err)
 
-	_, err = m.getTransactions(m.ctx, "", "", "cannot be specified with pending", true, "")
-	assert.Regexp(t, "FF21063", err)
+	_, err = m.getTransactions(m.ctx, "", "", &persistence.TransactionFilters{Status: apitypes.TxStatusFailed}, true, "")
+	assert.Regexp(t, "FF21088", err)
 
-	_, err = m.getTransactions(m.ctx, "after-causes-failure", "", "", false, "")
+	_, err = m.getTransactions(m.ctx, "after-causes-failure", "", &persistence.TransactionFilters{}, false, "")
 	assert.Regexp(t, "pop", err)
 
-	_, err = m.getTransactions(m.ctx, "after-not-found", "", "", false, "")
+	_, err = m.getTransactions(m.ctx, "after-not-found", "", &persistence.TransactionFilters{}, false, "")
 	assert.Regexp(t, "FF21062", err)
 
+	// Listing the transactions of a signer that have not been assigned a nonce fails
+	mp.On("ListTransactionsByNonce", m.ctx, "0xaaaaa", mock.Anything, 0, mock.Anything).Return([]*apitypes.ManagedTX{}, nil).Once()
+	mp.On("ListTransactionsFiltered", m.ctx, &persistence.TransactionFilters{Signer: "0xaaaaa", NoNonce: true}, mock.Anything, 0, mock.Anything).Return(nil, fmt.Errorf("pop")).Once()
+	_, err = m.getTransactions(m.ctx, "", "", &persistence.TransactionFilters{Signer: "0xaaaaa"}, false, "")
+	assert.Regexp(t, "pop", err)
+
 	mp.AssertExpectations(t)
 
 }
 
+func TestParseTransactionFilters(t *testing.T) {
+
+	_, m, close := newTestManagerMockPersistence(t)
+	defer close()
+
+	filters, err := m.parseTransactionFilters(m.ctx, map[string]string{
+		"signer":        "0xaaaa",
+		"status":        "succeeded",
+		"subStatus":     "Confirmed",
+		"to":            "0xbbbb",
+		"hash":          "0xcccc",
+		"createdAfter":  "2023-01-01T00:00:00Z",
+		"createdBefore": "2023-01-02T00:00:00Z",
+		"updatedAfter":  "1672531200",
+		"updatedBefore": "2023-01-02T00:00:00Z",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "0xaaaa", filters.Signer)
+	assert.Equal(t, apitypes.TxStatusSucceeded, filters.Status)
+	assert.Equal(t, apitypes.TxSubStatusConfirmed, filters.SubStatus)
+	assert.Equal(t, "0xbbbb", filters.To)
+	assert.Equal(t, "0xcccc", filters.TransactionHash)
+	assert.Equal(t, int64(1672531200), filters.CreatedAfter.Time().Unix())
+	assert.Equal(t, int64(1672617600), filters.CreatedBefore.Time().Unix())
+	assert.Equal(t, int64(1672531200), filters.UpdatedAfter.Time().Unix())
+	assert.Equal(t, int64(1672617600), filters.UpdatedBefore.Time().Unix())
+
+	_, err = m.parseTransactionFilters(m.ctx, map[string]string{"status": "unknown"})
+	assert.Regexp(t, "FF21087", err)
+
+	_, err = m.parseTransactionFilters(m.ctx, map[string]string{"updatedBefore": "not a time"})
+	assert.Regexp(t, "FF21089.*updatedBefore", err)
+
+}
+
 func TestDeleteTransactionError(t *testing.T) {
 	_, m, done := newTestManager(t)
 	defer done()
@@ -92,3 +136,82 @@ func TestDeleteTransactionError(t *testing.T) {
 	mth.AssertExpectations(t)
 
 }
+
+func TestSubmitIdempotentErrors(t *testing.T) {
+
+	_, m, close := newTestManagerMockPersistence(t)
+	defer close()
+
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("GetTransactionByID", m.ctx, "id1").Return(nil, fmt.Errorf("pop")).Once()
+	mp.On("GetTransactionByID", m.ctx, "id1").Return(nil, nil).Twice()
+	mp.On("Close", mock.Anything).Return(nil).Maybe()
+
+	_, _, err := m.submitIdempotent(m.ctx, &apitypes.RequestHeaders{ID: "id1"}, "hash1", func() (*apitypes.ManagedTX, error) {
+		panic("should not be called")
+	})
+	assert.Regexp(t, "pop", err)
+
+	_, _, err = m.submitIdempotent(m.ctx, &apitypes.RequestHeaders{ID: "id1"}, "hash1", func() (*apitypes.ManagedTX, error) {
+		return nil, fmt.Errorf("bang")
+	})
+	assert.Regexp(t, "bang", err)
+
+	status, mtx, err := m.submitIdempotent(m.ctx, &apitypes.RequestHeaders{}, "hash1", func() (*apitypes.ManagedTX, error) {
+		return &apitypes.ManagedTX{ID: "generated"}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, "generated", mtx.ID)
+
+	mp.AssertExpectations(t)
+
+}
+
+func TestSendTransactionBatchExisting(t *testing.T) {
+
+	_, m, close := newTestManagerMockPersistence(t)
+	defer close()
+
+	sameReq := &apitypes.TransactionRequest{Headers: apitypes.RequestHeaders{ID: "same", Type: apitypes.RequestTypeSendTransaction}}
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("GetTransactionByID", m.ctx, "same").Return(&apitypes.ManagedTX{ID: "same", RequestHash: sameReq.RequestHash()}, nil)
+	mp.On("GetTransactionByID", m.ctx, "different").Return(&apitypes.ManagedTX{ID: "different", RequestHash: "other"}, nil)
+	mp.On("GetTransactionByID", m.ctx, "fail").Return(nil, fmt.Errorf("pop"))
+	mp.On("GetTransactionByID", m.ctx, "new").Return(nil, nil)
+	mp.On("GetTransactionByID", m.ctx, "unhashed").Return(&apitypes.ManagedTX{ID: "unhashed"}, nil)
+	mp.On("Close", mock.Anything).Return(nil).Maybe()
+
+	mth := txhandlermocks.TransactionBatchHandler{}
+	mth.On("HandleNewTransactionBatch", m.ctx, mock.MatchedBy(func(items []*apitypes.TransactionBatchItem) bool {
+		return len(items) == 1 && items[0].Transaction.Headers.ID == "new"
+	})).Return([]*apitypes.TransactionBatchResult{
+		{Transaction: &apitypes.ManagedTX{ID: "new"}},
+	}, nil).Once()
+	m.txHandler = &mockCapableTXHandler{TransactionBatchHandler: &mth}
+
+	var batchReq *apitypes.TransactionBatchRequest
+	err := json.Unmarshal([]byte(`{"requests":[
+		{"headers":{"id":"same","type":"SendTransaction"}},
+		{"headers":{"id":"different","type":"SendTransaction"}},
+		{"headers":{"id":"fail","type":"SendTransaction"}},
+		{"headers":{"id":"new","type":"SendTransaction"}},
+		{"headers":{"id":"unhashed","type":"SendTransaction"}}
+	]}`), &batchReq)
+	assert.NoError(t, err)
+	res, err := m.sendTransactionBatch(m.ctx, batchReq)
+	assert.NoError(t, err)
+	assert.Equal(t, "same", res.Results[0].Transaction.ID)
+	assert.Empty(t, res.Results[0].Error)
+	assert.Equal(t, "different", res.Results[1].Transaction.ID)
+	assert.Regexp(t, "FF21099", res.Results[1].Error)
+	assert.Nil(t, res.Results[2].Transaction)
+	assert.Regexp(t, "pop", res.Results[2].Error)
+	assert.Equal(t, "new", res.Results[3].Transaction.ID)
+	assert.Equal(t, "unhashed", res.Results[4].Transaction.ID)
+	assert.Empty(t, res.Results[4].Error)
+
+	mp.AssertExpectations(t)
+	mth.AssertExpectations(t)
+
+}
@@ -0,0 +1,203 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+)
+
+const txPurgePageSize = 50
+
+type txRetentionPolicy struct {
+	maxAge            time.Duration
+	maxCountPerSigner int
+	interval          time.Duration
+}
+
+func newTXRetentionPolicy() *txRetentionPolicy {
+	return &txRetentionPolicy{
+		maxAge:            config.GetDuration(tmconfig.TransactionsRetentionMaxAge),
+		maxCountPerSigner: config.GetInt(tmconfig.TransactionsRetentionMaxCountPerSigner),
+		interval:          config.GetDuration(tmconfig.TransactionsRetentionInterval),
+	}
+}
+
+func (rp *txRetentionPolicy) enabled() bool {
+	return rp.maxAge > 0 || rp.maxCountPerSigner > 0
+}
+
+// txPurgeCycle holds the state for a single pass over the persisted transactions
+type txPurgeCycle struct {
+	m            *manager
+	ctx          context.Context
+	latestNonces map[string]*fftypes.FFBigInt
+	purged       int
+}
+
+func isTerminalTX(tx *apitypes.ManagedTX) bool {
+	return tx.Status == apitypes.TxStatusSucceeded || tx.Status == apitypes.TxStatusFailed
+}
+
+func (m *manager) txPurgerLoop() {
+	defer close(m.txPurgerDone)
+	ctx := log.WithLogField(m.ctx, "role", "tx-purger")
+	ticker := time.NewTicker(m.txRetention.interval)
+
+	for {
+		if err := m.purgeTransactions(ctx); err != nil {
+			// We'll retry on the next interval
+			log.L(ctx).Errorf("Transaction purge failed: %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			ticker.Stop()
+			log.L(ctx).Infof("Transaction purger exiting")
+			return
+		}
+	}
+}
+
+// purgeTransactions deletes the Succeeded and Failed transactions that fall outside the retention policy.
+// The record with the highest nonce for each signer is never deleted, as nonce allocation relies on it.
+func (m *manager) purgeTransactions(ctx context.Context) error {
+	pc := &txPurgeCycle{
+		m:            m,
+		ctx:          ctx,
+		latestNonces: make(map[string]*fftypes.FFBigInt),
+	}
+
+	// The create time scan purges by age, and discovers the signers to check the counts of
+	signers, err := pc.purgeByAge()
+	if err == nil && m.txRetention.maxCountPerSigner > 0 {
+		for _, signer := range signers {
+			if err = pc.purgeByCount(signer); err != nil {
+				break
+			}
+		}
+	}
+	if pc.purged > 0 {
+		log.L(ctx).Infof("Purged %d transactions outside of the retention policy", pc.purged)
+	}
+	return err
+}
+
+func (pc *txPurgeCycle) isLatestNonce(tx *apitypes.ManagedTX) (bool, error) {
+	signer := tx.TransactionHeaders.From
+	latest, ok := pc.latestNonces[signer]
+	if !ok {
+		txns, err := pc.m.persistence.ListTransactionsByNonce(pc.ctx, signer, nil, 1, persistence.SortDirectionDescending)
+		if err != nil {
+			return false, err
+		}
+		if len(txns) > 0 {
+			latest = txns[0].Nonce
+		}
+		// Nonces only move forwards, so a cached answer can only cause us to retain more than required
+		pc.latestNonces[signer] = latest
+	}
+	// A scheduled transaction that was cancelled before it was assigned a nonce never holds the latest nonce
+	if tx.Nonce == nil {
+		return false, nil
+	}
+	return latest == nil || tx.Nonce.Int().Cmp(latest.Int()) >= 0, nil
+}
+
+func (pc *txPurgeCycle) purge(tx *apitypes.ManagedTX) error {
+	latest, err := pc.isLatestNonce(tx)
+	if err != nil || latest {
+		return err
+	}
+	log.L(pc.ctx).Debugf("Purging transaction %s (signer=%s,nonce=%s,status=%s)", tx.ID, tx.TransactionHeaders.From, tx.Nonce, tx.Status)
+	if err := pc.m.persistence.DeleteTransaction(pc.ctx, tx.ID); err != nil {
+		return err
+	}
+	pc.purged++
+	return nil
+}
+
+func (pc *txPurgeCycle) purgeByAge() (signers []string, err error) {
+	maxAge := pc.m.txRetention.maxAge
+	cutoff := time.Now().Add(-maxAge)
+	knownSigners := make(map[string]bool)
+	var after *apitypes.ManagedTX
+	for {
+		txns, err := pc.m.persistence.ListTransactionsByCreateTime(pc.ctx, after, txPurgePageSize, persistence.SortDirectionAscending)
+		if err != nil {
+			return nil, err
+		}
+		if len(txns) == 0 {
+			return signers, nil
+		}
+		for _, tx := range txns {
+			after = tx
+			if maxAge > 0 && pc.m.txRetention.maxCountPerSigner <= 0 && tx.Created.Time().After(cutoff) {
+				// Nothing newer can be outside the retention policy
+				return nil, nil
+			}
+			signer := tx.TransactionHeaders.From
+			if !knownSigners[signer] {
+				knownSigners[signer] = true
+				signers = append(signers, signer)
+			}
+			lastUpdate := tx.Updated
+			if lastUpdate == nil {
+				lastUpdate = tx.Created
+			}
+			if maxAge > 0 && isTerminalTX(tx) && lastUpdate.Time().Before(cutoff) {
+				if err := pc.purge(tx); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+}
+
+func (pc *txPurgeCycle) purgeByCount(signer string) error {
+	retained := 0
+	var after *fftypes.FFBigInt
+	for {
+		txns, err := pc.m.persistence.ListTransactionsByNonce(pc.ctx, signer, after, txPurgePageSize, persistence.SortDirectionDescending)
+		if err != nil {
+			return err
+		}
+		if len(txns) == 0 {
+			return nil
+		}
+		for _, tx := range txns {
+			after = tx.Nonce
+			if !isTerminalTX(tx) {
+				continue
+			}
+			if retained < pc.m.txRetention.maxCountPerSigner {
+				retained++
+				continue
+			}
+			if err := pc.purge(tx); err != nil {
+				return err
+			}
+		}
+	}
+}
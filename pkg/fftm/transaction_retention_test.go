@@ -0,0 +1,267 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fftm
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/ffcapimocks"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func writeTestRetentionTX(t *testing.T, m *manager, signer string, nonce int64, status apitypes.TxStatus, age time.Duration) *apitypes.ManagedTX {
+	ts := fftypes.FFTime(time.Now().Add(-age))
+	tx := &apitypes.ManagedTX{
+		ID:      fmt.Sprintf("ns1:%s", fftypes.NewUUID()),
+		Created: &ts,
+		Updated: &ts,
+		Nonce:   fftypes.NewFFBigInt(nonce),
+		Status:  status,
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: signer,
+		},
+	}
+	err := m.persistence.WriteTransaction(m.ctx, tx, true)
+	assert.NoError(t, err)
+	return tx
+}
+
+func assertTXRetained(t *testing.T, m *manager, tx *apitypes.ManagedTX, retained bool) {
+	tx, err := m.persistence.GetTransactionByID(m.ctx, tx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, retained, tx != nil)
+}
+
+func TestRetentionPolicyConfig(t *testing.T) {
+	tmconfig.Reset()
+	assert.False(t, newTXRetentionPolicy().enabled())
+
+	config.Set(tmconfig.TransactionsRetentionMaxAge, "24h")
+	rp := newTXRetentionPolicy()
+	assert.True(t, rp.enabled())
+	assert.Equal(t, 24*time.Hour, rp.maxAge)
+	assert.Equal(t, 5*time.Minute, rp.interval)
+
+	tmconfig.Reset()
+	config.Set(tmconfig.TransactionsRetentionMaxCountPerSigner, 10)
+	assert.True(t, newTXRetentionPolicy().enabled())
+}
+
+func TestPurgeTransactionsByAge(t *testing.T) {
+	_, m, done := newTestManager(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxAge: 1 * time.Hour}
+
+	old := 2 * time.Hour
+	a1 := writeTestRetentionTX(t, m, "0xaaaa", 1, apitypes.TxStatusSucceeded, old)
+	a2 := writeTestRetentionTX(t, m, "0xaaaa", 2, apitypes.TxStatusFailed, old)
+	a3 := writeTestRetentionTX(t, m, "0xaaaa", 3, apitypes.TxStatusPending, old)
+	b1 := writeTestRetentionTX(t, m, "0xbbbb", 1, apitypes.TxStatusSucceeded, old)
+	b2 := writeTestRetentionTX(t, m, "0xbbbb", 2, apitypes.TxStatusSucceeded, old)
+	a4 := writeTestRetentionTX(t, m, "0xaaaa", 4, apitypes.TxStatusSucceeded, 0)
+	b3 := writeTestRetentionTX(t, m, "0xbbbb", 3, apitypes.TxStatusSucceeded, 0)
+
+	err := m.purgeTransactions(m.ctx)
+	assert.NoError(t, err)
+
+	assertTXRetained(t, m, a1, false)
+	assertTXRetained(t, m, a2, false)
+	assertTXRetained(t, m, a3, true) // pending
+	assertTXRetained(t, m, a4, true) // new
+	assertTXRetained(t, m, b1, false)
+	assertTXRetained(t, m, b2, false)
+	assertTXRetained(t, m, b3, true) // new
+}
+
+func TestPurgeTransactionsByAgeKeepsLatestNonce(t *testing.T) {
+	_, m, done := newTestManager(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxAge: 1 * time.Hour}
+
+	a1 := writeTestRetentionTX(t, m, "0xaaaa", 1, apitypes.TxStatusSucceeded, 3*time.Hour)
+	a2 := writeTestRetentionTX(t, m, "0xaaaa", 2, apitypes.TxStatusSucceeded, 2*time.Hour)
+
+	err := m.purgeTransactions(m.ctx)
+	assert.NoError(t, err)
+
+	assertTXRetained(t, m, a1, false)
+	assertTXRetained(t, m, a2, true)
+}
+
+func TestPurgeTransactionsByAgeScheduledWithoutNonce(t *testing.T) {
+	_, m, done := newTestManager(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxAge: 1 * time.Hour}
+
+	a1 := writeTestRetentionTX(t, m, "0xaaaa", 1, apitypes.TxStatusSucceeded, 3*time.Hour)
+	a2 := writeTestRetentionTX(t, m, "0xaaaa", 2, apitypes.TxStatusFailed, 3*time.Hour)
+	a2.Nonce = nil
+	err := m.persistence.WriteTransaction(m.ctx, a2, false)
+	assert.NoError(t, err)
+
+	err = m.purgeTransactions(m.ctx)
+	assert.NoError(t, err)
+
+	assertTXRetained(t, m, a1, true)
+	assertTXRetained(t, m, a2, false)
+}
+
+func TestPurgeTransactionsByCount(t *testing.T) {
+	_, m, done := newTestManager(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxCountPerSigner: 2}
+
+	var txns []*apitypes.ManagedTX
+	for i := int64(1); i <= 5; i++ {
+		txns = append(txns, writeTestRetentionTX(t, m, "0xaaaa", i, apitypes.TxStatusSucceeded, 0))
+	}
+	pending := writeTestRetentionTX(t, m, "0xaaaa", 6, apitypes.TxStatusPending, 0)
+	b1 := writeTestRetentionTX(t, m, "0xbbbb", 1, apitypes.TxStatusFailed, 0)
+
+	err := m.purgeTransactions(m.ctx)
+	assert.NoError(t, err)
+
+	assertTXRetained(t, m, txns[0], false)
+	assertTXRetained(t, m, txns[1], false)
+	assertTXRetained(t, m, txns[2], false)
+	assertTXRetained(t, m, txns[3], true)
+	assertTXRetained(t, m, txns[4], true)
+	assertTXRetained(t, m, pending, true)
+	assertTXRetained(t, m, b1, true)
+}
+
+func TestPurgeTransactionsListFail(t *testing.T) {
+	_, m, done := newTestManagerMockPersistence(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxAge: 1 * time.Hour}
+
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("ListTransactionsByCreateTime", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	err := m.purgeTransactions(m.ctx)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestPurgeTransactionsLatestNonceFail(t *testing.T) {
+	_, m, done := newTestManagerMockPersistence(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxAge: 1 * time.Hour}
+
+	ts := fftypes.FFTime(time.Now().Add(-2 * time.Hour))
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("ListTransactionsByCreateTime", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*apitypes.ManagedTX{
+		{ID: "tx1", Created: &ts, Nonce: fftypes.NewFFBigInt(1), Status: apitypes.TxStatusSucceeded, TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaa"}},
+	}, nil)
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaa", mock.Anything, 1, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	err := m.purgeTransactions(m.ctx)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestPurgeTransactionsDeleteFail(t *testing.T) {
+	_, m, done := newTestManagerMockPersistence(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxCountPerSigner: 1}
+
+	ts := fftypes.FFTime(time.Now())
+	txns := []*apitypes.ManagedTX{
+		{ID: "tx2", Created: &ts, Nonce: fftypes.NewFFBigInt(2), Status: apitypes.TxStatusSucceeded, TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaa"}},
+		{ID: "tx1", Created: &ts, Nonce: fftypes.NewFFBigInt(1), Status: apitypes.TxStatusSucceeded, TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaa"}},
+	}
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("ListTransactionsByCreateTime", mock.Anything, (*apitypes.ManagedTX)(nil), mock.Anything, mock.Anything).Return(txns, nil)
+	mp.On("ListTransactionsByCreateTime", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*apitypes.ManagedTX{}, nil)
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaa", mock.Anything, mock.Anything, mock.Anything).Return(txns, nil)
+	mp.On("DeleteTransaction", mock.Anything, "tx1").Return(fmt.Errorf("pop"))
+
+	err := m.purgeTransactions(m.ctx)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestPurgeTransactionsByCountListFail(t *testing.T) {
+	_, m, done := newTestManagerMockPersistence(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxCountPerSigner: 1}
+
+	ts := fftypes.FFTime(time.Now())
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("ListTransactionsByCreateTime", mock.Anything, (*apitypes.ManagedTX)(nil), mock.Anything, mock.Anything).Return([]*apitypes.ManagedTX{
+		{ID: "tx1", Created: &ts, Nonce: fftypes.NewFFBigInt(1), Status: apitypes.TxStatusSucceeded, TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaa"}},
+	}, nil)
+	mp.On("ListTransactionsByCreateTime", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*apitypes.ManagedTX{}, nil)
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaa", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	err := m.purgeTransactions(m.ctx)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestTXPurgerLoop(t *testing.T) {
+	_, m, done := newTestManager(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxCountPerSigner: 1, interval: 1 * time.Millisecond}
+
+	mfc := m.connector.(*ffcapimocks.API)
+	mfc.On("NewBlockListener", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), nil).Maybe()
+
+	a1 := writeTestRetentionTX(t, m, "0xaaaa", 1, apitypes.TxStatusSucceeded, 0)
+	a2 := writeTestRetentionTX(t, m, "0xaaaa", 2, apitypes.TxStatusSucceeded, 0)
+
+	err := m.Start()
+	assert.NoError(t, err)
+
+	for {
+		tx, err := m.persistence.GetTransactionByID(m.ctx, a1.ID)
+		assert.NoError(t, err)
+		if tx == nil {
+			break
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
+	assertTXRetained(t, m, a2, true)
+}
+
+func TestTXPurgerLoopFail(t *testing.T) {
+	_, m, done := newTestManagerMockPersistence(t)
+	defer done()
+	m.txRetention = &txRetentionPolicy{maxAge: 1 * time.Hour, interval: 1 * time.Millisecond}
+	m.txPurgerDone = make(chan struct{})
+
+	purged := make(chan struct{})
+	mp := m.persistence.(*persistencemocks.Persistence)
+	mp.On("ListTransactionsByCreateTime", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop")).Once()
+	mp.On("ListTransactionsByCreateTime", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*apitypes.ManagedTX{}, nil).Run(func(args mock.Arguments) {
+		select {
+		case <-purged:
+		default:
+			close(purged)
+		}
+	})
+
+	go m.txPurgerLoop()
+	<-purged
+	m.cancelCtx()
+	<-m.txPurgerDone
+}
@@ -0,0 +1,72 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistencefactory
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
+)
+
+// Persistence is the interface a registered persistence plugin must implement
+type Persistence = persistence.Persistence
+
+type SortDirection = persistence.SortDirection
+
+type TransactionFilters = persistence.TransactionFilters
+
+const (
+	SortDirectionAscending  = persistence.SortDirectionAscending
+	SortDirectionDescending = persistence.SortDirectionDescending
+)
+
+var persistencePlugins = make(map[string]Factory)
+
+func NewPersistence(ctx context.Context, baseConfig config.Section, name string) (Persistence, error) {
+	factory, ok := persistencePlugins[name]
+	if !ok {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgUnknownPersistence, name)
+	}
+	p, err := factory.NewPersistence(ctx, baseConfig.SubSection(name))
+	if err != nil {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgPersistenceInitFail, name, err)
+	}
+	return p, nil
+}
+
+type Factory interface {
+	Name() string
+	InitConfig(conf config.Section)
+	NewPersistence(ctx context.Context, conf config.Section) (Persistence, error)
+}
+
+// RegisterPersistence registers a persistence type. The name must not be the name of one of the persistence types
+// built into FFTM, as the built-in type would always be used in preference to the registered one.
+func RegisterPersistence(factory Factory) (string, error) {
+	name := factory.Name()
+	if persistence.IsBuiltinPersistence(name) {
+		return "", i18n.NewError(context.Background(), tmmsgs.MsgPersistenceNameClash, name)
+	}
+	persistencePlugins[name] = factory
+	// init the new persistence configurations
+	factory.InitConfig(tmconfig.PersistenceBaseConfig.SubSection(name))
+	return name, nil
+}
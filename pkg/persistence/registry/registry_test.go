@@ -0,0 +1,77 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistencefactory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type testFactory struct {
+	name string
+}
+
+func (tf *testFactory) Name() string {
+	return tf.name
+}
+
+func (tf *testFactory) InitConfig(conf config.Section) {
+	conf.AddKnownKey("url")
+}
+
+func (tf *testFactory) NewPersistence(ctx context.Context, conf config.Section) (Persistence, error) {
+	if conf.GetString("url") == "" {
+		return nil, fmt.Errorf("pop")
+	}
+	return &persistencemocks.Persistence{}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	tmconfig.Reset()
+	name, err := RegisterPersistence(&testFactory{name: "custom"})
+	assert.NoError(t, err)
+	assert.Equal(t, "custom", name)
+
+	p, err := NewPersistence(context.Background(), tmconfig.PersistenceBaseConfig, "custom")
+	assert.Nil(t, p)
+	assert.Regexp(t, "FF21049.*pop", err)
+
+	tmconfig.PersistenceBaseConfig.SubSection("custom").Set("url", "http://localhost")
+	p, err = NewPersistence(context.Background(), tmconfig.PersistenceBaseConfig, "custom")
+	assert.NotNil(t, p)
+	assert.NoError(t, err)
+
+	p, err = NewPersistence(context.Background(), tmconfig.PersistenceBaseConfig, "bob")
+	assert.Nil(t, p)
+	assert.Regexp(t, "FF21043", err)
+
+}
+
+func TestRegisterBuiltinName(t *testing.T) {
+	tmconfig.Reset()
+	_, err := RegisterPersistence(&testFactory{name: "leveldb"})
+	assert.Regexp(t, "FF21106.*leveldb", err)
+
+	_, err = NewPersistence(context.Background(), tmconfig.PersistenceBaseConfig, "leveldb")
+	assert.Regexp(t, "FF21043", err)
+}
@@ -0,0 +1,146 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs" // replace with your own messages if you are developing a customized transaction handler
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+)
+
+// preparedBatchTX is a transaction from a batch that has been prepared, and is waiting for a nonce to be assigned
+type preparedBatchTX struct {
+	index           int
+	txID            string
+	reqHeaders      *apitypes.RequestHeaders
+	requestHash     string
+	txHeaders       *ffcapi.TransactionHeaders
+	gas             *fftypes.FFBigInt
+	transactionData string
+}
+
+// HandleNewTransactionBatch prepares all the transactions in the batch, then assigns the nonces for each signer in
+// turn. The nonce lock for a signer is taken once for the whole batch, so the transactions for each signer are
+// assigned contiguous nonces in the order they are in the batch. Scheduled transactions are not assigned a nonce
+// until they are due, so are written without taking the nonce lock. A request that repeats the ID of an earlier
+// request in the batch fails without being prepared.
+func (sth *simpleTransactionHandler) HandleNewTransactionBatch(ctx context.Context, items []*apitypes.TransactionBatchItem) (results []*apitypes.TransactionBatchResult, err error) {
+	if sth.maxBatchSize > 0 && len(items) > sth.maxBatchSize {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgTransactionBatchTooLarge, len(items), sth.maxBatchSize)
+	}
+	results = make([]*apitypes.TransactionBatchResult, len(items))
+	signers := []string{}
+	bySigner := make(map[string][]*preparedBatchTX)
+	ids := make(map[string]bool)
+	for i, item := range items {
+		results[i] = &apitypes.TransactionBatchResult{}
+		if id := item.RequestHeaders().ID; id != "" {
+			if ids[id] {
+				results[i].Error = i18n.NewError(ctx, tmmsgs.MsgDuplicateID, id).Error()
+				continue
+			}
+			ids[id] = true
+		}
+		ptx, err := sth.prepareBatchTX(ctx, item)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		ptx.index = i
+		if notYetDue(ptx.reqHeaders.NotBefore) {
+			if results[i].Transaction, err = sth.createScheduledTx(ctx, ptx.txID, ptx.reqHeaders, ptx.requestHash, ptx.txHeaders, ptx.gas, ptx.transactionData); err != nil {
+				results[i].Error = err.Error()
+			}
+			continue
+		}
+		signer := ptx.txHeaders.From
+		if _, ok := bySigner[signer]; !ok {
+			signers = append(signers, signer)
+		}
+		bySigner[signer] = append(bySigner[signer], ptx)
+	}
+	for _, signer := range signers {
+		sth.createManagedTxBatch(ctx, signer, bySigner[signer], results)
+	}
+	return results, nil
+}
+
+// prepareBatchTX prepares a transaction or contract deployment from a batch, in the same way as if it was sent on its own
+func (sth *simpleTransactionHandler) prepareBatchTX(ctx context.Context, item *apitypes.TransactionBatchItem) (*preparedBatchTX, error) {
+	ptx := &preparedBatchTX{}
+	if item.Deploy != nil {
+		prepared, _, err := sth.toolkit.Connector.DeployContractPrepare(ctx, &item.Deploy.ContractDeployPrepareRequest)
+		if err != nil {
+			return nil, err
+		}
+		ptx.reqHeaders, ptx.txHeaders = &item.Deploy.Headers, &item.Deploy.TransactionHeaders
+		ptx.gas, ptx.transactionData = prepared.Gas, prepared.TransactionData
+	} else {
+		txInput, err := sth.preflightTransaction(ctx, item.Transaction.TransactionInput)
+		if err != nil {
+			return nil, err
+		}
+		prepared, _, err := sth.toolkit.Connector.TransactionPrepare(ctx, &ffcapi.TransactionPrepareRequest{
+			TransactionInput: *txInput,
+		})
+		if err != nil {
+			return nil, err
+		}
+		ptx.reqHeaders, ptx.txHeaders = &item.Transaction.Headers, &txInput.TransactionHeaders
+		ptx.gas, ptx.transactionData = prepared.Gas, prepared.TransactionData
+	}
+	if err := checkNotBefore(ctx, ptx.reqHeaders); err != nil {
+		return nil, err
+	}
+	ptx.requestHash = item.RequestHash()
+	ptx.txID = ptx.reqHeaders.ID
+	if ptx.txID == "" {
+		ptx.txID = fftypes.NewUUID().String()
+	}
+	return ptx, nil
+}
+
+// createManagedTxBatch assigns nonces to, and persists, the prepared transactions for a signer within a single nonce lock.
+// A nonce is only used by a transaction that is persisted successfully, so the nonces remain contiguous when some fail.
+func (sth *simpleTransactionHandler) createManagedTxBatch(ctx context.Context, signer string, batch []*preparedBatchTX, results []*apitypes.TransactionBatchResult) {
+	lockedNonce, err := sth.assignAndLockNonce(ctx, batch[0].txID, signer)
+	if err != nil {
+		for _, ptx := range batch {
+			results[ptx.index].Error = err.Error()
+		}
+		return
+	}
+	defer lockedNonce.complete(ctx)
+
+	nonce := lockedNonce.nonce
+	for _, ptx := range batch {
+		mtx, err := sth.writeNewManagedTx(ctx, ptx.txID, nonce, ptx.reqHeaders, ptx.requestHash, ptx.txHeaders, ptx.gas, ptx.transactionData)
+		if err != nil {
+			log.L(ctx).Errorf("Failed to create transaction %s from batch at nonce %s / %d: %s", ptx.txID, signer, nonce, err)
+			results[ptx.index].Error = err.Error()
+			continue
+		}
+		lockedNonce.nonce, lockedNonce.spent = nonce, mtx
+		results[ptx.index].Transaction = mtx
+		nonce++
+	}
+}
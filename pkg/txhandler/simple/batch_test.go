@@ -0,0 +1,189 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestBatchTX(id, signer, data string) *apitypes.TransactionBatchItem {
+	return &apitypes.TransactionBatchItem{
+		Transaction: &apitypes.TransactionRequest{
+			Headers: apitypes.RequestHeaders{ID: id},
+			TransactionInput: ffcapi.TransactionInput{
+				TransactionHeaders: ffcapi.TransactionHeaders{From: signer},
+				Method:             fftypes.JSONAnyPtr(`"` + data + `"`),
+			},
+		},
+	}
+}
+
+func TestHandleNewTransactionBatch(t *testing.T) {
+	f, tk, mockFFCAPI, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.MatchedBy(func(req *ffcapi.NextNonceForSignerRequest) bool {
+		return req.Signer == "0xaaaaa"
+	})).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(10),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	mockFFCAPI.On("TransactionPrepare", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionPrepareRequest) bool {
+		return req.Method.String() == `"ok"`
+	})).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(100000),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil)
+
+	// An existing transaction, so that a request in the batch with the same ID fails
+	existing, err := sth.HandleNewTransaction(sth.ctx, &apitypes.TransactionRequest{
+		Headers: apitypes.RequestHeaders{ID: "ns1:existing"},
+		TransactionInput: ffcapi.TransactionInput{
+			TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"},
+			Method:             fftypes.JSONAnyPtr(`"ok"`),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), existing.Nonce.Int64())
+
+	// The node is only queried once for each signer
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.MatchedBy(func(req *ffcapi.NextNonceForSignerRequest) bool {
+		return req.Signer == "0xbbbbb"
+	})).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(20),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	mockFFCAPI.On("TransactionPrepare", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionPrepareRequest) bool {
+		return req.Method.String() == `"fail"`
+	})).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Once()
+	mockFFCAPI.On("DeployContractPrepare", mock.Anything, mock.Anything).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(200000),
+		TransactionData: "0xdeploy",
+	}, ffcapi.ErrorReason(""), nil)
+
+	results, err := sth.HandleNewTransactionBatch(sth.ctx, []*apitypes.TransactionBatchItem{
+		newTestBatchTX("ns1:a1", "0xaaaaa", "ok"),
+		newTestBatchTX("ns1:b1", "0xbbbbb", "ok"),
+		newTestBatchTX("ns1:existing", "0xaaaaa", "ok"),
+		newTestBatchTX("ns1:a2", "0xaaaaa", "fail"),
+		{
+			Deploy: &apitypes.ContractDeployRequest{
+				Headers: apitypes.RequestHeaders{ID: "ns1:a3"},
+				ContractDeployPrepareRequest: ffcapi.ContractDeployPrepareRequest{
+					TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"},
+				},
+			},
+		},
+		newTestBatchTX("", "0xbbbbb", "ok"),
+		newTestBatchTX("ns1:a2", "0xaaaaa", "fail"), // repeated, so not prepared again
+		newTestBatchTX("", "0xbbbbb", "ok"),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 8)
+
+	// The nonces for each signer are contiguous, skipping those that failed
+	assert.Equal(t, int64(11), results[0].Transaction.Nonce.Int64())
+	assert.Equal(t, newTestBatchTX("ns1:a1", "0xaaaaa", "ok").RequestHash(), results[0].Transaction.RequestHash)
+	assert.Equal(t, int64(20), results[1].Transaction.Nonce.Int64())
+	assert.Regexp(t, "FF21065", results[2].Error)
+	assert.Nil(t, results[2].Transaction)
+	assert.Equal(t, "pop", results[3].Error)
+	assert.Equal(t, int64(12), results[4].Transaction.Nonce.Int64())
+	assert.Equal(t, "0xdeploy", results[4].Transaction.TransactionData)
+	assert.Equal(t, int64(21), results[5].Transaction.Nonce.Int64())
+	assert.NotEmpty(t, results[5].Transaction.ID)
+	assert.Regexp(t, "FF21065.*ns1:a2", results[6].Error)
+	assert.Nil(t, results[6].Transaction)
+	assert.Equal(t, int64(22), results[7].Transaction.Nonce.Int64())
+
+	mtx, err := tk.TXPersistence.GetTransactionByID(sth.ctx, "ns1:a3")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), mtx.Nonce.Int64())
+	assert.Empty(t, sth.lockedNonces)
+
+	// The failed duplicate did not add any history to the existing transaction
+	history, err := tk.TXPersistence.(persistence.Persistence).ListTransactionHistory(sth.ctx, "ns1:existing", nil, 0, persistence.SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, `{"nonce":"10"}`, history[0].Actions[0].LastInfo.String())
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestHandleNewTransactionBatchNonceFail(t *testing.T) {
+	f, tk, mockFFCAPI, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mockFFCAPI.On("TransactionPrepare", mock.Anything, mock.Anything).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(100000),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil)
+	mockFFCAPI.On("DeployContractPrepare", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("snap"))
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop"))
+
+	results, err := sth.HandleNewTransactionBatch(sth.ctx, []*apitypes.TransactionBatchItem{
+		newTestBatchTX("ns1:a1", "0xaaaaa", "ok"),
+		newTestBatchTX("ns1:a2", "0xaaaaa", "ok"),
+		{Deploy: &apitypes.ContractDeployRequest{}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "pop", results[0].Error)
+	assert.Equal(t, "pop", results[1].Error)
+	assert.Equal(t, "snap", results[2].Error)
+	assert.Empty(t, sth.lockedNonces)
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestHandleNewTransactionBatchTooLarge(t *testing.T) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.Set(MaxBatchSize, 1)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	_, err = sth.HandleNewTransactionBatch(sth.ctx, []*apitypes.TransactionBatchItem{
+		newTestBatchTX("ns1:a1", "0xaaaaa", "ok"),
+		newTestBatchTX("ns1:a2", "0xaaaaa", "ok"),
+	})
+	assert.Regexp(t, "FF21108", err)
+}
@@ -25,8 +25,10 @@ import (
 )
 
 const (
-	MaxInFlight       = "maxInFlight"
-	NonceStateTimeout = "nonceStateTimeout"
+	MaxInFlight          = "maxInFlight"
+	MaxInFlightPerSigner = "maxInFlightPerSigner" // when set, the in-flight set is filled round-robin across signers up to this limit for each
+	NonceStateTimeout    = "nonceStateTimeout"
+	MaxBatchSize         = "maxBatchSize" // the maximum number of new transactions in a batch request
 
 	Interval       = "interval"
 	RetryInitDelay = "retry.initialDelay"
@@ -35,11 +37,32 @@ const (
 
 	FixedGasPrice          = "fixedGasPrice"    // when not using a gas station - will be treated as a raw JSON string, so can be numeric 123, or string "123", or object {"maxPriorityFeePerGas":123})
 	ResubmitInterval       = "resubmitInterval" // warnings will be written to the log at this interval if mining has not occurred, and the TX will be resubmitted
+	DefaultDeadline        = "defaultDeadline"  // the deadline for transactions that do not specify one in their request headers - after which they are cancelled if not mined
 	GasOracleConfig        = "gasOracle"
 	GasOracleMode          = "mode"
 	GasOracleMethod        = "method"
 	GasOracleTemplate      = "template"
 	GasOracleQueryInterval = "queryInterval"
+
+	GasPriceEscalationConfig          = "gasPriceEscalation"
+	GasPriceEscalationPercentage      = "percentage"      // the percentage to increase the gas price by, over the last submitted gas price, on each resubmit
+	GasPriceEscalationMinimumIncrease = "minimumIncrease" // the minimum absolute increase over the last submitted gas price on each resubmit
+	GasPriceEscalationMaximumGasPrice = "maximumGasPrice" // a hard ceiling that the gas price will never exceed
+
+	NonceGapConfig        = "nonceGaps"
+	NonceGapFill          = "fill"          // whether to submit zero value transfers to fill gaps in the nonces of signers with pending transactions
+	NonceGapCheckInterval = "checkInterval" // how often to check signers with pending transactions for gaps, when filling is enabled
+
+	PriorityConfig             = "priority"
+	PriorityEnabled            = "enabled"            // whether to select higher priority transactions first when filling the in-flight set
+	PriorityGasPricePercentage = "gasPricePercentage" // the percentage increase in gas price for each level of priority above zero
+
+	InsufficientFundsConfig               = "insufficientFunds"
+	InsufficientFundsBalanceCheckInterval = "balanceCheckInterval" // how often to check the balance of a signer paused for insufficient funds
+
+	PreflightConfig        = "preflight"
+	PreflightEnabled       = "enabled"       // whether to estimate the gas for new transactions, rejecting those that would revert
+	PreflightGasMultiplier = "gasMultiplier" // the multiplier applied to the gas estimate, for transactions that do not specify the gas
 )
 
 const (
@@ -49,6 +72,7 @@ const (
 
 	defaultMaxInFlight       = 100
 	defaultNonceStateTimeout = "1h"
+	defaultMaxBatchSize      = 1000
 	defaultInterval          = "10s"
 	defaultRetryInitDelay    = "250ms"
 	defaultRetryMaxDelay     = "30s"
@@ -57,17 +81,34 @@ const (
 
 const (
 	defaultResubmitInterval       = "5m"
+	defaultMaxInFlightPerSigner   = 0
 	defaultGasOracleQueryInterval = "5m"
 	defaultGasOracleMethod        = http.MethodGet
 	defaultGasOracleMode          = GasOracleModeConnector
+
+	defaultGasPriceEscalationPercentage = 0
+
+	defaultNonceGapFill          = false
+	defaultNonceGapCheckInterval = "1m"
+
+	defaultPriorityEnabled            = false
+	defaultPriorityGasPricePercentage = 10
+
+	defaultInsufficientFundsBalanceCheckInterval = "30s"
+
+	defaultPreflightEnabled       = false
+	defaultPreflightGasMultiplier = 1.5
 )
 
 func (f *TransactionHandlerFactory) InitConfig(conf config.Section) {
 	conf.AddKnownKey(FixedGasPrice)
 	conf.AddKnownKey(ResubmitInterval, defaultResubmitInterval)
+	conf.AddKnownKey(DefaultDeadline)
 
 	conf.AddKnownKey(MaxInFlight, defaultMaxInFlight)
+	conf.AddKnownKey(MaxInFlightPerSigner, defaultMaxInFlightPerSigner)
 	conf.AddKnownKey(NonceStateTimeout, defaultNonceStateTimeout)
+	conf.AddKnownKey(MaxBatchSize, defaultMaxBatchSize)
 	conf.AddKnownKey(Interval, defaultInterval)
 	conf.AddKnownKey(RetryInitDelay, defaultRetryInitDelay)
 	conf.AddKnownKey(RetryMaxDelay, defaultRetryMaxDelay)
@@ -80,6 +121,26 @@ func (f *TransactionHandlerFactory) InitConfig(conf config.Section) {
 	gasOracleConfig.AddKnownKey(GasOracleQueryInterval, defaultGasOracleQueryInterval)
 	gasOracleConfig.AddKnownKey(GasOracleTemplate)
 
+	gasPriceEscalationConfig := conf.SubSection(GasPriceEscalationConfig)
+	gasPriceEscalationConfig.AddKnownKey(GasPriceEscalationPercentage, defaultGasPriceEscalationPercentage)
+	gasPriceEscalationConfig.AddKnownKey(GasPriceEscalationMinimumIncrease)
+	gasPriceEscalationConfig.AddKnownKey(GasPriceEscalationMaximumGasPrice)
+
+	nonceGapConfig := conf.SubSection(NonceGapConfig)
+	nonceGapConfig.AddKnownKey(NonceGapFill, defaultNonceGapFill)
+	nonceGapConfig.AddKnownKey(NonceGapCheckInterval, defaultNonceGapCheckInterval)
+
+	priorityConfig := conf.SubSection(PriorityConfig)
+	priorityConfig.AddKnownKey(PriorityEnabled, defaultPriorityEnabled)
+	priorityConfig.AddKnownKey(PriorityGasPricePercentage, defaultPriorityGasPricePercentage)
+
+	insufficientFundsConfig := conf.SubSection(InsufficientFundsConfig)
+	insufficientFundsConfig.AddKnownKey(InsufficientFundsBalanceCheckInterval, defaultInsufficientFundsBalanceCheckInterval)
+
+	preflightConfig := conf.SubSection(PreflightConfig)
+	preflightConfig.AddKnownKey(PreflightEnabled, defaultPreflightEnabled)
+	preflightConfig.AddKnownKey(PreflightGasMultiplier, defaultPreflightGasMultiplier)
+
 	// Init the deprecated policy engine config in case people are still using them
 	legacyConfig := tmconfig.DeprecatedPolicyEngineBaseConfig.SubSection(f.Name())
 	legacyConfig.AddKnownKey(FixedGasPrice)
@@ -0,0 +1,183 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs" // replace with your own messages if you are developing a customized transaction handler
+)
+
+// minimumReplacementPercentage is the minimum gas price increase that nodes (such as geth) accept for a replacement transaction
+const minimumReplacementPercentage = 10
+
+// gasPriceEscalation is the policy applied to the gas price each time a transaction is resubmitted.
+// Nodes reject a replacement transaction at the same nonce unless the gas price is increased, so simply
+// resubmitting with the latest price from the gas oracle can leave a transaction stuck indefinitely.
+type gasPriceEscalation struct {
+	percentage      int64    // the percentage increase over the last submitted gas price
+	minimumIncrease *big.Int // the minimum absolute increase over the last submitted gas price
+	maximumGasPrice *big.Int // a hard ceiling on the gas price, which also applies to the first submission
+}
+
+func newGasPriceEscalation(ctx context.Context, conf config.Section) (*gasPriceEscalation, error) {
+	gpe := &gasPriceEscalation{
+		percentage: conf.GetInt64(GasPriceEscalationPercentage),
+	}
+	if gpe.percentage < 0 {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidGasPriceEscalation, GasPriceEscalationPercentage, gpe.percentage)
+	}
+	var err error
+	if gpe.minimumIncrease, err = parseGasPriceConfig(ctx, conf, GasPriceEscalationMinimumIncrease); err != nil {
+		return nil, err
+	}
+	if gpe.maximumGasPrice, err = parseGasPriceConfig(ctx, conf, GasPriceEscalationMaximumGasPrice); err != nil {
+		return nil, err
+	}
+	return gpe, nil
+}
+
+func parseGasPriceConfig(ctx context.Context, conf config.Section, key string) (*big.Int, error) {
+	s := conf.GetString(key)
+	if s == "" {
+		return nil, nil
+	}
+	i, ok := new(big.Int).SetString(s, 0)
+	if !ok || i.Sign() < 0 {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidGasPriceEscalation, key, s)
+	}
+	return i, nil
+}
+
+func (gpe *gasPriceEscalation) enabled() bool {
+	return gpe.percentage > 0 || (gpe.minimumIncrease != nil && gpe.minimumIncrease.Sign() > 0)
+}
+
+// applyCeiling caps the supplied gas price at the configured maximum. For EIP-1559 fees the maximum applies
+// to the maxFeePerGas, and the maxPriorityFeePerGas can never exceed the maxFeePerGas.
+func (gpe *gasPriceEscalation) applyCeiling(ctx context.Context, gasPrice *fftypes.JSONAny) *fftypes.JSONAny {
+	fees := parseGasFees(gasPrice)
+	if fees == nil || !gpe.capFees(ctx, fees) {
+		return gasPrice
+	}
+	return fees.format()
+}
+
+func (gpe *gasPriceEscalation) capFees(ctx context.Context, fees *gasFees) (changed bool) {
+	if gpe.maximumGasPrice != nil && fees.limit().Cmp(gpe.maximumGasPrice) > 0 {
+		log.L(ctx).Warnf("Gas price %s exceeds the maximum gas price %s", fees.limit(), gpe.maximumGasPrice)
+		if fees.isDynamic() {
+			fees.maxFeePerGas = gpe.maximumGasPrice
+		} else {
+			fees.gasPrice = gpe.maximumGasPrice
+		}
+		changed = true
+	}
+	if fees.isDynamic() && fees.maxPriorityFeePerGas.Cmp(fees.maxFeePerGas) > 0 {
+		fees.maxPriorityFeePerGas = fees.maxFeePerGas
+		changed = true
+	}
+	return changed
+}
+
+// escalate returns the gas price to use for a resubmission, which is the highest of the latest price from the
+// gas oracle, and the last submitted price increased by the configured percentage and minimum increase.
+// For EIP-1559 fees the maxPriorityFeePerGas and maxFeePerGas are escalated independently.
+func (gpe *gasPriceEscalation) escalate(ctx context.Context, lastGasPrice, latestGasPrice *fftypes.JSONAny) *fftypes.JSONAny {
+	last := parseGasFees(lastGasPrice)
+	latest := parseGasFees(latestGasPrice)
+	if !gpe.enabled() || last == nil || latest == nil || last.isDynamic() != latest.isDynamic() {
+		return gpe.applyCeiling(ctx, latestGasPrice)
+	}
+	if latest.isDynamic() {
+		latest.maxPriorityFeePerGas = gpe.escalateFee(last.maxPriorityFeePerGas, latest.maxPriorityFeePerGas)
+		latest.maxFeePerGas = gpe.escalateFee(last.maxFeePerGas, latest.maxFeePerGas)
+	} else {
+		latest.gasPrice = gpe.escalateFee(last.gasPrice, latest.gasPrice)
+	}
+	gpe.capFees(ctx, latest)
+	escalated := latest.format()
+	log.L(ctx).Debugf("Escalated gas price from %s to %s (latest=%s)", lastGasPrice, escalated, latestGasPrice)
+	return escalated
+}
+
+func (gpe *gasPriceEscalation) escalateFee(last, latest *big.Int) *big.Int {
+	escalated := new(big.Int).Mul(last, big.NewInt(100+gpe.percentage))
+	escalated.Div(escalated, big.NewInt(100))
+	if gpe.minimumIncrease != nil {
+		minimum := new(big.Int).Add(last, gpe.minimumIncrease)
+		if escalated.Cmp(minimum) < 0 {
+			escalated = minimum
+		}
+	}
+	if latest.Cmp(escalated) >= 0 {
+		return latest
+	}
+	return escalated
+}
+
+// escalateReplacement returns the gas price for a different transaction to replace one already submitted at the
+// same nonce. Nodes require the price to be increased for a replacement, so a minimum increase applies even if
+// escalation is disabled.
+func (gpe *gasPriceEscalation) escalateReplacement(ctx context.Context, lastGasPrice, latestGasPrice *fftypes.JSONAny) *fftypes.JSONAny {
+	percentage := gpe.percentage
+	if percentage < minimumReplacementPercentage {
+		percentage = minimumReplacementPercentage
+	}
+	return gpe.withPercentage(percentage).escalate(ctx, lastGasPrice, latestGasPrice)
+}
+
+// withPercentage returns a copy of the policy, with a different percentage increase
+func (gpe *gasPriceEscalation) withPercentage(percentage int64) *gasPriceEscalation {
+	escalation := *gpe
+	escalation.percentage = percentage
+	return &escalation
+}
+
+// canEscalate returns true if a further escalation would increase the supplied gas price
+func (gpe *gasPriceEscalation) canEscalate(gasPrice *fftypes.JSONAny) bool {
+	fees := parseGasFees(gasPrice)
+	return gpe.enabled() && fees != nil && (gpe.maximumGasPrice == nil || fees.limit().Cmp(gpe.maximumGasPrice) < 0)
+}
+
+// prioritizeGasPrice increases the gas price from the gas oracle by the configured percentage for each level of priority
+// above zero. For EIP-1559 fees the maxPriorityFeePerGas and maxFeePerGas are both increased.
+func (sth *simpleTransactionHandler) prioritizeGasPrice(ctx context.Context, priority int, gasPrice *fftypes.JSONAny) *fftypes.JSONAny {
+	fees := parseGasFees(gasPrice)
+	if priority <= 0 || sth.priorityGasPricePercentage == 0 || fees == nil {
+		return gasPrice
+	}
+	percentage := big.NewInt(100 + int64(priority)*sth.priorityGasPricePercentage)
+	increase := func(fee *big.Int) *big.Int {
+		increased := new(big.Int).Mul(fee, percentage)
+		return increased.Div(increased, big.NewInt(100))
+	}
+	if fees.isDynamic() {
+		fees.maxPriorityFeePerGas = increase(fees.maxPriorityFeePerGas)
+		fees.maxFeePerGas = increase(fees.maxFeePerGas)
+	} else {
+		fees.gasPrice = increase(fees.gasPrice)
+	}
+	prioritized := fees.format()
+	log.L(ctx).Debugf("Increased gas price from %s to %s for priority %d", gasPrice, prioritized, priority)
+	return prioritized
+}
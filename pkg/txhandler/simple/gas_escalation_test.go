@@ -0,0 +1,165 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGasPriceEscalationConfig(t *testing.T) {
+	_, _, _, conf := newTestTransactionHandlerFactory(t)
+	gpeConf := conf.SubSection(GasPriceEscalationConfig)
+	gpeConf.Set(GasPriceEscalationPercentage, 10)
+	gpeConf.Set(GasPriceEscalationMinimumIncrease, "1000")
+	gpeConf.Set(GasPriceEscalationMaximumGasPrice, "0x2710")
+
+	gpe, err := newGasPriceEscalation(context.Background(), gpeConf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), gpe.percentage)
+	assert.Equal(t, int64(1000), gpe.minimumIncrease.Int64())
+	assert.Equal(t, int64(10000), gpe.maximumGasPrice.Int64())
+	assert.True(t, gpe.enabled())
+}
+
+func TestGasPriceEscalationConfigBadPercentage(t *testing.T) {
+	_, _, _, conf := newTestTransactionHandlerFactory(t)
+	gpeConf := conf.SubSection(GasPriceEscalationConfig)
+	gpeConf.Set(GasPriceEscalationPercentage, -1)
+
+	_, err := newGasPriceEscalation(context.Background(), gpeConf)
+	assert.Regexp(t, "FF21090.*percentage", err)
+}
+
+func TestGasPriceEscalationConfigBadMinimumIncrease(t *testing.T) {
+	_, _, _, conf := newTestTransactionHandlerFactory(t)
+	gpeConf := conf.SubSection(GasPriceEscalationConfig)
+	gpeConf.Set(GasPriceEscalationMinimumIncrease, "lots")
+
+	_, err := newGasPriceEscalation(context.Background(), gpeConf)
+	assert.Regexp(t, "FF21090.*minimumIncrease", err)
+}
+
+func TestGasPriceEscalationConfigBadMaximum(t *testing.T) {
+	_, _, _, conf := newTestTransactionHandlerFactory(t)
+	gpeConf := conf.SubSection(GasPriceEscalationConfig)
+	gpeConf.Set(GasPriceEscalationMaximumGasPrice, "-1")
+
+	_, err := newGasPriceEscalation(context.Background(), gpeConf)
+	assert.Regexp(t, "FF21090.*maximumGasPrice", err)
+}
+
+func TestGasPriceEscalationBadHandlerConfig(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(GasPriceEscalationConfig).Set(GasPriceEscalationPercentage, -1)
+
+	_, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.Regexp(t, "FF21090", err)
+}
+
+func TestGasPriceEscalate(t *testing.T) {
+	ctx := context.Background()
+	gpe := &gasPriceEscalation{
+		percentage:      10,
+		minimumIncrease: big.NewInt(50),
+		maximumGasPrice: big.NewInt(2000),
+	}
+
+	// Percentage increase over the last price, when the oracle has not moved
+	assert.Equal(t, `"1100"`, gpe.escalate(ctx, fftypes.JSONAnyPtr(`1000`), fftypes.JSONAnyPtr(`1000`)).String())
+	// Minimum increase applies when the percentage is too small
+	assert.Equal(t, `"150"`, gpe.escalate(ctx, fftypes.JSONAnyPtr(`"100"`), fftypes.JSONAnyPtr(`"100"`)).String())
+	// Oracle price used when it is higher than the escalated price
+	assert.Equal(t, `"1500"`, gpe.escalate(ctx, fftypes.JSONAnyPtr(`1000`), fftypes.JSONAnyPtr(`"0x5dc"`)).String())
+	// Capped at the ceiling
+	assert.Equal(t, `"2000"`, gpe.escalate(ctx, fftypes.JSONAnyPtr(`1900`), fftypes.JSONAnyPtr(`1900`)).String())
+	assert.Equal(t, `"2000"`, gpe.escalate(ctx, fftypes.JSONAnyPtr(`1000`), fftypes.JSONAnyPtr(`3000`)).String())
+	// No last price, or a structure we cannot reason about
+	assert.Equal(t, `1000`, gpe.escalate(ctx, nil, fftypes.JSONAnyPtr(`1000`)).String())
+	assert.Equal(t, `{"maxFeePerGas":1000}`, gpe.escalate(ctx, fftypes.JSONAnyPtr(`1000`), fftypes.JSONAnyPtr(`{"maxFeePerGas":1000}`)).String())
+
+	assert.True(t, gpe.canEscalate(fftypes.JSONAnyPtr(`1999`)))
+	assert.False(t, gpe.canEscalate(fftypes.JSONAnyPtr(`2000`)))
+	assert.False(t, gpe.canEscalate(fftypes.JSONAnyPtr(`{}`)))
+}
+
+func TestGasPriceEscalateDynamicFees(t *testing.T) {
+	ctx := context.Background()
+	gpe := &gasPriceEscalation{
+		percentage:      10,
+		maximumGasPrice: big.NewInt(2000),
+	}
+
+	// The fees are bumped independently, with other fields passed through
+	assert.Equal(t, `{"maxFeePerGas":"1500","maxPriorityFeePerGas":"110","other":true}`, gpe.escalate(ctx,
+		fftypes.JSONAnyPtr(`{"maxFeePerGas":1000,"maxPriorityFeePerGas":100,"other":true}`),
+		fftypes.JSONAnyPtr(`{"maxFeePerGas":"1500","maxPriorityFeePerGas":"100","other":true}`),
+	).String())
+	// Capped at the ceiling, and the priority fee never exceeds the max fee
+	assert.Equal(t, `{"maxFeePerGas":"2000","maxPriorityFeePerGas":"2000"}`, gpe.escalate(ctx,
+		fftypes.JSONAnyPtr(`{"maxFeePerGas":1900,"maxPriorityFeePerGas":1900}`),
+		fftypes.JSONAnyPtr(`{"maxFeePerGas":1900,"maxPriorityFeePerGas":1900}`),
+	).String())
+	assert.Equal(t, `{"maxFeePerGas":"2000","maxPriorityFeePerGas":"100"}`, gpe.applyCeiling(ctx,
+		fftypes.JSONAnyPtr(`{"maxFeePerGas":3000,"maxPriorityFeePerGas":100}`),
+	).String())
+	// Switching between legacy and dynamic fees uses the latest
+	assert.Equal(t, `{"maxFeePerGas":1000,"maxPriorityFeePerGas":100}`, gpe.escalate(ctx,
+		fftypes.JSONAnyPtr(`1000`),
+		fftypes.JSONAnyPtr(`{"maxFeePerGas":1000,"maxPriorityFeePerGas":100}`),
+	).String())
+	// Legacy gas price in an object
+	assert.Equal(t, `{"gasPrice":"1100"}`, gpe.escalate(ctx,
+		fftypes.JSONAnyPtr(`{"gasPrice":1000}`),
+		fftypes.JSONAnyPtr(`{"gasPrice":1000}`),
+	).String())
+
+	assert.True(t, gpe.canEscalate(fftypes.JSONAnyPtr(`{"maxFeePerGas":1999,"maxPriorityFeePerGas":100}`)))
+	assert.False(t, gpe.canEscalate(fftypes.JSONAnyPtr(`{"maxFeePerGas":2000,"maxPriorityFeePerGas":100}`)))
+}
+
+func TestGasPriceEscalateDisabled(t *testing.T) {
+	ctx := context.Background()
+	gpe := &gasPriceEscalation{}
+
+	assert.False(t, gpe.enabled())
+	assert.Equal(t, `1000`, gpe.escalate(ctx, fftypes.JSONAnyPtr(`1000`), fftypes.JSONAnyPtr(`1000`)).String())
+	assert.Equal(t, `1000`, gpe.applyCeiling(ctx, fftypes.JSONAnyPtr(`1000`)).String())
+	assert.False(t, gpe.canEscalate(fftypes.JSONAnyPtr(`1000`)))
+}
+
+func TestPrioritizeGasPrice(t *testing.T) {
+	ctx := context.Background()
+	sth := &simpleTransactionHandler{priorityGasPricePercentage: 25}
+
+	assert.Equal(t, `"1500"`, sth.prioritizeGasPrice(ctx, 2, fftypes.JSONAnyPtr(`1000`)).String())
+	assert.Equal(t, `{"maxFeePerGas":"2500","maxPriorityFeePerGas":"125"}`, sth.prioritizeGasPrice(ctx, 1,
+		fftypes.JSONAnyPtr(`{"maxFeePerGas":2000,"maxPriorityFeePerGas":100}`),
+	).String())
+	// No increase for the default priority, or a structure we cannot reason about
+	assert.Equal(t, `1000`, sth.prioritizeGasPrice(ctx, 0, fftypes.JSONAnyPtr(`1000`)).String())
+	assert.Equal(t, `1000`, sth.prioritizeGasPrice(ctx, -1, fftypes.JSONAnyPtr(`1000`)).String())
+	assert.Equal(t, `{}`, sth.prioritizeGasPrice(ctx, 1, fftypes.JSONAnyPtr(`{}`)).String())
+
+	sth.priorityGasPricePercentage = 0
+	assert.Equal(t, `1000`, sth.prioritizeGasPrice(ctx, 1, fftypes.JSONAnyPtr(`1000`)).String())
+}
@@ -0,0 +1,121 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+)
+
+const (
+	feeFieldGasPrice             = "gasPrice"
+	feeFieldMaxFeePerGas         = "maxFeePerGas"
+	feeFieldMaxPriorityFeePerGas = "maxPriorityFeePerGas"
+)
+
+// gasFees is the parsed form of the gas price passed to the connector, which is either a legacy gas price
+// (a number, or an object with a gasPrice field), or an EIP-1559 dynamic fee object with a maxFeePerGas
+// and maxPriorityFeePerGas.
+type gasFees struct {
+	gasPrice             *big.Int
+	maxFeePerGas         *big.Int
+	maxPriorityFeePerGas *big.Int
+	fields               map[string]json.RawMessage // nil for a simple numeric gas price - otherwise all fields are passed through to the connector
+}
+
+// parseGasFees returns the parsed fees, or nil if the gas price is not a structure we can reason about
+func parseGasFees(gasPrice *fftypes.JSONAny) *gasFees {
+	if gasPrice.IsNil() {
+		return nil
+	}
+	var legacy fftypes.FFBigInt
+	if err := json.Unmarshal(gasPrice.Bytes(), &legacy); err == nil {
+		return &gasFees{gasPrice: legacy.Int()}
+	}
+	fees := &gasFees{}
+	if err := json.Unmarshal(gasPrice.Bytes(), &fees.fields); err != nil || fees.fields == nil {
+		return nil
+	}
+	for name, target := range map[string]**big.Int{
+		feeFieldGasPrice:             &fees.gasPrice,
+		feeFieldMaxFeePerGas:         &fees.maxFeePerGas,
+		feeFieldMaxPriorityFeePerGas: &fees.maxPriorityFeePerGas,
+	} {
+		if b, ok := fees.fields[name]; ok {
+			var i fftypes.FFBigInt
+			if err := json.Unmarshal(b, &i); err != nil {
+				return nil
+			}
+			*target = i.Int()
+		}
+	}
+	// We need exactly one of a legacy gas price, or a complete set of dynamic fees
+	switch {
+	case fees.gasPrice != nil && fees.maxFeePerGas == nil && fees.maxPriorityFeePerGas == nil:
+		return fees
+	case fees.gasPrice == nil && fees.maxFeePerGas != nil && fees.maxPriorityFeePerGas != nil:
+		return fees
+	default:
+		return nil
+	}
+}
+
+func (f *gasFees) isDynamic() bool {
+	return f.maxFeePerGas != nil
+}
+
+// limit is the maximum price per unit of gas that might be paid
+func (f *gasFees) limit() *big.Int {
+	if f.isDynamic() {
+		return f.maxFeePerGas
+	}
+	return f.gasPrice
+}
+
+func (f *gasFees) format() *fftypes.JSONAny {
+	if f.fields == nil {
+		b, _ := json.Marshal((*fftypes.FFBigInt)(f.gasPrice))
+		return fftypes.JSONAnyPtrBytes(b)
+	}
+	fields := make(map[string]interface{}, len(f.fields))
+	for k, v := range f.fields {
+		fields[k] = v
+	}
+	if f.isDynamic() {
+		fields[feeFieldMaxFeePerGas] = (*fftypes.FFBigInt)(f.maxFeePerGas)
+		fields[feeFieldMaxPriorityFeePerGas] = (*fftypes.FFBigInt)(f.maxPriorityFeePerGas)
+	} else {
+		fields[feeFieldGasPrice] = (*fftypes.FFBigInt)(f.gasPrice)
+	}
+	b, _ := json.Marshal(fields)
+	return fftypes.JSONAnyPtrBytes(b)
+}
+
+// gasPriceHistoryInfo is the information recorded in the history when a gas price is retrieved,
+// which records the fees individually for an EIP-1559 fee object
+func gasPriceHistoryInfo(gasPrice *fftypes.JSONAny) *fftypes.JSONAny {
+	if fees := parseGasFees(gasPrice); fees != nil && fees.isDynamic() {
+		b, _ := json.Marshal(map[string]*fftypes.FFBigInt{
+			feeFieldMaxFeePerGas:         (*fftypes.FFBigInt)(fees.maxFeePerGas),
+			feeFieldMaxPriorityFeePerGas: (*fftypes.FFBigInt)(fees.maxPriorityFeePerGas),
+		})
+		return fftypes.JSONAnyPtrBytes(b)
+	}
+	return fftypes.JSONAnyPtr(`{"gasPrice":` + gasPrice.String() + `}`)
+}
@@ -0,0 +1,48 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGasFees(t *testing.T) {
+	assert.Nil(t, parseGasFees(nil))
+	assert.Nil(t, parseGasFees(fftypes.JSONAnyPtr(`[]`)))
+	assert.Nil(t, parseGasFees(fftypes.JSONAnyPtr(`null`)))
+	assert.Nil(t, parseGasFees(fftypes.JSONAnyPtr(`{"maxFeePerGas":"bad","maxPriorityFeePerGas":100}`)))
+	assert.Nil(t, parseGasFees(fftypes.JSONAnyPtr(`{"maxFeePerGas":1000}`)))
+	assert.Nil(t, parseGasFees(fftypes.JSONAnyPtr(`{"gasPrice":1000,"maxFeePerGas":1000,"maxPriorityFeePerGas":100}`)))
+
+	fees := parseGasFees(fftypes.JSONAnyPtr(`{"maxFeePerGas":"0x3e8","maxPriorityFeePerGas":100}`))
+	assert.True(t, fees.isDynamic())
+	assert.Equal(t, int64(1000), fees.limit().Int64())
+	assert.Equal(t, int64(100), fees.maxPriorityFeePerGas.Int64())
+
+	fees = parseGasFees(fftypes.JSONAnyPtr(`"12345"`))
+	assert.False(t, fees.isDynamic())
+	assert.Equal(t, int64(12345), fees.limit().Int64())
+}
+
+func TestGasPriceHistoryInfo(t *testing.T) {
+	assert.Equal(t, `{"gasPrice":12345}`, gasPriceHistoryInfo(fftypes.JSONAnyPtr(`12345`)).String())
+	assert.Equal(t, `{"gasPrice":{"other":"structure"}}`, gasPriceHistoryInfo(fftypes.JSONAnyPtr(`{"other":"structure"}`)).String())
+	assert.Equal(t, `{"maxFeePerGas":"1000","maxPriorityFeePerGas":"100"}`, gasPriceHistoryInfo(fftypes.JSONAnyPtr(`{"maxFeePerGas":1000,"maxPriorityFeePerGas":"0x64"}`)).String())
+}
@@ -27,11 +27,52 @@ func (sth *simpleTransactionHandler) initSimpleHandlerMetrics(ctx context.Contex
 	sth.toolkit.MetricsManager.InitTxHandlerHistogramMetricWithLabels(ctx, metricsHistogramTransactionProcessOperationsDuration, metricsHistogramTransactionProcessOperationsDurationDescription, []float64{} /*fallback to default buckets*/, []string{metricsLabelNameOperation}, true)
 	sth.toolkit.MetricsManager.InitTxHandlerGaugeMetric(ctx, metricsGaugeTransactionsInflightUsed, metricsGaugeTransactionsInflightUsedDescription, false)
 	sth.toolkit.MetricsManager.InitTxHandlerGaugeMetric(ctx, metricsGaugeTransactionsInflightFree, metricsGaugeTransactionsInflightFreeDescription, false)
+	sth.toolkit.MetricsManager.InitTxHandlerGaugeMetricWithLabels(ctx, metricsGaugeTransactionsInflightSigner, metricsGaugeTransactionsInflightSignerDescription, []string{metricsLabelNameSigner}, false)
+	sth.toolkit.MetricsManager.InitTxHandlerGaugeMetricWithLabels(ctx, metricsGaugeTransactionsQueuedSigner, metricsGaugeTransactionsQueuedSignerDescription, []string{metricsLabelNameSigner}, false)
+	sth.toolkit.MetricsManager.InitTxHandlerGaugeMetricWithLabels(ctx, metricsGaugeSignerUnderfunded, metricsGaugeSignerUnderfundedDescription, []string{metricsLabelNameSigner}, false)
 }
 
 func (sth *simpleTransactionHandler) setTransactionInflightQueueMetrics(ctx context.Context) {
 	sth.toolkit.MetricsManager.SetTxHandlerGaugeMetric(ctx, metricsGaugeTransactionsInflightUsed, float64(len(sth.inflight)), nil)
-	sth.toolkit.MetricsManager.SetTxHandlerGaugeMetric(ctx, metricsGaugeTransactionsInflightFree, float64(sth.maxInFlight-len(sth.inflight)), nil)
+	sth.toolkit.MetricsManager.SetTxHandlerGaugeMetric(ctx, metricsGaugeTransactionsInflightFree, float64(sth.inflightSpaces()), nil)
+}
+
+// setSignerQueueMetrics reports the number of in-flight transactions for each signer, and the number waiting outside
+// of the in-flight set when we have read all the pending transactions. Signers that no longer have any transactions
+// are reported as zero once, and then no longer reported.
+func (sth *simpleTransactionHandler) setSignerQueueMetrics(ctx context.Context, queued map[string]int) {
+	inflight := make(map[string]int)
+	signers := make(map[string]bool)
+	for _, p := range sth.inflight {
+		inflight[p.mtx.TransactionHeaders.From]++
+		signers[p.mtx.TransactionHeaders.From] = true
+	}
+	for signer := range queued {
+		signers[signer] = true
+	}
+	for signer := range sth.signerMetricsReported {
+		signers[signer] = true
+	}
+	reported := make(map[string]bool)
+	for signer := range signers {
+		labels := map[string]string{metricsLabelNameSigner: signer}
+		sth.toolkit.MetricsManager.SetTxHandlerGaugeMetricWithLabels(ctx, metricsGaugeTransactionsInflightSigner, float64(inflight[signer]), labels, nil)
+		if queued != nil {
+			sth.toolkit.MetricsManager.SetTxHandlerGaugeMetricWithLabels(ctx, metricsGaugeTransactionsQueuedSigner, float64(queued[signer]), labels, nil)
+		}
+		if inflight[signer] > 0 || queued[signer] > 0 {
+			reported[signer] = true
+		}
+	}
+	sth.signerMetricsReported = reported
+}
+
+func (sth *simpleTransactionHandler) setSignerUnderfundedMetric(ctx context.Context, signer string, underfunded bool) {
+	value := 0.0
+	if underfunded {
+		value = 1.0
+	}
+	sth.toolkit.MetricsManager.SetTxHandlerGaugeMetricWithLabels(ctx, metricsGaugeSignerUnderfunded, value, map[string]string{metricsLabelNameSigner: signer}, nil)
 }
 
 func (sth *simpleTransactionHandler) incTransactionOperationCounter(ctx context.Context, fireflyNamespace string, operationName string) {
@@ -0,0 +1,192 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+)
+
+// nonceGapPageSize is the number of transactions read at a time, when scanning the nonces of a signer for gaps
+const nonceGapPageSize = 100
+
+// maxNonceGapFills is the maximum number of nonces filled for a signer on each check, so that a very large
+// gap is filled progressively rather than holding up the policy loop
+const maxNonceGapFills = 100
+
+// GetSignerStatus compares the next nonce according to the node, with the nonces of the transactions we have for
+// the signer. The node includes the transactions in its pending pool when calculating the next nonce, so any nonce
+// from there up to our last pending transaction, that is not used by one of our transactions or reserved for use
+// outside of FFTM, is a gap.
+func (sth *simpleTransactionHandler) GetSignerStatus(ctx context.Context, signer string) (*apitypes.SignerStatus, error) {
+	nextNonceRes, _, err := sth.toolkit.Connector.NextNonceForSigner(ctx, &ffcapi.NextNonceForSignerRequest{
+		Signer: signer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	status := &apitypes.SignerStatus{
+		Signer:    signer,
+		NextNonce: nextNonceRes.Nonce,
+		NonceGaps: []*apitypes.NonceGap{},
+	}
+
+	expected := nextNonceRes.Nonce.Int64()
+	var after *fftypes.FFBigInt
+	if expected > 0 {
+		after = fftypes.NewFFBigInt(expected - 1)
+	}
+	for {
+		txns, err := sth.toolkit.TXPersistence.ListTransactionsByNonce(ctx, signer, after, nonceGapPageSize, 0 /* ascending */)
+		if err != nil {
+			return nil, err
+		}
+		for _, mtx := range txns {
+			// A transaction that completed without being mined, such as one that was cancelled or passed
+			// its deadline, does not use its nonce
+			if mtx.Status != apitypes.TxStatusPending && mtx.Receipt == nil {
+				continue
+			}
+			nonce := mtx.Nonce.Int64()
+			if nonce > expected {
+				status.NonceGaps = append(status.NonceGaps, &apitypes.NonceGap{
+					First: fftypes.NewFFBigInt(expected),
+					Last:  fftypes.NewFFBigInt(nonce - 1),
+				})
+			}
+			if nonce >= expected {
+				expected = nonce + 1
+			}
+			if mtx.Status == apitypes.TxStatusPending {
+				status.LastPendingNonce = mtx.Nonce
+			}
+		}
+		if len(txns) < nonceGapPageSize {
+			if len(status.NonceGaps) > 0 {
+				if status.NonceGaps, err = sth.excludeReservedNonces(ctx, signer, status.NonceGaps); err != nil {
+					return nil, err
+				}
+			}
+			sth.setUnderfundedStatus(status)
+			return status, nil
+		}
+		after = txns[len(txns)-1].Nonce
+	}
+}
+
+// excludeReservedNonces removes the nonces reserved for use outside of FFTM from the gaps. Each reservation is
+// above all the previous reservations for the signer, so we read them from the highest down, until we reach
+// one that is below the first gap.
+func (sth *simpleTransactionHandler) excludeReservedNonces(ctx context.Context, signer string, gaps []*apitypes.NonceGap) ([]*apitypes.NonceGap, error) {
+	lowest := gaps[0].First.Int64()
+	var after *fftypes.FFBigInt
+	for {
+		reservations, err := sth.toolkit.TXPersistence.ListNonceReservations(ctx, signer, after, nonceGapPageSize, 1 /* descending */)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range reservations {
+			if r.Last.Int64() < lowest {
+				return gaps, nil
+			}
+			gaps = subtractNonceRange(gaps, r.First.Int64(), r.Last.Int64())
+		}
+		if len(reservations) < nonceGapPageSize {
+			return gaps, nil
+		}
+		after = reservations[len(reservations)-1].First
+	}
+}
+
+// subtractNonceRange returns the gaps with the nonces from first to last (inclusive) removed, splitting any gap
+// that contains the range
+func subtractNonceRange(gaps []*apitypes.NonceGap, first, last int64) []*apitypes.NonceGap {
+	remaining := make([]*apitypes.NonceGap, 0, len(gaps))
+	for _, gap := range gaps {
+		if last < gap.First.Int64() || first > gap.Last.Int64() {
+			remaining = append(remaining, gap)
+			continue
+		}
+		if first > gap.First.Int64() {
+			remaining = append(remaining, &apitypes.NonceGap{First: gap.First, Last: fftypes.NewFFBigInt(first - 1)})
+		}
+		if last < gap.Last.Int64() {
+			remaining = append(remaining, &apitypes.NonceGap{First: fftypes.NewFFBigInt(last + 1), Last: gap.Last})
+		}
+	}
+	return remaining
+}
+
+// checkNonceGaps fills any gaps in the nonces of the signers of our in-flight transactions, at the configured interval
+func (sth *simpleTransactionHandler) checkNonceGaps(ctx context.Context) {
+	if !sth.nonceGapFill || time.Since(sth.lastNonceGapCheck) < sth.nonceGapCheckInterval {
+		return
+	}
+	sth.lastNonceGapCheck = time.Now()
+
+	signers := []string{}
+	namespaces := make(map[string]string)
+	for _, pending := range sth.inflight {
+		signer := pending.mtx.TransactionHeaders.From
+		if _, ok := namespaces[signer]; !ok {
+			signers = append(signers, signer)
+			namespaces[signer] = pending.mtx.Namespace(ctx)
+		}
+	}
+	for _, signer := range signers {
+		status, err := sth.GetSignerStatus(ctx, signer)
+		if err != nil {
+			log.L(ctx).Errorf("Failed to check for nonce gaps for signer %s: %s", signer, err)
+			continue
+		}
+		sth.fillNonceGaps(ctx, status, namespaces[signer])
+	}
+}
+
+// fillNonceGaps submits a zero value transfer from the signer to itself at each nonce in the gaps. We do not
+// track these transfers - if one is dropped by the node, the gap is found and filled again on a later check.
+func (sth *simpleTransactionHandler) fillNonceGaps(ctx context.Context, status *apitypes.SignerStatus, namespace string) {
+	filled := 0
+	for _, gap := range status.NonceGaps {
+		log.L(ctx).Warnf("Nonce gap for signer %s from %d to %d (nextNonce=%d)", status.Signer, gap.First.Int64(), gap.Last.Int64(), status.NextNonce.Int64())
+		for nonce := gap.First.Int64(); nonce <= gap.Last.Int64(); nonce++ {
+			if filled >= maxNonceGapFills {
+				log.L(ctx).Infof("Filled %d nonces for signer %s - remaining gaps will be filled on the next check", filled, status.Signer)
+				return
+			}
+			filled++
+			gasPrice, err := sth.getGasPrice(ctx, sth.toolkit.Connector)
+			if err != nil {
+				log.L(ctx).Errorf("Failed to get gas price to fill nonce %s / %d: %s", status.Signer, nonce, err)
+				return
+			}
+			gasPrice = sth.gasPriceEscalation.applyCeiling(ctx, gasPrice)
+			res, reason, err := sth.toolkit.Connector.TransactionSend(ctx, noopTransaction(status.Signer, fftypes.NewFFBigInt(nonce), gasPrice))
+			if err != nil {
+				log.L(ctx).Errorf("Failed to fill nonce %s / %d (reason=%s): %s", status.Signer, nonce, reason, err)
+				continue
+			}
+			log.L(ctx).Infof("Nonce %s / %d filled. Hash: %s", status.Signer, nonce, res.TransactionHash)
+			sth.incTransactionOperationCounter(ctx, namespace, "nonce_gap_filled")
+		}
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestNonceGapTX(signer string, nonce int64, status apitypes.TxStatus) *apitypes.ManagedTX {
+	return &apitypes.ManagedTX{
+		ID:      fmt.Sprintf("ns1:%s", fftypes.NewUUID()),
+		Created: fftypes.Now(),
+		Status:  status,
+		Nonce:   fftypes.NewFFBigInt(nonce),
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: signer,
+		},
+	}
+}
+
+func TestNonceGapConfig(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(NonceGapConfig).Set(NonceGapFill, true)
+	conf.SubSection(NonceGapConfig).Set(NonceGapCheckInterval, "5m")
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	assert.True(t, sth.nonceGapFill)
+	assert.Equal(t, 5*time.Minute, sth.nonceGapCheckInterval)
+}
+
+func TestGetSignerStatusNonceGaps(t *testing.T) {
+	f, tk, mfc, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.Init(context.Background(), tk)
+
+	mined := newTestNonceGapTX("0xaaaaa", 11, apitypes.TxStatusSucceeded)
+	mined.Receipt = &ffcapi.TransactionReceiptResponse{}
+	for _, mtx := range []*apitypes.ManagedTX{
+		newTestNonceGapTX("0xaaaaa", 9, apitypes.TxStatusSucceeded),
+		newTestNonceGapTX("0xaaaaa", 10, apitypes.TxStatusPending),
+		mined, // mined since we queried the node
+		newTestNonceGapTX("0xaaaaa", 13, apitypes.TxStatusFailed),
+		newTestNonceGapTX("0xaaaaa", 14, apitypes.TxStatusPending),
+		newTestNonceGapTX("0xaaaaa", 15, apitypes.TxStatusPending),
+		newTestNonceGapTX("0xaaaaa", 18, apitypes.TxStatusPending),
+		newTestNonceGapTX("0xaaaaa", 19, apitypes.TxStatusFailed),
+		newTestNonceGapTX("0xbbbbb", 16, apitypes.TxStatusPending),
+	} {
+		err := tk.TXPersistence.WriteTransaction(context.Background(), mtx, true)
+		assert.NoError(t, err)
+	}
+
+	mfc.On("NextNonceForSigner", mock.Anything, &ffcapi.NextNonceForSignerRequest{Signer: "0xaaaaa"}).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(10),
+	}, ffcapi.ErrorReason(""), nil)
+
+	status, err := sth.GetSignerStatus(context.Background(), "0xaaaaa")
+	assert.NoError(t, err)
+	assert.Equal(t, "0xaaaaa", status.Signer)
+	assert.Equal(t, int64(10), status.NextNonce.Int64())
+	assert.Equal(t, int64(18), status.LastPendingNonce.Int64())
+	assert.Len(t, status.NonceGaps, 2)
+	assert.Equal(t, int64(12), status.NonceGaps[0].First.Int64())
+	assert.Equal(t, int64(13), status.NonceGaps[0].Last.Int64())
+	assert.Equal(t, int64(16), status.NonceGaps[1].First.Int64())
+	assert.Equal(t, int64(17), status.NonceGaps[1].Last.Int64())
+
+	mfc.AssertExpectations(t)
+}
+
+func TestGetSignerStatusPaging(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.Init(context.Background(), tk)
+
+	page1 := make([]*apitypes.ManagedTX, nonceGapPageSize)
+	for i := range page1 {
+		page1[i] = newTestNonceGapTX("0xaaaaa", int64(i), apitypes.TxStatusPending)
+	}
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaaa", (*fftypes.FFBigInt)(nil), nonceGapPageSize, mock.Anything).Return(page1, nil).Once()
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaaa", page1[nonceGapPageSize-1].Nonce, nonceGapPageSize, mock.Anything).Return([]*apitypes.ManagedTX{
+		newTestNonceGapTX("0xaaaaa", int64(nonceGapPageSize+1), apitypes.TxStatusPending),
+	}, nil).Once()
+	mfc.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(0),
+	}, ffcapi.ErrorReason(""), nil)
+
+	status, err := sth.GetSignerStatus(context.Background(), "0xaaaaa")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(nonceGapPageSize+1), status.LastPendingNonce.Int64())
+	assert.Len(t, status.NonceGaps, 1)
+	assert.Equal(t, int64(nonceGapPageSize), status.NonceGaps[0].First.Int64())
+	assert.Equal(t, int64(nonceGapPageSize), status.NonceGaps[0].Last.Int64())
+
+	mp.AssertExpectations(t)
+}
+
+func TestGetSignerStatusExcludesReservedNonces(t *testing.T) {
+	f, tk, mfc, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.Init(context.Background(), tk)
+
+	for _, mtx := range []*apitypes.ManagedTX{
+		newTestNonceGapTX("0xaaaaa", 10, apitypes.TxStatusPending),
+		newTestNonceGapTX("0xaaaaa", 20, apitypes.TxStatusPending),
+	} {
+		err := tk.TXPersistence.WriteTransaction(context.Background(), mtx, true)
+		assert.NoError(t, err)
+	}
+	for _, r := range []*apitypes.NonceReservation{
+		{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(1), Last: fftypes.NewFFBigInt(5)},   // below the gaps
+		{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(11), Last: fftypes.NewFFBigInt(12)}, // start of the gap
+		{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(15), Last: fftypes.NewFFBigInt(15)}, // middle of the gap
+		{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(19), Last: fftypes.NewFFBigInt(19)}, // end of the gap
+		{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(30), Last: fftypes.NewFFBigInt(39)}, // above the gaps
+		{Signer: "0xbbbbb", First: fftypes.NewFFBigInt(13), Last: fftypes.NewFFBigInt(14)}, // another signer
+	} {
+		err := tk.TXPersistence.WriteNonceReservation(context.Background(), r)
+		assert.NoError(t, err)
+	}
+
+	mfc.On("NextNonceForSigner", mock.Anything, &ffcapi.NextNonceForSignerRequest{Signer: "0xaaaaa"}).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(10),
+	}, ffcapi.ErrorReason(""), nil)
+
+	status, err := sth.GetSignerStatus(context.Background(), "0xaaaaa")
+	assert.NoError(t, err)
+	assert.Len(t, status.NonceGaps, 2)
+	assert.Equal(t, int64(13), status.NonceGaps[0].First.Int64())
+	assert.Equal(t, int64(14), status.NonceGaps[0].Last.Int64())
+	assert.Equal(t, int64(16), status.NonceGaps[1].First.Int64())
+	assert.Equal(t, int64(18), status.NonceGaps[1].Last.Int64())
+
+	mfc.AssertExpectations(t)
+}
+
+func TestExcludeReservedNoncesPaging(t *testing.T) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	// A fresh persistence mock, without the default empty nonce reservations
+	mp := &persistencemocks.TransactionPersistence{}
+	tk.TXPersistence = mp
+	sth.Init(context.Background(), tk)
+
+	page1 := make([]*apitypes.NonceReservation, nonceGapPageSize)
+	for i := range page1 {
+		nonce := int64(1000 - i)
+		page1[i] = &apitypes.NonceReservation{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(nonce), Last: fftypes.NewFFBigInt(nonce)}
+	}
+	mp.On("ListNonceReservations", mock.Anything, "0xaaaaa", (*fftypes.FFBigInt)(nil), nonceGapPageSize, mock.Anything).Return(page1, nil).Once()
+	mp.On("ListNonceReservations", mock.Anything, "0xaaaaa", page1[nonceGapPageSize-1].First, nonceGapPageSize, mock.Anything).Return([]*apitypes.NonceReservation{
+		{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(20), Last: fftypes.NewFFBigInt(29)},
+		{Signer: "0xaaaaa", First: fftypes.NewFFBigInt(0), Last: fftypes.NewFFBigInt(9)},
+	}, nil).Once()
+
+	gaps, err := sth.excludeReservedNonces(context.Background(), "0xaaaaa", []*apitypes.NonceGap{
+		{First: fftypes.NewFFBigInt(10), Last: fftypes.NewFFBigInt(25)},
+		{First: fftypes.NewFFBigInt(999), Last: fftypes.NewFFBigInt(1001)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*apitypes.NonceGap{
+		{First: fftypes.NewFFBigInt(10), Last: fftypes.NewFFBigInt(19)},
+		{First: fftypes.NewFFBigInt(1001), Last: fftypes.NewFFBigInt(1001)},
+	}, gaps)
+
+	mp.AssertExpectations(t)
+}
+
+func TestExcludeReservedNoncesFail(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	// A fresh persistence mock, without the default empty nonce reservations
+	mp := &persistencemocks.TransactionPersistence{}
+	tk.TXPersistence = mp
+	sth.Init(context.Background(), tk)
+
+	mfc.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(10),
+	}, ffcapi.ErrorReason(""), nil)
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaaa", fftypes.NewFFBigInt(9), nonceGapPageSize, mock.Anything).Return([]*apitypes.ManagedTX{
+		newTestNonceGapTX("0xaaaaa", 12, apitypes.TxStatusPending),
+	}, nil)
+	mp.On("ListNonceReservations", mock.Anything, "0xaaaaa", (*fftypes.FFBigInt)(nil), nonceGapPageSize, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	_, err = sth.GetSignerStatus(context.Background(), "0xaaaaa")
+	assert.Regexp(t, "pop", err)
+}
+
+func TestGetSignerStatusNextNonceFail(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.Init(context.Background(), tk)
+
+	mfc.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop"))
+
+	_, err = sth.GetSignerStatus(context.Background(), "0xaaaaa")
+	assert.Regexp(t, "pop", err)
+}
+
+func TestGetSignerStatusListFail(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.Init(context.Background(), tk)
+
+	mfc.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(10),
+	}, ffcapi.ErrorReason(""), nil)
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaaa", fftypes.NewFFBigInt(9), nonceGapPageSize, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	_, err = sth.GetSignerStatus(context.Background(), "0xaaaaa")
+	assert.Regexp(t, "pop", err)
+}
+
+func TestCheckNonceGapsFill(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(GasPriceEscalationConfig).Set(GasPriceEscalationMaximumGasPrice, "10000")
+	conf.SubSection(NonceGapConfig).Set(NonceGapFill, true)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.Init(context.Background(), tk)
+
+	sth.inflight = []*pendingState{
+		{mtx: newTestNonceGapTX("0xaaaaa", 5, apitypes.TxStatusPending)},
+		{mtx: newTestNonceGapTX("0xbbbbb", 10, apitypes.TxStatusPending)},
+		{mtx: newTestNonceGapTX("0xbbbbb", 13, apitypes.TxStatusPending)},
+	}
+	mfc.On("NextNonceForSigner", mock.Anything, &ffcapi.NextNonceForSignerRequest{Signer: "0xaaaaa"}).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Once()
+	mfc.On("NextNonceForSigner", mock.Anything, &ffcapi.NextNonceForSignerRequest{Signer: "0xbbbbb"}).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(10),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xbbbbb", fftypes.NewFFBigInt(9), nonceGapPageSize, mock.Anything).Return([]*apitypes.ManagedTX{
+		sth.inflight[1].mtx,
+		sth.inflight[2].mtx,
+	}, nil).Once()
+	mfc.On("TransactionSend", mock.Anything, mock.MatchedBy(func(r *ffcapi.TransactionSendRequest) bool {
+		return r.From == "0xbbbbb" && r.To == "0xbbbbb" && r.Nonce.Int64() == 11 && r.Value.Int64() == 0 && r.GasPrice.String() == `"10000"`
+	})).Return(nil, ffcapi.ErrorReasonNonceTooLow, fmt.Errorf("pop")).Once()
+	mfc.On("TransactionSend", mock.Anything, mock.MatchedBy(func(r *ffcapi.TransactionSendRequest) bool {
+		return r.From == "0xbbbbb" && r.Nonce.Int64() == 12
+	})).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0x1111"}, ffcapi.ErrorReason(""), nil).Once()
+
+	sth.checkNonceGaps(context.Background())
+	// Not checked again until the interval has passed
+	sth.checkNonceGaps(context.Background())
+
+	mfc.AssertExpectations(t)
+	mp.AssertExpectations(t)
+}
+
+func TestFillNonceGapsLimit(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.Init(context.Background(), tk)
+
+	mfc.On("TransactionSend", mock.Anything, mock.Anything).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0x1111"}, ffcapi.ErrorReason(""), nil).Times(maxNonceGapFills)
+
+	sth.fillNonceGaps(context.Background(), &apitypes.SignerStatus{
+		Signer:    "0xaaaaa",
+		NextNonce: fftypes.NewFFBigInt(0),
+		NonceGaps: []*apitypes.NonceGap{
+			{First: fftypes.NewFFBigInt(0), Last: fftypes.NewFFBigInt(maxNonceGapFills * 2)},
+		},
+	}, "ns1")
+
+	mfc.AssertExpectations(t)
+}
+
+func TestFillNonceGapsGasPriceFail(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.SubSection(GasOracleConfig).Set(GasOracleMode, GasOracleModeConnector)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.Init(context.Background(), tk)
+
+	mfc.On("GasPriceEstimate", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Once()
+
+	sth.fillNonceGaps(context.Background(), &apitypes.SignerStatus{
+		Signer:    "0xaaaaa",
+		NextNonce: fftypes.NewFFBigInt(0),
+		NonceGaps: []*apitypes.NonceGap{
+			{First: fftypes.NewFFBigInt(0), Last: fftypes.NewFFBigInt(1)},
+		},
+	}, "ns1")
+
+	mfc.AssertExpectations(t)
+}
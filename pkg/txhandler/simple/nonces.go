@@ -20,11 +20,17 @@ import (
 	"context"
 	"time"
 
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs" // replace with your own messages if you are developing a customized transaction handler
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
 )
 
+// maxNonceReservation is the largest block of nonces that can be reserved for a signer in one request
+const maxNonceReservation = 1000
+
 type lockedNonce struct {
 	th       *simpleTransactionHandler
 	nsOpID   string
@@ -48,11 +54,31 @@ func (ln *lockedNonce) complete(ctx context.Context) {
 }
 
 func (sth *simpleTransactionHandler) assignAndLockNonce(ctx context.Context, nsOpID, signer string) (*lockedNonce, error) {
+	return sth.assignLockedNonce(ctx, sth.lockNonce(ctx, nsOpID, signer))
+}
+
+// assignLockedNonce calculates the next nonce for a signer whose nonce lock we hold
+func (sth *simpleTransactionHandler) assignLockedNonce(ctx context.Context, locked *lockedNonce) (*lockedNonce, error) {
+
+	signer := locked.signer
+	// We have to ensure we either successfully return a nonce,
+	// or otherwise we unlock when we send the error
+	nextNonce, err := sth.calcNextNonce(ctx, signer)
+	if err != nil {
+		locked.complete(ctx)
+		return nil, err
+	}
+	locked.nonce = nextNonce
+	return locked, nil
+
+}
+
+// lockNonce blocks until we hold the nonce lock for the signer. The caller must call complete on the returned lockedNonce
+func (sth *simpleTransactionHandler) lockNonce(ctx context.Context, nsOpID, signer string) *lockedNonce {
 
 	for {
 		// Take the lock to query our nonce cache, and check if we are already locked
 		sth.mux.Lock()
-		doLookup := false
 		locked, isLocked := sth.lockedNonces[signer]
 		if !isLocked {
 			locked = &lockedNonce{
@@ -62,29 +88,37 @@ func (sth *simpleTransactionHandler) assignAndLockNonce(ctx context.Context, nsO
 				unlocked: make(chan struct{}),
 			}
 			sth.lockedNonces[signer] = locked
-			doLookup = true
 		}
 		sth.mux.Unlock()
 
 		// If we're locked, then wait
-		if isLocked {
-			log.L(ctx).Debugf("Contention for next nonce for signer %s", signer)
-			<-locked.unlocked
-		} else if doLookup {
-			// We have to ensure we either successfully return a nonce,
-			// or otherwise we unlock when we send the error
-			nextNonce, err := sth.calcNextNonce(ctx, signer)
-			if err != nil {
-				locked.complete(ctx)
-				return nil, err
-			}
-			locked.nonce = nextNonce
-			return locked, nil
+		if !isLocked {
+			return locked
 		}
+		log.L(ctx).Debugf("Contention for next nonce for signer %s", signer)
+		<-locked.unlocked
 	}
 
 }
 
+// tryLockNonce takes the nonce lock for the signer if it is free, without blocking. It returns nil if the lock is held.
+// The caller must call complete on a returned lockedNonce
+func (sth *simpleTransactionHandler) tryLockNonce(nsOpID, signer string) *lockedNonce {
+	sth.mux.Lock()
+	defer sth.mux.Unlock()
+	if _, isLocked := sth.lockedNonces[signer]; isLocked {
+		return nil
+	}
+	locked := &lockedNonce{
+		th:       sth,
+		nsOpID:   nsOpID,
+		signer:   signer,
+		unlocked: make(chan struct{}),
+	}
+	sth.lockedNonces[signer] = locked
+	return locked
+}
+
 func (sth *simpleTransactionHandler) calcNextNonce(ctx context.Context, signer string) (uint64, error) {
 
 	// First we check our DB to find the last nonce we used for this address.
@@ -100,7 +134,7 @@ func (sth *simpleTransactionHandler) calcNextNonce(ctx context.Context, signer s
 		if time.Since(*lastTxn.Created.Time()) < sth.nonceStateTimeout {
 			nextNonce := lastTxn.Nonce.Uint64() + 1
 			log.L(ctx).Debugf("Allocating next nonce '%s' / '%d' after TX '%s' (status=%s)", signer, nextNonce, lastTxn.ID, lastTxn.Status)
-			return nextNonce, nil
+			return sth.applyMinimumNonce(ctx, signer, nextNonce)
 		}
 	}
 
@@ -121,6 +155,181 @@ func (sth *simpleTransactionHandler) calcNextNonce(ctx context.Context, signer s
 		nextNonce = lastTxn.Nonce.Uint64() + 1
 	}
 
+	return sth.applyMinimumNonce(ctx, signer, nextNonce)
+
+}
+
+// applyMinimumNonce ensures we never assign a nonce below the minimum for the signer, which is set when the
+// next nonce is reset from the node, or when a block of nonces is reserved for use outside of FFTM
+func (sth *simpleTransactionHandler) applyMinimumNonce(ctx context.Context, signer string, nextNonce uint64) (uint64, error) {
+	minimumNonce, err := sth.getMinimumNonce(ctx, signer)
+	if err != nil {
+		return 0, err
+	}
+	if nextNonce < minimumNonce {
+		log.L(ctx).Debugf("Next nonce '%s' / '%d' is below the minimum '%d'", signer, nextNonce, minimumNonce)
+		return minimumNonce, nil
+	}
 	return nextNonce, nil
+}
+
+// getMinimumNonce returns the minimum nonce for the signer. The first time a signer is used, this is loaded from
+// the persisted minimum and the last nonce reservation, so neither a reset nor the reserved nonces are lost on a restart.
+func (sth *simpleTransactionHandler) getMinimumNonce(ctx context.Context, signer string) (uint64, error) {
+	sth.mux.Lock()
+	minimumNonce, isLoaded := sth.minimumNonces[signer]
+	sth.mux.Unlock()
+	if isLoaded {
+		return minimumNonce, nil
+	}
+	persisted, err := sth.toolkit.TXPersistence.GetSignerMinimumNonce(ctx, signer)
+	if err != nil {
+		return 0, err
+	}
+	if persisted != nil {
+		minimumNonce = persisted.Nonce.Uint64()
+	}
+	// The reservation is written before the minimum, so we check it in case we stopped between the two
+	reservations, err := sth.toolkit.TXPersistence.ListNonceReservations(ctx, signer, nil, 1, 1 /* descending */)
+	if err != nil {
+		return 0, err
+	}
+	if len(reservations) > 0 && reservations[0].Last.Uint64() >= minimumNonce {
+		minimumNonce = reservations[0].Last.Uint64() + 1
+	}
+	sth.mux.Lock()
+	defer sth.mux.Unlock()
+	if current, isLoaded := sth.minimumNonces[signer]; !isLoaded || minimumNonce > current {
+		sth.minimumNonces[signer] = minimumNonce
+	}
+	return sth.minimumNonces[signer], nil
+}
+
+// raiseMinimumNonce increases the minimum nonce for the signer - it is never lowered. The caller must have
+// loaded the minimum nonce for the signer with getMinimumNonce first.
+func (sth *simpleTransactionHandler) raiseMinimumNonce(signer string, minimumNonce uint64) {
+	sth.mux.Lock()
+	defer sth.mux.Unlock()
+	if minimumNonce > sth.minimumNonces[signer] {
+		sth.minimumNonces[signer] = minimumNonce
+	}
+}
+
+// persistMinimumNonce writes a raised minimum nonce for the signer to persistence, before raising it in memory.
+// The caller must hold the nonce lock for the signer.
+func (sth *simpleTransactionHandler) persistMinimumNonce(ctx context.Context, signer string, minimumNonce uint64) error {
+	if err := sth.toolkit.TXPersistence.WriteSignerMinimumNonce(ctx, &apitypes.SignerMinimumNonce{
+		Signer:  signer,
+		Nonce:   fftypes.NewFFBigInt(int64(minimumNonce)),
+		Updated: fftypes.Now(),
+	}); err != nil {
+		return err
+	}
+	sth.raiseMinimumNonce(signer, minimumNonce)
+	return nil
+}
+
+// GetSignerNonceStatus returns the next nonce for a signer from each source, along with the nonce we would assign now
+func (sth *simpleTransactionHandler) GetSignerNonceStatus(ctx context.Context, signer string) (*apitypes.SignerNonceStatus, error) {
+	status := &apitypes.SignerNonceStatus{
+		Signer: signer,
+	}
+	sth.mux.Lock()
+	if locked, isLocked := sth.lockedNonces[signer]; isLocked {
+		status.Locked = true
+		status.LockedBy = locked.nsOpID
+	}
+	sth.mux.Unlock()
+	minimumNonce, err := sth.getMinimumNonce(ctx, signer)
+	if err != nil {
+		return nil, err
+	}
+	if minimumNonce > 0 {
+		status.MinimumNonce = fftypes.NewFFBigInt(int64(minimumNonce))
+	}
+
+	txns, err := sth.toolkit.TXPersistence.ListTransactionsByNonce(ctx, signer, nil, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(txns) > 0 {
+		status.PersistedNextNonce = fftypes.NewFFBigInt(txns[0].Nonce.Int64() + 1)
+		status.PersistedStale = time.Since(*txns[0].Created.Time()) >= sth.nonceStateTimeout
+	}
+	nextNonceRes, _, err := sth.toolkit.Connector.NextNonceForSigner(ctx, &ffcapi.NextNonceForSignerRequest{
+		Signer: signer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	status.NodeNextNonce = nextNonceRes.Nonce
+	// This is the answer we would use if we assigned a nonce now
+	nextNonce, err := sth.calcNextNonce(ctx, signer)
+	if err != nil {
+		return nil, err
+	}
+	status.NextNonce = fftypes.NewFFBigInt(int64(nextNonce))
+	return status, nil
+}
+
+// HandleResetSignerNonce sets the minimum nonce for the signer to the next nonce according to the node, so that we
+// do not re-use nonces that were used outside of FFTM while we have recent transactions in persistence. We never go
+// backwards from the nonces of the transactions in persistence - any gaps are handled separately. The minimum is
+// persisted, so it is kept after a restart.
+func (sth *simpleTransactionHandler) HandleResetSignerNonce(ctx context.Context, signer string) (*apitypes.SignerNonceStatus, error) {
+	if err := sth.resetMinimumNonce(ctx, signer); err != nil {
+		return nil, err
+	}
+	return sth.GetSignerNonceStatus(ctx, signer)
+}
+
+func (sth *simpleTransactionHandler) resetMinimumNonce(ctx context.Context, signer string) error {
+	locked := sth.lockNonce(ctx, "", signer)
+	defer locked.complete(ctx)
 
+	minimumNonce, err := sth.getMinimumNonce(ctx, signer)
+	if err != nil {
+		return err
+	}
+	nextNonceRes, _, err := sth.toolkit.Connector.NextNonceForSigner(ctx, &ffcapi.NextNonceForSignerRequest{
+		Signer: signer,
+	})
+	if err != nil {
+		return err
+	}
+	log.L(ctx).Infof("Reset next nonce for signer %s from node: %d", signer, nextNonceRes.Nonce.Int64())
+	if nextNonceRes.Nonce.Uint64() <= minimumNonce {
+		return nil
+	}
+	return sth.persistMinimumNonce(ctx, signer, nextNonceRes.Nonce.Uint64())
+}
+
+// HandleReserveSignerNonces assigns a block of nonces that will not be used by any transaction, by raising the
+// minimum nonce for the signer above them. The reservation and the new minimum are persisted, so the nonces are not
+// assigned after a restart, and are not reported or filled as nonce gaps.
+func (sth *simpleTransactionHandler) HandleReserveSignerNonces(ctx context.Context, signer string, req *apitypes.NonceReservationRequest) (*apitypes.NonceReservation, error) {
+	if req.Count < 1 || req.Count > maxNonceReservation {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidNonceReservation, req.Count, maxNonceReservation)
+	}
+	locked, err := sth.assignAndLockNonce(ctx, "", signer)
+	if err != nil {
+		return nil, err
+	}
+	defer locked.complete(ctx)
+
+	first := locked.nonce
+	last := first + uint64(req.Count) - 1
+	reservation := &apitypes.NonceReservation{
+		Signer: signer,
+		First:  fftypes.NewFFBigInt(int64(first)),
+		Last:   fftypes.NewFFBigInt(int64(last)),
+	}
+	if err := sth.toolkit.TXPersistence.WriteNonceReservation(ctx, reservation); err != nil {
+		return nil, err
+	}
+	if err := sth.persistMinimumNonce(ctx, signer, last+1); err != nil {
+		return nil, err
+	}
+	log.L(ctx).Infof("Reserved nonces %d to %d for signer %s", first, last, signer)
+	return reservation, nil
 }
@@ -204,3 +204,274 @@ func TestNonceListNotStale(t *testing.T) {
 	assert.Equal(t, uint64(1001), n)
 
 }
+
+func TestSignerNonceResetAndReserve(t *testing.T) {
+
+	f, tk, mFFC, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	// A recent transaction, so the node is not normally queried
+	err = tk.TXPersistence.WriteTransaction(sth.ctx, &apitypes.ManagedTX{
+		ID:      "tx1",
+		Created: fftypes.Now(),
+		Status:  apitypes.TxStatusPending,
+		Nonce:   fftypes.NewFFBigInt(10),
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0x12345",
+		},
+	}, true)
+	assert.NoError(t, err)
+
+	mFFC.On("NextNonceForSigner", mock.Anything, &ffcapi.NextNonceForSignerRequest{Signer: "0x12345"}).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(20),
+	}, ffcapi.ErrorReason(""), nil).Times(3)
+
+	status, err := sth.GetSignerNonceStatus(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), status.NextNonce.Int64())
+	assert.Equal(t, int64(11), status.PersistedNextNonce.Int64())
+	assert.False(t, status.PersistedStale)
+	assert.Equal(t, int64(20), status.NodeNextNonce.Int64())
+	assert.Nil(t, status.MinimumNonce)
+	assert.False(t, status.Locked)
+
+	// Reset from the node, which is ahead of persistence
+	status, err = sth.HandleResetSignerNonce(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), status.NextNonce.Int64())
+	assert.Equal(t, int64(20), status.MinimumNonce.Int64())
+
+	// Reserve a block of nonces after that
+	reservation, err := sth.HandleReserveSignerNonces(sth.ctx, "0x12345", &apitypes.NonceReservationRequest{Count: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, "0x12345", reservation.Signer)
+	assert.Equal(t, int64(20), reservation.First.Int64())
+	assert.Equal(t, int64(24), reservation.Last.Int64())
+	n, err := sth.calcNextNonce(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(25), n)
+
+	// A reset never lowers the minimum
+	mFFC.On("NextNonceForSigner", mock.Anything, &ffcapi.NextNonceForSignerRequest{Signer: "0x12345"}).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(22),
+	}, ffcapi.ErrorReason(""), nil).Times(3)
+	status, err = sth.HandleResetSignerNonce(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25), status.NextNonce.Int64())
+	assert.Equal(t, int64(22), status.NodeNextNonce.Int64())
+
+	// The minimum from the reservation is loaded from persistence after a restart
+	restart := func() {
+		th, err = f.NewTransactionHandler(context.Background(), conf)
+		assert.NoError(t, err)
+		sth = th.(*simpleTransactionHandler)
+		sth.ctx = context.Background()
+		sth.Init(sth.ctx, tk)
+	}
+	restart()
+	n, err = sth.calcNextNonce(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(25), n)
+	status, err = sth.GetSignerNonceStatus(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25), status.MinimumNonce.Int64())
+
+	// As is the minimum from a reset
+	mFFC.On("NextNonceForSigner", mock.Anything, &ffcapi.NextNonceForSignerRequest{Signer: "0x12345"}).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(30),
+	}, ffcapi.ErrorReason(""), nil)
+	err = sth.resetMinimumNonce(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	restart()
+	n, err = sth.calcNextNonce(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(30), n)
+
+	mFFC.AssertExpectations(t)
+
+}
+
+func TestMinimumNonceReservationAheadOfPersistedMinimum(t *testing.T) {
+
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	// A fresh persistence mock, without the default empty nonce reservations
+	mp := &persistencemocks.TransactionPersistence{}
+	tk.TXPersistence = mp
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	// We stopped after writing the reservation, but before writing the new minimum
+	mp.On("GetSignerMinimumNonce", mock.Anything, "0x12345").Return(&apitypes.SignerMinimumNonce{
+		Signer: "0x12345",
+		Nonce:  fftypes.NewFFBigInt(20),
+	}, nil)
+	mp.On("ListNonceReservations", mock.Anything, "0x12345", (*fftypes.FFBigInt)(nil), 1, mock.Anything).Return([]*apitypes.NonceReservation{
+		{Signer: "0x12345", First: fftypes.NewFFBigInt(20), Last: fftypes.NewFFBigInt(24)},
+	}, nil)
+
+	minimumNonce, err := sth.getMinimumNonce(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(25), minimumNonce)
+
+	mp.AssertExpectations(t)
+
+}
+
+func TestSignerNonceStatusLocked(t *testing.T) {
+
+	f, tk, mFFC, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	old := fftypes.FFTime(time.Now().Add(-10000 * time.Hour))
+	mp.On("ListTransactionsByNonce", mock.Anything, "0x12345", (*fftypes.FFBigInt)(nil), 1, mock.Anything).
+		Return([]*apitypes.ManagedTX{
+			{ID: "id12345", Created: &old, Status: apitypes.TxStatusSucceeded, Nonce: fftypes.NewFFBigInt(1000)},
+		}, nil)
+	mFFC.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(1005),
+	}, ffcapi.ErrorReason(""), nil)
+
+	locked := sth.lockNonce(sth.ctx, "ns1:tx1", "0x12345")
+	defer locked.complete(sth.ctx)
+
+	status, err := sth.GetSignerNonceStatus(sth.ctx, "0x12345")
+	assert.NoError(t, err)
+	assert.True(t, status.Locked)
+	assert.Equal(t, "ns1:tx1", status.LockedBy)
+	assert.True(t, status.PersistedStale)
+	assert.Equal(t, int64(1005), status.NextNonce.Int64())
+
+}
+
+func TestSignerNonceStatusErrors(t *testing.T) {
+
+	f, tk, mFFC, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("ListTransactionsByNonce", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop")).Once()
+	mp.On("ListTransactionsByNonce", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*apitypes.ManagedTX{}, nil)
+	mFFC.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Once()
+	mFFC.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(1005),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	mFFC.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Twice()
+
+	// Persistence fails
+	_, err = sth.GetSignerNonceStatus(sth.ctx, "0x12345")
+	assert.Regexp(t, "pop", err)
+	// Node fails
+	_, err = sth.GetSignerNonceStatus(sth.ctx, "0x12345")
+	assert.Regexp(t, "pop", err)
+	// Node fails calculating the next nonce
+	_, err = sth.GetSignerNonceStatus(sth.ctx, "0x12345")
+	assert.Regexp(t, "pop", err)
+	// Node fails on reset
+	_, err = sth.HandleResetSignerNonce(sth.ctx, "0x12345")
+	assert.Regexp(t, "pop", err)
+	assert.Empty(t, sth.lockedNonces)
+
+	mp.AssertExpectations(t)
+	mFFC.AssertExpectations(t)
+
+}
+
+func TestReserveSignerNoncesErrors(t *testing.T) {
+
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	_, err = sth.HandleReserveSignerNonces(sth.ctx, "0x12345", &apitypes.NonceReservationRequest{})
+	assert.Regexp(t, "FF21097", err)
+	_, err = sth.HandleReserveSignerNonces(sth.ctx, "0x12345", &apitypes.NonceReservationRequest{Count: maxNonceReservation + 1})
+	assert.Regexp(t, "FF21097", err)
+
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("ListTransactionsByNonce", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+	_, err = sth.HandleReserveSignerNonces(sth.ctx, "0x12345", &apitypes.NonceReservationRequest{Count: 1})
+	assert.Regexp(t, "pop", err)
+
+}
+
+func TestNonceReservationPersistenceErrors(t *testing.T) {
+
+	f, tk, mFFC, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	// A fresh persistence mock, without the default empty nonce reservations
+	mp := &persistencemocks.TransactionPersistence{}
+	tk.TXPersistence = mp
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mp.On("ListTransactionsByNonce", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*apitypes.ManagedTX{}, nil)
+	mp.On("GetSignerMinimumNonce", mock.Anything, "0x12345").Return(nil, fmt.Errorf("pop")).Once()
+	mp.On("GetSignerMinimumNonce", mock.Anything, "0x12345").Return(nil, nil)
+	mp.On("ListNonceReservations", mock.Anything, "0x12345", (*fftypes.FFBigInt)(nil), 1, mock.Anything).Return(nil, fmt.Errorf("pop")).Twice()
+	mp.On("ListNonceReservations", mock.Anything, "0x12345", (*fftypes.FFBigInt)(nil), 1, mock.Anything).Return([]*apitypes.NonceReservation{}, nil)
+	mp.On("WriteNonceReservation", mock.Anything, mock.Anything).Return(fmt.Errorf("pop")).Once()
+	mp.On("WriteNonceReservation", mock.Anything, mock.Anything).Return(nil)
+	mp.On("WriteSignerMinimumNonce", mock.Anything, mock.Anything).Return(fmt.Errorf("pop"))
+	mFFC.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(1005),
+	}, ffcapi.ErrorReason(""), nil)
+
+	// Loading the persisted minimum fails
+	_, err = sth.calcNextNonce(sth.ctx, "0x12345")
+	assert.Regexp(t, "pop", err)
+	// Loading the reservations fails
+	_, err = sth.GetSignerNonceStatus(sth.ctx, "0x12345")
+	assert.Regexp(t, "pop", err)
+	_, err = sth.HandleResetSignerNonce(sth.ctx, "0x12345")
+	assert.Regexp(t, "pop", err)
+
+	// Persisting the reset minimum fails, so the minimum is not raised
+	_, err = sth.HandleResetSignerNonce(sth.ctx, "0x12345")
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, uint64(0), sth.minimumNonces["0x12345"])
+
+	// Persisting the reservation or the new minimum fails, so the minimum is not raised
+	for i := 0; i < 2; i++ {
+		_, err = sth.HandleReserveSignerNonces(sth.ctx, "0x12345", &apitypes.NonceReservationRequest{Count: 1})
+		assert.Regexp(t, "pop", err)
+		assert.Equal(t, uint64(0), sth.minimumNonces["0x12345"])
+	}
+	assert.Empty(t, sth.lockedNonces)
+
+	mp.AssertExpectations(t)
+
+}
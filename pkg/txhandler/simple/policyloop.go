@@ -18,6 +18,7 @@ package simple
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -35,15 +36,27 @@ const metricsGaugeTransactionsInflightUsedDescription = "Number of transactions
 const metricsGaugeTransactionsInflightFree = "tx_in_flight_free_total"
 const metricsGaugeTransactionsInflightFreeDescription = "Number of transactions left in the in flight queue"
 
+const metricsGaugeTransactionsInflightSigner = "tx_in_flight_signer_total"
+const metricsGaugeTransactionsInflightSignerDescription = "Number of transactions currently in flight grouped by signer"
+
+const metricsGaugeTransactionsQueuedSigner = "tx_queued_signer_total"
+const metricsGaugeTransactionsQueuedSignerDescription = "Number of pending transactions waiting to be in flight grouped by signer"
+
+const metricsGaugeSignerUnderfunded = "tx_signer_underfunded"
+const metricsGaugeSignerUnderfundedDescription = "Whether submission of transactions is paused for a signer, as it has insufficient funds, grouped by signer"
+
 type policyEngineAPIRequestType int
 
 const (
 	policyEngineAPIRequestTypeDelete policyEngineAPIRequestType = iota
+	policyEngineAPIRequestTypeCancel
+	policyEngineAPIRequestTypeResubmit
 )
 
 type policyEngineAPIRequest struct {
 	requestType policyEngineAPIRequestType
 	txID        string
+	resubmit    *apitypes.ResubmitTransactionRequest
 	startTime   time.Time
 	response    chan policyEngineAPIResponse
 }
@@ -112,35 +125,91 @@ func (sth *simpleTransactionHandler) updateInflightSet(ctx context.Context) bool
 		}
 	}
 
-	// If we are not at maximum, then query if there are more candidates now
-	spaces := sth.maxInFlight - len(sth.inflight)
-	if spaces > 0 {
-		var after string
-		if len(sth.inflight) > 0 {
-			after = sth.inflight[len(sth.inflight)-1].mtx.SequenceID
+	// If we are not at maximum, or a scheduled transaction left out of the in-flight set is nearly due,
+	// then query if there are more candidates now
+	spaces := sth.inflightSpaces()
+	rescan := sth.scheduledRefreshDue()
+	var additional []*apitypes.ManagedTX
+	var queued map[string]int
+	ok := true
+	if sth.maxInFlightPerSigner > 0 || sth.priorityEnabled {
+		additional, queued, ok = sth.selectPendingBySigner(ctx, spaces, rescan)
+	} else if spaces > 0 || rescan {
+		additional, ok = sth.selectPending(ctx, spaces, rescan)
+	}
+	if !ok {
+		log.L(ctx).Infof("Policy loop context cancelled while retrying")
+		return false
+	}
+	for _, mtx := range additional {
+		sth.incTransactionOperationCounter(ctx, mtx.Namespace(ctx), "polled")
+		sth.inflight = append(sth.inflight, &pendingState{mtx: mtx})
+	}
+	if len(additional) > 0 {
+		log.L(ctx).Debugf("Inflight set updated len=%d head-seq=%s tail-seq=%s added=%d", len(sth.inflight), sth.inflight[0].mtx.SequenceID, sth.inflight[len(sth.inflight)-1].mtx.SequenceID, len(additional))
+	}
+	sth.setTransactionInflightQueueMetrics(ctx)
+	sth.setSignerQueueMetrics(ctx, queued)
+	return true
+
+}
+
+// selectPending returns the next pending transactions after the tail of the in-flight set, in submission order.
+// We read on past any scheduled transactions that have not yet been assigned a nonce, as they do not take up a space.
+// Those that are not nearly due are left out, so when one becomes nearly due we rescan from the start to find it.
+// A rescan reads all the pending transactions, even once the spaces are filled, so no scheduled transaction is missed.
+func (sth *simpleTransactionHandler) selectPending(ctx context.Context, spaces int, rescan bool) ([]*apitypes.ManagedTX, bool) {
+	var after string
+	inflightIDs := make(map[string]bool)
+	for _, p := range sth.inflight {
+		if rescan {
+			inflightIDs[p.mtx.ID] = true
+		} else if p.mtx.SequenceID > after {
+			after = p.mtx.SequenceID
 		}
-		var additional []*apitypes.ManagedTX
-		// We retry the get from persistence indefinitely (until the context cancels)
-		err := sth.retry.Do(ctx, "get pending transactions", func(attempt int) (retry bool, err error) {
-			additional, err = sth.toolkit.TXPersistence.ListTransactionsPending(ctx, after, spaces, 0)
-			return true, err
-		})
+	}
+	if rescan {
+		sth.scheduledRefresh = time.Time{}
+	}
+	var additional []*apitypes.ManagedTX
+	for spaces > 0 || rescan {
+		limit := spaces
+		if rescan {
+			limit = sth.maxInFlight
+		}
+		page, err := sth.listPending(ctx, after, limit)
 		if err != nil {
-			log.L(ctx).Infof("Policy loop context cancelled while retrying")
-			return false
+			return nil, false
 		}
-		for _, mtx := range additional {
-			sth.incTransactionOperationCounter(ctx, mtx.Namespace(ctx), "polled")
-			sth.inflight = append(sth.inflight, &pendingState{mtx: mtx})
+		for _, mtx := range page {
+			switch {
+			case inflightIDs[mtx.ID]:
+			case !awaitingNonce(mtx):
+				if spaces > 0 {
+					additional = append(additional, mtx)
+					spaces--
+				}
+			case sth.nearlyDue(mtx):
+				additional = append(additional, mtx)
+			default:
+				sth.deferScheduled(mtx)
+			}
 		}
-		newLen := len(sth.inflight)
-		if newLen > 0 {
-			log.L(ctx).Debugf("Inflight set updated len=%d head-seq=%s tail-seq=%s old-tail=%s", len(sth.inflight), sth.inflight[0].mtx.SequenceID, sth.inflight[newLen-1].mtx.SequenceID, after)
+		if len(page) < limit {
+			break
 		}
+		after = page[len(page)-1].SequenceID
 	}
-	sth.setTransactionInflightQueueMetrics(ctx)
-	return true
+	return additional, true
+}
 
+// listPending reads a page of pending transactions, retrying indefinitely (until the context cancels)
+func (sth *simpleTransactionHandler) listPending(ctx context.Context, after string, limit int) (txns []*apitypes.ManagedTX, err error) {
+	err = sth.retry.Do(ctx, "get pending transactions", func(attempt int) (retry bool, err error) {
+		txns, err = sth.toolkit.TXPersistence.ListTransactionsPending(ctx, after, limit, 0)
+		return true, err
+	})
+	return txns, err
 }
 
 func (sth *simpleTransactionHandler) policyLoopCycle(ctx context.Context, inflightStale bool) {
@@ -148,7 +217,7 @@ func (sth *simpleTransactionHandler) policyLoopCycle(ctx context.Context, inflig
 	// Process any synchronous commands first - these might not be in our inflight set
 	sth.processPolicyAPIRequests(ctx)
 
-	if inflightStale {
+	if inflightStale || sth.scheduledRefreshDue() {
 		if !sth.updateInflightSet(ctx) {
 			return
 		}
@@ -162,6 +231,12 @@ func (sth *simpleTransactionHandler) policyLoopCycle(ctx context.Context, inflig
 		}
 	}
 
+	// Check whether any of the signers are stuck behind a gap in their nonces
+	sth.checkNonceGaps(ctx)
+
+	// Check whether any signers paused for insufficient funds have been topped up
+	sth.checkUnderfundedSigners(ctx)
+
 }
 
 func (sth *simpleTransactionHandler) getTransactionByID(ctx context.Context, txID string) (transaction *apitypes.ManagedTX, err error) {
@@ -216,6 +291,18 @@ func (sth *simpleTransactionHandler) processPolicyAPIRequests(ctx context.Contex
 				}
 				request.response <- res
 			}
+		case policyEngineAPIRequestTypeCancel:
+			if err := sth.requestCancellation(ctx, pending); err != nil {
+				request.response <- policyEngineAPIResponse{err: err}
+			} else {
+				request.response <- policyEngineAPIResponse{tx: pending.mtx, status: http.StatusAccepted}
+			}
+		case policyEngineAPIRequestTypeResubmit:
+			if err := sth.requestResubmit(ctx, pending, request.resubmit); err != nil {
+				request.response <- policyEngineAPIResponse{err: err}
+			} else {
+				request.response <- policyEngineAPIResponse{tx: pending.mtx, status: http.StatusOK}
+			}
 		default:
 			request.response <- policyEngineAPIResponse{
 				err: i18n.NewError(ctx, tmmsgs.MsgTransactionHandlerRequestInvalid, request.requestType),
@@ -225,10 +312,132 @@ func (sth *simpleTransactionHandler) processPolicyAPIRequests(ctx context.Contex
 
 }
 
+// requestCancellation marks a transaction to be cancelled by the policy engine on its next cycle
+func (sth *simpleTransactionHandler) requestCancellation(ctx context.Context, pending *pendingState) error {
+	if pending.mtx.PreSigned {
+		// We cannot sign a replacement at the same nonce for a transaction signed outside of the connector
+		return i18n.NewError(ctx, tmmsgs.MsgTXPreSignedNotReplaceable, pending.mtx.ID)
+	}
+	sth.mux.Lock()
+	mtx := pending.mtx
+	cancellable := mtx.Status == apitypes.TxStatusPending && mtx.Receipt == nil && mtx.DeleteRequested == nil
+	if cancellable && mtx.CancelRequested == nil {
+		mtx.CancelRequested = fftypes.Now()
+	}
+	sth.mux.Unlock()
+	if !cancellable {
+		return i18n.NewError(ctx, tmmsgs.MsgTXNotCancellable, mtx.ID)
+	}
+	if err := sth.toolkit.TXPersistence.WriteTransaction(ctx, mtx, false); err != nil {
+		return err
+	}
+	// We do not wait for the policy loop interval before submitting the cancellation
+	pending.lastPolicyCycle = time.Time{}
+	sth.markInflightUpdate()
+	return nil
+}
+
+// requestResubmit validates and performs a resubmission requested through the API, synchronously on the policy loop
+func (sth *simpleTransactionHandler) requestResubmit(ctx context.Context, pending *pendingState, req *apitypes.ResubmitTransactionRequest) error {
+	if req == nil {
+		req = &apitypes.ResubmitTransactionRequest{}
+	}
+	if req.GasPrice != nil && req.BumpPercentage != nil {
+		return i18n.NewError(ctx, tmmsgs.MsgResubmitGasPriceAndBump)
+	}
+	if req.BumpPercentage != nil && *req.BumpPercentage < 0 {
+		return i18n.NewError(ctx, tmmsgs.MsgResubmitInvalidBump, *req.BumpPercentage)
+	}
+	if pending.mtx.PreSigned && (req.GasPrice != nil || req.BumpPercentage != nil) {
+		return i18n.NewError(ctx, tmmsgs.MsgTXPreSignedNotReplaceable, pending.mtx.ID)
+	}
+	sth.mux.Lock()
+	mtx := pending.mtx
+	resubmittable := mtx.Status == apitypes.TxStatusPending && mtx.FirstSubmit != nil && mtx.Receipt == nil &&
+		mtx.DeleteRequested == nil && mtx.CancelRequested == nil
+	sth.mux.Unlock()
+	if !resubmittable {
+		return i18n.NewError(ctx, tmmsgs.MsgTXNotResubmittable, mtx.ID)
+	}
+	pending.resubmitRequest = req
+	return sth.execPolicy(ctx, pending, false)
+}
+
+// trackCancellation adds the latest replacement submitted to cancel the transaction to the confirmation manager.
+// The transaction itself remains tracked, as it could still be mined instead of the replacement.
+func (sth *simpleTransactionHandler) trackCancellation(ctx context.Context, pending *pendingState) {
+	mtx := pending.mtx
+	cancellationHash := mtx.CancellationHashes[len(mtx.CancellationHashes)-1]
+	if pending.trackingCancellation != "" {
+		if err := sth.notifyTransactionHash(ctx, apitypes.ManagedTXTransactionHashRemoved, mtx, pending.trackingCancellation); err != nil {
+			log.L(ctx).Infof("Error detected notifying confirmation manager to remove old cancellation hash: %s", err.Error())
+		}
+	}
+	if err := sth.notifyTransactionHash(ctx, apitypes.ManagedTXTransactionHashAdded, mtx, cancellationHash); err != nil {
+		log.L(ctx).Infof("Error detected notifying confirmation manager to add cancellation hash: %s", err.Error())
+		sth.incTransactionOperationCounter(ctx, mtx.Namespace(ctx), "tracking_failed")
+		return
+	}
+	pending.trackingCancellation = cancellationHash
+	sth.incTransactionOperationCounter(ctx, mtx.Namespace(ctx), "tracking")
+}
+
+// resolveCancellation determines whether it was the transaction, or a replacement submitted to cancel it, that
+// was mined. The receipt could be for either, so we check whether the connector has a receipt for a replacement.
+func (sth *simpleTransactionHandler) resolveCancellation(ctx context.Context, pending *pendingState) (cancelled bool, err error) {
+	mtx := pending.mtx
+	minedHash := mtx.TransactionHash
+	for i := len(mtx.CancellationHashes) - 1; i >= 0 && !cancelled; i-- {
+		_, reason, err := sth.toolkit.Connector.TransactionReceipt(ctx, &ffcapi.TransactionReceiptRequest{
+			TransactionHash: mtx.CancellationHashes[i],
+		})
+		if err != nil && reason != ffcapi.ErrorReasonNotFound {
+			// We will try again on the next cycle of the policy loop
+			return false, err
+		}
+		if err == nil {
+			cancelled = true
+			minedHash = mtx.CancellationHashes[i]
+		}
+	}
+
+	// Whichever was not mined no longer needs to be tracked
+	untrackHash := pending.trackingCancellation
+	if cancelled {
+		untrackHash = pending.trackingTransactionHash
+		if cancelledAfterDeadline(mtx) {
+			mtx.ErrorMessage = i18n.NewError(ctx, tmmsgs.MsgTXDeadlineExceeded, mtx.Deadline).Error()
+		} else {
+			mtx.ErrorMessage = i18n.NewError(ctx, tmmsgs.MsgTXCancelled, minedHash).Error()
+		}
+		log.L(ctx).Infof("Transaction %s at nonce %s / %d was cancelled by replacement %s", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64(), minedHash)
+	} else {
+		log.L(ctx).Infof("Transaction %s at nonce %s / %d was mined before it could be cancelled", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64())
+	}
+	sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionResolveCancellation, fftypes.JSONAnyPtr(fmt.Sprintf(`{"cancelled":%t,"transactionHash":"%s"}`, cancelled, minedHash)), nil)
+	if untrackHash != "" {
+		if err := sth.notifyTransactionHash(ctx, apitypes.ManagedTXTransactionHashRemoved, mtx, untrackHash); err != nil {
+			log.L(ctx).Infof("Error detected notifying confirmation manager to remove transaction hash: %s", err.Error())
+		}
+	}
+	return cancelled, nil
+}
+
+// notifyTransactionHash emits an event to add or remove tracking of a hash submitted at the nonce of the transaction
+func (sth *simpleTransactionHandler) notifyTransactionHash(ctx context.Context, eventType apitypes.ManagedTransactionEventType, mtx *apitypes.ManagedTX, hash string) error {
+	tx := *mtx
+	tx.TransactionHash = hash
+	return sth.toolkit.EventHandler.HandleEvent(ctx, apitypes.ManagedTransactionEvent{
+		Type: eventType,
+		Tx:   &tx,
+	})
+}
+
 func (sth *simpleTransactionHandler) execPolicy(ctx context.Context, pending *pendingState, syncDeleteRequest bool) (err error) {
 
 	update := UpdateNo
 	completed := false
+	var resubmitErr error
 	var receiptProtocolID string
 	var lastStatusChange *fftypes.FFTime
 	currentSubStatus := sth.toolkit.TXHistory.CurrentSubStatus(ctx, pending.mtx)
@@ -255,9 +464,17 @@ func (sth *simpleTransactionHandler) execPolicy(ctx context.Context, pending *pe
 	var updateReason ffcapi.ErrorReason
 	switch {
 	case receiptProtocolID != "" && confirmed && !syncDeleteRequest:
+		cancelled := false
+		if mtx.CancelRequested != nil {
+			if cancelled, err = sth.resolveCancellation(ctx, pending); err != nil {
+				return err
+			}
+		}
 		update = UpdateYes
 		completed = true
-		if pending.mtx.Receipt.Success {
+		if cancelled {
+			mtx.Status = apitypes.TxStatusFailed
+		} else if pending.mtx.Receipt.Success {
 			mtx.Status = apitypes.TxStatusSucceeded
 		} else {
 			mtx.Status = apitypes.TxStatusFailed
@@ -268,46 +485,33 @@ func (sth *simpleTransactionHandler) execPolicy(ctx context.Context, pending *pe
 		// to drive the policy engine at regular intervals.
 		// So we track the last time we ran the policy engine against each pending item.
 		// We always call the policy engine on every loop, when deletion has been requested.
-		if syncDeleteRequest || time.Since(pending.lastPolicyCycle) > sth.policyLoopInterval {
+		// We also call the policy engine immediately when a resubmit is requested.
+		resubmitRequest := pending.resubmitRequest
+		if syncDeleteRequest || resubmitRequest != nil || time.Since(pending.lastPolicyCycle) > sth.policyLoopInterval {
 			// Pass the state to the pluggable policy engine to potentially perform more actions against it,
 			// such as submitting for the first time, or raising the gas etc.
 
-			update, updateReason, updateErr = sth.processTransaction(ctx, pending.mtx)
+			if resubmitRequest != nil {
+				pending.resubmitRequest = nil
+				update, updateReason, updateErr = sth.processResubmitRequest(ctx, pending.mtx, resubmitRequest)
+				// Unlike the regular cycles of the policy loop, an error is returned to the requester
+				resubmitErr = updateErr
+			} else {
+				update, updateReason, updateErr = sth.processTransaction(ctx, pending.mtx)
+			}
 			if updateErr != nil {
 				log.L(ctx).Errorf("Policy engine returned error for transaction %s reason=%s: %s", mtx.ID, updateReason, err)
 				update = UpdateYes
 			} else {
 				log.L(ctx).Debugf("Policy engine executed for tx %s (update=%d,status=%s,hash=%s)", mtx.ID, update, mtx.Status, mtx.TransactionHash)
+				// The policy engine can complete a transaction without a receipt, such as when its deadline passes
+				completed = mtx.Status != apitypes.TxStatusPending
 				if mtx.FirstSubmit != nil &&
 					pending.trackingTransactionHash != mtx.TransactionHash {
 
 					if pending.trackingTransactionHash != "" {
 						// if had a previous transaction hash, emit an event to for transaction hash removal
-						if err = sth.toolkit.EventHandler.HandleEvent(ctx, apitypes.ManagedTransactionEvent{
-							Type: apitypes.ManagedTXTransactionHashRemoved,
-							Tx: &apitypes.ManagedTX{
-								ID:                 mtx.ID,
-								Created:            mtx.Created,
-								Updated:            mtx.Updated,
-								Status:             mtx.Status,
-								DeleteRequested:    mtx.DeleteRequested,
-								SequenceID:         mtx.SequenceID,
-								Nonce:              mtx.Nonce,
-								Gas:                mtx.Gas,
-								TransactionHeaders: mtx.TransactionHeaders,
-								TransactionData:    mtx.TransactionData,
-								TransactionHash:    pending.trackingTransactionHash, // using the previous hash
-								GasPrice:           mtx.GasPrice,
-								PolicyInfo:         mtx.PolicyInfo,
-								FirstSubmit:        mtx.FirstSubmit,
-								LastSubmit:         mtx.LastSubmit,
-								Receipt:            mtx.Receipt,
-								ErrorMessage:       mtx.ErrorMessage,
-								Confirmations:      mtx.Confirmations,
-								History:            mtx.History,
-								HistorySummary:     mtx.HistorySummary,
-							},
-						}); err != nil {
+						if err = sth.notifyTransactionHash(ctx, apitypes.ManagedTXTransactionHashRemoved, mtx, pending.trackingTransactionHash); err != nil {
 							log.L(ctx).Infof("Error detected notifying confirmation manager to remove old transaction hash: %s", err.Error())
 						}
 					}
@@ -326,6 +530,9 @@ func (sth *simpleTransactionHandler) execPolicy(ctx context.Context, pending *pe
 						sth.incTransactionOperationCounter(ctx, mtx.Namespace(ctx), "tracking")
 					}
 				}
+				if !completed && len(mtx.CancellationHashes) > 0 && pending.trackingCancellation != mtx.CancellationHashes[len(mtx.CancellationHashes)-1] {
+					sth.trackCancellation(ctx, pending)
+				}
 				pending.lastPolicyCycle = time.Now()
 			}
 		}
@@ -378,7 +585,7 @@ func (sth *simpleTransactionHandler) execPolicy(ctx context.Context, pending *pe
 		})
 	}
 
-	return nil
+	return resubmitErr
 }
 
 func (sth *simpleTransactionHandler) policyEngineAPIRequest(ctx context.Context, req *policyEngineAPIRequest) policyEngineAPIResponse {
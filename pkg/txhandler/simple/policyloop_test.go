@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -32,11 +34,11 @@ import (
 	"github.com/hyperledger/firefly-transaction-manager/mocks/ffcapimocks"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/metricsmocks"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/txhandlermocks"
 	"github.com/hyperledger/firefly-transaction-manager/mocks/wsmocks"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/fftm"
-	"github.com/hyperledger/firefly-transaction-manager/pkg/txhistory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -278,10 +280,14 @@ func TestPolicyLoopE2EReverted(t *testing.T) {
 	mmm := &metricsmocks.TransactionHandlerMetrics{}
 	mmm.On("InitTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightUsed, metricsGaugeTransactionsInflightUsedDescription, false).Return(nil).Maybe()
 	mmm.On("InitTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightFree, metricsGaugeTransactionsInflightFreeDescription, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, metricsGaugeTransactionsInflightSignerDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsQueuedSigner, metricsGaugeTransactionsQueuedSignerDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeSignerUnderfunded, metricsGaugeSignerUnderfundedDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
 	mmm.On("InitTxHandlerCounterMetricWithLabels", mock.Anything, metricsCounterTransactionProcessOperationsTotal, metricsCounterTransactionProcessOperationsTotalDescription, []string{metricsLabelNameOperation}, true).Return(nil).Maybe()
 	mmm.On("InitTxHandlerHistogramMetricWithLabels", mock.Anything, metricsHistogramTransactionProcessOperationsDuration, metricsHistogramTransactionProcessOperationsDurationDescription, []float64{}, []string{metricsLabelNameOperation}, true).Return(nil).Maybe()
 	mmm.On("SetTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightUsed, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("SetTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightFree, mock.Anything, mock.Anything).Return().Maybe()
+	mmm.On("SetTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("IncTxHandlerCounterMetricWithLabels", mock.Anything, metricsCounterTransactionProcessOperationsTotal, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("ObserveTxHandlerHistogramMetricWithLabels", mock.Anything, metricsHistogramTransactionProcessOperationsDuration, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 
@@ -348,6 +354,91 @@ func TestPolicyLoopE2EReverted(t *testing.T) {
 	mfc.AssertExpectations(t)
 }
 
+func TestPolicyLoopE2EDeadlineExceeded(t *testing.T) {
+	f, tk, _, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `12345`)
+	conf.Set(ResubmitInterval, "100s")
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	// Only the cancellation is submitted, to consume the nonce
+	mfc := sth.toolkit.Connector.(*ffcapimocks.API)
+	mfc.On("TransactionSend", sth.ctx, mock.MatchedBy(func(r *ffcapi.TransactionSendRequest) bool {
+		return r.Nonce.Equals(fftypes.NewFFBigInt(12345)) && r.To == "0xaaaaa" && r.TransactionData == ""
+	})).Return(&ffcapi.TransactionSendResponse{
+		TransactionHash: "0xcancel",
+	}, ffcapi.ErrorReason(""), nil).Once()
+
+	eh := &fftm.ManagedTransactionEventHandler{
+		Ctx:       context.Background(),
+		TxHandler: sth,
+	}
+	mc := &confirmationsmocks.Manager{}
+	mc.On("Notify", mock.MatchedBy(func(n *confirmations.Notification) bool {
+		return n.NotificationType == confirmations.NewTransaction && n.Transaction.TransactionHash == "0xcancel"
+	})).Return(nil).Once()
+	eh.ConfirmationManager = mc
+	mws := &wsmocks.WebSocketServer{}
+	mws.On("SendReply", mock.MatchedBy(func(r *apitypes.TransactionUpdateReply) bool {
+		return r.Status == apitypes.TxStatusFailed && strings.Contains(r.ErrorMessage, "FF21091")
+	})).Return(nil).Once()
+	eh.WsServer = mws
+	sth.toolkit.EventHandler = eh
+
+	txInput := ffcapi.TransactionInput{
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0xaaaaa",
+		},
+	}
+	mfc.On("NextNonceForSigner", sth.ctx, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(12345),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	mfc.On("TransactionPrepare", sth.ctx, mock.Anything).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(100000),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil).Once()
+	deadline := fftypes.FFTime(time.Now().Add(-1 * time.Second))
+	mtx, err := sth.HandleNewTransaction(sth.ctx, &apitypes.TransactionRequest{
+		Headers:          apitypes.RequestHeaders{Deadline: &deadline},
+		TransactionInput: txInput,
+	})
+	assert.NoError(t, err)
+
+	// Run the policy once, which submits the cancellation. The transaction is not failed yet, as it could still be mined.
+	<-sth.inflightStale // from sending the TX
+	sth.policyLoopCycle(sth.ctx, true)
+	assert.Equal(t, apitypes.TxStatusPending, sth.inflight[0].mtx.Status)
+	assert.NotNil(t, sth.inflight[0].mtx.CancelRequested)
+	assert.Equal(t, []string{"0xcancel"}, sth.inflight[0].mtx.CancellationHashes)
+
+	// The transaction is failed once the cancellation is mined
+	confirmTestTransaction(t, sth, mtx)
+	mfc.On("TransactionReceipt", sth.ctx, &ffcapi.TransactionReceiptRequest{TransactionHash: "0xcancel"}).
+		Return(&ffcapi.TransactionReceiptResponse{}, ffcapi.ErrorReason(""), nil).Once()
+	sth.policyLoopCycle(sth.ctx, false)
+	assert.Equal(t, apitypes.TxStatusFailed, sth.inflight[0].mtx.Status)
+
+	<-sth.inflightStale // policy loop should have marked us stale, to clean up the TX
+	sth.policyLoopCycle(sth.ctx, true)
+	assert.Empty(t, sth.inflight)
+
+	// Check the update is persisted
+	rtx, err := sth.toolkit.TXPersistence.GetTransactionByID(sth.ctx, mtx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, apitypes.TxStatusFailed, rtx.Status)
+	assert.Regexp(t, "FF21091", rtx.ErrorMessage)
+	assert.Nil(t, rtx.FirstSubmit)
+
+	mws.AssertExpectations(t)
+	mfc.AssertExpectations(t)
+	mc.AssertExpectations(t)
+}
+
 func TestPolicyLoopResubmitNewTXID(t *testing.T) {
 	f, tk, _, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
 	defer cleanup()
@@ -431,6 +522,7 @@ func TestPolicyLoopResubmitNewTXID(t *testing.T) {
 	assert.Equal(t, mtx.ID, sth.inflight[0].mtx.ID)
 	assert.Equal(t, apitypes.TxStatusPending, sth.inflight[0].mtx.Status)
 	assert.Equal(t, txHash2, sth.inflight[0].mtx.TransactionHash)
+	assert.Equal(t, []string{txHash1, txHash2}, sth.inflight[0].mtx.SubmittedHashes)
 
 	mc.AssertExpectations(t)
 	mfc.AssertExpectations(t)
@@ -448,10 +540,14 @@ func TestNotifyConfirmationMgrFail(t *testing.T) {
 	mmm := &metricsmocks.TransactionHandlerMetrics{}
 	mmm.On("InitTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightUsed, metricsGaugeTransactionsInflightUsedDescription, false).Return(nil).Maybe()
 	mmm.On("InitTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightFree, metricsGaugeTransactionsInflightFreeDescription, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, metricsGaugeTransactionsInflightSignerDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsQueuedSigner, metricsGaugeTransactionsQueuedSignerDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeSignerUnderfunded, metricsGaugeSignerUnderfundedDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
 	mmm.On("InitTxHandlerCounterMetricWithLabels", mock.Anything, metricsCounterTransactionProcessOperationsTotal, metricsCounterTransactionProcessOperationsTotalDescription, []string{metricsLabelNameOperation}, true).Return(nil).Maybe()
 	mmm.On("InitTxHandlerHistogramMetricWithLabels", mock.Anything, metricsHistogramTransactionProcessOperationsDuration, metricsHistogramTransactionProcessOperationsDurationDescription, []float64{}, []string{metricsLabelNameOperation}, true).Return(nil).Maybe()
 	mmm.On("SetTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightUsed, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("SetTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightFree, mock.Anything, mock.Anything).Return().Maybe()
+	mmm.On("SetTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("IncTxHandlerCounterMetricWithLabels", mock.Anything, metricsCounterTransactionProcessOperationsTotal, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("ObserveTxHandlerHistogramMetricWithLabels", mock.Anything, metricsHistogramTransactionProcessOperationsDuration, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 
@@ -607,7 +703,7 @@ func TestPolicyLoopUpdateFail(t *testing.T) {
 		},
 	}
 
-	h := txhistory.NewTxHistoryManager(sth.ctx)
+	h := sth.toolkit.TXHistory
 	h.SetSubStatus(sth.ctx, sth.inflight[0].mtx, apitypes.TxSubStatusReceived)
 
 	mp := sth.toolkit.TXPersistence.(*persistencemocks.TransactionPersistence)
@@ -684,7 +780,7 @@ func TestPolicyLoopUpdateEventHandlerError(t *testing.T) {
 		},
 	}
 
-	h := txhistory.NewTxHistoryManager(sth.ctx)
+	h := sth.toolkit.TXHistory
 	h.SetSubStatus(sth.ctx, sth.inflight[0].mtx, apitypes.TxSubStatusReceived)
 
 	mp := sth.toolkit.TXPersistence.(*persistencemocks.TransactionPersistence)
@@ -707,10 +803,14 @@ func TestPolicyEngineFailStaleThenUpdated(t *testing.T) {
 	mmm := &metricsmocks.TransactionHandlerMetrics{}
 	mmm.On("InitTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightUsed, metricsGaugeTransactionsInflightUsedDescription, false).Return(nil).Maybe()
 	mmm.On("InitTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightFree, metricsGaugeTransactionsInflightFreeDescription, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, metricsGaugeTransactionsInflightSignerDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsQueuedSigner, metricsGaugeTransactionsQueuedSignerDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeSignerUnderfunded, metricsGaugeSignerUnderfundedDescription, []string{metricsLabelNameSigner}, false).Return(nil).Maybe()
 	mmm.On("InitTxHandlerCounterMetricWithLabels", mock.Anything, metricsCounterTransactionProcessOperationsTotal, metricsCounterTransactionProcessOperationsTotalDescription, []string{metricsLabelNameOperation}, true).Return(nil).Maybe()
 	mmm.On("InitTxHandlerHistogramMetricWithLabels", mock.Anything, metricsHistogramTransactionProcessOperationsDuration, metricsHistogramTransactionProcessOperationsDurationDescription, []float64{}, []string{metricsLabelNameOperation}, true).Return(nil).Maybe()
 	mmm.On("SetTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightUsed, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("SetTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightFree, mock.Anything, mock.Anything).Return().Maybe()
+	mmm.On("SetTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("IncTxHandlerCounterMetricWithLabels", mock.Anything, metricsCounterTransactionProcessOperationsTotal, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 	mmm.On("ObserveTxHandlerHistogramMetricWithLabels", mock.Anything, metricsHistogramTransactionProcessOperationsDuration, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 
@@ -1107,3 +1207,391 @@ func TestExecPolicyUpdateNewInfo(t *testing.T) {
 	assert.NoError(t, err)
 
 }
+
+type trackedHashEvents struct {
+	mux    sync.Mutex
+	events []string
+}
+
+func (te *trackedHashEvents) record(args mock.Arguments) {
+	te.mux.Lock()
+	defer te.mux.Unlock()
+	e := args[1].(apitypes.ManagedTransactionEvent)
+	switch e.Type {
+	case apitypes.ManagedTXTransactionHashAdded:
+		te.events = append(te.events, "add:"+e.Tx.TransactionHash)
+	case apitypes.ManagedTXTransactionHashRemoved:
+		te.events = append(te.events, "remove:"+e.Tx.TransactionHash)
+	}
+}
+
+func newTestCancelByReplacement(t *testing.T, resubmitInterval string) (*simpleTransactionHandler, *ffcapimocks.API, *trackedHashEvents, *apitypes.ManagedTX, func()) {
+	f, tk, mfc, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	conf.Set(FixedGasPrice, `1000`)
+	conf.Set(ResubmitInterval, resubmitInterval)
+	conf.Set(Interval, "0s")
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	te := &trackedHashEvents{}
+	meh := tk.EventHandler.(*txhandlermocks.ManagedTxEventHandler)
+	meh.On("HandleEvent", mock.Anything, mock.Anything).Run(te.record).Return(nil)
+
+	mfc.On("TransactionSend", sth.ctx, mock.MatchedBy(func(r *ffcapi.TransactionSendRequest) bool {
+		return r.TransactionData == "0xabce1234"
+	})).Return(&ffcapi.TransactionSendResponse{
+		TransactionHash: "0x1111",
+	}, ffcapi.ErrorReason(""), nil).Once()
+
+	mtx := sendSampleTX(t, sth, "0xaaaaa", 12345)
+	// Run the policy once to do the send
+	<-sth.inflightStale // from sending the TX
+	sth.policyLoopCycle(sth.ctx, true)
+	assert.Equal(t, []string{"add:0x1111"}, te.events)
+
+	// Request the cancellation, which is submitted on the same cycle
+	mfc.On("TransactionSend", sth.ctx, mock.MatchedBy(func(r *ffcapi.TransactionSendRequest) bool {
+		return r.TransactionData == "" && r.To == "0xaaaaa" && r.Nonce.Int64() == 12345 && r.GasPrice.String() == `"1100"`
+	})).Return(&ffcapi.TransactionSendResponse{
+		TransactionHash: "0x2222",
+	}, ffcapi.ErrorReason(""), nil).Once()
+	req := &policyEngineAPIRequest{
+		requestType: policyEngineAPIRequestTypeCancel,
+		txID:        mtx.ID,
+		response:    make(chan policyEngineAPIResponse, 1),
+	}
+	sth.policyEngineAPIRequests = append(sth.policyEngineAPIRequests, req)
+	sth.policyLoopCycle(sth.ctx, false)
+	res := <-req.response
+	assert.NoError(t, res.err)
+	assert.Equal(t, http.StatusAccepted, res.status)
+	assert.NotNil(t, res.tx.CancelRequested)
+	assert.Equal(t, apitypes.TxStatusPending, res.tx.Status)
+	assert.Equal(t, []string{"add:0x1111", "add:0x2222"}, te.events)
+
+	return sth, mfc, te, mtx, cleanup
+}
+
+func confirmTestTransaction(t *testing.T, sth *simpleTransactionHandler, mtx *apitypes.ManagedTX) {
+	err := sth.HandleTransactionReceiptReceived(sth.ctx, mtx.ID, &ffcapi.TransactionReceiptResponse{
+		BlockNumber:      fftypes.NewFFBigInt(12345),
+		TransactionIndex: fftypes.NewFFBigInt(10),
+		BlockHash:        fftypes.NewRandB32().String(),
+		ProtocolID:       fmt.Sprintf("%.12d/%.6d", 12345, 10),
+		Success:          true,
+	})
+	assert.NoError(t, err)
+	err = sth.HandleTransactionConfirmed(sth.ctx, mtx.ID, []apitypes.BlockInfo{})
+	assert.NoError(t, err)
+}
+
+func TestPolicyLoopCancelByReplacementCancelled(t *testing.T) {
+	sth, mfc, te, mtx, cleanup := newTestCancelByReplacement(t, "100s")
+	defer cleanup()
+
+	// Within the resubmit interval, we do not resubmit the cancellation
+	sth.policyLoopCycle(sth.ctx, false)
+	assert.Equal(t, []string{"add:0x1111", "add:0x2222"}, te.events)
+
+	// The replacement is mined
+	confirmTestTransaction(t, sth, mtx)
+	mfc.On("TransactionReceipt", sth.ctx, &ffcapi.TransactionReceiptRequest{TransactionHash: "0x2222"}).
+		Return(&ffcapi.TransactionReceiptResponse{}, ffcapi.ErrorReason(""), nil).Once()
+	sth.policyLoopCycle(sth.ctx, false)
+	<-sth.inflightStale // policy loop should have marked us stale, to clean up the TX
+	sth.policyLoopCycle(sth.ctx, true)
+	assert.Empty(t, sth.inflight)
+	assert.Equal(t, []string{"add:0x1111", "add:0x2222", "remove:0x1111"}, te.events)
+
+	// Check the update is persisted
+	rtx, err := sth.toolkit.TXPersistence.GetTransactionByID(sth.ctx, mtx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, apitypes.TxStatusFailed, rtx.Status)
+	assert.Regexp(t, "FF21093.*0x2222", rtx.ErrorMessage)
+	assert.Equal(t, "0x1111", rtx.TransactionHash)
+	assert.Equal(t, []string{"0x2222"}, rtx.CancellationHashes)
+	assert.Equal(t, `"1100"`, rtx.GasPrice.String())
+
+	mfc.AssertExpectations(t)
+}
+
+func TestPolicyLoopCancelByReplacementOriginalMined(t *testing.T) {
+	sth, mfc, te, mtx, cleanup := newTestCancelByReplacement(t, "0s")
+	defer cleanup()
+
+	// The cancellation is resubmitted with a further increase in gas price
+	mfc.On("TransactionSend", sth.ctx, mock.MatchedBy(func(r *ffcapi.TransactionSendRequest) bool {
+		return r.TransactionData == "" && r.GasPrice.String() == `"1210"`
+	})).Return(&ffcapi.TransactionSendResponse{
+		TransactionHash: "0x3333",
+	}, ffcapi.ErrorReason(""), nil).Once()
+	sth.policyLoopCycle(sth.ctx, false)
+	assert.Equal(t, []string{"add:0x1111", "add:0x2222", "remove:0x2222", "add:0x3333"}, te.events)
+
+	// The original transaction is mined
+	confirmTestTransaction(t, sth, mtx)
+	mfc.On("TransactionReceipt", sth.ctx, mock.Anything).
+		Return(nil, ffcapi.ErrorReasonNotFound, fmt.Errorf("not found")).Twice()
+	sth.policyLoopCycle(sth.ctx, false)
+	<-sth.inflightStale // policy loop should have marked us stale, to clean up the TX
+	sth.policyLoopCycle(sth.ctx, true)
+	assert.Empty(t, sth.inflight)
+	assert.Equal(t, []string{"add:0x1111", "add:0x2222", "remove:0x2222", "add:0x3333", "remove:0x3333"}, te.events)
+
+	rtx, err := sth.toolkit.TXPersistence.GetTransactionByID(sth.ctx, mtx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, apitypes.TxStatusSucceeded, rtx.Status)
+	assert.Empty(t, rtx.ErrorMessage)
+	assert.Equal(t, []string{"0x2222", "0x3333"}, rtx.CancellationHashes)
+
+	// Cannot cancel a completed transaction
+	req := &policyEngineAPIRequest{
+		requestType: policyEngineAPIRequestTypeCancel,
+		txID:        mtx.ID,
+		response:    make(chan policyEngineAPIResponse, 1),
+	}
+	sth.policyEngineAPIRequests = append(sth.policyEngineAPIRequests, req)
+	sth.processPolicyAPIRequests(sth.ctx)
+	res := <-req.response
+	assert.Regexp(t, "FF21092", res.err)
+
+	mfc.AssertExpectations(t)
+}
+
+func TestPolicyLoopCancelByReplacementReceiptLookupFail(t *testing.T) {
+	sth, mfc, _, mtx, cleanup := newTestCancelByReplacement(t, "100s")
+	defer cleanup()
+
+	confirmTestTransaction(t, sth, mtx)
+	mfc.On("TransactionReceipt", sth.ctx, mock.Anything).
+		Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Once()
+	err := sth.execPolicy(sth.ctx, sth.inflight[0], false)
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, apitypes.TxStatusPending, mtx.Status)
+
+	mfc.AssertExpectations(t)
+}
+
+func TestRequestCancellationPersistFail(t *testing.T) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mp := sth.toolkit.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("WriteTransaction", mock.Anything, mock.Anything, false).Return(fmt.Errorf("pop"))
+
+	err = sth.requestCancellation(sth.ctx, &pendingState{
+		mtx: &apitypes.ManagedTX{
+			ID:     "id1",
+			Status: apitypes.TxStatusPending,
+		},
+	})
+	assert.Regexp(t, "pop", err)
+
+	mp.AssertExpectations(t)
+}
+
+func TestCancelByReplacementTrackingErrors(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+	meh := &txhandlermocks.ManagedTxEventHandler{}
+	meh.On("HandleEvent", mock.Anything, mock.Anything).Return(fmt.Errorf("pop"))
+	sth.toolkit.EventHandler = meh
+
+	pending := &pendingState{
+		mtx: &apitypes.ManagedTX{
+			ID:                 "id1",
+			TransactionHash:    "0x1111",
+			CancellationHashes: []string{"0x2222", "0x3333"},
+			Receipt:            &ffcapi.TransactionReceiptResponse{},
+		},
+		trackingTransactionHash: "0x1111",
+		trackingCancellation:    "0x2222",
+	}
+	sth.trackCancellation(sth.ctx, pending)
+	assert.Equal(t, "0x2222", pending.trackingCancellation)
+
+	// Once we have a receipt, we wait for confirmation before resolving the cancellation
+	pending.mtx.CancelRequested = fftypes.Now()
+	update, _, err := sth.processTransaction(sth.ctx, pending.mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateNo, update)
+
+	mfc.On("TransactionReceipt", sth.ctx, mock.Anything).Return(&ffcapi.TransactionReceiptResponse{}, ffcapi.ErrorReason(""), nil).Once()
+	cancelled, err := sth.resolveCancellation(sth.ctx, pending)
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+
+	meh.AssertExpectations(t)
+	mfc.AssertExpectations(t)
+}
+
+func TestHandleCancelTransactionByReplacementTimeout(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	cancelCtx()
+
+	_, err = sth.HandleCancelTransactionByReplacement(ctx, "id1")
+	assert.Regexp(t, "FF21072", err)
+}
+
+func TestPolicyLoopResubmitRequests(t *testing.T) {
+	f, tk, mfc, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `1000`)
+	conf.Set(ResubmitInterval, "100s")
+	conf.SubSection(GasPriceEscalationConfig).Set(GasPriceEscalationPercentage, 10)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	te := &trackedHashEvents{}
+	meh := tk.EventHandler.(*txhandlermocks.ManagedTxEventHandler)
+	meh.On("HandleEvent", mock.Anything, mock.Anything).Run(te.record).Return(nil)
+
+	mockSend := func(gasPrice, txHash string) {
+		mfc.On("TransactionSend", sth.ctx, mock.MatchedBy(func(r *ffcapi.TransactionSendRequest) bool {
+			return r.GasPrice.String() == gasPrice
+		})).Return(&ffcapi.TransactionSendResponse{
+			TransactionHash: txHash,
+		}, ffcapi.ErrorReason(""), nil).Once()
+	}
+	resubmit := func(req *apitypes.ResubmitTransactionRequest) policyEngineAPIResponse {
+		r := &policyEngineAPIRequest{
+			requestType: policyEngineAPIRequestTypeResubmit,
+			txID:        sth.inflight[0].mtx.ID,
+			resubmit:    req,
+			response:    make(chan policyEngineAPIResponse, 1),
+		}
+		sth.policyEngineAPIRequests = append(sth.policyEngineAPIRequests, r)
+		sth.processPolicyAPIRequests(sth.ctx)
+		return <-r.response
+	}
+
+	mockSend(`1000`, "0x1111")
+	mtx := sendSampleTX(t, sth, "0xaaaaa", 12345)
+	<-sth.inflightStale // from sending the TX
+	sth.policyLoopCycle(sth.ctx, true)
+
+	// An explicit gas price
+	mockSend(`2000`, "0x2222")
+	res := resubmit(&apitypes.ResubmitTransactionRequest{GasPrice: fftypes.JSONAnyPtr(`2000`)})
+	assert.NoError(t, res.err)
+	assert.Equal(t, http.StatusOK, res.status)
+	assert.Equal(t, "0x2222", res.tx.TransactionHash)
+
+	// A bump over the last submitted gas price
+	bump := int64(50)
+	mockSend(`"3000"`, "0x3333")
+	res = resubmit(&apitypes.ResubmitTransactionRequest{BumpPercentage: &bump})
+	assert.NoError(t, res.err)
+	assert.Equal(t, "0x3333", res.tx.TransactionHash)
+
+	// The escalation policy of the handler
+	mockSend(`"3300"`, "0x4444")
+	res = resubmit(nil)
+	assert.NoError(t, res.err)
+	assert.Equal(t, "0x4444", res.tx.TransactionHash)
+	assert.Equal(t, []string{
+		"add:0x1111",
+		"remove:0x1111", "add:0x2222",
+		"remove:0x2222", "add:0x3333",
+		"remove:0x3333", "add:0x4444",
+	}, te.events)
+
+	// The error from a failed submission is returned
+	mfc.On("TransactionSend", sth.ctx, mock.Anything).Return(nil, ffcapi.ErrorReasonTransactionUnderpriced, fmt.Errorf("underpriced")).Once()
+	res = resubmit(&apitypes.ResubmitTransactionRequest{GasPrice: fftypes.JSONAnyPtr(`100`)})
+	assert.Regexp(t, "underpriced", res.err)
+	assert.True(t, sth.inflight[0].mtx.PolicyInfo.JSONObject().GetBool("underpriced"))
+
+	// Check the updates are persisted, and recorded in the history
+	rtx, err := sth.toolkit.TXPersistence.GetTransactionByID(sth.ctx, mtx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, `100`, rtx.GasPrice.String())
+	assert.Equal(t, []string{"0x1111", "0x2222", "0x3333", "0x4444"}, rtx.SubmittedHashes)
+	history, err := sth.toolkit.TXPersistence.(persistence.Persistence).ListTransactionHistory(sth.ctx, mtx.ID, nil, 0, persistence.SortDirectionAscending)
+	assert.NoError(t, err)
+	manualResubmits := 0
+	for _, h := range history {
+		for _, a := range h.Actions {
+			if a.Action == apitypes.TxActionManualResubmit {
+				manualResubmits += a.Count
+			}
+		}
+	}
+	assert.Equal(t, 4, manualResubmits)
+
+	// Invalid requests
+	res = resubmit(&apitypes.ResubmitTransactionRequest{GasPrice: fftypes.JSONAnyPtr(`100`), BumpPercentage: &bump})
+	assert.Regexp(t, "FF21095", res.err)
+	bump = -1
+	res = resubmit(&apitypes.ResubmitTransactionRequest{BumpPercentage: &bump})
+	assert.Regexp(t, "FF21096", res.err)
+	sth.inflight[0].mtx.CancelRequested = fftypes.Now()
+	res = resubmit(nil)
+	assert.Regexp(t, "FF21094", res.err)
+
+	mfc.AssertExpectations(t)
+}
+
+func TestProcessResubmitRequestGasPriceFail(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.SubSection(GasOracleConfig).Set(GasOracleMode, GasOracleModeConnector)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mfc.On("GasPriceEstimate", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Twice()
+
+	bump := int64(10)
+	mtx := &apitypes.ManagedTX{ID: "id1", GasPrice: fftypes.JSONAnyPtr(`1000`)}
+	_, _, err = sth.processResubmitRequest(sth.ctx, mtx, &apitypes.ResubmitTransactionRequest{BumpPercentage: &bump})
+	assert.Regexp(t, "pop", err)
+	_, _, err = sth.processResubmitRequest(sth.ctx, mtx, &apitypes.ResubmitTransactionRequest{})
+	assert.Regexp(t, "pop", err)
+
+	mfc.AssertExpectations(t)
+}
+
+func TestHandleResubmitTransactionTimeout(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	cancelCtx()
+
+	_, err = sth.HandleResubmitTransaction(ctx, "id1", &apitypes.ResubmitTransactionRequest{})
+	assert.Regexp(t, "FF21072", err)
+}
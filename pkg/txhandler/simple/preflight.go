@@ -0,0 +1,51 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+)
+
+// preflightTransaction estimates the gas for a new transaction when pre-flight is enabled, so that a transaction that
+// would revert is rejected before it is assigned a nonce, rather than being mined and burning gas. Contract deployments
+// are not checked, as the connector cannot estimate the gas for them.
+// The returned input is a copy, with the gas set from the estimate if the request did not specify it.
+func (sth *simpleTransactionHandler) preflightTransaction(ctx context.Context, txInput ffcapi.TransactionInput) (*ffcapi.TransactionInput, error) {
+	if !sth.preflightEnabled {
+		return &txInput, nil
+	}
+	res, reason, err := sth.toolkit.Connector.GasEstimate(ctx, &txInput)
+	if err != nil {
+		if reason == ffcapi.ErrorReasonTransactionReverted {
+			return nil, i18n.NewError(ctx, tmmsgs.MsgTransactionReverted, err)
+		}
+		return nil, err
+	}
+	if txInput.Gas == nil && res.GasEstimate != nil {
+		gas, _ := new(big.Float).Mul(new(big.Float).SetInt(res.GasEstimate.Int()), big.NewFloat(sth.preflightGasMultiplier)).Int(nil)
+		log.L(ctx).Debugf("Gas estimate for transaction from %s is %s - setting gas to %s", txInput.From, res.GasEstimate, gas)
+		txInput.Gas = (*fftypes.FFBigInt)(gas)
+	}
+	return &txInput, nil
+}
@@ -0,0 +1,188 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/ffcapimocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestPreflightHandler(t *testing.T) (*simpleTransactionHandler, *ffcapimocks.API, func()) {
+	f, tk, mockFFCAPI, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(PreflightConfig).Set(PreflightEnabled, true)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+	return sth, mockFFCAPI, cleanup
+}
+
+func TestPreflightConfig(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(PreflightConfig).Set(PreflightEnabled, true)
+	conf.SubSection(PreflightConfig).Set(PreflightGasMultiplier, 2)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	assert.True(t, sth.preflightEnabled)
+	assert.Equal(t, 2.0, sth.preflightGasMultiplier)
+}
+
+func TestPreflightConfigBadMultiplier(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(PreflightConfig).Set(PreflightGasMultiplier, 0.5)
+	_, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.Regexp(t, "FF21101.*gasMultiplier", err)
+}
+
+func TestHandleNewTransactionPreflight(t *testing.T) {
+	sth, mockFFCAPI, cleanup := newTestPreflightHandler(t)
+	defer cleanup()
+
+	mockFFCAPI.On("GasEstimate", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionInput) bool {
+		return req.From == "0xaaaaa" && req.Gas == nil
+	})).Return(&ffcapi.GasEstimateResponse{
+		GasEstimate: fftypes.NewFFBigInt(100001),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	// The gas is set from the estimate, with the default multiplier
+	mockFFCAPI.On("TransactionPrepare", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionPrepareRequest) bool {
+		return req.Gas.Int64() == 150001
+	})).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(150001),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil).Once()
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(1),
+	}, ffcapi.ErrorReason(""), nil).Once()
+
+	txReq := &apitypes.TransactionRequest{
+		TransactionInput: ffcapi.TransactionInput{
+			TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"},
+			Method:             fftypes.JSONAnyPtr(`"ok"`),
+		},
+	}
+	mtx, err := sth.HandleNewTransaction(sth.ctx, txReq)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(150001), mtx.Gas.Int64())
+	assert.Equal(t, int64(150001), mtx.TransactionHeaders.Gas.Int64())
+	// The request itself is not modified
+	assert.Nil(t, txReq.Gas)
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestHandleNewTransactionPreflightReverted(t *testing.T) {
+	sth, mockFFCAPI, cleanup := newTestPreflightHandler(t)
+	defer cleanup()
+
+	mockFFCAPI.On("GasEstimate", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReasonTransactionReverted, fmt.Errorf("execution reverted: not allowed")).Once()
+
+	_, err := sth.HandleNewTransaction(sth.ctx, &apitypes.TransactionRequest{
+		TransactionInput: ffcapi.TransactionInput{
+			TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"},
+		},
+	})
+	assert.Regexp(t, "FF21100.*not allowed", err)
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestHandleNewTransactionBatchPreflight(t *testing.T) {
+	sth, mockFFCAPI, cleanup := newTestPreflightHandler(t)
+	defer cleanup()
+
+	mockFFCAPI.On("GasEstimate", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionInput) bool {
+		return req.Method.String() == `"ok"`
+	})).Return(&ffcapi.GasEstimateResponse{
+		GasEstimate: fftypes.NewFFBigInt(100000),
+	}, ffcapi.ErrorReason(""), nil)
+	mockFFCAPI.On("GasEstimate", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionInput) bool {
+		return req.Method.String() == `"revert"`
+	})).Return(nil, ffcapi.ErrorReasonTransactionReverted, fmt.Errorf("execution reverted"))
+	mockFFCAPI.On("TransactionPrepare", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionPrepareRequest) bool {
+		return req.Gas.Int64() == 150000
+	})).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(150000),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil).Once()
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(1),
+	}, ffcapi.ErrorReason(""), nil).Once()
+
+	results, err := sth.HandleNewTransactionBatch(sth.ctx, []*apitypes.TransactionBatchItem{
+		newTestBatchTX("ns1:a1", "0xaaaaa", "revert"),
+		newTestBatchTX("ns1:a2", "0xaaaaa", "ok"),
+	})
+	assert.NoError(t, err)
+	assert.Regexp(t, "FF21100", results[0].Error)
+	assert.Equal(t, int64(1), results[1].Transaction.Nonce.Int64())
+	assert.Equal(t, int64(150000), results[1].Transaction.TransactionHeaders.Gas.Int64())
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestPreflightTransaction(t *testing.T) {
+	f, tk, mockFFCAPI, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.toolkit = tk
+	ctx := context.Background()
+
+	// Disabled by default
+	txInput, err := sth.preflightTransaction(ctx, ffcapi.TransactionInput{})
+	assert.NoError(t, err)
+	assert.Nil(t, txInput.Gas)
+
+	sth.preflightEnabled = true
+	mockFFCAPI.On("GasEstimate", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Once()
+	_, err = sth.preflightTransaction(ctx, ffcapi.TransactionInput{})
+	assert.Regexp(t, "pop", err)
+
+	// The gas specified in the request is used in preference to the estimate
+	mockFFCAPI.On("GasEstimate", mock.Anything, mock.Anything).Return(&ffcapi.GasEstimateResponse{
+		GasEstimate: fftypes.NewFFBigInt(100000),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	txInput, err = sth.preflightTransaction(ctx, ffcapi.TransactionInput{
+		TransactionHeaders: ffcapi.TransactionHeaders{Gas: fftypes.NewFFBigInt(200000)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200000), txInput.Gas.Int64())
+
+	// No estimate returned by the connector
+	mockFFCAPI.On("GasEstimate", mock.Anything, mock.Anything).Return(&ffcapi.GasEstimateResponse{}, ffcapi.ErrorReason(""), nil).Once()
+	txInput, err = sth.preflightTransaction(ctx, ffcapi.TransactionInput{})
+	assert.NoError(t, err)
+	assert.Nil(t, txInput.Gas)
+
+	mockFFCAPI.AssertExpectations(t)
+}
@@ -0,0 +1,132 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmmsgs" // replace with your own messages if you are developing a customized transaction handler
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+)
+
+// notYetDue returns true if a transaction has a not-before time that has not yet passed
+func notYetDue(notBefore *fftypes.FFTime) bool {
+	return notBefore != nil && time.Now().Before(*notBefore.Time())
+}
+
+// awaitingNonce returns true for a scheduled transaction that has not yet been assigned a nonce
+func awaitingNonce(mtx *apitypes.ManagedTX) bool {
+	return mtx.Nonce == nil && mtx.NotBefore != nil
+}
+
+// nearlyDue returns true for a scheduled transaction that is due before the next cycle of the policy loop, so it
+// must be in the in-flight set to be assigned a nonce when it is due
+func (sth *simpleTransactionHandler) nearlyDue(mtx *apitypes.ManagedTX) bool {
+	return !mtx.NotBefore.Time().After(time.Now().Add(sth.policyLoopInterval))
+}
+
+// deferScheduled records that a scheduled transaction has been left out of the in-flight set as it is not nearly due,
+// so the pending transactions are read again once it is
+func (sth *simpleTransactionHandler) deferScheduled(mtx *apitypes.ManagedTX) {
+	refresh := mtx.NotBefore.Time().Add(-sth.policyLoopInterval)
+	if sth.scheduledRefresh.IsZero() || refresh.Before(sth.scheduledRefresh) {
+		sth.scheduledRefresh = refresh
+	}
+}
+
+// scheduledRefreshDue returns true once a scheduled transaction that was left out of the in-flight set is nearly due
+func (sth *simpleTransactionHandler) scheduledRefreshDue() bool {
+	return !sth.scheduledRefresh.IsZero() && !time.Now().Before(sth.scheduledRefresh)
+}
+
+// checkNotBefore rejects a request that could never be submitted, as its deadline is not after its not-before time
+func checkNotBefore(ctx context.Context, reqHeaders *apitypes.RequestHeaders) error {
+	if reqHeaders.NotBefore != nil && reqHeaders.Deadline != nil && !reqHeaders.Deadline.Time().After(*reqHeaders.NotBefore.Time()) {
+		return i18n.NewError(ctx, tmmsgs.MsgNotBeforeAfterDeadline, reqHeaders.NotBefore, reqHeaders.Deadline)
+	}
+	return nil
+}
+
+// createScheduledTx persists a new transaction that is not to be submitted until its not-before time. We do not
+// assign a nonce until then, as the transaction would otherwise hold up all the later transactions for the signer.
+func (sth *simpleTransactionHandler) createScheduledTx(ctx context.Context, txID string, reqHeaders *apitypes.RequestHeaders, requestHash string, txHeaders *ffcapi.TransactionHeaders, gas *fftypes.FFBigInt, transactionData string) (*apitypes.ManagedTX, error) {
+	mtx := sth.newManagedTx(txID, nil, reqHeaders, requestHash, txHeaders, gas, transactionData)
+	sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusScheduled)
+	if err := sth.persistNewManagedTx(ctx, mtx); err != nil {
+		return nil, err
+	}
+	return mtx, nil
+}
+
+// assignScheduledNonce assigns the next nonce for the signer to a scheduled transaction that is now due. The nonce
+// is persisted within the nonce lock, in the same way as for a new transaction. We do not wait for the nonce lock,
+// as that would block the policy loop, so if it is held the nonce is assigned on a later cycle.
+func (sth *simpleTransactionHandler) assignScheduledNonce(ctx context.Context, mtx *apitypes.ManagedTX) error {
+	locked := sth.tryLockNonce(mtx.ID, mtx.TransactionHeaders.From)
+	if locked == nil {
+		log.L(ctx).Debugf("Nonce for signer %s is locked, so scheduled transaction %s will be assigned a nonce on a later cycle", mtx.TransactionHeaders.From, mtx.ID)
+		return nil
+	}
+	lockedNonce, err := sth.assignLockedNonce(ctx, locked)
+	if err != nil {
+		return err
+	}
+	defer lockedNonce.complete(ctx)
+
+	mtx.Nonce = fftypes.NewFFBigInt(int64(lockedNonce.nonce))
+	sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
+	sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionAssignNonce, fftypes.JSONAnyPtr(`{"nonce":"`+mtx.Nonce.String()+`"}`), nil)
+	if err := sth.toolkit.TXPersistence.WriteTransaction(ctx, mtx, false); err != nil {
+		// We will assign a nonce again on the next cycle of the policy loop
+		mtx.Nonce = nil
+		return err
+	}
+	log.L(ctx).Infof("Scheduled transaction %s is due, and assigned nonce %s / %d", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64())
+	lockedNonce.spent = mtx
+	return nil
+}
+
+// cancelScheduledTx fails a scheduled transaction that has not yet been assigned a nonce. It has never been submitted,
+// so there is nothing to replace.
+func (sth *simpleTransactionHandler) cancelScheduledTx(ctx context.Context, mtx *apitypes.ManagedTX) (update UpdateType, reason ffcapi.ErrorReason, err error) {
+	log.L(ctx).Infof("Scheduled transaction %s cancelled before it was assigned a nonce (notBefore=%s)", mtx.ID, mtx.NotBefore)
+	mtx.Status = apitypes.TxStatusFailed
+	mtx.ErrorMessage = i18n.NewError(ctx, tmmsgs.MsgTXScheduledCancelled).Error()
+	sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusFailed)
+	return UpdateYes, "", nil
+}
+
+// inflightSpaces returns the number of transactions that can be added to the in-flight set. Scheduled transactions
+// that have not yet been assigned a nonce do not take up a space, so they cannot hold up the transactions that are due.
+func (sth *simpleTransactionHandler) inflightSpaces() int {
+	spaces := sth.maxInFlight
+	for _, p := range sth.inflight {
+		if !awaitingNonce(p.mtx) {
+			spaces--
+		}
+	}
+	if spaces < 0 {
+		// Scheduled transactions that become due while the in-flight set is full remain in it
+		return 0
+	}
+	return spaces
+}
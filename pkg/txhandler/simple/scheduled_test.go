@@ -0,0 +1,470 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/ffcapimocks"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/txhistory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestScheduledTransactionHandler(t *testing.T) (*simpleTransactionHandler, *ffcapimocks.API, func()) {
+	f, tk, mockFFCAPI, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	conf.Set(FixedGasPrice, `1000`)
+	conf.Set(DefaultDeadline, "1h")
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(5),
+	}, ffcapi.ErrorReason(""), nil).Maybe()
+	mockFFCAPI.On("TransactionPrepare", mock.Anything, mock.Anything).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(100000),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil).Maybe()
+	return sth, mockFFCAPI, cleanup
+}
+
+func newTestScheduledRequest(id string, notBefore time.Time) *apitypes.TransactionRequest {
+	nb := fftypes.FFTime(notBefore)
+	return &apitypes.TransactionRequest{
+		Headers: apitypes.RequestHeaders{ID: id, NotBefore: &nb},
+		TransactionInput: ffcapi.TransactionInput{
+			TransactionHeaders: ffcapi.TransactionHeaders{
+				From: "0xaaaaa",
+			},
+		},
+	}
+}
+
+func TestScheduledTransaction(t *testing.T) {
+	sth, mockFFCAPI, cleanup := newTestScheduledTransactionHandler(t)
+	defer cleanup()
+	ctx := sth.ctx
+
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionSendRequest) bool {
+		return req.Nonce.Int64() == 6
+	})).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0x12345"}, ffcapi.ErrorReason(""), nil).Once()
+
+	// The scheduled transaction is not assigned a nonce, and the default deadline runs from its not-before time
+	notBefore := time.Now().Add(1 * time.Hour)
+	mtx, err := sth.HandleNewTransaction(ctx, newTestScheduledRequest("ns1:scheduled1", notBefore))
+	assert.NoError(t, err)
+	assert.Nil(t, mtx.Nonce)
+	assert.Equal(t, apitypes.TxSubStatusScheduled, sth.toolkit.TXHistory.CurrentSubStatus(ctx, mtx).Status)
+	assert.Equal(t, time.Hour, mtx.Deadline.Time().Sub(notBefore))
+
+	// Transactions sent in the meantime are not held up
+	mtx2, err := sth.HandleNewTransaction(ctx, &apitypes.TransactionRequest{
+		TransactionInput: ffcapi.TransactionInput{
+			TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), mtx2.Nonce.Int64())
+
+	// It can be found by its sub-status
+	txns, err := sth.toolkit.TXPersistence.ListTransactionsFiltered(ctx, &persistence.TransactionFilters{SubStatus: apitypes.TxSubStatusScheduled}, nil, 0, persistence.SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, mtx.ID, txns[0].ID)
+
+	// Nothing happens until it is due
+	updated, _, err := sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateNo, updated)
+	assert.Nil(t, mtx.Nonce)
+
+	// It waits for the next cycle if the nonce is locked when it is due
+	past := fftypes.FFTime(time.Now().Add(-1 * time.Second))
+	mtx.NotBefore = &past
+	locked := sth.lockNonce(ctx, "ns1:other", "0xaaaaa")
+	updated, _, err = sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateNo, updated)
+	assert.Nil(t, mtx.Nonce)
+	locked.complete(ctx)
+
+	// Then it is assigned the next nonce, and submitted
+	updated, _, err = sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Equal(t, int64(6), mtx.Nonce.Int64())
+	assert.Equal(t, "0x12345", mtx.TransactionHash)
+
+	persisted, err := sth.toolkit.TXPersistence.GetTransactionByNonce(ctx, "0xaaaaa", fftypes.NewFFBigInt(6))
+	assert.NoError(t, err)
+	assert.Equal(t, mtx.ID, persisted.ID)
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestScheduledTransactionHistoryDisabled(t *testing.T) {
+	sth, _, cleanup := newTestScheduledTransactionHandler(t)
+	defer cleanup()
+	ctx := sth.ctx
+	config.Set(tmconfig.TransactionsHistoryEnabled, false)
+	sth.toolkit.TXHistory = txhistory.NewTxHistoryManager(ctx, nil)
+
+	// The current sub-status is still retained, so the scheduled transaction can be found by it
+	mtx, err := sth.HandleNewTransaction(ctx, newTestScheduledRequest("ns1:scheduled1", time.Now().Add(1*time.Hour)))
+	assert.NoError(t, err)
+	assert.Equal(t, apitypes.TxSubStatusScheduled, sth.toolkit.TXHistory.CurrentSubStatus(ctx, mtx).Status)
+	assert.Empty(t, mtx.HistorySummary)
+
+	txns, err := sth.toolkit.TXPersistence.ListTransactionsFiltered(ctx, &persistence.TransactionFilters{SubStatus: apitypes.TxSubStatusScheduled}, nil, 0, persistence.SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, mtx.ID, txns[0].ID)
+
+	// Once it is due and assigned a nonce, it is no longer scheduled
+	past := fftypes.FFTime(time.Now().Add(-1 * time.Second))
+	mtx.NotBefore = &past
+	err = sth.assignScheduledNonce(ctx, mtx)
+	assert.NoError(t, err)
+	txns, err = sth.toolkit.TXPersistence.ListTransactionsFiltered(ctx, &persistence.TransactionFilters{SubStatus: apitypes.TxSubStatusScheduled}, nil, 0, persistence.SortDirectionDescending)
+	assert.NoError(t, err)
+	assert.Empty(t, txns)
+}
+
+func TestScheduledTransactionCancel(t *testing.T) {
+	sth, mockFFCAPI, cleanup := newTestScheduledTransactionHandler(t)
+	defer cleanup()
+	ctx := sth.ctx
+
+	mockFFCAPI.On("DeployContractPrepare", mock.Anything, mock.Anything).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(100000),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil)
+
+	mtx, err := sth.HandleNewContractDeployment(ctx, &apitypes.ContractDeployRequest{
+		Headers: newTestScheduledRequest("ns1:scheduled1", time.Now().Add(1*time.Hour)).Headers,
+		ContractDeployPrepareRequest: ffcapi.ContractDeployPrepareRequest{
+			TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"},
+		},
+	})
+	assert.NoError(t, err)
+
+	// There is nothing to replace, so it fails straight away
+	err = sth.requestCancellation(ctx, &pendingState{mtx: mtx})
+	assert.NoError(t, err)
+	updated, _, err := sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Equal(t, apitypes.TxStatusFailed, mtx.Status)
+	assert.Regexp(t, "FF21105", mtx.ErrorMessage)
+	assert.Equal(t, apitypes.TxSubStatusFailed, sth.toolkit.TXHistory.CurrentSubStatus(ctx, mtx).Status)
+
+	mockFFCAPI.AssertNotCalled(t, "TransactionSend", mock.Anything, mock.Anything)
+}
+
+func TestScheduledTransactionDeadline(t *testing.T) {
+	sth, mockFFCAPI, cleanup := newTestScheduledTransactionHandler(t)
+	defer cleanup()
+	ctx := sth.ctx
+
+	// The deadline must be after the not-before time
+	txReq := newTestScheduledRequest("ns1:scheduled1", time.Now().Add(1*time.Hour))
+	txReq.Headers.Deadline = fftypes.Now()
+	_, err := sth.HandleNewTransaction(ctx, txReq)
+	assert.Regexp(t, "FF21104", err)
+
+	// There is nothing to cancel when the deadline passes without a nonce having been assigned
+	txReq.Headers.Deadline = nil
+	mtx, err := sth.HandleNewTransaction(ctx, txReq)
+	assert.NoError(t, err)
+	mtx.Deadline = fftypes.Now()
+	updated, _, err := sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Equal(t, apitypes.TxStatusFailed, mtx.Status)
+	assert.Regexp(t, "FF21091", mtx.ErrorMessage)
+
+	mockFFCAPI.AssertNotCalled(t, "TransactionSend", mock.Anything, mock.Anything)
+}
+
+func TestScheduledRawTransaction(t *testing.T) {
+	sth, _, cleanup := newTestScheduledTransactionHandler(t)
+	defer cleanup()
+
+	// The nonce is fixed by the signature, so the transaction cannot wait without holding up the later ones
+	_, err := sth.HandleNewRawTransaction(sth.ctx, &apitypes.RawTransactionRequest{
+		Headers: newTestScheduledRequest("ns1:raw1", time.Now().Add(1*time.Hour)).Headers,
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From:  "0xaaaaa",
+			Nonce: fftypes.NewFFBigInt(10),
+		},
+		RawTransaction: "0xf86c0a85",
+	})
+	assert.Regexp(t, "FF21110", err)
+}
+
+func TestScheduledTransactionBatch(t *testing.T) {
+	sth, _, cleanup := newTestScheduledTransactionHandler(t)
+	defer cleanup()
+	ctx := sth.ctx
+
+	scheduled := newTestBatchTX("ns1:scheduled1", "0xaaaaa", "ok")
+	scheduled.Transaction.Headers.NotBefore = newTestScheduledRequest("", time.Now().Add(1*time.Hour)).Headers.NotBefore
+	invalid := newTestBatchTX("ns1:invalid1", "0xaaaaa", "ok")
+	invalid.Transaction.Headers.NotBefore = scheduled.Transaction.Headers.NotBefore
+	invalid.Transaction.Headers.Deadline = fftypes.Now()
+	results, err := sth.HandleNewTransactionBatch(ctx, []*apitypes.TransactionBatchItem{
+		newTestBatchTX("ns1:tx1", "0xaaaaa", "ok"),
+		scheduled,
+		newTestBatchTX("ns1:tx2", "0xaaaaa", "ok"),
+		invalid,
+		// The same ID again within the batch
+		scheduled,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), results[0].Transaction.Nonce.Int64())
+	assert.Equal(t, "ns1:scheduled1", results[1].Transaction.ID)
+	assert.Nil(t, results[1].Transaction.Nonce)
+	assert.Equal(t, int64(6), results[2].Transaction.Nonce.Int64())
+	assert.Regexp(t, "FF21104", results[3].Error)
+	assert.Regexp(t, "FF21065", results[4].Error)
+
+	// The same ID in a later batch, which fails to be written
+	results, err = sth.HandleNewTransactionBatch(ctx, []*apitypes.TransactionBatchItem{scheduled})
+	assert.NoError(t, err)
+	assert.Regexp(t, "FF21065", results[0].Error)
+	assert.Nil(t, results[0].Transaction)
+}
+
+func TestAssignScheduledNonceFail(t *testing.T) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+	ctx := sth.ctx
+
+	mtx := &apitypes.ManagedTX{
+		ID:                 "ns1:scheduled1",
+		Status:             apitypes.TxStatusPending,
+		NotBefore:          fftypes.Now(),
+		TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"},
+	}
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaaa", (*fftypes.FFBigInt)(nil), 1, persistence.SortDirectionDescending).Return(nil, fmt.Errorf("pop")).Once()
+	_, _, err = sth.processTransaction(ctx, mtx)
+	assert.Regexp(t, "pop", err)
+	assert.Nil(t, mtx.Nonce)
+
+	// The nonce is not kept if we fail to persist it
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaaa", (*fftypes.FFBigInt)(nil), 1, persistence.SortDirectionDescending).Return([]*apitypes.ManagedTX{
+		{ID: "ns1:tx1", Created: fftypes.Now(), Nonce: fftypes.NewFFBigInt(5)},
+	}, nil).Once()
+	mp.On("WriteTransaction", mock.Anything, mtx, false).Return(fmt.Errorf("pop")).Once()
+	_, _, err = sth.processTransaction(ctx, mtx)
+	assert.Regexp(t, "pop", err)
+	assert.Nil(t, mtx.Nonce)
+
+	mp.AssertExpectations(t)
+}
+
+func TestScheduledTransactionsInflight(t *testing.T) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	conf.Set(MaxInFlight, 2)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+	ctx := sth.ctx
+
+	newTX := func(seq string, scheduled bool) *apitypes.ManagedTX {
+		mtx := &apitypes.ManagedTX{ID: "ns1:" + seq, SequenceID: seq, Nonce: fftypes.NewFFBigInt(1), TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"}}
+		if scheduled {
+			mtx.Nonce, mtx.NotBefore = nil, fftypes.Now()
+		}
+		return mtx
+	}
+	s1, t1, s2, t2 := newTX("1", true), newTX("2", false), newTX("3", true), newTX("4", false)
+
+	// Scheduled transactions do not take up a space, so we read on past them
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("ListTransactionsPending", mock.Anything, "", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{s1, t1}, nil).Once()
+	mp.On("ListTransactionsPending", mock.Anything, "2", 1, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{s2}, nil).Once()
+	mp.On("ListTransactionsPending", mock.Anything, "3", 1, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{t2}, nil).Once()
+	assert.True(t, sth.updateInflightSet(ctx))
+	assert.Len(t, sth.inflight, 4)
+	assert.Equal(t, 0, sth.inflightSpaces())
+
+	// Once assigned a nonce they count, but remain in flight
+	s1.Nonce = fftypes.NewFFBigInt(2)
+	s2.Nonce = fftypes.NewFFBigInt(3)
+	assert.Equal(t, 0, sth.inflightSpaces())
+
+	// When selecting by signer, scheduled transactions are always selected
+	sth.inflight = []*pendingState{{mtx: t1}}
+	sth.maxInFlightPerSigner = 1
+	s3 := newTX("5", true)
+	mp.On("ListTransactionsPending", mock.Anything, "", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{t1, s3}, nil).Once()
+	mp.On("ListTransactionsPending", mock.Anything, "5", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{t2}, nil).Once()
+	assert.True(t, sth.updateInflightSet(ctx))
+	assert.Len(t, sth.inflight, 2)
+	assert.Equal(t, s3.ID, sth.inflight[1].mtx.ID)
+
+	// A failure is retried until the context is cancelled
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	sth.maxInFlightPerSigner = 0
+	sth.inflight = nil
+	mp.On("ListTransactionsPending", mock.Anything, "", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{s3, s3}, nil).Once()
+	mp.On("ListTransactionsPending", mock.Anything, "5", 2, persistence.SortDirectionAscending).Return(nil, fmt.Errorf("pop"))
+	assert.False(t, sth.updateInflightSet(cancelCtx))
+
+	mp.AssertExpectations(t)
+}
+
+func TestScheduledTransactionsLeftOutUntilNearlyDue(t *testing.T) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	conf.Set(MaxInFlight, 2)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+	ctx := sth.ctx
+
+	newTX := func(seq string, notBefore *fftypes.FFTime) *apitypes.ManagedTX {
+		mtx := &apitypes.ManagedTX{ID: "ns1:" + seq, SequenceID: seq, Nonce: fftypes.NewFFBigInt(1), TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"}}
+		if notBefore != nil {
+			mtx.Nonce, mtx.NotBefore = nil, notBefore
+		}
+		return mtx
+	}
+	later := fftypes.FFTime(time.Now().Add(1 * time.Hour))
+	evenLater := fftypes.FFTime(time.Now().Add(2 * time.Hour))
+	t1, s1, s2, t2 := newTX("1", nil), newTX("2", &later), newTX("3", &evenLater), newTX("4", nil)
+
+	// Scheduled transactions that are not nearly due are left out, until the earliest of them is
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	mp.On("ListTransactionsPending", mock.Anything, "", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{t1, s1}, nil).Once()
+	mp.On("ListTransactionsPending", mock.Anything, "2", 1, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{s2}, nil).Once()
+	mp.On("ListTransactionsPending", mock.Anything, "3", 1, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{t2}, nil).Once()
+	assert.True(t, sth.updateInflightSet(ctx))
+	assert.Equal(t, []string{t1.ID, t2.ID}, inflightIDs(sth))
+	assert.Equal(t, later.Time().Add(-sth.policyLoopInterval), sth.scheduledRefresh)
+	assert.False(t, sth.scheduledRefreshDue())
+
+	// Once it is nearly due, we read again from the start, skipping those already in flight
+	s1.NotBefore = fftypes.Now()
+	sth.scheduledRefresh = time.Now().Add(-1 * time.Second)
+	assert.True(t, sth.scheduledRefreshDue())
+	sth.inflight = sth.inflight[0:1]
+	mp.On("ListTransactionsPending", mock.Anything, "", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{t1, s1}, nil).Once()
+	mp.On("ListTransactionsPending", mock.Anything, "2", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{s2, t2}, nil).Once()
+	mp.On("ListTransactionsPending", mock.Anything, "4", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{}, nil).Once()
+	assert.True(t, sth.updateInflightSet(ctx))
+	assert.Equal(t, []string{t1.ID, s1.ID, t2.ID}, inflightIDs(sth))
+	assert.Equal(t, evenLater.Time().Add(-sth.policyLoopInterval), sth.scheduledRefresh)
+
+	// Otherwise we read on from the highest sequence in flight
+	sth.maxInFlight = 3
+	mp.On("ListTransactionsPending", mock.Anything, "4", 1, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{}, nil).Once()
+	assert.True(t, sth.updateInflightSet(ctx))
+	assert.Len(t, sth.inflight, 3)
+
+	// When selecting by signer, they are also left out until nearly due
+	sth.inflight = nil
+	sth.maxInFlightPerSigner = 1
+	mp.On("ListTransactionsPending", mock.Anything, "", 3, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{s2, t2}, nil).Once()
+	assert.True(t, sth.updateInflightSet(ctx))
+	assert.Equal(t, []string{t2.ID}, inflightIDs(sth))
+	assert.Equal(t, evenLater.Time().Add(-sth.policyLoopInterval), sth.scheduledRefresh)
+
+	mp.AssertExpectations(t)
+}
+
+func TestScheduledTransactionsRescanFullInflightSet(t *testing.T) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	conf.Set(MaxInFlight, 2)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+	ctx := sth.ctx
+
+	newTX := func(seq, signer string, notBefore *fftypes.FFTime) *apitypes.ManagedTX {
+		mtx := &apitypes.ManagedTX{ID: "ns1:" + seq, SequenceID: seq, Nonce: fftypes.NewFFBigInt(1), TransactionHeaders: ffcapi.TransactionHeaders{From: signer}}
+		if notBefore != nil {
+			mtx.Nonce, mtx.NotBefore = nil, notBefore
+		}
+		return mtx
+	}
+	later := fftypes.FFTime(time.Now().Add(1 * time.Hour))
+	t1, t2, t3, s1, s2 := newTX("1", "0xaaaaa", nil), newTX("2", "0xbbbbb", nil), newTX("3", "0xaaaaa", nil), newTX("4", "0xaaaaa", fftypes.Now()), newTX("5", "0xaaaaa", &later)
+
+	// Every rescan reads all the pending transactions, however many spaces there are
+	mp := tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+	rescan := func(inflight ...*apitypes.ManagedTX) {
+		sth.inflight = nil
+		for _, mtx := range inflight {
+			sth.inflight = append(sth.inflight, &pendingState{mtx: mtx})
+		}
+		sth.scheduledRefresh = time.Now().Add(-1 * time.Second)
+		mp.On("ListTransactionsPending", mock.Anything, "", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{t1, t2}, nil).Once()
+		mp.On("ListTransactionsPending", mock.Anything, "2", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{t3, s1}, nil).Once()
+		mp.On("ListTransactionsPending", mock.Anything, "4", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{s2}, nil).Once()
+		assert.True(t, sth.updateInflightSet(ctx))
+		// We do not rescan again until the next scheduled transaction is nearly due
+		assert.Equal(t, later.Time().Add(-sth.policyLoopInterval), sth.scheduledRefresh)
+		assert.False(t, sth.scheduledRefreshDue())
+	}
+
+	// The in-flight set is full, but the scheduled transaction that is nearly due is added
+	rescan(t1, t2)
+	assert.Equal(t, []string{t1.ID, t2.ID, s1.ID}, inflightIDs(sth))
+
+	// The same when selecting by signer
+	sth.maxInFlightPerSigner = 2
+	rescan(t1, t2)
+	assert.Equal(t, []string{t1.ID, t2.ID, s1.ID}, inflightIDs(sth))
+
+	// We do not stop reading once the signers we have read fill the spaces
+	sth.maxInFlightPerSigner = 1
+	rescan(t1)
+	assert.Equal(t, []string{t1.ID, t2.ID, s1.ID}, inflightIDs(sth))
+
+	mp.AssertExpectations(t)
+}
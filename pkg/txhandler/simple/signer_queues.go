@@ -0,0 +1,170 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+)
+
+// signerQueue is the pending transactions for a signer that are waiting outside of the in-flight set, which
+// can only be selected in submission order as that is also nonce order
+type signerQueue struct {
+	signer     string
+	limit      int
+	candidates []*apitypes.ManagedTX
+	priorities []int // the effective priority of each candidate - see addPriority
+	selected   int
+}
+
+// addPriority records the priority of a transaction for the signer. A transaction cannot be mined until all the
+// earlier transactions for the signer have been, so the earlier candidates take on its priority if it is higher.
+func (q *signerQueue) addPriority(priority int) {
+	for i := len(q.priorities) - 1; i >= 0 && q.priorities[i] < priority; i-- {
+		q.priorities[i] = priority
+	}
+}
+
+func (q *signerQueue) next() (*apitypes.ManagedTX, int) {
+	return q.candidates[q.selected], q.priorities[q.selected]
+}
+
+// selectPendingBySigner reads the pending transactions, and selects those to add to the in-flight set across
+// all the signers. Higher priority transactions are selected first if enabled. Otherwise transactions are selected
+// round-robin across the signers when there is an in-flight limit for each signer, or in submission order if not.
+// The number of pending transactions for each signer that are left waiting outside of the in-flight set is
+// also returned, when all the pending transactions have been read. When rescanning for a scheduled transaction that
+// was left out of the in-flight set, all the pending transactions are always read, even if there are no spaces.
+func (sth *simpleTransactionHandler) selectPendingBySigner(ctx context.Context, spaces int, rescan bool) (selected []*apitypes.ManagedTX, queued map[string]int, ok bool) {
+	if spaces == 0 && !rescan {
+		return nil, nil, true
+	}
+	if rescan {
+		// We read all the pending transactions, so find all the scheduled transactions left out again
+		sth.scheduledRefresh = time.Time{}
+	}
+
+	inflightIDs := make(map[string]bool)
+	inflightCounts := make(map[string]int)
+	for _, p := range sth.inflight {
+		inflightIDs[p.mtx.ID] = true
+		if !awaitingNonce(p.mtx) {
+			inflightCounts[p.mtx.TransactionHeaders.From]++
+		}
+	}
+
+	// Signers are in the order of their oldest waiting transaction, which gets the first choice in each round
+	queues := []*signerQueue{}
+	bySigner := make(map[string]*signerQueue)
+	queued = make(map[string]int)
+	scheduled := []*apitypes.ManagedTX{}
+	signersWithCandidates := 0
+	after := ""
+	for {
+		page, err := sth.listPending(ctx, after, sth.maxInFlight)
+		if err != nil {
+			return nil, nil, false
+		}
+		for _, mtx := range page {
+			if inflightIDs[mtx.ID] {
+				continue
+			}
+			// Scheduled transactions that have not yet been assigned a nonce do not take up a space, and are always
+			// selected once they are nearly due
+			if awaitingNonce(mtx) {
+				if sth.nearlyDue(mtx) {
+					scheduled = append(scheduled, mtx)
+				} else {
+					sth.deferScheduled(mtx)
+				}
+				continue
+			}
+			signer := mtx.TransactionHeaders.From
+			q := bySigner[signer]
+			if q == nil {
+				q = &signerQueue{signer: signer, limit: spaces}
+				if sth.maxInFlightPerSigner > 0 {
+					q.limit = sth.maxInFlightPerSigner - inflightCounts[signer]
+				}
+				bySigner[signer] = q
+				queues = append(queues, q)
+			}
+			queued[signer]++
+			priority := 0
+			if sth.priorityEnabled {
+				priority = mtx.Priority
+				q.addPriority(priority)
+			}
+			if len(q.candidates) < q.limit {
+				if len(q.candidates) == 0 {
+					signersWithCandidates++
+				}
+				q.candidates = append(q.candidates, mtx)
+				q.priorities = append(q.priorities, priority)
+			}
+		}
+		if len(page) < sth.maxInFlight {
+			break
+		}
+		// Without priority, the first candidate of each signer is selected in the first round-robin round, ahead of
+		// any signer we have not yet read. So once that fills all the spaces, reading further cannot change the result,
+		// unless we are rescanning for scheduled transactions.
+		if !sth.priorityEnabled && !rescan && signersWithCandidates >= spaces {
+			log.L(ctx).Debugf("Stopped reading pending transactions with %d signers to fill %d spaces", signersWithCandidates, spaces)
+			queued = nil
+			break
+		}
+		after = page[len(page)-1].SequenceID
+	}
+
+	selected = make([]*apitypes.ManagedTX, 0, spaces)
+	for len(selected) < spaces {
+		var next *signerQueue
+		for _, q := range queues {
+			if q.selected < len(q.candidates) && (next == nil || sth.selectBefore(q, next)) {
+				next = q
+			}
+		}
+		if next == nil {
+			break
+		}
+		mtx, _ := next.next()
+		selected = append(selected, mtx)
+		if queued != nil {
+			queued[next.signer]--
+		}
+		next.selected++
+	}
+	return append(selected, scheduled...), queued, true
+}
+
+// selectBefore returns true if the next candidate of one signer should be selected before the next candidate of another
+func (sth *simpleTransactionHandler) selectBefore(q1, q2 *signerQueue) bool {
+	mtx1, priority1 := q1.next()
+	mtx2, priority2 := q2.next()
+	switch {
+	case priority1 != priority2:
+		return priority1 > priority2
+	case sth.maxInFlightPerSigner > 0:
+		return q1.selected < q2.selected
+	default:
+		return mtx1.SequenceID < mtx2.SequenceID
+	}
+}
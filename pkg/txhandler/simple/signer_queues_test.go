@@ -0,0 +1,272 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/metricsmocks"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestSignerQueuesHandler(t *testing.T, maxInFlight, maxInFlightPerSigner int) (*simpleTransactionHandler, *persistencemocks.TransactionPersistence) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.Set(MaxInFlight, maxInFlight)
+	conf.Set(MaxInFlightPerSigner, maxInFlightPerSigner)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+	return sth, tk.TXPersistence.(*persistencemocks.TransactionPersistence)
+}
+
+func newTestSignerQueueTX(signer string, seq int, priority ...int) *apitypes.ManagedTX {
+	mtx := &apitypes.ManagedTX{
+		ID:         fmt.Sprintf("ns1:%s-%d", signer, seq),
+		SequenceID: fmt.Sprintf("%.3d", seq),
+		Status:     apitypes.TxStatusPending,
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: signer,
+		},
+	}
+	if len(priority) > 0 {
+		mtx.Priority = priority[0]
+	}
+	return mtx
+}
+
+func inflightIDs(sth *simpleTransactionHandler) []string {
+	ids := make([]string, len(sth.inflight))
+	for i, p := range sth.inflight {
+		ids[i] = p.mtx.ID
+	}
+	return ids
+}
+
+func TestSignerQueuesConfig(t *testing.T) {
+	sth, _ := newTestSignerQueuesHandler(t, 10, 2)
+	assert.Equal(t, 2, sth.maxInFlightPerSigner)
+}
+
+func TestPriorityConfig(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(PriorityConfig).Set(PriorityEnabled, true)
+	conf.SubSection(PriorityConfig).Set(PriorityGasPricePercentage, 5)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	assert.True(t, sth.priorityEnabled)
+	assert.Equal(t, int64(5), sth.priorityGasPricePercentage)
+}
+
+func TestPriorityConfigBadPercentage(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(PriorityConfig).Set(PriorityGasPricePercentage, -1)
+	_, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.Regexp(t, "FF21098.*gasPricePercentage", err)
+}
+
+func TestSignerQueuesRoundRobin(t *testing.T) {
+	sth, mp := newTestSignerQueuesHandler(t, 4, 2)
+
+	// signer "a" is busy, with its transactions ahead of all the others
+	mp.On("ListTransactionsPending", sth.ctx, "", 4, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{
+		newTestSignerQueueTX("a", 1),
+		newTestSignerQueueTX("a", 2),
+		newTestSignerQueueTX("a", 3),
+		newTestSignerQueueTX("c", 4),
+	}, nil).Once()
+	mp.On("ListTransactionsPending", sth.ctx, "004", 4, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{
+		newTestSignerQueueTX("b", 5),
+		newTestSignerQueueTX("c", 6),
+		newTestSignerQueueTX("c", 7),
+	}, nil).Once()
+
+	queued := make(map[string]float64)
+	mmm := &metricsmocks.TransactionHandlerMetrics{}
+	mmm.On("SetTxHandlerGaugeMetric", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mmm.On("IncTxHandlerCounterMetricWithLabels", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mmm.On("SetTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, mock.Anything, mock.Anything, mock.Anything).Return()
+	mmm.On("SetTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsQueuedSigner, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		queued[args[3].(map[string]string)[metricsLabelNameSigner]] = args[2].(float64)
+	}).Return()
+	sth.toolkit.MetricsManager = mmm
+
+	assert.True(t, sth.updateInflightSet(sth.ctx))
+	assert.Equal(t, []string{"ns1:a-1", "ns1:c-4", "ns1:b-5", "ns1:a-2"}, inflightIDs(sth))
+	assert.Equal(t, map[string]float64{"a": 1, "b": 0, "c": 2}, queued)
+
+	mp.AssertExpectations(t)
+}
+
+func TestSignerQueuesPerSignerLimit(t *testing.T) {
+	sth, mp := newTestSignerQueuesHandler(t, 10, 1)
+	sth.inflight = []*pendingState{{mtx: newTestSignerQueueTX("a", 1)}}
+
+	mp.On("ListTransactionsPending", sth.ctx, "", 10, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{
+		newTestSignerQueueTX("a", 1),
+		newTestSignerQueueTX("a", 2),
+		newTestSignerQueueTX("b", 3),
+		newTestSignerQueueTX("b", 4),
+	}, nil).Once()
+
+	assert.True(t, sth.updateInflightSet(sth.ctx))
+	assert.Equal(t, []string{"ns1:a-1", "ns1:b-3"}, inflightIDs(sth))
+
+	mp.AssertExpectations(t)
+}
+
+func TestSignerQueuesFullDoesNotReadPending(t *testing.T) {
+	sth, mp := newTestSignerQueuesHandler(t, 1, 1)
+	sth.inflight = []*pendingState{{mtx: newTestSignerQueueTX("a", 1)}}
+
+	assert.True(t, sth.updateInflightSet(sth.ctx))
+	assert.Equal(t, []string{"ns1:a-1"}, inflightIDs(sth))
+	assert.Equal(t, map[string]bool{"a": true}, sth.signerMetricsReported)
+
+	mp.AssertNotCalled(t, "ListTransactionsPending", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSignerQueuesStopReadingOnceSpacesFilled(t *testing.T) {
+	sth, mp := newTestSignerQueuesHandler(t, 2, 2)
+
+	// Each signer has a candidate for the first round, so the later transactions cannot be selected
+	mp.On("ListTransactionsPending", sth.ctx, "", 2, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{
+		newTestSignerQueueTX("a", 1),
+		newTestSignerQueueTX("b", 2),
+	}, nil).Once()
+
+	mmm := &metricsmocks.TransactionHandlerMetrics{}
+	mmm.On("SetTxHandlerGaugeMetric", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mmm.On("IncTxHandlerCounterMetricWithLabels", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mmm.On("SetTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, mock.Anything, mock.Anything, mock.Anything).Return()
+	sth.toolkit.MetricsManager = mmm
+
+	assert.True(t, sth.updateInflightSet(sth.ctx))
+	assert.Equal(t, []string{"ns1:a-1", "ns1:b-2"}, inflightIDs(sth))
+
+	// The queue for each signer is not known, so is not reported
+	mp.AssertExpectations(t)
+	mmm.AssertNotCalled(t, "SetTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsQueuedSigner, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPrioritySelection(t *testing.T) {
+	sth, mp := newTestSignerQueuesHandler(t, 4, 0)
+	sth.priorityEnabled = true
+
+	// The earlier transactions for "a" are selected ahead of "b", as they must be mined before its urgent transaction.
+	// Transactions of the same priority are selected in submission order.
+	mp.On("ListTransactionsPending", sth.ctx, "", 4, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{
+		newTestSignerQueueTX("a", 1),
+		newTestSignerQueueTX("a", 2),
+		newTestSignerQueueTX("b", 3),
+		newTestSignerQueueTX("c", 4, 5),
+	}, nil).Once()
+	mp.On("ListTransactionsPending", sth.ctx, "004", 4, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{
+		newTestSignerQueueTX("a", 5, 5),
+		newTestSignerQueueTX("b", 6),
+	}, nil).Once()
+
+	assert.True(t, sth.updateInflightSet(sth.ctx))
+	assert.Equal(t, []string{"ns1:a-1", "ns1:a-2", "ns1:c-4", "ns1:a-5"}, inflightIDs(sth))
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, sth.signerMetricsReported)
+
+	mp.AssertExpectations(t)
+}
+
+func TestPrioritySelectionPerSignerLimit(t *testing.T) {
+	sth, mp := newTestSignerQueuesHandler(t, 10, 2)
+	sth.priorityEnabled = true
+
+	// Signers with the same priority are selected round-robin
+	mp.On("ListTransactionsPending", sth.ctx, "", 10, persistence.SortDirectionAscending).Return([]*apitypes.ManagedTX{
+		newTestSignerQueueTX("a", 1),
+		newTestSignerQueueTX("a", 2),
+		newTestSignerQueueTX("b", 3),
+		newTestSignerQueueTX("b", 4),
+		newTestSignerQueueTX("c", 5, 1),
+		newTestSignerQueueTX("c", 6, 1),
+		newTestSignerQueueTX("c", 7, 1),
+	}, nil).Once()
+
+	assert.True(t, sth.updateInflightSet(sth.ctx))
+	assert.Equal(t, []string{"ns1:c-5", "ns1:c-6", "ns1:a-1", "ns1:b-3", "ns1:a-2", "ns1:b-4"}, inflightIDs(sth))
+
+	mp.AssertExpectations(t)
+}
+
+func TestSignerQueuesListFailCancel(t *testing.T) {
+	sth, mp := newTestSignerQueuesHandler(t, 10, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mp.On("ListTransactionsPending", ctx, "", 10, persistence.SortDirectionAscending).Return(nil, fmt.Errorf("pop"))
+
+	assert.False(t, sth.updateInflightSet(ctx))
+
+	mp.AssertExpectations(t)
+}
+
+func TestSignerQueueMetricsRemovedSigners(t *testing.T) {
+	sth, _ := newTestSignerQueuesHandler(t, 10, 1)
+
+	reported := func(name string) map[string]float64 {
+		values := make(map[string]float64)
+		for _, call := range sth.toolkit.MetricsManager.(*metricsmocks.TransactionHandlerMetrics).Calls {
+			if call.Arguments[1] == name {
+				values[call.Arguments[3].(map[string]string)[metricsLabelNameSigner]] = call.Arguments[2].(float64)
+			}
+		}
+		return values
+	}
+	resetMetrics := func() {
+		mmm := &metricsmocks.TransactionHandlerMetrics{}
+		mmm.On("SetTxHandlerGaugeMetricWithLabels", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		sth.toolkit.MetricsManager = mmm
+	}
+
+	resetMetrics()
+	sth.inflight = []*pendingState{{mtx: newTestSignerQueueTX("a", 1)}}
+	sth.setSignerQueueMetrics(sth.ctx, map[string]int{"b": 1})
+	assert.Equal(t, map[string]float64{"a": 1, "b": 0}, reported(metricsGaugeTransactionsInflightSigner))
+	assert.Equal(t, map[string]float64{"a": 0, "b": 1}, reported(metricsGaugeTransactionsQueuedSigner))
+
+	// Signers that have gone are reported as zero once
+	resetMetrics()
+	sth.inflight = []*pendingState{}
+	sth.setSignerQueueMetrics(sth.ctx, map[string]int{})
+	assert.Equal(t, map[string]float64{"a": 0, "b": 0}, reported(metricsGaugeTransactionsInflightSigner))
+	assert.Equal(t, map[string]float64{"a": 0, "b": 0}, reported(metricsGaugeTransactionsQueuedSigner))
+
+	resetMetrics()
+	sth.setSignerQueueMetrics(sth.ctx, map[string]int{})
+	assert.Empty(t, reported(metricsGaugeTransactionsInflightSigner))
+	assert.Empty(t, sth.signerMetricsReported)
+}
@@ -62,12 +62,16 @@ func newTestTransactionHandlerFactory(t *testing.T) (*TransactionHandlerFactory,
 	assert.Equal(t, "simple", f.Name())
 
 	mockPersistence := &persistencemocks.TransactionPersistence{}
+	mockPersistence.On("ListNonceReservations", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*apitypes.NonceReservation{}, nil).Maybe()
+	mockPersistence.On("GetSignerMinimumNonce", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	mockHistoryPersistence := &persistencemocks.TransactionHistoryPersistence{}
+	mockHistoryPersistence.On("WriteTransactionHistory", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 	mockFFCAPI := &ffcapimocks.API{}
 
 	return f, &txhandler.Toolkit{
 		Connector:      mockFFCAPI,
-		TXHistory:      txhistory.NewTxHistoryManager(context.Background()),
+		TXHistory:      txhistory.NewTxHistoryManager(context.Background(), mockHistoryPersistence),
 		TXPersistence:  mockPersistence,
 		MetricsManager: metrics.NewMetricsManager(context.Background()),
 	}, mockFFCAPI, conf
@@ -95,7 +99,7 @@ func newTestTransactionHandlerFactoryWithFilePersistence(t *testing.T) (*Transac
 
 	return f, &txhandler.Toolkit{
 			Connector:      mockFFCAPI,
-			TXHistory:      txhistory.NewTxHistoryManager(context.Background()),
+			TXHistory:      txhistory.NewTxHistoryManager(context.Background(), filePersistence),
 			TXPersistence:  filePersistence,
 			MetricsManager: metrics.NewMetricsManager(context.Background()),
 			EventHandler:   mockEventHandler,
@@ -126,6 +130,21 @@ func TestSupportDeprecatedPolicyEngineConfiguration(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestImplementsOptionalInterfaces(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	assert.Implements(t, (*txhandler.CancelByReplacementHandler)(nil), th)
+	assert.Implements(t, (*txhandler.ResubmitHandler)(nil), th)
+	assert.Implements(t, (*txhandler.SignerStatusHandler)(nil), th)
+	assert.Implements(t, (*txhandler.SignerNonceHandler)(nil), th)
+	assert.Implements(t, (*txhandler.TransactionBatchHandler)(nil), th)
+	assert.Implements(t, (*txhandler.RawTransactionHandler)(nil), th)
+}
+
 func TestMissingGasConfig(t *testing.T) {
 	f, _, _, conf := newTestTransactionHandlerFactory(t)
 	conf.SubSection(GasOracleConfig).Set(GasOracleMode, GasOracleModeDisabled)
@@ -166,6 +185,9 @@ func TestFixedGasPriceOK(t *testing.T) {
 	mmm := &metricsmocks.TransactionHandlerMetrics{}
 	mmm.On("InitTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightUsed, metricsGaugeTransactionsInflightUsedDescription, false).Return(fmt.Errorf("fail")).Once()
 	mmm.On("InitTxHandlerGaugeMetric", mock.Anything, metricsGaugeTransactionsInflightFree, metricsGaugeTransactionsInflightFreeDescription, false).Return(fmt.Errorf("fail")).Once()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsInflightSigner, metricsGaugeTransactionsInflightSignerDescription, []string{metricsLabelNameSigner}, false).Return(fmt.Errorf("fail")).Once()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeTransactionsQueuedSigner, metricsGaugeTransactionsQueuedSignerDescription, []string{metricsLabelNameSigner}, false).Return(fmt.Errorf("fail")).Once()
+	mmm.On("InitTxHandlerGaugeMetricWithLabels", mock.Anything, metricsGaugeSignerUnderfunded, metricsGaugeSignerUnderfundedDescription, []string{metricsLabelNameSigner}, false).Return(fmt.Errorf("fail")).Once()
 	mmm.On("InitTxHandlerCounterMetricWithLabels", mock.Anything, metricsCounterTransactionProcessOperationsTotal, metricsCounterTransactionProcessOperationsTotalDescription, []string{metricsLabelNameOperation}, true).Return(fmt.Errorf("fail")).Once()
 	mmm.On("InitTxHandlerHistogramMetricWithLabels", mock.Anything, metricsHistogramTransactionProcessOperationsDuration, metricsHistogramTransactionProcessOperationsDurationDescription, []float64{}, []string{metricsLabelNameOperation}, true).Return(fmt.Errorf("fail")).Once()
 	mmm.On("IncTxHandlerCounterMetricWithLabels", mock.Anything, metricsCounterTransactionProcessOperationsTotal, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
@@ -833,7 +855,529 @@ func TestSendTXPersistFail(t *testing.T) {
 	err = json.Unmarshal([]byte(sampleSendTX), &txReq)
 	assert.NoError(t, err)
 
-	_, err = sth.createManagedTx(sth.ctx, "id1", &txReq.TransactionHeaders, fftypes.NewFFBigInt(12345), "0x123456")
+	_, err = sth.createManagedTx(sth.ctx, &apitypes.RequestHeaders{ID: "id1"}, "", &txReq.TransactionHeaders, fftypes.NewFFBigInt(12345), "0x123456")
+	assert.Regexp(t, "pop", err)
+
+}
+
+func TestHandleNewTransactionDeadline(t *testing.T) {
+	f, tk, mockFFCAPI, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `12345`)
+	conf.Set(DefaultDeadline, "1h")
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(1),
+	}, ffcapi.ErrorReason(""), nil)
+	mockFFCAPI.On("TransactionPrepare", mock.Anything, mock.Anything).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(100000),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil)
+
+	txInput := ffcapi.TransactionInput{
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0xaaaaa",
+		},
+	}
+
+	// The deadline in the request takes precedence
+	deadline := fftypes.FFTime(time.Now().Add(10 * time.Minute))
+	mtx, err := sth.HandleNewTransaction(sth.ctx, &apitypes.TransactionRequest{
+		Headers:          apitypes.RequestHeaders{Deadline: &deadline},
+		TransactionInput: txInput,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, deadline.String(), mtx.Deadline.String())
+
+	// Otherwise the default applies
+	mtx, err = sth.HandleNewTransaction(sth.ctx, &apitypes.TransactionRequest{
+		TransactionInput: txInput,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, mtx.Deadline.Time().Sub(*mtx.Created.Time()))
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestHandleNewTransactionPriority(t *testing.T) {
+	f, tk, mockFFCAPI, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `1000`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(1),
+	}, ffcapi.ErrorReason(""), nil)
+	mockFFCAPI.On("TransactionPrepare", mock.Anything, mock.Anything).Return(&ffcapi.TransactionPrepareResponse{
+		Gas:             fftypes.NewFFBigInt(100000),
+		TransactionData: "0xabce1234",
+	}, ffcapi.ErrorReason(""), nil)
+	// The gas price is increased by the default 10% for each level of priority
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionSendRequest) bool {
+		return req.GasPrice.String() == `"1200"`
+	})).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0x12345"}, ffcapi.ErrorReason(""), nil)
+
+	mtx, err := sth.HandleNewTransaction(sth.ctx, &apitypes.TransactionRequest{
+		Headers: apitypes.RequestHeaders{Priority: 2},
+		TransactionInput: ffcapi.TransactionInput{
+			TransactionHeaders: ffcapi.TransactionHeaders{
+				From: "0xaaaaa",
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, mtx.Priority)
+
+	updated, _, err := sth.processTransaction(sth.ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Equal(t, `"1200"`, mtx.GasPrice.String())
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestHandleNewRawTransaction(t *testing.T) {
+	f, tk, mockFFCAPI, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `1000`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(10),
+	}, ffcapi.ErrorReason(""), nil).Once()
+
+	// The signed transaction is submitted as-is, without a gas price
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionSendRequest) bool {
+		return req.PreSigned && req.TransactionData == "0xf86c0a85" && req.Nonce.Int64() == 10 && req.GasPrice == nil
+	})).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0x12345"}, ffcapi.ErrorReason(""), nil).Once()
+
+	txReq := &apitypes.RawTransactionRequest{
+		Headers: apitypes.RequestHeaders{ID: "ns1:raw1"},
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From:  "0xaaaaa",
+			Nonce: fftypes.NewFFBigInt(10),
+		},
+		RawTransaction: "0xf86c0a85",
+	}
+	mtx, err := sth.HandleNewRawTransaction(sth.ctx, txReq)
+	assert.NoError(t, err)
+	assert.True(t, mtx.PreSigned)
+	assert.Equal(t, int64(10), mtx.Nonce.Int64())
+	assert.Equal(t, txReq.RequestHash(), mtx.RequestHash)
+
+	updated, _, err := sth.processTransaction(sth.ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Nil(t, mtx.GasPrice)
+	assert.Equal(t, "0x12345", mtx.TransactionHash)
+
+	// The gas price cannot be changed, and it cannot be cancelled by a replacement
+	bump := int64(10)
+	err = sth.requestResubmit(sth.ctx, &pendingState{mtx: mtx}, &apitypes.ResubmitTransactionRequest{BumpPercentage: &bump})
+	assert.Regexp(t, "FF21103", err)
+	err = sth.requestCancellation(sth.ctx, &pendingState{mtx: mtx})
+	assert.Regexp(t, "FF21103", err)
+
+	// A transaction with a nonce that is already used is rejected
+	_, err = sth.HandleNewRawTransaction(sth.ctx, &apitypes.RawTransactionRequest{
+		Headers:            apitypes.RequestHeaders{ID: "ns1:raw2"},
+		TransactionHeaders: txReq.TransactionHeaders,
+		RawTransaction:     "0xf86c0a86",
+	})
+	assert.Regexp(t, "FF21109.*ns1:raw1", err)
+
+	// A transaction with a nonce after the next one is rejected, as it would leave a gap
+	_, err = sth.HandleNewRawTransaction(sth.ctx, &apitypes.RawTransactionRequest{
+		Headers: apitypes.RequestHeaders{ID: "ns1:raw2"},
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From:  "0xaaaaa",
+			Nonce: fftypes.NewFFBigInt(12),
+		},
+		RawTransaction: "0xf86c0a86",
+	})
+	assert.Regexp(t, "FF21111.*12.*11", err)
+
+	// A transaction with the same ID is rejected
+	txReq.Nonce = fftypes.NewFFBigInt(11)
+	_, err = sth.HandleNewRawTransaction(sth.ctx, txReq)
+	assert.Regexp(t, "FF21065", err)
+
+	// The nonce is never assigned to a transaction we sign
+	assert.Equal(t, uint64(11), sth.minimumNonces["0xaaaaa"])
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestHandleNewRawTransactionNonceFail(t *testing.T) {
+	f, tk, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	// A fresh persistence mock, without the default minimum nonce
+	mp := &persistencemocks.TransactionPersistence{}
+	tk.TXPersistence = mp
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	txReq := &apitypes.RawTransactionRequest{
+		Headers: apitypes.RequestHeaders{ID: "ns1:raw1"},
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From:  "0xaaaaa",
+			Nonce: fftypes.NewFFBigInt(10),
+		},
+		RawTransaction: "0xf86c0a85",
+	}
+
+	// Loading the minimum nonce fails
+	mp.On("GetSignerMinimumNonce", mock.Anything, "0xaaaaa").Return(nil, fmt.Errorf("pop")).Once()
+	_, err = sth.HandleNewRawTransaction(sth.ctx, txReq)
+	assert.Regexp(t, "pop", err)
+
+	// Checking the nonce is free fails
+	mp.On("GetSignerMinimumNonce", mock.Anything, "0xaaaaa").Return(nil, nil).Once()
+	mp.On("ListNonceReservations", mock.Anything, "0xaaaaa", (*fftypes.FFBigInt)(nil), 1, mock.Anything).Return([]*apitypes.NonceReservation{}, nil).Once()
+	mp.On("GetTransactionByNonce", mock.Anything, "0xaaaaa", txReq.Nonce).Return(nil, fmt.Errorf("pop")).Once()
+	_, err = sth.HandleNewRawTransaction(sth.ctx, txReq)
+	assert.Regexp(t, "pop", err)
+
+	// Calculating the next nonce fails
+	mp.On("GetTransactionByNonce", mock.Anything, "0xaaaaa", txReq.Nonce).Return(nil, nil).Once()
+	mp.On("ListTransactionsByNonce", mock.Anything, "0xaaaaa", (*fftypes.FFBigInt)(nil), 1, persistence.SortDirectionDescending).Return(nil, fmt.Errorf("pop")).Once()
+	_, err = sth.HandleNewRawTransaction(sth.ctx, txReq)
+	assert.Regexp(t, "pop", err)
+
+	// The nonce lock is released in every case
+	assert.Empty(t, sth.lockedNonces)
+
+	mp.AssertExpectations(t)
+}
+
+func TestHandleNewRawTransactionMissingFields(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	ctx := context.Background()
+
+	_, err = sth.HandleNewRawTransaction(ctx, &apitypes.RawTransactionRequest{})
+	assert.Regexp(t, "FF21102.*from", err)
+
+	_, err = sth.HandleNewRawTransaction(ctx, &apitypes.RawTransactionRequest{
+		TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa"},
+	})
+	assert.Regexp(t, "FF21102.*nonce", err)
+
+	_, err = sth.HandleNewRawTransaction(ctx, &apitypes.RawTransactionRequest{
+		TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa", Nonce: fftypes.NewFFBigInt(1)},
+	})
+	assert.Regexp(t, "FF21102.*rawTransaction", err)
+}
+
+func TestHandleNewRawTransactionGeneratedID(t *testing.T) {
+	f, tk, mockFFCAPI, conf, cleanup := newTestTransactionHandlerFactoryWithFilePersistence(t)
+	defer cleanup()
+	conf.Set(FixedGasPrice, `1000`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+
+	mockFFCAPI.On("NextNonceForSigner", mock.Anything, mock.Anything).Return(&ffcapi.NextNonceForSignerResponse{
+		Nonce: fftypes.NewFFBigInt(1),
+	}, ffcapi.ErrorReason(""), nil).Once()
+
+	mtx, err := sth.HandleNewRawTransaction(sth.ctx, &apitypes.RawTransactionRequest{
+		TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa", Nonce: fftypes.NewFFBigInt(1)},
+		RawTransaction:     "0xf86c0a85",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, mtx.ID)
+}
+
+func TestDeadlineExceededSubmitsCancellation(t *testing.T) {
+	f, tk, mockFFCAPI, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	submitTime := fftypes.FFTime(time.Now().Add(-100 * time.Hour))
+	deadline := fftypes.FFTime(time.Now().Add(-1 * time.Hour))
+	mtx := &apitypes.ManagedTX{
+		ID: "ns1:" + fftypes.NewUUID().String(),
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0x6b7cfa4cf9709d3b3f5f7c22de123d2e16aee712",
+			To:   "0x7fb1b1de2c4f21eb62b1a9b0bb1c2f1a5c2b1c14",
+		},
+		Nonce:           fftypes.NewFFBigInt(10),
+		TransactionData: "SOME_RAW_TX_BYTES",
+		FirstSubmit:     &submitTime,
+		GasPrice:        fftypes.JSONAnyPtr(`1000`),
+		Status:          apitypes.TxStatusPending,
+		Deadline:        &deadline,
+		History:         []*apitypes.TxHistoryStateTransitionEntry{{Status: apitypes.TxSubStatusReceived, Time: fftypes.Now(), Actions: []*apitypes.TxHistoryActionEntry{}}},
+	}
+
+	// A zero value transfer to self replaces the transaction, with the minimum replacement gas price increase
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionSendRequest) bool {
+		return req.To == mtx.TransactionHeaders.From &&
+			req.Nonce.Int64() == 10 &&
+			req.Value.Int64() == 0 &&
+			req.TransactionData == "" &&
+			req.GasPrice.String() == `"1100"`
+	})).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0xcancel"}, ffcapi.ErrorReason(""), nil).Once()
+
+	ctx := context.Background()
+	th.Init(ctx, tk)
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	updated, reason, err := sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Empty(t, reason)
+	assert.Equal(t, UpdateYes, updated)
+	assert.NotNil(t, mtx.CancelRequested)
+	assert.Equal(t, []string{"0xcancel"}, mtx.CancellationHashes)
+	assert.Equal(t, apitypes.TxSubStatusCancelling, sth.toolkit.TXHistory.CurrentSubStatus(ctx, mtx).Status)
+
+	// The transaction is not failed until the cancellation is mined, as it could still be mined itself
+	assert.Equal(t, apitypes.TxStatusPending, mtx.Status)
+	assert.Empty(t, mtx.ErrorMessage)
+
+	// The cancellation is retried if it is rejected
+	sth.resubmitInterval = 0
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.Anything).
+		Return(nil, ffcapi.ErrorReasonTransactionUnderpriced, fmt.Errorf("underpriced")).Once()
+	updated, reason, err = sth.processTransaction(ctx, mtx)
+	assert.Regexp(t, "underpriced", err)
+	assert.Equal(t, ffcapi.ErrorReasonTransactionUnderpriced, reason)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Equal(t, apitypes.TxStatusPending, mtx.Status)
+
+	// Or if we cannot get a gas price for the cancellation
+	sth.gasOracleMode = GasOracleModeConnector
+	mockFFCAPI.On("GasPriceEstimate", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Once()
+	updated, _, err = sth.processTransaction(ctx, mtx)
 	assert.Regexp(t, "pop", err)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Equal(t, apitypes.TxStatusPending, mtx.Status)
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestDeadlineExceededPreSignedFails(t *testing.T) {
+	f, tk, mockFFCAPI, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	submitTime := fftypes.FFTime(time.Now().Add(-100 * time.Hour))
+	deadline := fftypes.FFTime(time.Now().Add(-1 * time.Hour))
+	mtx := &apitypes.ManagedTX{
+		ID: "ns1:" + fftypes.NewUUID().String(),
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0x6b7cfa4cf9709d3b3f5f7c22de123d2e16aee712",
+		},
+		Nonce:           fftypes.NewFFBigInt(10),
+		TransactionData: "SIGNED_TX_BYTES",
+		PreSigned:       true,
+		FirstSubmit:     &submitTime,
+		Status:          apitypes.TxStatusPending,
+		Deadline:        &deadline,
+	}
+
+	// We cannot sign a replacement to cancel a pre-signed transaction, so it is failed immediately
+	ctx := context.Background()
+	th.Init(ctx, tk)
+	sth := th.(*simpleTransactionHandler)
+	updated, _, err := sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Nil(t, mtx.CancelRequested)
+	assert.Equal(t, apitypes.TxStatusFailed, mtx.Status)
+	assert.Regexp(t, "FF21091", mtx.ErrorMessage)
 
+	mockFFCAPI.AssertNotCalled(t, "TransactionSend", mock.Anything, mock.Anything)
+}
+
+func TestWarnStaleResubmitEscalatesGasPrice(t *testing.T) {
+	f, tk, mockFFCAPI, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	conf.SubSection(GasPriceEscalationConfig).Set(GasPriceEscalationPercentage, 10)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	submitTime := fftypes.FFTime(time.Now().Add(-100 * time.Hour))
+	mtx := &apitypes.ManagedTX{
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0x6b7cfa4cf9709d3b3f5f7c22de123d2e16aee712",
+		},
+		TransactionData: "SOME_RAW_TX_BYTES",
+		FirstSubmit:     &submitTime,
+		GasPrice:        fftypes.JSONAnyPtr(`1000`),
+		History:         []*apitypes.TxHistoryStateTransitionEntry{{Status: apitypes.TxSubStatusReceived, Time: fftypes.Now(), Actions: []*apitypes.TxHistoryActionEntry{}}},
+	}
+
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionSendRequest) bool {
+		return req.GasPrice.String() == `"1100"`
+	})).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0x12345"}, ffcapi.ErrorReason(""), nil)
+
+	ctx := context.Background()
+	th.Init(ctx, tk)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	updated, reason, err := sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Empty(t, reason)
+	assert.Equal(t, UpdateYes, updated)
+	assert.Equal(t, `"1100"`, mtx.GasPrice.String())
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestUnderpricedEscalatesWithoutWaiting(t *testing.T) {
+	f, tk, mockFFCAPI, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	conf.Set(ResubmitInterval, "100s")
+	conf.SubSection(GasPriceEscalationConfig).Set(GasPriceEscalationPercentage, 10)
+	conf.SubSection(GasPriceEscalationConfig).Set(GasPriceEscalationMaximumGasPrice, "1250")
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	mtx := &apitypes.ManagedTX{
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0x6b7cfa4cf9709d3b3f5f7c22de123d2e16aee712",
+		},
+		TransactionData: "SOME_RAW_TX_BYTES",
+		History:         []*apitypes.TxHistoryStateTransitionEntry{{Status: apitypes.TxSubStatusReceived, Time: fftypes.Now(), Actions: []*apitypes.TxHistoryActionEntry{}}},
+	}
+
+	ctx := context.Background()
+	th.Init(ctx, tk)
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+
+	// First submission is rejected as underpriced
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.Anything).
+		Return(nil, ffcapi.ErrorReasonTransactionUnderpriced, fmt.Errorf("underpriced")).Once()
+	updated, reason, err := sth.processTransaction(ctx, mtx)
+	assert.Regexp(t, "underpriced", err)
+	assert.Equal(t, ffcapi.ErrorReasonTransactionUnderpriced, reason)
+	assert.Equal(t, UpdateYes, updated)
+	assert.True(t, mtx.PolicyInfo.JSONObject().GetBool("underpriced"))
+
+	// Next attempt escalates from the rejected price, and succeeds
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionSendRequest) bool {
+		return req.GasPrice.String() == `"1100"`
+	})).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0x12345"}, ffcapi.ErrorReason(""), nil).Once()
+	updated, _, err = sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateYes, updated)
+	assert.NotNil(t, mtx.FirstSubmit)
+	assert.False(t, mtx.PolicyInfo.JSONObject().GetBool("underpriced"))
+
+	// A resubmission within the resubmit interval is not required
+	updated, _, err = sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateNo, updated)
+
+	// If a resubmission is rejected as underpriced, we escalate again without waiting for the resubmit interval
+	mtx.PolicyInfo = fftypes.JSONAnyPtr(fmt.Sprintf(`{"lastWarnTime":"%s"}`, fftypes.FFTime(time.Now().Add(-1*time.Hour))))
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.Anything).
+		Return(nil, ffcapi.ErrorReasonTransactionUnderpriced, fmt.Errorf("underpriced")).Once()
+	_, reason, err = sth.processTransaction(ctx, mtx)
+	assert.Regexp(t, "underpriced", err)
+	assert.Equal(t, ffcapi.ErrorReasonTransactionUnderpriced, reason)
+	assert.Equal(t, `"1210"`, mtx.GasPrice.String())
+	assert.True(t, mtx.PolicyInfo.JSONObject().GetBool("underpriced"))
+
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.Anything).
+		Return(nil, ffcapi.ErrorReasonTransactionUnderpriced, fmt.Errorf("underpriced")).Once()
+	_, reason, err = sth.processTransaction(ctx, mtx)
+	assert.Regexp(t, "underpriced", err)
+	assert.Equal(t, ffcapi.ErrorReasonTransactionUnderpriced, reason)
+	assert.Equal(t, `"1250"`, mtx.GasPrice.String())
+	// We have now hit the ceiling, so cannot escalate further until the next resubmit
+	assert.False(t, mtx.PolicyInfo.JSONObject().GetBool("underpriced"))
+	updated, _, err = sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateNo, updated)
+
+	mockFFCAPI.AssertExpectations(t)
+}
+
+func TestWarnStaleResubmitEscalatesDynamicFees(t *testing.T) {
+	f, tk, mockFFCAPI, conf := newTestTransactionHandlerFactory(t)
+	conf.SubSection(GasOracleConfig).Set(GasOracleMode, GasOracleModeConnector)
+	conf.SubSection(GasPriceEscalationConfig).Set(GasPriceEscalationPercentage, 20)
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	submitTime := fftypes.FFTime(time.Now().Add(-100 * time.Hour))
+	mtx := &apitypes.ManagedTX{
+		SequenceID: apitypes.NewULID().String(),
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From: "0x6b7cfa4cf9709d3b3f5f7c22de123d2e16aee712",
+		},
+		TransactionData: "SOME_RAW_TX_BYTES",
+		FirstSubmit:     &submitTime,
+		GasPrice:        fftypes.JSONAnyPtr(`{"maxFeePerGas":"1000","maxPriorityFeePerGas":"100"}`),
+		History:         []*apitypes.TxHistoryStateTransitionEntry{{Status: apitypes.TxSubStatusReceived, Time: fftypes.Now(), Actions: []*apitypes.TxHistoryActionEntry{}}},
+	}
+
+	// The base fee has gone up, but the priority fee has not
+	mockFFCAPI.On("GasPriceEstimate", mock.Anything, mock.Anything).Return(&ffcapi.GasPriceEstimateResponse{
+		GasPrice: fftypes.JSONAnyPtr(`{"maxFeePerGas":"2000","maxPriorityFeePerGas":"100"}`),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	mockFFCAPI.On("TransactionSend", mock.Anything, mock.MatchedBy(func(req *ffcapi.TransactionSendRequest) bool {
+		return req.GasPrice.String() == `{"maxFeePerGas":"2000","maxPriorityFeePerGas":"120"}`
+	})).Return(&ffcapi.TransactionSendResponse{TransactionHash: "0x12345"}, ffcapi.ErrorReason(""), nil)
+
+	ctx := context.Background()
+	mhp := &persistencemocks.TransactionHistoryPersistence{}
+	mhp.On("WriteTransactionHistory", mock.Anything, mock.Anything).Return(nil)
+	tk.TXHistory = txhistory.NewTxHistoryManager(ctx, mhp)
+	th.Init(ctx, tk)
+
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	updated, reason, err := sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Empty(t, reason)
+	assert.Equal(t, UpdateYes, updated)
+
+	// Both fees are recorded in the history
+	var gasPriceAction *apitypes.TxHistoryActionEntry
+	for _, c := range mhp.Calls {
+		for _, a := range c.Arguments[1].(*apitypes.TxHistoryRecord).Actions {
+			if a.Action == apitypes.TxActionRetrieveGasPrice {
+				gasPriceAction = a
+			}
+		}
+	}
+	assert.NotNil(t, gasPriceAction)
+	assert.Equal(t, "2000", gasPriceAction.LastInfo.JSONObject().GetString("maxFeePerGas"))
+	assert.Equal(t, "120", gasPriceAction.LastInfo.JSONObject().GetString("maxPriorityFeePerGas"))
+
+	mockFFCAPI.AssertExpectations(t)
 }
@@ -43,6 +43,10 @@ const metricsCounterTransactionProcessOperationsTotal = "tx_process_operation_to
 const metricsCounterTransactionProcessOperationsTotalDescription = "Number of transaction process operations occurred grouped by operation name"
 
 const metricsLabelNameOperation = "operation"
+const metricsLabelNameSigner = "signer"
+
+// cancellationGas is the gas limit for the zero value transfer used to cancel a transaction, or fill a nonce gap
+const cancellationGas = 21000
 
 const metricsHistogramTransactionProcessOperationsDuration = "tx_process_duration_seconds"
 const metricsHistogramTransactionProcessOperationsDurationDescription = "Duration of transaction process grouped by operation name"
@@ -64,7 +68,10 @@ func (f *TransactionHandlerFactory) Name() string {
 
 // simpleTransactionHandler is a base transaction handler forming an example for extension:
 // - It offers three ways of calculating gas price: use a fixed number, use the built-in API of a ethereum connector, use a RESTful gas oracle
-// - It resubmits the transaction based on a configured interval until it succeed or fail
+// - It understands both legacy gas prices, and EIP-1559 fee objects containing a maxFeePerGas and maxPriorityFeePerGas
+// - It resubmits the transaction based on a configured interval until it succeed or fail, escalating the gas price on each resubmit
+// - It can estimate the gas for new transactions before they are assigned a nonce, rejecting those that would revert
+// - It holds back transactions scheduled with a not-before time, only assigning them a nonce once they are due
 func (f *TransactionHandlerFactory) NewTransactionHandler(ctx context.Context, conf config.Section) (txhandler.TransactionHandler, error) {
 	gasOracleConfig := conf.SubSection(GasOracleConfig)
 	sth := &simpleTransactionHandler{
@@ -75,9 +82,11 @@ func (f *TransactionHandlerFactory) NewTransactionHandler(ctx context.Context, c
 		gasOracleQueryInterval: gasOracleConfig.GetDuration(GasOracleQueryInterval),
 		gasOracleMode:          gasOracleConfig.GetString(GasOracleMode),
 
-		lockedNonces:   make(map[string]*lockedNonce),
-		inflightStale:  make(chan bool, 1),
-		inflightUpdate: make(chan bool, 1),
+		lockedNonces:       make(map[string]*lockedNonce),
+		minimumNonces:      make(map[string]uint64),
+		underfundedSigners: make(map[string]*underfundedSigner),
+		inflightStale:      make(chan bool, 1),
+		inflightUpdate:     make(chan bool, 1),
 	}
 
 	// check whether we are using deprecated configuration
@@ -91,16 +100,42 @@ func (f *TransactionHandlerFactory) NewTransactionHandler(ctx context.Context, c
 			MaximumDelay: config.GetDuration(tmconfig.DeprecatedPolicyLoopRetryMaxDelay),
 			Factor:       config.GetFloat64(tmconfig.DeprecatedPolicyLoopRetryFactor),
 		}
+		// gas price escalation and deadlines are not supported with the deprecated configuration
+		sth.gasPriceEscalation = &gasPriceEscalation{}
 	} else {
 		// if not, use the new transaction handler configurations
 		sth.nonceStateTimeout = conf.GetDuration(NonceStateTimeout)
 		sth.maxInFlight = conf.GetInt(MaxInFlight)
+		sth.maxInFlightPerSigner = conf.GetInt(MaxInFlightPerSigner)
+		sth.maxBatchSize = conf.GetInt(MaxBatchSize)
 		sth.policyLoopInterval = conf.GetDuration(Interval)
 		sth.retry = &retry.Retry{
 			InitialDelay: conf.GetDuration(RetryInitDelay),
 			MaximumDelay: conf.GetDuration(RetryMaxDelay),
 			Factor:       conf.GetFloat64(RetryFactor),
 		}
+		gasPriceEscalation, err := newGasPriceEscalation(ctx, conf.SubSection(GasPriceEscalationConfig))
+		if err != nil {
+			return nil, err
+		}
+		sth.gasPriceEscalation = gasPriceEscalation
+		sth.defaultDeadline = conf.GetDuration(DefaultDeadline)
+		nonceGapConfig := conf.SubSection(NonceGapConfig)
+		sth.nonceGapFill = nonceGapConfig.GetBool(NonceGapFill)
+		sth.nonceGapCheckInterval = nonceGapConfig.GetDuration(NonceGapCheckInterval)
+		priorityConfig := conf.SubSection(PriorityConfig)
+		sth.priorityEnabled = priorityConfig.GetBool(PriorityEnabled)
+		sth.priorityGasPricePercentage = priorityConfig.GetInt64(PriorityGasPricePercentage)
+		if sth.priorityGasPricePercentage < 0 {
+			return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidPriorityConfig, PriorityGasPricePercentage, sth.priorityGasPricePercentage)
+		}
+		sth.balanceCheckInterval = conf.SubSection(InsufficientFundsConfig).GetDuration(InsufficientFundsBalanceCheckInterval)
+		preflightConfig := conf.SubSection(PreflightConfig)
+		sth.preflightEnabled = preflightConfig.GetBool(PreflightEnabled)
+		sth.preflightGasMultiplier = preflightConfig.GetFloat64(PreflightGasMultiplier)
+		if sth.preflightGasMultiplier < 1 {
+			return nil, i18n.NewError(ctx, tmmsgs.MsgInvalidPreflightConfig, PreflightGasMultiplier, sth.preflightGasMultiplier)
+		}
 	}
 
 	switch sth.gasOracleMode {
@@ -134,6 +169,7 @@ type simpleTransactionHandler struct {
 	toolkit          *txhandler.Toolkit
 	fixedGasPrice    *fftypes.JSONAny
 	resubmitInterval time.Duration
+	defaultDeadline  time.Duration
 
 	gasOracleMode          string
 	gasOracleClient        *resty.Client
@@ -142,8 +178,23 @@ type simpleTransactionHandler struct {
 	gasOracleQueryInterval time.Duration
 	gasOracleQueryValue    *fftypes.JSONAny
 	gasOracleLastQueryTime *fftypes.FFTime
+	gasPriceEscalation     *gasPriceEscalation
+
+	nonceGapFill          bool
+	nonceGapCheckInterval time.Duration
+	lastNonceGapCheck     time.Time
+
+	priorityEnabled            bool
+	priorityGasPricePercentage int64
+
+	preflightEnabled       bool
+	preflightGasMultiplier float64
+
+	underfundedSigners   map[string]*underfundedSigner // guarded by mux, as it is read by the API
+	balanceCheckInterval time.Duration
 
 	lockedNonces            map[string]*lockedNonce
+	minimumNonces           map[string]uint64
 	policyLoopInterval      time.Duration
 	policyLoopDone          chan struct{}
 	nonceStateTimeout       time.Duration
@@ -151,20 +202,28 @@ type simpleTransactionHandler struct {
 	inflightUpdate          chan bool
 	mux                     sync.Mutex
 	inflight                []*pendingState
+	scheduledRefresh        time.Time // when a scheduled transaction left out of the in-flight set is nearly due
 	policyEngineAPIRequests []*policyEngineAPIRequest
 	maxInFlight             int
+	maxInFlightPerSigner    int
+	maxBatchSize            int
+	signerMetricsReported   map[string]bool
 	retry                   *retry.Retry
 }
 type pendingState struct {
 	mtx                     *apitypes.ManagedTX
 	trackingTransactionHash string
+	trackingCancellation    string // the latest replacement submitted to cancel the transaction, which is tracked alongside the transaction
+	resubmitRequest         *apitypes.ResubmitTransactionRequest
 	lastPolicyCycle         time.Time
 	confirmed               bool
 	remove                  bool
 }
 
 type simplePolicyInfo struct {
-	LastWarnTime *fftypes.FFTime `json:"lastWarnTime"`
+	LastWarnTime   *fftypes.FFTime `json:"lastWarnTime"`
+	Underpriced    bool            `json:"underpriced,omitempty"`    // the last submission was rejected as underpriced, and the gas price can be escalated
+	LastCancelTime *fftypes.FFTime `json:"lastCancelTime,omitempty"` // the last time we attempted to submit a replacement to cancel the transaction
 }
 
 // withPolicyInfo is a convenience helper to run some logic that accesses/updates our policy section
@@ -203,17 +262,23 @@ func (sth *simpleTransactionHandler) Start(ctx context.Context) (done <-chan str
 }
 func (sth *simpleTransactionHandler) HandleNewTransaction(ctx context.Context, txReq *apitypes.TransactionRequest) (mtx *apitypes.ManagedTX, err error) {
 
+	// Check the transaction will not revert, before we assign it a nonce
+	txInput, err := sth.preflightTransaction(ctx, txReq.TransactionInput)
+	if err != nil {
+		return nil, err
+	}
+
 	// Prepare the transaction, which will mean we have a transaction that should be submittable.
 	// If we fail at this stage, we don't need to write any state as we are sure we haven't submitted
 	// anything to the blockchain itself.
 	prepared, _, err := sth.toolkit.Connector.TransactionPrepare(ctx, &ffcapi.TransactionPrepareRequest{
-		TransactionInput: txReq.TransactionInput,
+		TransactionInput: *txInput,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return sth.createManagedTx(ctx, txReq.Headers.ID, &txReq.TransactionHeaders, prepared.Gas, prepared.TransactionData)
+	return sth.createManagedTx(ctx, &txReq.Headers, txReq.RequestHash(), &txInput.TransactionHeaders, prepared.Gas, prepared.TransactionData)
 }
 func (sth *simpleTransactionHandler) HandleNewContractDeployment(ctx context.Context, txReq *apitypes.ContractDeployRequest) (mtx *apitypes.ManagedTX, err error) {
 
@@ -225,7 +290,65 @@ func (sth *simpleTransactionHandler) HandleNewContractDeployment(ctx context.Con
 		return nil, err
 	}
 
-	return sth.createManagedTx(ctx, txReq.Headers.ID, &txReq.TransactionHeaders, prepared.Gas, prepared.TransactionData)
+	return sth.createManagedTx(ctx, &txReq.Headers, txReq.RequestHash(), &txReq.TransactionHeaders, prepared.Gas, prepared.TransactionData)
+}
+func (sth *simpleTransactionHandler) HandleNewRawTransaction(ctx context.Context, txReq *apitypes.RawTransactionRequest) (mtx *apitypes.ManagedTX, err error) {
+
+	// We do not assign a nonce, as it is fixed by the signature. The signer and nonce must be supplied, as we cannot
+	// decode them from the signed transaction, and we need them to track the transaction alongside any others for the signer.
+	switch {
+	case txReq.From == "":
+		return nil, i18n.NewError(ctx, tmmsgs.MsgMissingRawTransactionField, "from")
+	case txReq.Nonce == nil:
+		return nil, i18n.NewError(ctx, tmmsgs.MsgMissingRawTransactionField, "nonce")
+	case txReq.RawTransaction == "":
+		return nil, i18n.NewError(ctx, tmmsgs.MsgMissingRawTransactionField, "rawTransaction")
+	case txReq.Headers.NotBefore != nil:
+		return nil, i18n.NewError(ctx, tmmsgs.MsgRawTransactionNotBefore)
+	}
+
+	txID := txReq.Headers.ID
+	if txID == "" {
+		txID = fftypes.NewUUID().String()
+	}
+
+	// We hold the nonce lock for the signer while we check the nonce is free and persist the transaction, so the
+	// nonce cannot be assigned to another transaction in the meantime
+	lockedNonce := sth.lockNonce(ctx, txID, txReq.From)
+	defer lockedNonce.complete(ctx)
+	if _, err := sth.getMinimumNonce(ctx, txReq.From); err != nil {
+		return nil, err
+	}
+
+	existing, err := sth.toolkit.TXPersistence.GetTransactionByNonce(ctx, txReq.From, txReq.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgNonceAlreadyUsed, txReq.Nonce, txReq.From, existing.ID)
+	}
+
+	// The nonces we skipped over would be seen as a gap, and filled with transactions we sign
+	nextNonce, err := sth.calcNextNonce(ctx, txReq.From)
+	if err != nil {
+		return nil, err
+	}
+	if txReq.Nonce.Uint64() > nextNonce {
+		return nil, i18n.NewError(ctx, tmmsgs.MsgRawTransactionNonceGap, txReq.Nonce, txReq.From, nextNonce)
+	}
+
+	mtx = sth.newManagedTx(txID, txReq.Nonce, &txReq.Headers, txReq.RequestHash(), &txReq.TransactionHeaders, txReq.Gas, txReq.RawTransaction)
+	mtx.PreSigned = true
+	sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
+	if err := sth.persistNewManagedTx(ctx, mtx); err != nil {
+		return nil, err
+	}
+
+	// The nonce is used now, so we never assign it (or any nonce before it) to a transaction we sign
+	sth.raiseMinimumNonce(txReq.From, txReq.Nonce.Uint64()+1)
+	lockedNonce.nonce = txReq.Nonce.Uint64()
+	lockedNonce.spent = mtx
+	return mtx, nil
 }
 func (sth *simpleTransactionHandler) HandleCancelTransaction(ctx context.Context, txID string) (mtx *apitypes.ManagedTX, err error) {
 	res := sth.policyEngineAPIRequest(ctx, &policyEngineAPIRequest{
@@ -234,13 +357,38 @@ func (sth *simpleTransactionHandler) HandleCancelTransaction(ctx context.Context
 	})
 	return res.tx, nil
 }
-func (sth *simpleTransactionHandler) createManagedTx(ctx context.Context, txID string, txHeaders *ffcapi.TransactionHeaders, gas *fftypes.FFBigInt, transactionData string) (*apitypes.ManagedTX, error) {
+func (sth *simpleTransactionHandler) HandleCancelTransactionByReplacement(ctx context.Context, txID string) (mtx *apitypes.ManagedTX, err error) {
+	res := sth.policyEngineAPIRequest(ctx, &policyEngineAPIRequest{
+		requestType: policyEngineAPIRequestTypeCancel,
+		txID:        txID,
+	})
+	return res.tx, res.err
+}
+func (sth *simpleTransactionHandler) HandleResubmitTransaction(ctx context.Context, txID string, req *apitypes.ResubmitTransactionRequest) (mtx *apitypes.ManagedTX, err error) {
+	res := sth.policyEngineAPIRequest(ctx, &policyEngineAPIRequest{
+		requestType: policyEngineAPIRequestTypeResubmit,
+		txID:        txID,
+		resubmit:    req,
+	})
+	return res.tx, res.err
+}
+func (sth *simpleTransactionHandler) createManagedTx(ctx context.Context, reqHeaders *apitypes.RequestHeaders, requestHash string, txHeaders *ffcapi.TransactionHeaders, gas *fftypes.FFBigInt, transactionData string) (*apitypes.ManagedTX, error) {
+
+	if err := checkNotBefore(ctx, reqHeaders); err != nil {
+		return nil, err
+	}
 
 	// The request ID is the primary ID, and should be supplied by the user for idempotence
+	txID := reqHeaders.ID
 	if txID == "" {
 		txID = fftypes.NewUUID().String()
 	}
 
+	// A transaction scheduled for later is assigned a nonce by the policy loop once it is due
+	if notYetDue(reqHeaders.NotBefore) {
+		return sth.createScheduledTx(ctx, txID, reqHeaders, requestHash, txHeaders, gas, transactionData)
+	}
+
 	// First job is to assign the next nonce to this request.
 	// We block any further sends on this nonce until we've got this one successfully into the node, or
 	// fail deterministically in a way that allows us to return it.
@@ -251,39 +399,82 @@ func (sth *simpleTransactionHandler) createManagedTx(ctx context.Context, txID s
 	// We will call markSpent() once we reach the point the nonce has been used
 	defer lockedNonce.complete(ctx)
 
+	mtx, err := sth.writeNewManagedTx(ctx, txID, lockedNonce.nonce, reqHeaders, requestHash, txHeaders, gas, transactionData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ok - we've spent it. The rest of the processing will be triggered off of lockedNonce
+	// completion adding this transaction to the pool (and/or the change event that comes in from
+	// FireFly core from the update to the transaction)
+	lockedNonce.spent = mtx
+	return mtx, nil
+}
+
+// writeNewManagedTx persists a new transaction with an assigned nonce, which must be called within the nonce lock for the signer
+func (sth *simpleTransactionHandler) writeNewManagedTx(ctx context.Context, txID string, nonce uint64, reqHeaders *apitypes.RequestHeaders, requestHash string, txHeaders *ffcapi.TransactionHeaders, gas *fftypes.FFBigInt, transactionData string) (*apitypes.ManagedTX, error) {
+
 	// Next we update FireFly core with the pre-submitted record pending record, with the allocated nonce.
 	// From this point on, we will guide this transaction through to submission.
 	// We return an "ack" at this point, and dispatch the work of getting the transaction submitted
 	// to the background worker.
+	mtx := sth.newManagedTx(txID, fftypes.NewFFBigInt(int64(nonce)), reqHeaders, requestHash, txHeaders, gas, transactionData)
+
+	sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
+	sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionAssignNonce, fftypes.JSONAnyPtr(`{"nonce":"`+mtx.Nonce.String()+`"}`), nil)
+
+	// Sequencing ID will be added as part of persistence logic - so we have a deterministic order of transactions
+	// Note: We must ensure persistence happens this within the nonce lock, to ensure that the nonce sequence and the
+	//       global transaction sequence line up.
+	if err := sth.persistNewManagedTx(ctx, mtx); err != nil {
+		return nil, err
+	}
+	return mtx, nil
+}
+
+// newManagedTx builds the pending record for a new transaction, with the default deadline applied. For a scheduled
+// transaction the default deadline runs from its not-before time.
+func (sth *simpleTransactionHandler) newManagedTx(txID string, nonce *fftypes.FFBigInt, reqHeaders *apitypes.RequestHeaders, requestHash string, txHeaders *ffcapi.TransactionHeaders, gas *fftypes.FFBigInt, transactionData string) *apitypes.ManagedTX {
 	now := fftypes.Now()
 	mtx := &apitypes.ManagedTX{
 		ID:                 txID, // on input the request ID must be the namespaced operation ID
 		Created:            now,
 		Updated:            now,
-		Nonce:              fftypes.NewFFBigInt(int64(lockedNonce.nonce)),
+		Nonce:              nonce,
 		Gas:                gas,
 		TransactionHeaders: *txHeaders,
 		TransactionData:    transactionData,
 		Status:             apitypes.TxStatusPending,
+		Deadline:           reqHeaders.Deadline,
+		Priority:           reqHeaders.Priority,
+		NotBefore:          reqHeaders.NotBefore,
+		RequestHash:        requestHash,
 	}
+	if mtx.Deadline == nil && sth.defaultDeadline > 0 {
+		start := *now.Time()
+		if notYetDue(mtx.NotBefore) {
+			start = *mtx.NotBefore.Time()
+		}
+		deadline := fftypes.FFTime(start.Add(sth.defaultDeadline))
+		mtx.Deadline = &deadline
+	}
+	return mtx
+}
 
-	sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
-	sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionAssignNonce, fftypes.JSONAnyPtr(`{"nonce":"`+mtx.Nonce.String()+`"}`), nil)
-
-	// Sequencing ID will be added as part of persistence logic - so we have a deterministic order of transactions
-	// Note: We must ensure persistence happens this within the nonce lock, to ensure that the nonce sequence and the
-	//       global transaction sequence line up.
-	if err = sth.toolkit.TXPersistence.WriteTransaction(ctx, mtx, true); err != nil {
-		return nil, err
+// persistNewManagedTx writes a new transaction, followed by the history recorded for it, and triggers the policy
+// loop to add it to the in-flight set
+func (sth *simpleTransactionHandler) persistNewManagedTx(ctx context.Context, mtx *apitypes.ManagedTX) error {
+	if err := sth.toolkit.TXPersistence.WriteTransaction(ctx, mtx, true); err != nil {
+		return err
+	}
+	sth.toolkit.TXHistory.WriteNewTransactionHistory(ctx, mtx)
+	if awaitingNonce(mtx) {
+		log.L(ctx).Infof("Tracking transaction %s for signer %s scheduled at %s", mtx.ID, mtx.TransactionHeaders.From, mtx.NotBefore)
+	} else {
+		log.L(ctx).Infof("Tracking transaction %s at nonce %s / %d", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64())
 	}
-	log.L(ctx).Infof("Tracking transaction %s at nonce %s / %d", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64())
 	sth.markInflightStale()
-
-	// Ok - we've spent it. The rest of the processing will be triggered off of lockedNonce
-	// completion adding this transaction to the pool (and/or the change event that comes in from
-	// FireFly core from the update to the transaction)
-	lockedNonce.spent = mtx
-	return mtx, nil
+	return nil
 }
 
 func (sth *simpleTransactionHandler) submitTX(ctx context.Context, mtx *apitypes.ManagedTX) (reason ffcapi.ErrorReason, err error) {
@@ -291,6 +482,7 @@ func (sth *simpleTransactionHandler) submitTX(ctx context.Context, mtx *apitypes
 		TransactionHeaders: mtx.TransactionHeaders,
 		GasPrice:           mtx.GasPrice,
 		TransactionData:    mtx.TransactionData,
+		PreSigned:          mtx.PreSigned,
 	}
 	sendTX.TransactionHeaders.Nonce = (*fftypes.FFBigInt)(mtx.Nonce.Int())
 	sendTX.TransactionHeaders.Gas = (*fftypes.FFBigInt)(mtx.Gas.Int())
@@ -301,12 +493,16 @@ func (sth *simpleTransactionHandler) submitTX(ctx context.Context, mtx *apitypes
 	sth.recordTransactionOperationDuration(ctx, mtx.Namespace(ctx), "transaction_submission", time.Since(transactionSendStartTime).Seconds())
 	if err == nil {
 		sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionSubmitTransaction, fftypes.JSONAnyPtr(`{"reason":"`+string(reason)+`"}`), nil)
-		mtx.TransactionHash = res.TransactionHash
+		mtx.SetTransactionHash(res.TransactionHash)
 		mtx.LastSubmit = fftypes.Now()
 	} else {
 		sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionSubmitTransaction, fftypes.JSONAnyPtr(`{"reason":"`+string(reason)+`"}`), fftypes.JSONAnyPtr(`{"error":"`+err.Error()+`"}`))
 		// We have some simple rules for handling reasons from the connector, which could be enhanced by extending the connector.
 		switch reason {
+		case ffcapi.ErrorReasonInsufficientFunds:
+			// We stop submitting transactions for the signer, until we see its balance has been topped up
+			sth.markUnderfunded(ctx, mtx.TransactionHeaders.From)
+			return reason, err
 		case ffcapi.ErrorKnownTransaction, ffcapi.ErrorReasonNonceTooLow:
 			// If we already have a transaction hash, this is fine - we just return as if we submitted it
 			if mtx.TransactionHash != "" {
@@ -334,20 +530,47 @@ func (sth *simpleTransactionHandler) processTransaction(ctx context.Context, mtx
 		return UpdateDelete, "", nil
 	}
 
-	if mtx.FirstSubmit == nil {
-		// Only calculate gas price here in the simple policy engine
-		mtx.GasPrice, err = sth.getGasPrice(ctx, sth.toolkit.Connector)
-		if err != nil {
-			sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionRetrieveGasPrice, nil, fftypes.JSONAnyPtr(`{"error":"`+err.Error()+`"}`))
+	// Once the deadline has passed without a receipt, we stop resubmitting and cancel the transaction
+	if mtx.Receipt == nil && mtx.CancelRequested == nil && mtx.Deadline != nil && time.Now().After(*mtx.Deadline.Time()) {
+		return sth.failAfterDeadline(ctx, mtx)
+	}
+
+	if mtx.CancelRequested != nil {
+		return sth.processCancellation(ctx, mtx)
+	}
+
+	// A scheduled transaction is not submitted until its not-before time, and is only then assigned a nonce if it does not have one
+	if notYetDue(mtx.NotBefore) {
+		return UpdateNo, "", nil
+	}
+	if awaitingNonce(mtx) {
+		// The nonce is not assigned on this cycle if another routine holds the nonce lock for the signer
+		if err := sth.assignScheduledNonce(ctx, mtx); err != nil || awaitingNonce(mtx) {
 			return UpdateNo, "", err
 		}
-		sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionRetrieveGasPrice, fftypes.JSONAnyPtr(`{"gasPrice":`+string(*mtx.GasPrice)+`}`), nil)
-		// Submit the first time
-		if reason, err := sth.submitTX(ctx, mtx); err != nil {
-			return UpdateYes, reason, err
-		}
-		mtx.FirstSubmit = mtx.LastSubmit
-		return UpdateYes, "", nil
+	}
+
+	// We do not submit any transactions for a signer that does not have the funds to pay for them
+	if mtx.Receipt == nil && sth.isUnderfunded(mtx.TransactionHeaders.From) {
+		return UpdateNo, "", nil
+	}
+
+	if mtx.FirstSubmit == nil {
+		return sth.withPolicyInfo(ctx, mtx, func(info *simplePolicyInfo) (update UpdateType, reason ffcapi.ErrorReason, err error) {
+			// Only calculate gas price here in the simple policy engine.
+			// If our last attempt was rejected as underpriced, we escalate from the price of that attempt.
+			if err := sth.updateGasPrice(ctx, mtx, info.Underpriced); err != nil {
+				return UpdateNo, "", err
+			}
+			// Submit the first time
+			reason, err = sth.submitTX(ctx, mtx)
+			info.Underpriced = reason == ffcapi.ErrorReasonTransactionUnderpriced && sth.gasPriceEscalation.canEscalate(mtx.GasPrice)
+			if err != nil {
+				return UpdateYes, reason, err
+			}
+			mtx.FirstSubmit = mtx.LastSubmit
+			return UpdateYes, "", nil
+		})
 
 	} else if mtx.Receipt == nil {
 
@@ -360,20 +583,24 @@ func (sth *simpleTransactionHandler) processTransaction(ctx context.Context, mtx
 				lastWarnTime = mtx.FirstSubmit
 			}
 			now := fftypes.Now()
-			if now.Time().Sub(*lastWarnTime.Time()) > sth.resubmitInterval {
-				secsSinceSubmit := float64(now.Time().Sub(*mtx.FirstSubmit.Time())) / float64(time.Second)
-				log.L(ctx).Infof("Transaction %s at nonce %s / %d has not been mined after %.2fs", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64(), secsSinceSubmit)
-				info.LastWarnTime = now
-				// We do a resubmit at this point - as it might no longer be in the TX pool
-				sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionTimeout, nil, nil)
+			if info.Underpriced || now.Time().Sub(*lastWarnTime.Time()) > sth.resubmitInterval {
+				if info.Underpriced {
+					// We do not wait for the resubmit interval when the node rejected our last submission as underpriced
+					log.L(ctx).Infof("Transaction %s at nonce %s / %d was rejected as underpriced with gas price %s", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64(), mtx.GasPrice)
+				} else {
+					secsSinceSubmit := float64(now.Time().Sub(*mtx.FirstSubmit.Time())) / float64(time.Second)
+					log.L(ctx).Infof("Transaction %s at nonce %s / %d has not been mined after %.2fs", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64(), secsSinceSubmit)
+					info.LastWarnTime = now
+					// We do a resubmit at this point - as it might no longer be in the TX pool
+					sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionTimeout, nil, nil)
+				}
 				sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusStale)
-				mtx.GasPrice, err = sth.getGasPrice(ctx, sth.toolkit.Connector)
-				if err != nil {
-					sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionRetrieveGasPrice, nil, fftypes.JSONAnyPtr(`{"error":"`+err.Error()+`"}`))
+				if err := sth.updateGasPrice(ctx, mtx, true); err != nil {
 					return UpdateNo, "", err
 				}
-				sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionRetrieveGasPrice, fftypes.JSONAnyPtr(`{"gasPrice":`+string(*mtx.GasPrice)+`}`), nil)
-				if reason, err := sth.submitTX(ctx, mtx); err != nil {
+				reason, err := sth.submitTX(ctx, mtx)
+				info.Underpriced = reason == ffcapi.ErrorReasonTransactionUnderpriced && sth.gasPriceEscalation.canEscalate(mtx.GasPrice)
+				if err != nil {
 					if reason != ffcapi.ErrorKnownTransaction {
 						return UpdateYes, reason, err
 					}
@@ -389,6 +616,147 @@ func (sth *simpleTransactionHandler) processTransaction(ctx context.Context, mtx
 	return UpdateNo, "", nil
 }
 
+// processResubmitRequest resubmits the transaction immediately, as requested through the API. The gas price is
+// either supplied explicitly, bumped by the requested percentage, or escalated according to our usual policy.
+func (sth *simpleTransactionHandler) processResubmitRequest(ctx context.Context, mtx *apitypes.ManagedTX, req *apitypes.ResubmitTransactionRequest) (update UpdateType, reason ffcapi.ErrorReason, err error) {
+	return sth.withPolicyInfo(ctx, mtx, func(info *simplePolicyInfo) (update UpdateType, reason ffcapi.ErrorReason, err error) {
+		switch {
+		case req.GasPrice != nil:
+			mtx.GasPrice = sth.gasPriceEscalation.applyCeiling(ctx, req.GasPrice)
+		case req.BumpPercentage != nil:
+			lastGasPrice := mtx.GasPrice
+			if err := sth.updateGasPrice(ctx, mtx, false); err != nil {
+				return UpdateNo, "", err
+			}
+			mtx.GasPrice = sth.gasPriceEscalation.withPercentage(*req.BumpPercentage).escalate(ctx, lastGasPrice, mtx.GasPrice)
+		default:
+			if err := sth.updateGasPrice(ctx, mtx, true); err != nil {
+				return UpdateNo, "", err
+			}
+		}
+		historyInfo, _ := json.Marshal(&apitypes.ResubmitTransactionRequest{
+			GasPrice:       mtx.GasPrice,
+			BumpPercentage: req.BumpPercentage,
+		})
+		sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionManualResubmit, fftypes.JSONAnyPtrBytes(historyInfo), nil)
+		log.L(ctx).Infof("Resubmitting transaction %s at nonce %s / %d on request, with gas price %s", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64(), mtx.GasPrice)
+
+		// The automatic resubmit interval restarts from now
+		info.LastWarnTime = fftypes.Now()
+		reason, err = sth.submitTX(ctx, mtx)
+		info.Underpriced = reason == ffcapi.ErrorReasonTransactionUnderpriced && sth.gasPriceEscalation.canEscalate(mtx.GasPrice)
+		if err != nil && reason != ffcapi.ErrorKnownTransaction {
+			return UpdateYes, reason, err
+		}
+		return UpdateYes, "", nil
+	})
+}
+
+// processCancellation stops resubmitting the transaction once cancellation is requested, and instead submits a
+// replacement at the same nonce. We then wait for either the transaction or the replacement to be mined.
+func (sth *simpleTransactionHandler) processCancellation(ctx context.Context, mtx *apitypes.ManagedTX) (update UpdateType, reason ffcapi.ErrorReason, err error) {
+	if mtx.Receipt != nil {
+		return UpdateNo, "", nil
+	}
+	if awaitingNonce(mtx) {
+		return sth.cancelScheduledTx(ctx, mtx)
+	}
+	return sth.withPolicyInfo(ctx, mtx, func(info *simplePolicyInfo) (update UpdateType, reason ffcapi.ErrorReason, err error) {
+		now := fftypes.Now()
+		if info.LastCancelTime != nil && now.Time().Sub(*info.LastCancelTime.Time()) <= sth.resubmitInterval {
+			return UpdateNo, "", nil
+		}
+		info.LastCancelTime = now
+		sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusCancelling)
+		reason, err = sth.submitCancellation(ctx, mtx)
+		return UpdateYes, reason, err
+	})
+}
+
+// failAfterDeadline cancels a transaction that was not mined before its deadline. It is only marked failed once
+// the cancellation is mined (see resolveCancellation), as until then the transaction itself could still be mined.
+func (sth *simpleTransactionHandler) failAfterDeadline(ctx context.Context, mtx *apitypes.ManagedTX) (update UpdateType, reason ffcapi.ErrorReason, err error) {
+	log.L(ctx).Warnf("Transaction %s at nonce %s / %d was not mined before its deadline %s", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64(), mtx.Deadline)
+	sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionDeadlineExceeded, fftypes.JSONAnyPtr(`{"deadline":"`+mtx.Deadline.String()+`"}`), nil)
+	// A scheduled transaction that was never assigned a nonce has nothing to cancel, and we cannot sign a
+	// replacement for a pre-signed transaction. So these are failed immediately.
+	if awaitingNonce(mtx) || mtx.PreSigned {
+		mtx.Status = apitypes.TxStatusFailed
+		mtx.ErrorMessage = i18n.NewError(ctx, tmmsgs.MsgTXDeadlineExceeded, mtx.Deadline).Error()
+		sth.toolkit.TXHistory.SetSubStatus(ctx, mtx, apitypes.TxSubStatusFailed)
+		return UpdateYes, "", nil
+	}
+	sth.mux.Lock()
+	mtx.CancelRequested = fftypes.Now()
+	sth.mux.Unlock()
+	return sth.processCancellation(ctx, mtx)
+}
+
+// cancelledAfterDeadline returns true if the cancellation of a transaction was requested because its deadline passed
+func cancelledAfterDeadline(mtx *apitypes.ManagedTX) bool {
+	return mtx.Deadline != nil && !mtx.CancelRequested.Time().Before(*mtx.Deadline.Time())
+}
+
+// submitCancellation submits a zero value transfer from the signing address to itself, at the same nonce as
+// the transaction. The node only accepts this as a replacement if the gas price is increased, and once it is
+// mined the original transaction can never be mined.
+func (sth *simpleTransactionHandler) submitCancellation(ctx context.Context, mtx *apitypes.ManagedTX) (reason ffcapi.ErrorReason, err error) {
+	gasPrice, err := sth.getGasPrice(ctx, sth.toolkit.Connector)
+	if err != nil {
+		sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionSubmitCancellation, nil, fftypes.JSONAnyPtr(`{"error":"`+err.Error()+`"}`))
+		return "", err
+	}
+	gasPrice = sth.gasPriceEscalation.escalateReplacement(ctx, mtx.GasPrice, gasPrice)
+	res, reason, err := sth.toolkit.Connector.TransactionSend(ctx, noopTransaction(mtx.TransactionHeaders.From, mtx.Nonce, gasPrice))
+	if err != nil {
+		sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionSubmitCancellation, fftypes.JSONAnyPtr(`{"reason":"`+string(reason)+`"}`), fftypes.JSONAnyPtr(`{"error":"`+err.Error()+`"}`))
+		return reason, err
+	}
+	log.L(ctx).Infof("Cancellation for transaction %s at nonce %s / %d submitted. Hash: %s", mtx.ID, mtx.TransactionHeaders.From, mtx.Nonce.Int64(), res.TransactionHash)
+	sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionSubmitCancellation, fftypes.JSONAnyPtr(`{"transactionHash":"`+res.TransactionHash+`","gasPrice":`+gasPrice.String()+`}`), nil)
+	// Any further replacement must be priced over the cancellation
+	mtx.GasPrice = gasPrice
+	mtx.CancellationHashes = append(mtx.CancellationHashes, res.TransactionHash)
+	mtx.LastSubmit = fftypes.Now()
+	return "", nil
+}
+
+// noopTransaction builds a zero value transfer from the signing address to itself, at the specified nonce
+func noopTransaction(signer string, nonce *fftypes.FFBigInt, gasPrice *fftypes.JSONAny) *ffcapi.TransactionSendRequest {
+	return &ffcapi.TransactionSendRequest{
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From:  signer,
+			To:    signer,
+			Nonce: nonce,
+			Gas:   fftypes.NewFFBigInt(cancellationGas),
+			Value: fftypes.NewFFBigInt(0),
+		},
+		GasPrice: gasPrice,
+	}
+}
+
+// updateGasPrice sets the gas price for the next submission of the transaction. When escalating, the price is
+// increased over the last submitted price according to the escalation policy, even if the gas oracle has not moved.
+func (sth *simpleTransactionHandler) updateGasPrice(ctx context.Context, mtx *apitypes.ManagedTX, escalate bool) error {
+	if mtx.PreSigned {
+		// The gas price of a pre-signed transaction is fixed by its signature
+		return nil
+	}
+	gasPrice, err := sth.getGasPrice(ctx, sth.toolkit.Connector)
+	if err != nil {
+		sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionRetrieveGasPrice, nil, fftypes.JSONAnyPtr(`{"error":"`+err.Error()+`"}`))
+		return err
+	}
+	gasPrice = sth.prioritizeGasPrice(ctx, mtx.Priority, gasPrice)
+	if escalate {
+		mtx.GasPrice = sth.gasPriceEscalation.escalate(ctx, mtx.GasPrice, gasPrice)
+	} else {
+		mtx.GasPrice = sth.gasPriceEscalation.applyCeiling(ctx, gasPrice)
+	}
+	sth.toolkit.TXHistory.AddSubStatusAction(ctx, mtx, apitypes.TxActionRetrieveGasPrice, gasPriceHistoryInfo(mtx.GasPrice), nil)
+	return nil
+}
+
 // getGasPrice either uses a fixed gas price, or invokes a gas station API
 func (sth *simpleTransactionHandler) getGasPrice(ctx context.Context, cAPI ffcapi.API) (gasPrice *fftypes.JSONAny, err error) {
 	if sth.gasOracleQueryValue != nil && sth.gasOracleLastQueryTime != nil &&
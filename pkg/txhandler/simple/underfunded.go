@@ -0,0 +1,129 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+)
+
+// underfundedSigner is a signer for which we have paused submission of transactions, as the node rejected one of
+// its transactions for insufficient funds. Retrying on every policy cycle would just be rejected again.
+type underfundedSigner struct {
+	since            *fftypes.FFTime
+	lastBalanceCheck time.Time
+	balance          *big.Int // the balance at the last check
+	pendingCost      *big.Int // the cost of the in-flight transactions at the last check
+}
+
+func (sth *simpleTransactionHandler) markUnderfunded(ctx context.Context, signer string) {
+	sth.mux.Lock()
+	defer sth.mux.Unlock()
+	if _, ok := sth.underfundedSigners[signer]; ok {
+		return
+	}
+	log.L(ctx).Warnf("Pausing submission of transactions for signer %s due to insufficient funds", signer)
+	sth.underfundedSigners[signer] = &underfundedSigner{
+		since:            fftypes.Now(),
+		lastBalanceCheck: time.Now(),
+	}
+	sth.setSignerUnderfundedMetric(ctx, signer, true)
+}
+
+func (sth *simpleTransactionHandler) isUnderfunded(signer string) bool {
+	sth.mux.Lock()
+	defer sth.mux.Unlock()
+	_, ok := sth.underfundedSigners[signer]
+	return ok
+}
+
+// checkUnderfundedSigners queries the balance of each underfunded signer at the configured interval, and resumes
+// submission for the signer once its balance covers the cost of all its in-flight transactions
+func (sth *simpleTransactionHandler) checkUnderfundedSigners(ctx context.Context) {
+	sth.mux.Lock()
+	signers := make([]string, 0, len(sth.underfundedSigners))
+	for signer, uf := range sth.underfundedSigners {
+		if time.Since(uf.lastBalanceCheck) >= sth.balanceCheckInterval {
+			uf.lastBalanceCheck = time.Now()
+			signers = append(signers, signer)
+		}
+	}
+	sth.mux.Unlock()
+
+	for _, signer := range signers {
+		res, reason, err := sth.toolkit.Connector.AddressBalance(ctx, &ffcapi.AddressBalanceRequest{
+			Address:  signer,
+			BlockTag: "latest",
+		})
+		if err != nil {
+			log.L(ctx).Errorf("Failed to check the balance of underfunded signer %s (reason=%s): %s", signer, reason, err)
+			continue
+		}
+		balance := res.Balance.Int()
+		pendingCost := sth.pendingCost(signer)
+
+		sth.mux.Lock()
+		if balance.Cmp(pendingCost) >= 0 {
+			log.L(ctx).Infof("Resuming submission of transactions for signer %s with balance %s (pendingCost=%s)", signer, balance, pendingCost)
+			delete(sth.underfundedSigners, signer)
+			sth.setSignerUnderfundedMetric(ctx, signer, false)
+		} else {
+			log.L(ctx).Debugf("Signer %s remains underfunded with balance %s (pendingCost=%s)", signer, balance, pendingCost)
+			uf := sth.underfundedSigners[signer]
+			uf.balance, uf.pendingCost = balance, pendingCost
+		}
+		sth.mux.Unlock()
+	}
+}
+
+// pendingCost is the maximum that could be spent by the in-flight transactions of a signer, that have not been mined.
+// Transactions that we have not yet calculated a gas price for only contribute their value.
+func (sth *simpleTransactionHandler) pendingCost(signer string) *big.Int {
+	cost := new(big.Int)
+	for _, p := range sth.inflight {
+		mtx := p.mtx
+		// Scheduled transactions that are not yet due do not need to be covered before we resume submission
+		if mtx.TransactionHeaders.From != signer || mtx.Receipt != nil || notYetDue(mtx.NotBefore) {
+			continue
+		}
+		if fees := parseGasFees(mtx.GasPrice); fees != nil && mtx.Gas != nil {
+			cost.Add(cost, new(big.Int).Mul(mtx.Gas.Int(), fees.limit()))
+		}
+		if mtx.TransactionHeaders.Value != nil {
+			cost.Add(cost, mtx.TransactionHeaders.Value.Int())
+		}
+	}
+	return cost
+}
+
+// setUnderfundedStatus adds whether submission of transactions is paused for the signer to its status
+func (sth *simpleTransactionHandler) setUnderfundedStatus(status *apitypes.SignerStatus) {
+	sth.mux.Lock()
+	defer sth.mux.Unlock()
+	if uf, ok := sth.underfundedSigners[status.Signer]; ok {
+		status.Underfunded = true
+		status.UnderfundedSince = uf.since
+		status.Balance = (*fftypes.FFBigInt)(uf.balance)
+		status.PendingCost = (*fftypes.FFBigInt)(uf.pendingCost)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
+	"github.com/hyperledger/firefly-transaction-manager/pkg/ffcapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInsufficientFundsConfig(t *testing.T) {
+	f, _, _, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `12345`)
+	conf.SubSection(InsufficientFundsConfig).Set(InsufficientFundsBalanceCheckInterval, "5m")
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+
+	sth := th.(*simpleTransactionHandler)
+	assert.Equal(t, 5*time.Minute, sth.balanceCheckInterval)
+}
+
+func TestInsufficientFundsPauseAndResume(t *testing.T) {
+	f, tk, mfc, conf := newTestTransactionHandlerFactory(t)
+	conf.Set(FixedGasPrice, `1000`)
+	conf.SubSection(InsufficientFundsConfig).Set(InsufficientFundsBalanceCheckInterval, "1h")
+	th, err := f.NewTransactionHandler(context.Background(), conf)
+	assert.NoError(t, err)
+	sth := th.(*simpleTransactionHandler)
+	sth.ctx = context.Background()
+	sth.Init(sth.ctx, tk)
+	ctx := context.Background()
+
+	mtx := &apitypes.ManagedTX{
+		ID:      "ns1:" + fftypes.NewUUID().String(),
+		Created: fftypes.Now(),
+		Status:  apitypes.TxStatusPending,
+		Nonce:   fftypes.NewFFBigInt(1),
+		Gas:     fftypes.NewFFBigInt(100),
+		TransactionHeaders: ffcapi.TransactionHeaders{
+			From:  "0xaaaaa",
+			Value: fftypes.NewFFBigInt(5),
+		},
+	}
+	sth.inflight = []*pendingState{
+		{mtx: mtx},
+		// A later transaction that has not been given a gas price, and so does not add to the cost
+		{mtx: newTestNonceGapTX("0xaaaaa", 2, apitypes.TxStatusPending)},
+		// Transactions that are mined, or are for another signer, do not add to the cost
+		{mtx: &apitypes.ManagedTX{
+			TransactionHeaders: ffcapi.TransactionHeaders{From: "0xaaaaa", Value: fftypes.NewFFBigInt(1000)},
+			Receipt:            &ffcapi.TransactionReceiptResponse{},
+		}},
+		{mtx: &apitypes.ManagedTX{
+			TransactionHeaders: ffcapi.TransactionHeaders{From: "0xbbbbb", Value: fftypes.NewFFBigInt(1000)},
+		}},
+	}
+
+	mfc.On("TransactionSend", mock.Anything, mock.Anything).Return(nil, ffcapi.ErrorReasonInsufficientFunds, fmt.Errorf("insufficient funds")).Once()
+	_, reason, err := sth.processTransaction(ctx, mtx)
+	assert.Regexp(t, "insufficient funds", err)
+	assert.Equal(t, ffcapi.ErrorReasonInsufficientFunds, reason)
+	assert.True(t, sth.isUnderfunded("0xaaaaa"))
+	assert.False(t, sth.isUnderfunded("0xbbbbb"))
+
+	// No further submissions while the signer is paused
+	update, _, err := sth.processTransaction(ctx, mtx)
+	assert.NoError(t, err)
+	assert.Equal(t, UpdateNo, update)
+	sth.markUnderfunded(ctx, "0xaaaaa")
+
+	// The balance is not checked until the interval has passed
+	sth.checkUnderfundedSigners(ctx)
+	sth.balanceCheckInterval = 0
+
+	mfc.On("AddressBalance", mock.Anything, &ffcapi.AddressBalanceRequest{Address: "0xaaaaa", BlockTag: "latest"}).Return(nil, ffcapi.ErrorReason(""), fmt.Errorf("pop")).Once()
+	sth.checkUnderfundedSigners(ctx)
+	assert.True(t, sth.isUnderfunded("0xaaaaa"))
+
+	mfc.On("AddressBalance", mock.Anything, mock.Anything).Return(&ffcapi.AddressBalanceResponse{
+		Balance: fftypes.NewFFBigInt(100004),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	sth.checkUnderfundedSigners(ctx)
+	assert.True(t, sth.isUnderfunded("0xaaaaa"))
+
+	status := &apitypes.SignerStatus{Signer: "0xaaaaa"}
+	sth.setUnderfundedStatus(status)
+	assert.True(t, status.Underfunded)
+	assert.NotNil(t, status.UnderfundedSince)
+	assert.Equal(t, int64(100004), status.Balance.Int64())
+	assert.Equal(t, int64(100005), status.PendingCost.Int64())
+
+	// Resumed once the balance covers the gas and value of the in-flight transactions
+	mfc.On("AddressBalance", mock.Anything, mock.Anything).Return(&ffcapi.AddressBalanceResponse{
+		Balance: fftypes.NewFFBigInt(100005),
+	}, ffcapi.ErrorReason(""), nil).Once()
+	sth.checkUnderfundedSigners(ctx)
+	assert.False(t, sth.isUnderfunded("0xaaaaa"))
+
+	status = &apitypes.SignerStatus{Signer: "0xaaaaa"}
+	sth.setUnderfundedStatus(status)
+	assert.False(t, status.Underfunded)
+
+	mfc.AssertExpectations(t)
+}
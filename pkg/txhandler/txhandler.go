@@ -103,3 +103,50 @@ type TransactionHandler interface {
 	// HandleTransactionReceiptReceived - handles receipt of blockchain transactions for a managed transaction
 	HandleTransactionReceiptReceived(ctx context.Context, txID string, receipt *ffcapi.TransactionReceiptResponse) (err error)
 }
+
+// The following interfaces are optional capabilities of a Transaction Handler, in addition to the TransactionHandler interface.
+// The Transaction Manager checks whether the handler implements each of them, and the API requests that require a capability
+// the handler does not have are rejected as not supported. So existing handlers do not need to implement them all.
+
+// CancelByReplacementHandler is implemented by a Transaction Handler that can cancel a transaction that might already have been submitted
+type CancelByReplacementHandler interface {
+	// HandleCancelTransactionByReplacement - handles event of cancelling a managed transaction that might already have been submitted,
+	//                                        by replacing it at the same nonce so that it can never be mined
+	HandleCancelTransactionByReplacement(ctx context.Context, txID string) (mtx *apitypes.ManagedTX, err error)
+}
+
+// ResubmitHandler is implemented by a Transaction Handler that can resubmit a transaction on request
+type ResubmitHandler interface {
+	// HandleResubmitTransaction - handles event of an operator requesting immediate resubmission of a managed transaction
+	HandleResubmitTransaction(ctx context.Context, txID string, req *apitypes.ResubmitTransactionRequest) (mtx *apitypes.ManagedTX, err error)
+}
+
+// SignerStatusHandler is implemented by a Transaction Handler that can report on the nonces of a signing address
+type SignerStatusHandler interface {
+	// GetSignerStatus - returns the status of the nonces of a signing address, including any gaps that prevent its transactions being mined
+	GetSignerStatus(ctx context.Context, signer string) (status *apitypes.SignerStatus, err error)
+}
+
+// SignerNonceHandler is implemented by a Transaction Handler that allows the next nonce of a signing address to be managed
+type SignerNonceHandler interface {
+	// GetSignerNonceStatus - returns the next nonce for a signing address, according to each of the sources the transaction handler uses
+	GetSignerNonceStatus(ctx context.Context, signer string) (status *apitypes.SignerNonceStatus, err error)
+	// HandleResetSignerNonce - handles event of re-syncing the next nonce for a signing address from the blockchain node, for example
+	//                          when the signing key has also been used by another system
+	HandleResetSignerNonce(ctx context.Context, signer string) (status *apitypes.SignerNonceStatus, err error)
+	// HandleReserveSignerNonces - handles event of reserving a block of nonces for a signing address, for use outside of the transaction manager
+	HandleReserveSignerNonces(ctx context.Context, signer string, req *apitypes.NonceReservationRequest) (reservation *apitypes.NonceReservation, err error)
+}
+
+// TransactionBatchHandler is implemented by a Transaction Handler that can submit a batch of transactions
+type TransactionBatchHandler interface {
+	// HandleNewTransactionBatch - handles event of adding a batch of new transactions and smart contract deployments onto blockchain,
+	//                             returning a result for each item in the batch, as each item succeeds or fails on its own
+	HandleNewTransactionBatch(ctx context.Context, items []*apitypes.TransactionBatchItem) (results []*apitypes.TransactionBatchResult, err error)
+}
+
+// RawTransactionHandler is implemented by a Transaction Handler that can submit transactions that have already been signed
+type RawTransactionHandler interface {
+	// HandleNewRawTransaction - handles event of adding a new transaction that has already been signed onto blockchain, which has its nonce set by the signature
+	HandleNewRawTransaction(ctx context.Context, txReq *apitypes.RawTransactionRequest) (mtx *apitypes.ManagedTX, err error)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/hyperledger/firefly-common/pkg/config"
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-transaction-manager/internal/persistence"
 	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 )
@@ -31,15 +32,64 @@ type Manager interface {
 	CurrentSubStatus(ctx context.Context, mtx *apitypes.ManagedTX) *apitypes.TxHistoryStateTransitionEntry
 	SetSubStatus(ctx context.Context, mtx *apitypes.ManagedTX, subStatus apitypes.TxSubStatus)
 	AddSubStatusAction(ctx context.Context, mtx *apitypes.ManagedTX, action apitypes.TxAction, info *fftypes.JSONAny, err *fftypes.JSONAny)
+	WriteNewTransactionHistory(ctx context.Context, mtx *apitypes.ManagedTX)
 }
 
 type manager struct {
-	maxHistoryCount int
+	enabled     bool
+	persistence persistence.TransactionHistoryPersistence
 }
 
-func NewTxHistoryManager(_ context.Context) Manager {
+func NewTxHistoryManager(ctx context.Context, p persistence.TransactionHistoryPersistence) Manager {
+	enabled := config.GetBool(tmconfig.TransactionsHistoryEnabled)
+	// History is no longer limited to a maximum number of entries, so the deprecated count only turns it off
+	if config.IsSet(tmconfig.DeprecatedTransactionsMaxHistoryCount) {
+		log.L(ctx).Warnf("Configuration 'transactions.maxHistoryCount' is deprecated. Please use 'transactions.history.enabled' instead")
+		enabled = enabled && config.GetInt(tmconfig.DeprecatedTransactionsMaxHistoryCount) > 0
+	}
 	return &manager{
-		maxHistoryCount: config.GetInt(tmconfig.TransactionsMaxHistoryCount),
+		enabled:     enabled,
+		persistence: p,
+	}
+}
+
+// writeHistory stores a history entry as a record separate from the transaction. History is informational,
+// so a failure is logged rather than failing the processing of the transaction.
+//
+// A transaction is assigned its sequence ID when it is first persisted. Until then the history is only
+// recorded on the transaction, and is written by WriteNewTransactionHistory once the transaction has
+// been persisted. Otherwise a failure to persist the transaction (such as a duplicate ID) would leave
+// records against an existing transaction, or orphaned.
+func (h *manager) writeHistory(ctx context.Context, mtx *apitypes.ManagedTX, entry *apitypes.TxHistoryStateTransitionEntry) {
+	if mtx.SequenceID == "" {
+		return
+	}
+	if err := h.persistence.WriteTransactionHistory(ctx, &apitypes.TxHistoryRecord{
+		TransactionID:                 mtx.ID,
+		TxHistoryStateTransitionEntry: *entry,
+	}); err != nil {
+		log.L(ctx).Errorf("Failed to write history for transaction %s: %s", mtx.ID, err)
+	}
+}
+
+// writeInlineHistory moves entries stored inline by earlier versions, which kept the whole history
+// on the transaction, into separate history records
+func (h *manager) writeInlineHistory(ctx context.Context, mtx *apitypes.ManagedTX) {
+	for _, entry := range mtx.History {
+		if entry.ID == nil {
+			entry.ID = apitypes.NewULID()
+			h.writeHistory(ctx, mtx, entry)
+		}
+	}
+}
+
+// WriteNewTransactionHistory writes the history recorded for a new transaction before it was persisted
+func (h *manager) WriteNewTransactionHistory(ctx context.Context, mtx *apitypes.ManagedTX) {
+	if !h.enabled {
+		return
+	}
+	for _, entry := range mtx.History {
+		h.writeHistory(ctx, mtx, entry)
 	}
 }
 
@@ -56,13 +106,12 @@ func (h *manager) CurrentSubStatus(_ context.Context, mtx *apitypes.ManagedTX) *
 // been in pending state for a given period of time. In order to progress the transaction
 // while it's in a given sub-status, certain actions might be taken (such as retrieving
 // the latest gas price for the chain). See AddSubStatusAction(). Since a transaction
-// might go through many sub-status changes before being confirmed on chain the list of
-// entries is capped at the configured number and FIFO approach used to keep within that cap.
+// might go through many sub-status changes before being confirmed on chain, each entry is
+// stored as a separate history record, and only the current entry is retained on the transaction.
+//
+// If history is turned off, the current entry is still retained on the transaction (without any
+// records, actions or summary) so transactions can be filtered on their current sub-status.
 func (h *manager) SetSubStatus(ctx context.Context, mtx *apitypes.ManagedTX, subStatus apitypes.TxSubStatus) {
-	if h.maxHistoryCount <= 0 {
-		// if history is turned off, it's a no op
-		return
-	}
 	// See if the status being transitioned to is the same as the current status.
 	// If so, there's nothing to do.
 	if len(mtx.History) > 0 {
@@ -71,22 +120,28 @@ func (h *manager) SetSubStatus(ctx context.Context, mtx *apitypes.ManagedTX, sub
 		}
 		log.L(ctx).Debugf("State transition to sub-status %s", subStatus)
 	}
+	if !h.enabled {
+		mtx.History = []*apitypes.TxHistoryStateTransitionEntry{{
+			Time:    fftypes.Now(),
+			Status:  subStatus,
+			Actions: make([]*apitypes.TxHistoryActionEntry, 0),
+		}}
+		return
+	}
+	h.writeInlineHistory(ctx, mtx)
 
 	// If this is a change in status add a new record
 	newStatus := &apitypes.TxHistoryStateTransitionEntry{
+		ID:      apitypes.NewULID(),
 		Time:    fftypes.Now(),
 		Status:  subStatus,
 		Actions: make([]*apitypes.TxHistoryActionEntry, 0),
 	}
-	mtx.History = append(mtx.History, newStatus)
-
-	if len(mtx.History) > h.maxHistoryCount {
-		// Need to trim the oldest record
-		mtx.History = mtx.History[1:]
-	}
+	h.writeHistory(ctx, mtx, newStatus)
+	mtx.History = []*apitypes.TxHistoryStateTransitionEntry{newStatus}
 
-	// As we have a possibly indefinite list of sub-status records (which might be a long list and early entries
-	// get purged at some point) we keep a separate list of all the discrete types of sub-status
+	// As we have a possibly indefinite list of sub-status records (which are not stored with the transaction)
+	// we keep a separate list of all the discrete types of sub-status
 	// and action we've we've ever seen for this transaction along with a count of them. This means an early sub-status
 	// (e.g. "queued") followed by 100s of different sub-status types will still be recorded
 	for _, statusType := range mtx.HistorySummary {
@@ -129,7 +184,7 @@ func jsonOrString(value *fftypes.JSONAny) *fftypes.JSONAny {
 // HTTP 4xx return code from a gas oracle. There is also an information field to record
 // arbitrary data about the action, for example the gas price retrieved from an oracle.
 func (h *manager) AddSubStatusAction(ctx context.Context, mtx *apitypes.ManagedTX, action apitypes.TxAction, info *fftypes.JSONAny, err *fftypes.JSONAny) {
-	if h.maxHistoryCount <= 0 {
+	if !h.enabled {
 		// if history is turned off, it's a no op
 		return
 	}
@@ -140,6 +195,7 @@ func (h *manager) AddSubStatusAction(ctx context.Context, mtx *apitypes.ManagedT
 		h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
 
 	}
+	h.writeInlineHistory(ctx, mtx)
 
 	// See if this action exists in the list already since we only want to update the single entry, not
 	// add a new one
@@ -183,6 +239,7 @@ func (h *manager) AddSubStatusAction(ctx context.Context, mtx *apitypes.ManagedT
 
 		currentSubStatus.Actions = append(currentSubStatus.Actions, newAction)
 	}
+	h.writeHistory(ctx, mtx, currentSubStatus)
 
 	// Check if the history summary needs updating
 	for _, actionType := range mtx.HistorySummary {
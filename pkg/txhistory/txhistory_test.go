@@ -25,19 +25,39 @@ import (
 	"github.com/hyperledger/firefly-common/pkg/config"
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-transaction-manager/internal/tmconfig"
+	"github.com/hyperledger/firefly-transaction-manager/mocks/persistencemocks"
 	"github.com/hyperledger/firefly-transaction-manager/pkg/apitypes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func newTestTxHistoryManager(t *testing.T) (context.Context, *manager, func()) {
 	tmconfig.Reset()
 	ctx, cancelCtx := context.WithCancel(context.Background())
-	h := NewTxHistoryManager(ctx).(*manager)
+	mp := &persistencemocks.TransactionHistoryPersistence{}
+	mp.On("WriteTransactionHistory", mock.Anything, mock.Anything).Return(nil).Maybe()
+	h := NewTxHistoryManager(ctx, mp).(*manager)
 	return ctx, h, cancelCtx
 }
 
+// storedHistory returns the latest version of each history record written, in the order they were first written
+func storedHistory(h *manager) []*apitypes.TxHistoryRecord {
+	records := []*apitypes.TxHistoryRecord{}
+	byID := map[string]int{}
+	for _, c := range h.persistence.(*persistencemocks.TransactionHistoryPersistence).Calls {
+		r := c.Arguments[1].(*apitypes.TxHistoryRecord)
+		if i, ok := byID[r.ID.String()]; ok {
+			records[i] = r
+		} else {
+			byID[r.ID.String()] = len(records)
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
 func TestManagedTXSubStatus(t *testing.T) {
-	mtx := &apitypes.ManagedTX{}
+	mtx := &apitypes.ManagedTX{SequenceID: "seq1"}
 	ctx, h, done := newTestTxHistoryManager(t)
 	defer done()
 
@@ -51,28 +71,31 @@ func TestManagedTXSubStatus(t *testing.T) {
 	}
 
 	assert.Equal(t, 1, len(mtx.History))
+	assert.Equal(t, 1, len(storedHistory(h)))
 	assert.Equal(t, "Received", string(h.CurrentSubStatus(ctx, mtx).Status))
 
 	// Adding a different type of sub-status should result in
-	// a new entry in the list
+	// a new entry in the stored history, with only the current entry on the transaction
 	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusTracking)
 
-	assert.Equal(t, 2, len(mtx.History))
+	assert.Equal(t, 1, len(mtx.History))
+	assert.Equal(t, 2, len(storedHistory(h)))
+	assert.Equal(t, "Tracking", string(h.CurrentSubStatus(ctx, mtx).Status))
 
-	// Even if many new types are added we shouldn't go over the
-	// configured upper limit
+	// Every transition is stored, without growing the transaction
 	for i := 0; i < 100; i++ {
 		h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusStale)
 		h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusTracking)
 	}
-	assert.Equal(t, 50, len(mtx.History))
+	assert.Equal(t, 1, len(mtx.History))
+	assert.Equal(t, 202, len(storedHistory(h)))
 
 }
 
 func TestManagedTXSubStatusRepeat(t *testing.T) {
 	ctx, h, done := newTestTxHistoryManager(t)
 	defer done()
-	mtx := &apitypes.ManagedTX{}
+	mtx := &apitypes.ManagedTX{SequenceID: "seq1"}
 
 	// Add a sub-status
 	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
@@ -81,19 +104,19 @@ func TestManagedTXSubStatusRepeat(t *testing.T) {
 
 	// Add another sub-status
 	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusTracking)
-	assert.Equal(t, 2, len(mtx.History))
+	assert.Equal(t, 2, len(storedHistory(h)))
 	assert.Equal(t, 2, len(mtx.HistorySummary))
 
 	// Add another that we've seen before
 	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
-	assert.Equal(t, 3, len(mtx.History))        // This goes up
+	assert.Equal(t, 3, len(storedHistory(h)))   // This goes up
 	assert.Equal(t, 2, len(mtx.HistorySummary)) // This doesn't
 }
 
 func TestManagedTXSubStatusAction(t *testing.T) {
 	ctx, h, done := newTestTxHistoryManager(t)
 	defer done()
-	mtx := &apitypes.ManagedTX{}
+	mtx := &apitypes.ManagedTX{SequenceID: "seq1"}
 
 	// Add at least 1 sub-status
 	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
@@ -170,7 +193,7 @@ func TestManagedTXSubStatusAction(t *testing.T) {
 func TestManagedTXSubStatusInvalidJSON(t *testing.T) {
 	ctx, h, done := newTestTxHistoryManager(t)
 	defer done()
-	mtx := &apitypes.ManagedTX{}
+	mtx := &apitypes.ManagedTX{SequenceID: "seq1"}
 
 	reason := "\"cannot-marshall\""
 
@@ -185,35 +208,137 @@ func TestManagedTXSubStatusInvalidJSON(t *testing.T) {
 
 }
 
-func TestManagedTXSubStatusMaxEntries(t *testing.T) {
+func TestManagedTXSubStatusAllEntriesStored(t *testing.T) {
 	ctx, h, done := newTestTxHistoryManager(t)
 	defer done()
-	mtx := &apitypes.ManagedTX{}
+	mtx := &apitypes.ManagedTX{ID: "tx1", SequenceID: "seq1"}
 	var nextSubStatus apitypes.TxSubStatus
 
-	// Create 100 unique sub-status strings. We should only keep the
-	// first 50
+	// Create 100 unique sub-status strings. We should store all of them, in order
 	for i := 0; i < 100; i++ {
 		nextSubStatus = apitypes.TxSubStatus(fmt.Sprint(i))
 		h.SetSubStatus(ctx, mtx, nextSubStatus)
 	}
 
-	assert.Equal(t, 50, len(mtx.History))
+	records := storedHistory(h)
+	assert.Equal(t, 100, len(records))
+	for i, r := range records {
+		assert.Equal(t, "tx1", r.TransactionID)
+		assert.Equal(t, apitypes.TxSubStatus(fmt.Sprint(i)), r.Status)
+		if i > 0 {
+			assert.Greater(t, r.ID.String(), records[i-1].ID.String())
+		}
+	}
+	assert.Equal(t, 1, len(mtx.History))
+	assert.Equal(t, records[99].ID, mtx.History[0].ID)
+
+}
+
+func TestManagedTXInlineHistoryMovedToRecords(t *testing.T) {
+	ctx, h, done := newTestTxHistoryManager(t)
+	defer done()
 
+	// A transaction written by an earlier version, with its history inline
+	mtx := &apitypes.ManagedTX{
+		ID:         "tx1",
+		SequenceID: "seq1",
+		History: []*apitypes.TxHistoryStateTransitionEntry{
+			{Status: apitypes.TxSubStatusReceived, Time: fftypes.Now()},
+			{Status: apitypes.TxSubStatusTracking, Time: fftypes.Now()},
+		},
+	}
+
+	h.AddSubStatusAction(ctx, mtx, apitypes.TxActionRetrieveGasPrice, nil, nil)
+	records := storedHistory(h)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, apitypes.TxSubStatusReceived, records[0].Status)
+	assert.Equal(t, apitypes.TxSubStatusTracking, records[1].Status)
+	assert.Equal(t, 1, len(records[1].Actions))
+
+	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusStale)
+	assert.Equal(t, 3, len(storedHistory(h)))
+	assert.Equal(t, 1, len(mtx.History))
 }
 
-func TestMaxHistoryCountSetToZero(t *testing.T) {
+func TestNewTransactionHistoryWrittenOncePersisted(t *testing.T) {
+	ctx, h, done := newTestTxHistoryManager(t)
+	defer done()
+
+	// The history of a new transaction is not written until it has been persisted
+	mtx := &apitypes.ManagedTX{ID: "tx1"}
+	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
+	h.AddSubStatusAction(ctx, mtx, apitypes.TxActionAssignNonce, nil, nil)
+	assert.Empty(t, storedHistory(h))
+	assert.Equal(t, apitypes.TxSubStatusReceived, h.CurrentSubStatus(ctx, mtx).Status)
+
+	mtx.SequenceID = "seq1"
+	h.WriteNewTransactionHistory(ctx, mtx)
+	records := storedHistory(h)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "tx1", records[0].TransactionID)
+	assert.Equal(t, apitypes.TxSubStatusReceived, records[0].Status)
+	assert.Equal(t, apitypes.TxActionAssignNonce, records[0].Actions[0].Action)
+}
+
+func TestManagedTXHistoryWriteFail(t *testing.T) {
+	tmconfig.Reset()
+	ctx := context.Background()
+	mp := &persistencemocks.TransactionHistoryPersistence{}
+	mp.On("WriteTransactionHistory", mock.Anything, mock.Anything).Return(fmt.Errorf("pop"))
+	h := NewTxHistoryManager(ctx, mp).(*manager)
+	mtx := &apitypes.ManagedTX{SequenceID: "seq1"}
+
+	// Failures to store history do not prevent the transaction being updated
+	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
+	assert.Equal(t, apitypes.TxSubStatusReceived, h.CurrentSubStatus(ctx, mtx).Status)
+	mp.AssertExpectations(t)
+}
+
+func TestHistoryEnabledConfig(t *testing.T) {
+	ctx := context.Background()
+
+	tmconfig.Reset()
+	assert.True(t, NewTxHistoryManager(ctx, nil).(*manager).enabled)
+
+	config.Set(tmconfig.TransactionsHistoryEnabled, false)
+	assert.False(t, NewTxHistoryManager(ctx, nil).(*manager).enabled)
+
+	// The deprecated maximum count is still honoured, but only to turn off the history
+	tmconfig.Reset()
+	config.Set(tmconfig.DeprecatedTransactionsMaxHistoryCount, 50)
+	assert.True(t, NewTxHistoryManager(ctx, nil).(*manager).enabled)
+
+	config.Set(tmconfig.DeprecatedTransactionsMaxHistoryCount, 0)
+	assert.False(t, NewTxHistoryManager(ctx, nil).(*manager).enabled)
+
+	config.Set(tmconfig.DeprecatedTransactionsMaxHistoryCount, 50)
+	config.Set(tmconfig.TransactionsHistoryEnabled, false)
+	assert.False(t, NewTxHistoryManager(ctx, nil).(*manager).enabled)
+}
+
+func TestHistoryDisabled(t *testing.T) {
 	tmconfig.Reset()
-	config.Set(tmconfig.TransactionsMaxHistoryCount, 0)
+	config.Set(tmconfig.TransactionsHistoryEnabled, false)
 	ctx, cancelCtx := context.WithCancel(context.Background())
-	h := NewTxHistoryManager(ctx).(*manager)
+	mp := &persistencemocks.TransactionHistoryPersistence{}
+	h := NewTxHistoryManager(ctx, mp).(*manager)
 	defer cancelCtx()
 	mtx := &apitypes.ManagedTX{}
 
 	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusReceived)
 	h.AddSubStatusAction(ctx, mtx, apitypes.TxActionSubmitTransaction, nil, nil)
-	assert.Equal(t, 0, len(mtx.History))
+	h.WriteNewTransactionHistory(ctx, mtx)
 	assert.Equal(t, 0, len(mtx.HistorySummary))
+	mp.AssertExpectations(t)
+
+	// Only the current sub-status is retained, with no actions
+	assert.Equal(t, 1, len(mtx.History))
+	assert.Nil(t, mtx.History[0].ID)
+	assert.Empty(t, mtx.History[0].Actions)
+	assert.Equal(t, apitypes.TxSubStatusReceived, h.CurrentSubStatus(ctx, mtx).Status)
+	h.SetSubStatus(ctx, mtx, apitypes.TxSubStatusTracking)
+	assert.Equal(t, 1, len(mtx.History))
+	assert.Equal(t, apitypes.TxSubStatusTracking, h.CurrentSubStatus(ctx, mtx).Status)
 
 }
 